@@ -0,0 +1,35 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+const (
+	configPrint    = "print"
+	configValidate = "validate"
+	configUsage    = "usage: config print | validate"
+)
+
+func runConfig(cfg *config.Config, args []string) error {
+	if len(args) != 1 {
+		return errors.New(configUsage)
+	}
+
+	switch args[0] {
+	case configPrint:
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(cfg.Redacted())
+	case configValidate:
+		// LoadConfig has already reported any problem with the config.
+		fmt.Println("Config is valid.")
+		return nil
+	default:
+		return errors.New(configUsage)
+	}
+}
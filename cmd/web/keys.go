@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base64"
+	"errors"
+	"flag"
+	"fmt"
+	"slices"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/service"
+)
+
+const rotateKeysUsage = "usage: rotate-keys [-batch N]"
+
+// Returns the keyring of the encryption section of the config.
+func newKeyring(cfg *config.Config) (*security.Keyring, error) {
+	c := cfg.Encryption
+
+	// Sorted so that errors do not depend on the map order.
+	ids := make([]string, 0, len(c.Keys))
+	for id := range c.Keys {
+		ids = append(ids, id)
+	}
+	slices.Sort(ids)
+
+	keys := make([]security.EncryptionKey, 0, len(ids))
+	for _, id := range ids {
+		key, err := base64.StdEncoding.DecodeString(c.Keys[id].Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", id, err)
+		}
+		keys = append(keys, security.EncryptionKey{ID: id, Algorithm: c.Keys[id].Algorithm, Key: key})
+	}
+
+	return security.NewKeyring(c.PrimaryKey, keys)
+}
+
+// Encrypts every encrypted column again with the primary key, after it was
+// changed or a key was compromised. Keys that no longer encrypt anything can
+// then be removed from the config.
+func runRotateKeys(ctx context.Context, keyring *security.Keyring, db *sql.DB, args []string) error {
+	flags := flag.NewFlagSet("rotate-keys", flag.ContinueOnError)
+	batch := flags.Int("batch", 500, "Rows re-encrypted per transaction")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 || *batch < 1 {
+		return errors.New(rotateKeysUsage)
+	}
+
+	svc := service.NewKeyRotationService(repository.NewRepository(db, keyring), keyring)
+	for _, col := range repository.EncryptedColumns {
+		// The batches before a failure stay committed, so the progress is
+		// printed either way.
+		result, err := svc.RotateKeys(ctx, col, *batch)
+		fmt.Printf("%s: %d re-encrypted of %d\n", col, result.Rotated, result.Scanned)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -2,27 +2,19 @@ package main
 
 import (
 	"context"
-	"database/sql"
 	"errors"
 	"flag"
 	"fmt"
 	"log/slog"
-	"net/http"
 	"os"
 	"os/signal"
 	"syscall"
-	"time"
 
-	"github.com/ferdiebergado/goexpress"
 	"github.com/ferdiebergado/gopherkit/env"
 	"github.com/ferdiebergado/goweb/internal/config"
-	"github.com/ferdiebergado/goweb/internal/handler"
 	"github.com/ferdiebergado/goweb/internal/infra/db"
 	"github.com/ferdiebergado/goweb/internal/pkg/environment"
 	"github.com/ferdiebergado/goweb/internal/pkg/logging"
-	"github.com/ferdiebergado/goweb/internal/pkg/security"
-	"github.com/ferdiebergado/goweb/internal/pkg/validation"
-	"github.com/go-playground/validator/v10"
 	_ "github.com/jackc/pgx/v5/stdlib"
 )
 
@@ -31,10 +23,37 @@ const (
 	envDev  = "development"
 	envProd = "production"
 	cfgFile = "config.json"
-	fmtAddr = ":%d"
 )
 
-var validate *validator.Validate
+// Set at build time with -ldflags="-X main.version=..."
+var version = "dev"
+
+const usage = `Usage: goweb [-cfg FILE] <command> [arguments]
+
+Commands:
+  serve                                  Start the web server (default)
+  migrate up [N]|down [N]|status|force VERSION|new NAME
+                                         Manage the database schema
+  user create|list|disable|set-password|assign-role|revoke-role|import
+                                         Manage users and their roles
+  config print|validate                  Print (with secrets redacted) or validate the config
+  password calibrate                     Pick the password hashing cost for this machine
+  rotate-keys [-batch N]                 Re-encrypt the encrypted columns with the primary key
+  version                                Print the version
+
+Flags:
+`
+
+const (
+	cmdServe    = "serve"
+	cmdMigrate  = "migrate"
+	cmdUser     = "user"
+	cmdConfig   = "config"
+	cmdPassword = "password"
+	cmdRotate   = "rotate-keys"
+	cmdVersion  = "version"
+	cmdHelp     = "help"
+)
 
 func main() {
 	signalCtx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
@@ -43,50 +62,104 @@ func main() {
 		slog.Info("Signal context cleanup complete.")
 	}()
 
-	if err := run(signalCtx); err != nil {
+	if err := run(signalCtx, os.Args[1:]); err != nil {
+		var cfgErr *config.ValidationError
+		if errors.As(err, &cfgErr) {
+			// One problem per line so that they can all be fixed in one go.
+			fmt.Fprintf(os.Stderr, "Invalid config %s:\n", cfgErr.Path)
+			for _, p := range cfgErr.Problems {
+				fmt.Fprintf(os.Stderr, "  %s\n", p)
+			}
+		}
 		slog.Error("fatal error", "reason", err)
 		os.Exit(1)
 	}
 }
 
-func run(ctx context.Context) error {
+func run(ctx context.Context, args []string) error {
+	flags := flag.NewFlagSet("goweb", flag.ContinueOnError)
+	cf := flags.String("cfg", cfgFile, "Config file")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return nil
+		}
+		return err
+	}
+
+	name, args := cmdServe, flags.Args()
+	if len(args) > 0 {
+		name, args = args[0], args[1:]
+	}
+
+	switch name {
+	case cmdServe, cmdMigrate, cmdUser, cmdConfig, cmdPassword, cmdRotate:
+	case cmdVersion:
+		fmt.Println(version)
+		return nil
+	case cmdHelp:
+		flags.Usage()
+		return nil
+	default:
+		flags.Usage()
+		return fmt.Errorf("unknown command %q", name)
+	}
+
 	appEnv, err := setupEnvironment()
 	if err != nil {
 		return err
 	}
 
-	logging.SetLogger(os.Stdout, appEnv)
+	// Logs of the management commands must not mix with their output.
+	logOut := os.Stderr
+	if name == cmdServe {
+		logOut = os.Stdout
+	}
+	logging.SetLogger(logOut, appEnv)
 
-	cfg, err := loadConfiguration()
+	// Creating a migration only touches the files on disk.
+	if name == cmdMigrate && len(args) > 0 && args[0] == migrateNew {
+		return newMigration(args[1:])
+	}
+
+	cfg, err := loadConfiguration(*cf)
 	if err != nil {
 		return err
 	}
 
-	dbConn, err := db.Connect(ctx, &cfg.Db)
+	switch name {
+	case cmdConfig:
+		return runConfig(cfg, args)
+	case cmdPassword:
+		return runPassword(cfg, args)
+	}
+
+	// The repositories encrypt and decrypt columns with it.
+	keyring, err := newKeyring(cfg)
 	if err != nil {
 		return err
 	}
-	defer dbConn.Close()
 
-	deps, err := setupDependencies(cfg, dbConn)
+	dbConn, err := db.Connect(ctx, &cfg.Db)
 	if err != nil {
 		return err
 	}
+	defer dbConn.Close()
 
-	app := handler.NewApp(deps)
-	app.SetupRoutes()
-
-	server := createServer(cfg, app.Router())
-
-	serverErr := startServer(server, cfg)
-	select {
-	case <-ctx.Done():
-		slog.Info("Shutdown signal received.")
-	case err := <-serverErr:
-		return fmt.Errorf("server error: %w", err)
+	switch name {
+	case cmdMigrate:
+		return runMigrate(ctx, dbConn, args)
+	case cmdUser:
+		return runUser(ctx, cfg, keyring, dbConn, args)
+	case cmdRotate:
+		return runRotateKeys(ctx, keyring, dbConn, args)
+	default:
+		return serve(ctx, cfg, keyring, dbConn)
 	}
-
-	return shutdownServer(server, cfg)
 }
 
 func setupEnvironment() (string, error) {
@@ -99,67 +172,10 @@ func setupEnvironment() (string, error) {
 	return appEnv, nil
 }
 
-func loadConfiguration() (*config.Config, error) {
-	cf := flag.String("cfg", cfgFile, "Config file")
-	flag.Parse()
-	cfg, err := config.LoadConfig(*cf)
+func loadConfiguration(cf string) (*config.Config, error) {
+	cfg, err := config.LoadConfig(cf)
 	if err != nil {
 		return nil, fmt.Errorf("load config: %w", err)
 	}
 	return cfg, nil
 }
-
-func setupDependencies(cfg *config.Config, db *sql.DB) (*handler.AppDependencies, error) {
-	router := goexpress.New()
-	validate = validation.New()
-	tmpl, err := handler.NewTemplate(cfg.Template)
-	if err != nil {
-		return nil, err
-	}
-	hasher := &security.Argon2Hasher{}
-
-	deps := &handler.AppDependencies{
-		Config:    cfg,
-		DB:        db,
-		Router:    router,
-		Validator: validate,
-		Template:  tmpl,
-		Hasher:    hasher,
-	}
-	return deps, nil
-}
-
-func createServer(cfg *config.Config, router *goexpress.Router) *http.Server {
-	return &http.Server{
-		Addr:         fmt.Sprintf(fmtAddr, cfg.Server.Port),
-		Handler:      router,
-		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
-		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
-		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
-	}
-}
-
-func startServer(server *http.Server, cfg *config.Config) chan error {
-	serverErr := make(chan error, 1)
-	go func() {
-		slog.Info("Server started", "address", server.Addr, "env", cfg.App.Env, slog.Bool("debug", cfg.App.IsDebug))
-		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
-			serverErr <- err
-		}
-		close(serverErr)
-	}()
-	return serverErr
-}
-
-func shutdownServer(server *http.Server, cfg *config.Config) error {
-	slog.Info("Shutting down server...")
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
-	defer cancel()
-
-	if err := server.Shutdown(shutdownCtx); err != nil {
-		return fmt.Errorf("server forced to shutdown: %w", err)
-	}
-
-	slog.Info("Server gracefully shut down.")
-	return nil
-}
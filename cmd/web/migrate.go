@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/ferdiebergado/goweb/db/migrations"
+	"github.com/ferdiebergado/goweb/internal/infra/db"
+)
+
+const (
+	migrateUp      = "up"
+	migrateDown    = "down"
+	migrateStatus  = "status"
+	migrateForce   = "force"
+	migrateNew     = "new"
+	migrationsPath = "db/migrations"
+	migrateUsage   = "usage: migrate up [N] | down [N] | status | force VERSION | new NAME"
+)
+
+func runMigrate(ctx context.Context, conn *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	migrator, err := db.NewMigrator(conn, migrations.FS)
+	if err != nil {
+		return err
+	}
+
+	switch args[0] {
+	case migrateUp:
+		steps, err := stepsArg(args[1:])
+		if err != nil {
+			return err
+		}
+		return migrateUpWith(ctx, migrator, steps)
+	case migrateDown:
+		steps, err := stepsArg(args[1:])
+		if err != nil {
+			return err
+		}
+		if steps == 0 {
+			// Reverting everything by accident is far worse than typing a number.
+			steps = 1
+		}
+		if err := migrator.Down(ctx, steps); err != nil && !errors.Is(err, db.ErrNoChange) {
+			return err
+		}
+		return nil
+	case migrateStatus:
+		return printMigrationStatus(ctx, migrator)
+	case migrateForce:
+		if len(args) != 2 {
+			return errors.New(migrateUsage)
+		}
+		version, err := strconv.ParseInt(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("invalid version %q: %w", args[1], err)
+		}
+		return migrator.Force(ctx, version)
+	default:
+		return errors.New(migrateUsage)
+	}
+}
+
+// autoMigrate applies all pending migrations when auto_migrate is enabled.
+func autoMigrate(ctx context.Context, conn *sql.DB) error {
+	migrator, err := db.NewMigrator(conn, migrations.FS)
+	if err != nil {
+		return err
+	}
+	return migrateUpWith(ctx, migrator, 0)
+}
+
+func migrateUpWith(ctx context.Context, migrator *db.Migrator, steps int) error {
+	if err := migrator.Up(ctx, steps); err != nil {
+		if errors.Is(err, db.ErrNoChange) {
+			slog.Info("Database is up to date.")
+			return nil
+		}
+		return err
+	}
+	return nil
+}
+
+func stepsArg(args []string) (int, error) {
+	if len(args) == 0 {
+		return 0, nil
+	}
+	steps, err := strconv.Atoi(args[0])
+	if err != nil || steps < 1 {
+		return 0, fmt.Errorf("invalid number of steps %q", args[0])
+	}
+	return steps, nil
+}
+
+func printMigrationStatus(ctx context.Context, migrator *db.Migrator) error {
+	status, err := migrator.Status(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "VERSION\tNAME\tSTATUS")
+	for _, m := range status.Migrations {
+		state := "pending"
+		if m.Applied {
+			state = "applied"
+		}
+		if m.Version == status.Version && status.Dirty {
+			state = "dirty"
+		}
+		fmt.Fprintf(w, "%06d\t%s\t%s\n", m.Version, m.Name, state)
+	}
+	return w.Flush()
+}
+
+func newMigration(args []string) error {
+	if len(args) != 1 {
+		return errors.New(migrateUsage)
+	}
+
+	files, err := db.NewMigration(migrationsPath, args[0])
+	if err != nil {
+		return err
+	}
+
+	for _, f := range files {
+		fmt.Println(f)
+	}
+	return nil
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+)
+
+const (
+	passwordCalibrate = "calibrate"
+	passwordUsage     = "usage: password calibrate [-target DURATION] [-max-memory KIB] [-parallelism N]"
+	// The least memory that the config accepts
+	minArgon2Memory = 8 * 1024
+)
+
+func runPassword(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return errors.New(passwordUsage)
+	}
+
+	switch args[0] {
+	case passwordCalibrate:
+		return calibratePassword(cfg, args[1:])
+	default:
+		return errors.New(passwordUsage)
+	}
+}
+
+// Prints the Argon2 parameters that take about the target time on this
+// machine, as the password.argon2 section of the config. It should be run on
+// the server, or one like it, since that is where the hashing happens.
+func calibratePassword(cfg *config.Config, args []string) error {
+	current := cfg.Password.Argon2
+
+	flags := flag.NewFlagSet(passwordCalibrate, flag.ContinueOnError)
+	target := flags.Duration("target", 500*time.Millisecond, "Time that hashing a password should take")
+	maxMemory := flags.Uint("max-memory", 256*1024, "Most memory in KiB that one hash may use")
+	parallelism := flags.Uint("parallelism", uint(current.Parallelism), "Threads that one hash may use")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if *target <= 0 || *maxMemory == 0 || *parallelism == 0 || *parallelism > 255 {
+		return errors.New(passwordUsage)
+	}
+
+	fmt.Fprintf(os.Stderr, "Calibrating for %s, this takes a while...\n", *target)
+	p, elapsed := security.CalibrateArgon2(*target, uint32(min(*maxMemory, 1<<32-1)), uint8(*parallelism))
+
+	if p.Memory < minArgon2Memory {
+		return fmt.Errorf("hashing with %d KiB already takes %s, raise the target", p.Memory, elapsed)
+	}
+
+	// The salt and key lengths have nothing to do with the cost.
+	calibrated := config.Argon2Config{
+		Memory:      p.Memory,
+		Iterations:  p.Iterations,
+		Parallelism: p.Parallelism,
+		SaltLength:  current.SaltLength,
+		KeyLength:   current.KeyLength,
+	}
+	fmt.Fprintf(os.Stderr, "Hashing takes %s with these parameters:\n", elapsed.Round(time.Millisecond))
+
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	return enc.Encode(map[string]any{"argon2": calibrated})
+}
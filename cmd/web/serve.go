@@ -0,0 +1,196 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/pkg/mail"
+	"github.com/ferdiebergado/goweb/internal/pkg/password"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/worker"
+	"github.com/go-playground/validator/v10"
+)
+
+const fmtAddr = ":%d"
+
+var validate *validator.Validate
+
+func serve(ctx context.Context, cfg *config.Config, keyring *security.Keyring, dbConn *sql.DB) (err error) {
+	if cfg.Db.AutoMigrate {
+		if err := autoMigrate(ctx, dbConn); err != nil {
+			return err
+		}
+	}
+
+	deps, err := setupDependencies(cfg, keyring, dbConn)
+	if err != nil {
+		return err
+	}
+
+	pool, err := setupWorkers(cfg, keyring, dbConn)
+	if err != nil {
+		return err
+	}
+
+	app := handler.NewApp(deps)
+	if err := app.SetupRoutes(); err != nil {
+		return err
+	}
+
+	server := createServer(cfg, app.Router())
+
+	poolCtx, stopPool := context.WithCancel(ctx)
+	pool.Start(poolCtx)
+	// Stopped on every way out so that no job is left locked until the
+	// reaper releases it.
+	defer func() {
+		stopPool()
+		err = errors.Join(err, stopWorkers(pool, cfg))
+	}()
+
+	serverErr := startServer(server, cfg)
+	select {
+	case <-ctx.Done():
+		slog.Info("Shutdown signal received.")
+	case err := <-serverErr:
+		return fmt.Errorf("server error: %w", err)
+	}
+
+	return shutdown(server, cfg)
+}
+
+func setupDependencies(cfg *config.Config, keyring *security.Keyring, db *sql.DB) (*handler.AppDependencies, error) {
+	router := goexpress.New()
+	policy, err := newPasswordPolicy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	validate = validation.New(validation.WithPasswordPolicy(policy))
+	tmpl, err := handler.NewTemplate(cfg.Template)
+	if err != nil {
+		return nil, err
+	}
+	// Only the server limits the hashes, the commands hash one at a time.
+	hasher := security.NewLimitedHasher(newHasher(cfg), cfg.Password.MaxConcurrency,
+		time.Duration(cfg.Password.QueueTimeout)*time.Second)
+	jwt, err := security.NewJWT(cfg.JWT)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := &handler.AppDependencies{
+		Config:    cfg,
+		DB:        db,
+		Keyring:   keyring,
+		Router:    router,
+		Validator: validate,
+		Template:  tmpl,
+		Hasher:    hasher,
+		JWT:       jwt,
+	}
+	return deps, nil
+}
+
+func setupWorkers(cfg *config.Config, keyring *security.Keyring, db *sql.DB) (*worker.Pool, error) {
+	mailer, err := mail.New(cfg.Mail)
+	if err != nil {
+		return nil, err
+	}
+	mailTmpl, err := mail.NewTemplate(cfg.Mail.Template)
+	if err != nil {
+		return nil, err
+	}
+	mails := service.NewMailService(repository.NewRepository(db, keyring), mailer, mailTmpl, cfg)
+
+	pool := worker.NewPool(repository.NewJobRepository(db), cfg.Worker)
+	pool.Register(service.JobSendEmail, worker.MailHandler(mails))
+	return pool, nil
+}
+
+func createServer(cfg *config.Config, router *goexpress.Router) *http.Server {
+	return &http.Server{
+		Addr:         fmt.Sprintf(fmtAddr, cfg.Server.Port),
+		Handler:      router,
+		ReadTimeout:  time.Duration(cfg.Server.ReadTimeout) * time.Second,
+		WriteTimeout: time.Duration(cfg.Server.WriteTimeout) * time.Second,
+		IdleTimeout:  time.Duration(cfg.Server.IdleTimeout) * time.Second,
+	}
+}
+
+func startServer(server *http.Server, cfg *config.Config) chan error {
+	serverErr := make(chan error, 1)
+	go func() {
+		slog.Info("Server started", "address", server.Addr, "env", cfg.App.Env, slog.Bool("debug", cfg.App.IsDebug))
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			serverErr <- err
+		}
+		close(serverErr)
+	}()
+	return serverErr
+}
+
+func shutdown(server *http.Server, cfg *config.Config) error {
+	slog.Info("Shutting down server...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("server forced to shutdown: %w", err)
+	}
+
+	slog.Info("Server gracefully shut down.")
+	return nil
+}
+
+// Waits for the jobs in flight of a pool whose context is done.
+func stopWorkers(pool *worker.Pool, cfg *config.Config) error {
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), time.Duration(cfg.Server.ShutdownTimeout)*time.Second)
+	defer cancel()
+
+	return pool.Shutdown(shutdownCtx)
+}
+
+// Returns the policy that new passwords are validated against.
+func newPasswordPolicy(cfg *config.Config) (*password.Policy, error) {
+	p := cfg.Password.Policy
+	policy := &password.Policy{
+		MinLength:   p.MinLength,
+		MaxLength:   p.MaxLength,
+		Classes:     p.Classes,
+		MinStrength: p.MinStrength,
+	}
+
+	if p.BreachedDir != "" {
+		list, err := password.NewBreachList(p.BreachedDir)
+		if err != nil {
+			return nil, err
+		}
+		policy.Breached = list
+	}
+
+	return policy, nil
+}
+
+// Returns the hasher of the passwords in the config. It also verifies the
+// hashes of imported users.
+func newHasher(cfg *config.Config) *security.MultiHasher {
+	p := cfg.Password.Argon2
+	return security.NewMultiHasher(security.NewArgon2Hasher(security.Argon2Params{
+		Memory:      p.Memory,
+		Iterations:  p.Iterations,
+		Parallelism: p.Parallelism,
+		SaltLength:  p.SaltLength,
+		KeyLength:   p.KeyLength,
+	}))
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/service"
+)
+
+const (
+	userCreate      = "create"
+	userList        = "list"
+	userDisable     = "disable"
+	userSetPassword = "set-password"
+	userAssignRole  = "assign-role"
+	userRevokeRole  = "revoke-role"
+	userImport      = "import"
+	userUsage       = "usage: user create [-verified] [-role ROLE] EMAIL | list | disable EMAIL | set-password EMAIL" +
+		" | assign-role EMAIL ROLE | revoke-role EMAIL ROLE | import [-verified] FILE"
+)
+
+func runUser(ctx context.Context, cfg *config.Config, keyring *security.Keyring, db *sql.DB, args []string) error {
+	if len(args) == 0 {
+		return errors.New(userUsage)
+	}
+
+	repo := repository.NewRepository(db, keyring)
+	hasher := newHasher(cfg)
+	users := service.NewUserService(repo, hasher, cfg)
+	authz := service.NewAuthorizationService(repo)
+
+	switch args[0] {
+	case userCreate:
+		return createUser(ctx, users, authz, args[1:])
+	case userList:
+		return listUsers(ctx, users)
+	case userImport:
+		return importUsers(ctx, users, hasher, args[1:])
+	case userDisable:
+		if len(args) != 2 {
+			return errors.New(userUsage)
+		}
+		if err := users.DisableUser(ctx, args[1]); err != nil {
+			return err
+		}
+		fmt.Printf("Disabled %s\n", args[1])
+		return nil
+	case userSetPassword:
+		if len(args) != 2 {
+			return errors.New(userUsage)
+		}
+		password, err := readPassword()
+		if err != nil {
+			return err
+		}
+		if err := users.SetUserPassword(ctx, args[1], password); err != nil {
+			return err
+		}
+		fmt.Printf("Password of %s changed, existing sessions revoked\n", args[1])
+		return nil
+	case userAssignRole:
+		if len(args) != 3 {
+			return errors.New(userUsage)
+		}
+		if err := authz.AssignRole(ctx, args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("Assigned role %s to %s\n", args[2], args[1])
+		return nil
+	case userRevokeRole:
+		if len(args) != 3 {
+			return errors.New(userUsage)
+		}
+		if err := authz.RevokeRole(ctx, args[1], args[2]); err != nil {
+			return err
+		}
+		fmt.Printf("Revoked role %s from %s\n", args[2], args[1])
+		return nil
+	default:
+		return errors.New(userUsage)
+	}
+}
+
+// Creates a user, optionally with a role. Running it with -verified -role admin
+// seeds the first administrator.
+func createUser(ctx context.Context, users service.UserService, authz service.AuthorizationService, args []string) error {
+	flags := flag.NewFlagSet(userCreate, flag.ContinueOnError)
+	verified := flags.Bool("verified", false, "Mark the email as verified instead of sending a verification link")
+	role := flags.String("role", "", "Assign the role to the new user")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New(userUsage)
+	}
+	email := flags.Arg(0)
+
+	password, err := readPassword()
+	if err != nil {
+		return err
+	}
+
+	user, err := users.CreateUser(ctx, service.CreateUserParams{Email: email, Password: password, Verified: *verified})
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Created user %s (%s)\n", user.Email, user.ID)
+
+	if *role != "" {
+		if err := authz.AssignRole(ctx, user.Email, *role); err != nil {
+			return err
+		}
+		fmt.Printf("Assigned role %s to %s\n", *role, user.Email)
+	}
+	return nil
+}
+
+// Imports the users of another system from a CSV file with the columns
+// email, password_hash and optionally verified_at, after a header row. The
+// file is checked as a whole before anything is imported.
+func importUsers(ctx context.Context, users service.UserService, hasher *security.MultiHasher, args []string) error {
+	flags := flag.NewFlagSet(userImport, flag.ContinueOnError)
+	verified := flags.Bool("verified", false, "Mark the users without a verified_at as verified")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	if flags.NArg() != 1 {
+		return errors.New(userUsage)
+	}
+
+	f, err := os.Open(flags.Arg(0))
+	if err != nil {
+		return fmt.Errorf("open import file: %w", err)
+	}
+	defer f.Close()
+
+	params, err := readImportFile(f, hasher, *verified)
+	if err != nil {
+		return err
+	}
+
+	result, err := users.ImportUsers(ctx, params)
+	if result != nil {
+		fmt.Printf("Imported %d users\n", result.Imported)
+		for _, email := range result.Skipped {
+			fmt.Printf("Skipped %s, the email is taken\n", email)
+		}
+	}
+	return err
+}
+
+func readImportFile(r io.Reader, hasher *security.MultiHasher, verified bool) ([]service.ImportUserParams, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read import file: %w", err)
+	}
+	if len(records) < 2 {
+		return nil, errors.New("import file has no users")
+	}
+
+	header := records[0]
+	if len(header) < 2 || header[0] != "email" || header[1] != "password_hash" ||
+		(len(header) > 2 && header[2] != "verified_at") || len(header) > 3 {
+		return nil, errors.New("import file must start with the header email,password_hash[,verified_at]")
+	}
+
+	validate := validation.New()
+	now := time.Now()
+
+	var params []service.ImportUserParams
+	var problems []string
+	for i, record := range records[1:] {
+		line := i + 2
+		if len(record) != len(header) {
+			problems = append(problems, fmt.Sprintf("line %d: expected %d columns", line, len(header)))
+			continue
+		}
+
+		u := service.ImportUserParams{Email: strings.TrimSpace(record[0]), PasswordHash: strings.TrimSpace(record[1])}
+		if err := validate.Var(u.Email, "required,email"); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: invalid email %q", line, u.Email))
+		}
+		if err := hasher.CheckHash(u.PasswordHash); err != nil {
+			problems = append(problems, fmt.Sprintf("line %d: %v", line, err))
+		}
+
+		if len(record) > 2 && record[2] != "" {
+			t, err := time.Parse(time.RFC3339, record[2])
+			if err != nil {
+				problems = append(problems, fmt.Sprintf("line %d: verified_at must be RFC 3339", line))
+			}
+			u.VerifiedAt = &t
+		} else if verified {
+			u.VerifiedAt = &now
+		}
+
+		params = append(params, u)
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("invalid import file:\n  %s", strings.Join(problems, "\n  "))
+	}
+	return params, nil
+}
+
+func listUsers(ctx context.Context, users service.UserService) error {
+	list, err := users.ListUsers(ctx)
+	if err != nil {
+		return err
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "ID\tEMAIL\tVERIFIED\tDISABLED\tCREATED")
+	for _, u := range list {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", u.ID, u.Email, formatTime(u.VerifiedAt), formatTime(u.DisabledAt),
+			u.CreatedAt.Format(time.DateTime))
+	}
+	return w.Flush()
+}
+
+func formatTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Format(time.DateTime)
+}
+
+// Reads the password from the first line of stdin so that it never shows up
+// in the shell history or the process list.
+func readPassword() (string, error) {
+	fmt.Fprint(os.Stderr, "Password: ")
+
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		return "", fmt.Errorf("read password: %w", err)
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", errors.New("password must not be empty")
+	}
+	return password, nil
+}
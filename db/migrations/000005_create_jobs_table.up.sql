@@ -0,0 +1,16 @@
+CREATE TABLE IF NOT EXISTS jobs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	kind TEXT NOT NULL,
+	payload JSONB NOT NULL DEFAULT '{}',
+	status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'done', 'dead')),
+	attempts INT NOT NULL DEFAULT 0,
+	last_error TEXT,
+	run_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	locked_at TIMESTAMPTZ,
+	locked_by TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_jobs_pending_run_at ON jobs (run_at) WHERE status = 'pending';
+CREATE INDEX IF NOT EXISTS idx_jobs_running_locked_at ON jobs (locked_at) WHERE status = 'running';
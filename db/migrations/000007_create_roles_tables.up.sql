@@ -0,0 +1,44 @@
+CREATE TABLE IF NOT EXISTS roles (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	name VARCHAR(100) NOT NULL UNIQUE,
+	description TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	updated_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS permissions (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	name VARCHAR(100) NOT NULL UNIQUE,
+	description TEXT,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+	role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+	permission_id UUID NOT NULL REFERENCES permissions (id) ON DELETE CASCADE,
+	PRIMARY KEY (role_id, permission_id)
+);
+
+CREATE TABLE IF NOT EXISTS user_roles (
+	user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	role_id UUID NOT NULL REFERENCES roles (id) ON DELETE CASCADE,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (user_id, role_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_roles_role_id ON user_roles (role_id);
+
+INSERT INTO permissions (name, description) VALUES
+	('users:read', 'View user accounts'),
+	('users:write', 'Create, disable and modify user accounts'),
+	('roles:assign', 'Assign and revoke roles')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO roles (name, description) VALUES
+	('admin', 'Full access to the administration of the application')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,17 @@
+-- Only a hash of each key is stored. The prefix is kept in the clear so that
+-- users can tell their keys apart.
+CREATE TABLE IF NOT EXISTS api_keys (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	name TEXT NOT NULL,
+	prefix TEXT NOT NULL,
+	key_hash TEXT NOT NULL UNIQUE,
+	-- Space separated permissions, empty for all permissions of the user
+	scopes TEXT NOT NULL DEFAULT '',
+	expires_at TIMESTAMPTZ,
+	last_used_at TIMESTAMPTZ,
+	revoked_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_keys_user_id ON api_keys (user_id);
@@ -0,0 +1,34 @@
+CREATE TABLE IF NOT EXISTS user_totp (
+	user_id UUID PRIMARY KEY REFERENCES users (id) ON DELETE CASCADE,
+	-- Encrypted with the keyring
+	secret TEXT NOT NULL,
+	-- NULL until the enrollment is confirmed with a valid code
+	enabled_at TIMESTAMPTZ,
+	-- Last time step accepted so that a code cannot be used twice
+	last_step BIGINT NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS recovery_codes (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	-- SHA-256 of the code, which is random enough not to need a slow hash
+	code_hash TEXT NOT NULL,
+	used_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_recovery_codes_user_id_code_hash ON recovery_codes (user_id, code_hash);
+
+-- Issued when the password of a user with 2FA is accepted, exchanged for a
+-- session once the second factor is verified.
+CREATE TABLE IF NOT EXISTS login_challenges (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	token_hash TEXT NOT NULL UNIQUE,
+	attempts INT NOT NULL DEFAULT 0,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_challenges_user_id ON login_challenges (user_id);
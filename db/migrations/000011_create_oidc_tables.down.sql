@@ -0,0 +1,9 @@
+DROP TABLE IF EXISTS oidc_flows;
+DROP TABLE IF EXISTS user_identities;
+
+-- Password-less users get a hash of an unknown password so that they can
+-- only sign in again after resetting it.
+UPDATE users
+SET password_hash = '$argon2id$v=19$m=65536,t=3,p=2$C6fPkXc51gMDWBNux5D+zg$BSmR5bpPc0ZZ7XivwP/UHqWGsJrMTH1+Qq4WDtMsVO8'
+WHERE password_hash IS NULL;
+ALTER TABLE users ALTER COLUMN password_hash SET NOT NULL;
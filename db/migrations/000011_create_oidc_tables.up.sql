@@ -0,0 +1,27 @@
+-- Users who only sign in with an identity provider have no password.
+ALTER TABLE users ALTER COLUMN password_hash DROP NOT NULL;
+
+-- Links the subject of an OpenID Connect provider to a user.
+CREATE TABLE IF NOT EXISTS user_identities (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+	provider VARCHAR(64) NOT NULL,
+	subject TEXT NOT NULL,
+	email VARCHAR(255),
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (provider, subject)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_identities_user_id ON user_identities (user_id);
+
+-- Authorization requests sent to a provider that are waiting for the
+-- callback. Each can only be completed once.
+CREATE TABLE IF NOT EXISTS oidc_flows (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	state_hash TEXT NOT NULL UNIQUE,
+	provider VARCHAR(64) NOT NULL,
+	nonce TEXT NOT NULL,
+	code_verifier TEXT NOT NULL,
+	expires_at TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,22 @@
+-- Failed sign in attempts counted per account (the normalized email) and per
+-- client address.
+CREATE TABLE IF NOT EXISTS login_attempts (
+	scope VARCHAR(16) NOT NULL CHECK (scope IN ('account', 'ip')),
+	key TEXT NOT NULL,
+	failures INT NOT NULL DEFAULT 0,
+	last_failed_at TIMESTAMPTZ NOT NULL,
+	locked_until TIMESTAMPTZ,
+	PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE IF NOT EXISTS audit_logs (
+	id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+	user_id UUID REFERENCES users (id) ON DELETE SET NULL,
+	action VARCHAR(64) NOT NULL,
+	ip_address TEXT,
+	details JSONB NOT NULL DEFAULT '{}',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_logs_user_id ON audit_logs (user_id);
+CREATE INDEX IF NOT EXISTS idx_audit_logs_created_at ON audit_logs (created_at);
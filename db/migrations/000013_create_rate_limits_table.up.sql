@@ -0,0 +1,10 @@
+-- State of the rate limiters shared by all replicas, see internal/pkg/ratelimit
+CREATE TABLE IF NOT EXISTS rate_limits (
+	key TEXT PRIMARY KEY,
+	count DOUBLE PRECISION NOT NULL DEFAULT 0,
+	previous DOUBLE PRECISION NOT NULL DEFAULT 0,
+	at TIMESTAMPTZ,
+	expires_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limits_expires_at ON rate_limits (expires_at);
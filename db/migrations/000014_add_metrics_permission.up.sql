@@ -0,0 +1,8 @@
+INSERT INTO permissions (name, description) VALUES
+	('metrics:read', 'View the runtime metrics of the server')
+ON CONFLICT (name) DO NOTHING;
+
+INSERT INTO role_permissions (role_id, permission_id)
+SELECT r.id, p.id FROM roles r CROSS JOIN permissions p
+WHERE r.name = 'admin' AND p.name = 'metrics:read'
+ON CONFLICT DO NOTHING;
@@ -0,0 +1,8 @@
+// Package migrations embeds the SQL migrations so that the binary can apply
+// them without the migration files being present on disk.
+package migrations
+
+import "embed"
+
+//go:embed *.sql
+var FS embed.FS
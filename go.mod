@@ -12,6 +12,7 @@ require (
 	github.com/stretchr/testify v1.10.0
 	go.uber.org/mock v0.5.0
 	golang.org/x/crypto v0.36.0
+	rsc.io/qr v0.2.0
 )
 
 require (
@@ -2,56 +2,272 @@ package config
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"log/slog"
 	"os"
 	"path/filepath"
 	"reflect"
 	"strconv"
+	"strings"
 )
 
 type EnvConfig struct {
 	Env     string `json:"env,omitempty" env:"ENV"`
 	IsDebug bool   `json:"is_debug,omitempty" env:"DEBUG"`
+	URL     string `json:"url,omitempty" env:"APP_URL" validate:"required,url"`
 }
 
 type DBConfig struct {
-	Driver          string `json:"driver,omitempty"`
-	User            string `json:"user" env:"POSTGRES_USER"`
+	Driver          string `json:"driver,omitempty" validate:"required"`
+	User            string `json:"user" env:"POSTGRES_USER" validate:"required"`
 	Pass            string `json:"pass" env:"POSTGRES_PASSWORD"`
-	Host            string `json:"host" env:"POSTGRES_HOST"`
-	Port            int    `json:"port" env:"POSTGRES_PORT"`
-	SSLMode         string `json:"ssl_mode" env:"POSTGRES_SSLMODE"`
-	PingTimeout     int    `json:"ping_timeout,omitempty"`
-	DB              string `json:"db" env:"POSTGRES_DB"`
-	MaxOpenConns    int    `json:"max_open_conns,omitempty"`
-	MaxIdleConns    int    `json:"max_idle_conns,omitempty"`
-	ConnMaxIdle     int    `json:"conn_max_idle,omitempty"`
-	ConnMaxLifetime int    `json:"conn_max_lifetime,omitempty"`
+	Host            string `json:"host" env:"POSTGRES_HOST" validate:"required"`
+	Port            int    `json:"port" env:"POSTGRES_PORT" validate:"min=1,max=65535"`
+	SSLMode         string `json:"ssl_mode" env:"POSTGRES_SSLMODE" validate:"required,oneof=disable allow prefer require verify-ca verify-full"`
+	PingTimeout     int    `json:"ping_timeout,omitempty" validate:"min=1"`
+	DB              string `json:"db" env:"POSTGRES_DB" validate:"required"`
+	MaxOpenConns    int    `json:"max_open_conns,omitempty" validate:"gte=0"`
+	MaxIdleConns    int    `json:"max_idle_conns,omitempty" validate:"gte=0"`
+	ConnMaxIdle     int    `json:"conn_max_idle,omitempty" validate:"gte=0"`
+	ConnMaxLifetime int    `json:"conn_max_lifetime,omitempty" validate:"gte=0"`
+	AutoMigrate     bool   `json:"auto_migrate,omitempty" env:"DB_AUTO_MIGRATE"`
 }
 
+// ServerConfig.TrustedProxies lists the addresses or CIDR ranges of the
+// reverse proxies in front of the server. Only requests from them are
+// trusted to tell the address of the client in X-Forwarded-For.
 type ServerConfig struct {
-	Port            int `json:"port" env:"PORT"`
-	ReadTimeout     int `json:"read_timeout,omitempty"`
-	WriteTimeout    int `json:"write_timeout,omitempty"`
-	IdleTimeout     int `json:"idle_timeout,omitempty"`
-	ShutdownTimeout int `json:"shutdown_timeout,omitempty"`
+	Port            int      `json:"port" env:"PORT" validate:"min=1,max=65535"`
+	ReadTimeout     int      `json:"read_timeout,omitempty" validate:"min=1"`
+	WriteTimeout    int      `json:"write_timeout,omitempty" validate:"min=1"`
+	IdleTimeout     int      `json:"idle_timeout,omitempty" validate:"min=1"`
+	ShutdownTimeout int      `json:"shutdown_timeout,omitempty" validate:"min=1"`
+	TrustedProxies  []string `json:"trusted_proxies,omitempty" validate:"dive,ip|cidr"`
 }
 
 type TemplateConfig struct {
-	Path         string `json:"path,omitempty"`
-	LayoutFile   string `json:"layout_file,omitempty"`
-	PartialsPath string `json:"partials_path,omitempty"`
-	PagesPath    string `json:"pages_path,omitempty"`
+	Path         string `json:"path,omitempty" validate:"required"`
+	LayoutFile   string `json:"layout_file,omitempty" validate:"required"`
+	PartialsPath string `json:"partials_path,omitempty" validate:"required"`
+	PagesPath    string `json:"pages_path,omitempty" validate:"required"`
 }
 
-type Config struct {
-	App      EnvConfig      `json:"app,omitempty"`
-	Db       DBConfig       `json:"db,omitempty"`
-	Server   ServerConfig   `json:"server,omitempty"`
+type SessionConfig struct {
+	CookieName string `json:"cookie_name,omitempty" validate:"required"`
+	Lifetime   int    `json:"lifetime,omitempty" validate:"min=1"`
+	Secure     bool   `json:"secure,omitempty" env:"SESSION_SECURE"`
+}
+
+// AuthConfig durations are in seconds. APIKeyTouchInterval is how stale the
+// last use of an API key may get before it is written again.
+type AuthConfig struct {
+	VerificationTTL     int `json:"verification_ttl,omitempty" validate:"min=1"`
+	PasswordResetTTL    int `json:"password_reset_ttl,omitempty" validate:"min=1"`
+	APIKeyTouchInterval int `json:"api_key_touch_interval,omitempty" validate:"min=1"`
+}
+
+// MailConfig selects the mail driver. Template.LayoutFile is given without a
+// suffix since every mail template has an .html and a .txt variant.
+type MailConfig struct {
+	Driver   string         `json:"driver,omitempty" env:"MAIL_DRIVER" validate:"omitempty,oneof=smtp file log"`
+	From     string         `json:"from,omitempty" env:"MAIL_FROM" validate:"required"`
+	Host     string         `json:"host,omitempty" env:"SMTP_HOST" validate:"required_if=Driver smtp"`
+	Port     int            `json:"port,omitempty" env:"SMTP_PORT" validate:"omitempty,min=1,max=65535"`
+	User     string         `json:"user,omitempty" env:"SMTP_USER"`
+	Pass     string         `json:"pass,omitempty" env:"SMTP_PASSWORD"`
+	Dir      string         `json:"dir,omitempty" env:"MAIL_DIR" validate:"required_if=Driver file"`
 	Template TemplateConfig `json:"template,omitempty"`
 }
 
+// WorkerConfig tunes the background job runner. Durations are in seconds.
+// Jobs locked for LockTimeout are requeued, so it has to be longer than
+// JobTimeout or running jobs would run twice; 0 disables the requeueing.
+type WorkerConfig struct {
+	Concurrency  int `json:"concurrency,omitempty" env:"WORKER_CONCURRENCY" validate:"min=1"`
+	PollInterval int `json:"poll_interval,omitempty" validate:"min=1"`
+	MaxAttempts  int `json:"max_attempts,omitempty" validate:"min=1"`
+	BackoffBase  int `json:"backoff_base,omitempty" validate:"min=1"`
+	BackoffMax   int `json:"backoff_max,omitempty" validate:"gtefield=BackoffBase"`
+	JobTimeout   int `json:"job_timeout,omitempty" validate:"min=1"`
+	LockTimeout  int `json:"lock_timeout,omitempty" validate:"omitempty,gtfield=JobTimeout"`
+}
+
+// JWTConfig configures the bearer tokens of the JSON API. Algorithm selects
+// the key used: Secret for HS256 or PrivateKey, a base64 Ed25519 seed or a
+// PKCS#8 PEM block, for EdDSA. TTLs are in seconds. The secret is not
+// committed, JWT_SECRET sets it.
+type JWTConfig struct {
+	Algorithm       string `json:"algorithm,omitempty" env:"JWT_ALGORITHM" validate:"oneof=HS256 EdDSA"`
+	Secret          string `json:"secret,omitempty" env:"JWT_SECRET" validate:"required_if=Algorithm HS256,omitempty,min=32"`
+	PrivateKey      string `json:"private_key,omitempty" env:"JWT_PRIVATE_KEY" validate:"required_if=Algorithm EdDSA"`
+	KeyID           string `json:"key_id,omitempty" env:"JWT_KEY_ID"`
+	Issuer          string `json:"issuer,omitempty" validate:"required"`
+	AccessTokenTTL  int    `json:"access_token_ttl,omitempty" validate:"min=1"`
+	RefreshTokenTTL int    `json:"refresh_token_ttl,omitempty" validate:"gtfield=AccessTokenTTL"`
+}
+
+// TOTPConfig configures two-factor authentication. ChallengeTTL is in
+// seconds.
+type TOTPConfig struct {
+	Issuer       string `json:"issuer,omitempty" validate:"required"`
+	ChallengeTTL int    `json:"challenge_ttl,omitempty" validate:"min=1"`
+	MaxAttempts  int    `json:"max_attempts,omitempty" validate:"min=1"`
+}
+
+// Algorithm of the keys that are only given in the environment
+const DefaultEncryptionAlgorithm = "xchacha20-poly1305"
+
+// EncryptionKeyConfig is a key of the keyring. Key is base64 encoded and 32
+// bytes long for either algorithm.
+type EncryptionKeyConfig struct {
+	Algorithm string `json:"algorithm,omitempty" validate:"oneof=aes-256-gcm xchacha20-poly1305"`
+	Key       string `json:"key,omitempty" validate:"required,base64"`
+}
+
+// EncryptionConfig is the keyring that sensitive columns are encrypted with.
+// Keys maps key IDs to keys, every one of which decrypts, while new values
+// are encrypted with PrimaryKey. No key is committed: the variable
+// ENCRYPTION_KEY_<ID> gives the key of an ID, which uses
+// DefaultEncryptionAlgorithm unless the config file declares it.
+type EncryptionConfig struct {
+	PrimaryKey string                         `json:"primary_key,omitempty" env:"ENCRYPTION_PRIMARY_KEY" validate:"required"`
+	Keys       map[string]EncryptionKeyConfig `json:"keys,omitempty" validate:"min=1,dive,keys,alphanum,max=32,endkeys,required"`
+}
+
+// LockoutConfig limits failed sign in attempts. Failures are forgotten after
+// Window seconds without one. After each failure on an account the next
+// attempt has to wait BaseDelay seconds, doubling up to MaxDelay. An account
+// or client address that reaches its threshold is locked for LockDuration
+// seconds.
+type LockoutConfig struct {
+	AccountThreshold int `json:"account_threshold,omitempty" validate:"min=1"`
+	IPThreshold      int `json:"ip_threshold,omitempty" validate:"min=1"`
+	LockDuration     int `json:"lock_duration,omitempty" validate:"min=1"`
+	Window           int `json:"window,omitempty" validate:"min=1"`
+	BaseDelay        int `json:"base_delay,omitempty" validate:"min=0"`
+	MaxDelay         int `json:"max_delay,omitempty" validate:"gtefield=BaseDelay"`
+}
+
+// OIDCProviderConfig configures a provider for "Sign in with ...". Issuer is
+// the URL that the discovery document is read from. ClientSecret can also be
+// set with OIDC_<NAME>_CLIENT_SECRET.
+type OIDCProviderConfig struct {
+	DisplayName  string   `json:"display_name,omitempty" validate:"required"`
+	Issuer       string   `json:"issuer,omitempty" validate:"required,url"`
+	ClientID     string   `json:"client_id,omitempty" validate:"required"`
+	ClientSecret string   `json:"client_secret,omitempty"`
+	Scopes       []string `json:"scopes,omitempty"`
+}
+
+// OIDCConfig maps the name used in the URLs of a provider to its config.
+// FlowTTL is how long in seconds the user has to sign in at the provider.
+type OIDCConfig struct {
+	FlowTTL   int                           `json:"flow_ttl,omitempty" validate:"min=1"`
+	Providers map[string]OIDCProviderConfig `json:"providers,omitempty" validate:"dive,keys,alphanum,lowercase,endkeys,required"`
+}
+
+// RateLimitRule limits the requests to the routes that use it to Requests
+// per Window seconds for each client. Key identifies the client: its address,
+// the signed in user or the API key, falling back to the user and then the
+// address on requests without them.
+type RateLimitRule struct {
+	Algorithm string `json:"algorithm,omitempty" validate:"oneof=token_bucket sliding_window"`
+	Requests  int    `json:"requests,omitempty" validate:"min=1"`
+	Window    int    `json:"window,omitempty" validate:"min=1"`
+	Key       string `json:"key,omitempty" validate:"oneof=ip user api_key"`
+}
+
+// RateLimitConfig maps the names that routes refer to to their rules. Store
+// selects where the counts are kept, postgres shares them between replicas.
+type RateLimitConfig struct {
+	Store string                   `json:"store,omitempty" env:"RATE_LIMIT_STORE" validate:"oneof=memory postgres"`
+	Rules map[string]RateLimitRule `json:"rules,omitempty" validate:"dive"`
+}
+
+// CSRFConfig configures the protection of cookie authenticated requests.
+// TrustedOrigins may send unsafe requests besides the origin of App.URL.
+type CSRFConfig struct {
+	CookieName     string   `json:"cookie_name,omitempty" validate:"required"`
+	TrustedOrigins []string `json:"trusted_origins,omitempty" validate:"dive,url"`
+}
+
+// CSPConfig maps Content-Security-Policy directives to their sources. A nonce
+// is added to script-src on every request and violations are reported to
+// /csp-report. ReportOnly only reports violations instead of blocking.
+type CSPConfig struct {
+	Directives map[string]string `json:"directives,omitempty" validate:"required,dive,keys,required,endkeys,required"`
+	ReportOnly bool              `json:"report_only,omitempty" env:"CSP_REPORT_ONLY"`
+}
+
+// SecurityHeadersConfig configures the headers sent with every response.
+// HSTSMaxAge is in seconds, 0 leaves out Strict-Transport-Security. Empty
+// policies are left out.
+type SecurityHeadersConfig struct {
+	CSP                       CSPConfig `json:"csp,omitempty"`
+	HSTSMaxAge                int       `json:"hsts_max_age,omitempty" validate:"min=0"`
+	HSTSIncludeSubdomains     bool      `json:"hsts_include_subdomains,omitempty"`
+	HSTSPreload               bool      `json:"hsts_preload,omitempty"`
+	ReferrerPolicy            string    `json:"referrer_policy,omitempty"`
+	PermissionsPolicy         string    `json:"permissions_policy,omitempty"`
+	CrossOriginOpenerPolicy   string    `json:"cross_origin_opener_policy,omitempty" validate:"omitempty,oneof=unsafe-none same-origin-allow-popups same-origin"`
+	CrossOriginEmbedderPolicy string    `json:"cross_origin_embedder_policy,omitempty" validate:"omitempty,oneof=unsafe-none require-corp credentialless"`
+}
+
+// Argon2Config sets the cost of new password hashes. Memory is in KiB.
+// Raising a cost rehashes the password of each user on their next sign in.
+// Use "goweb password calibrate" to pick them for the server.
+type Argon2Config struct {
+	Memory      uint32 `json:"memory,omitempty" validate:"min=8192"`
+	Iterations  uint32 `json:"iterations,omitempty" validate:"min=1"`
+	Parallelism uint8  `json:"parallelism,omitempty" validate:"min=1"`
+	SaltLength  uint32 `json:"salt_length,omitempty" validate:"min=16"`
+	KeyLength   uint32 `json:"key_length,omitempty" validate:"min=16"`
+}
+
+// PasswordPolicyConfig is what new passwords have to satisfy. BreachedDir
+// is a directory of Pwned Passwords range files, one per SHA-1 prefix, and
+// enables the breached password check when set.
+type PasswordPolicyConfig struct {
+	MinLength   int      `json:"min_length,omitempty" validate:"min=8"`
+	MaxLength   int      `json:"max_length,omitempty" validate:"gtefield=MinLength,max=256"`
+	Classes     []string `json:"classes,omitempty" validate:"dive,oneof=lower upper digit symbol"`
+	MinStrength int      `json:"min_strength,omitempty" validate:"min=0,max=4"`
+	BreachedDir string   `json:"breached_dir,omitempty" env:"PASSWORD_BREACHED_DIR" validate:"omitempty,dir"`
+}
+
+// PasswordConfig also limits the hashes that run at once, each one takes the
+// Argon2 memory. QueueTimeout is in seconds.
+type PasswordConfig struct {
+	Argon2         Argon2Config         `json:"argon2,omitempty"`
+	Policy         PasswordPolicyConfig `json:"policy,omitempty"`
+	MaxConcurrency int                  `json:"max_concurrency,omitempty" validate:"min=1"`
+	QueueTimeout   int                  `json:"queue_timeout,omitempty" validate:"min=1"`
+}
+
+type Config struct {
+	App        EnvConfig             `json:"app,omitempty"`
+	Db         DBConfig              `json:"db,omitempty"`
+	Server     ServerConfig          `json:"server,omitempty"`
+	Template   TemplateConfig        `json:"template,omitempty"`
+	Session    SessionConfig         `json:"session,omitempty"`
+	Auth       AuthConfig            `json:"auth,omitempty"`
+	Mail       MailConfig            `json:"mail,omitempty"`
+	Worker     WorkerConfig          `json:"worker,omitempty"`
+	JWT        JWTConfig             `json:"jwt,omitempty"`
+	TOTP       TOTPConfig            `json:"totp,omitempty"`
+	Encryption EncryptionConfig      `json:"encryption,omitempty"`
+	OIDC       OIDCConfig            `json:"oidc,omitempty"`
+	Lockout    LockoutConfig         `json:"lockout,omitempty"`
+	RateLimit  RateLimitConfig       `json:"rate_limit,omitempty"`
+	CSRF       CSRFConfig            `json:"csrf,omitempty"`
+	Headers    SecurityHeadersConfig `json:"headers,omitempty"`
+	Password   PasswordConfig        `json:"password,omitempty"`
+}
+
+// LoadConfig reads the config file at path, applies the environment overrides
+// and validates the result. All problems found are reported together in a
+// *ValidationError.
 func LoadConfig(path string) (*Config, error) {
 	slog.Info("Loading config...", "path", path)
 	path = filepath.Clean(path)
@@ -60,53 +276,177 @@ func LoadConfig(path string) (*Config, error) {
 		return nil, fmt.Errorf("open config file %s: %w", path, err)
 	}
 
+	var problems []string
+
 	var config Config
 	if err := json.Unmarshal(configFile, &config); err != nil {
-		return nil, fmt.Errorf("decode config %s %w", configFile, err)
+		// A type error does not stop decoding, so the other fields are still checked.
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("decode config %s: %w", path, err)
+		}
+		problems = append(problems, fmt.Sprintf("%s: cannot be a %s", typeErr.Field, typeErr.Value))
 	}
 
-	overrideWithEnv(reflect.ValueOf(&config).Elem())
+	problems = append(problems, unknownKeys(configFile, reflect.TypeOf(config), "")...)
 
-	cfgCopy := config
-	cfgCopy.Db.Pass = "*"
+	sources := make(map[string]string)
+	problems = append(problems, overrideWithEnv(reflect.ValueOf(&config).Elem(), "", sources)...)
+	overrideOIDCSecrets(&config.OIDC)
+	overrideEncryptionKeys(&config.Encryption)
+	problems = append(problems, validate(&config, sources)...)
+	problems = append(problems, productionProblems(&config)...)
 
-	slog.Debug("loadconfig", slog.Any("config", cfgCopy))
+	if len(problems) > 0 {
+		return nil, &ValidationError{Path: path, Problems: problems}
+	}
+
+	slog.Debug("loadconfig", slog.Any("config", config.Redacted()))
 
 	return &config, nil
 }
 
-func overrideWithEnv(v reflect.Value) {
+// Redacted returns a copy of the config with secrets masked so that it can be
+// logged or printed.
+func (c Config) Redacted() Config {
+	const mask = "*"
+	if c.Db.Pass != "" {
+		c.Db.Pass = mask
+	}
+	if c.Mail.Pass != "" {
+		c.Mail.Pass = mask
+	}
+	if c.JWT.Secret != "" {
+		c.JWT.Secret = mask
+	}
+	if c.JWT.PrivateKey != "" {
+		c.JWT.PrivateKey = mask
+	}
+	if c.Encryption.Keys != nil {
+		// The map is shared with the original config.
+		keys := make(map[string]EncryptionKeyConfig, len(c.Encryption.Keys))
+		for id, k := range c.Encryption.Keys {
+			if k.Key != "" {
+				k.Key = mask
+			}
+			keys[id] = k
+		}
+		c.Encryption.Keys = keys
+	}
+	if c.OIDC.Providers != nil {
+		// The map is shared with the original config.
+		providers := make(map[string]OIDCProviderConfig, len(c.OIDC.Providers))
+		for name, p := range c.OIDC.Providers {
+			if p.ClientSecret != "" {
+				p.ClientSecret = mask
+			}
+			providers[name] = p
+		}
+		c.OIDC.Providers = providers
+	}
+	return c
+}
+
+// Providers are only known once the config file is read, so their secrets
+// cannot be declared with env tags.
+func overrideOIDCSecrets(c *OIDCConfig) {
+	for name, p := range c.Providers {
+		if secret, ok := os.LookupEnv("OIDC_" + strings.ToUpper(name) + "_CLIENT_SECRET"); ok {
+			p.ClientSecret = secret
+			c.Providers[name] = p
+		}
+	}
+}
+
+// Prefix of the variables that give the keys of the keyring
+const encryptionKeyEnvPrefix = "ENCRYPTION_KEY_"
+
+// Sets the keys given in the environment. Unlike the OIDC secrets, their IDs
+// need not be in the config file, which only declares their algorithm.
+func overrideEncryptionKeys(c *EncryptionConfig) {
+	for _, env := range os.Environ() {
+		name, key, _ := strings.Cut(env, "=")
+		suffix, ok := strings.CutPrefix(name, encryptionKeyEnvPrefix)
+		if !ok || suffix == "" {
+			continue
+		}
+
+		if c.Keys == nil {
+			c.Keys = make(map[string]EncryptionKeyConfig)
+		}
+		id := encryptionKeyID(c.Keys, suffix)
+		k, ok := c.Keys[id]
+		if !ok {
+			k.Algorithm = DefaultEncryptionAlgorithm
+		}
+		k.Key = key
+		c.Keys[id] = k
+	}
+}
+
+// Returns the ID in keys that the variable suffix names, or the suffix in
+// lower case for a key that is not in the config file.
+func encryptionKeyID(keys map[string]EncryptionKeyConfig, suffix string) string {
+	for id := range keys {
+		if strings.ToUpper(id) == suffix {
+			return id
+		}
+	}
+	return strings.ToLower(suffix)
+}
+
+// Overrides the fields having an env tag with the value of that variable.
+// The path of every overridden field is recorded in sources and a problem is
+// returned for each value that cannot be parsed.
+func overrideWithEnv(v reflect.Value, prefix string, sources map[string]string) []string {
 	if v.Kind() == reflect.Ptr {
 		v = v.Elem()
 	}
 	if v.Kind() != reflect.Struct {
-		return
+		return nil
 	}
+
+	var problems []string
 	typeOfV := v.Type()
 	for i := 0; i < v.NumField(); i++ {
 		field := v.Field(i)
 		structField := typeOfV.Field(i)
+		path := prefix + jsonName(structField)
 		if field.Kind() == reflect.Struct {
-			overrideWithEnv(field)
+			problems = append(problems, overrideWithEnv(field, path+".", sources)...)
 			continue
 		}
 		envTag := structField.Tag.Get("env")
 		if envTag == "" {
 			continue
 		}
-		if envVal, exists := os.LookupEnv(envTag); exists {
-			switch field.Kind() {
-			case reflect.String:
-				field.SetString(envVal)
-			case reflect.Int:
-				if intVal, err := strconv.Atoi(envVal); err == nil {
-					field.SetInt(int64(intVal))
-				}
-			case reflect.Bool:
-				if boolVal, err := strconv.ParseBool(envVal); err == nil {
-					field.SetBool(boolVal)
-				}
+		envVal, exists := os.LookupEnv(envTag)
+		if !exists {
+			continue
+		}
+
+		source := fmt.Sprintf("env %s=%q", envTag, envVal)
+		sources[path] = source
+
+		switch field.Kind() {
+		case reflect.String:
+			field.SetString(envVal)
+		case reflect.Int:
+			intVal, err := strconv.Atoi(envVal)
+			if err != nil {
+				problems = append(problems, problem(path, ruleMessage(structField, "must be an integer"), source))
+				continue
 			}
+			field.SetInt(int64(intVal))
+		case reflect.Bool:
+			boolVal, err := strconv.ParseBool(envVal)
+			if err != nil {
+				problems = append(problems, problem(path, "must be true or false", source))
+				continue
+			}
+			field.SetBool(boolVal)
 		}
 	}
+
+	return problems
 }
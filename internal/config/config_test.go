@@ -0,0 +1,244 @@
+package config_test
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+const validConfig = `{
+  "app": {"url": "http://localhost:8888"},
+  "db": {"driver": "pgx", "host": "localhost", "port": 5432, "user": "gopher", "db": "goweb",
+    "ssl_mode": "disable", "ping_timeout": 10},
+  "server": {"port": 8080, "read_timeout": 5, "write_timeout": 10, "idle_timeout": 60, "shutdown_timeout": 5},
+  "template": {"path": "web/templates", "layout_file": "layout.html", "partials_path": "partials", "pages_path": "pages"},
+  "session": {"cookie_name": "goweb_session", "lifetime": 86400},
+  "auth": {"verification_ttl": 86400, "password_reset_ttl": 3600, "api_key_touch_interval": 300},
+  "mail": {"driver": "log", "from": "no-reply@localhost",
+    "template": {"path": "web/templates/mail", "layout_file": "layout", "partials_path": "partials", "pages_path": "pages"}},
+  "worker": {"concurrency": 1, "poll_interval": 1, "max_attempts": 5, "backoff_base": 10, "backoff_max": 3600,
+    "job_timeout": 30},
+  "jwt": {"algorithm": "HS256", "secret": "0123456789abcdef0123456789abcdef", "issuer": "goweb",
+    "access_token_ttl": 900, "refresh_token_ttl": 86400},
+  "totp": {"issuer": "GoWeb", "challenge_ttl": 300, "max_attempts": 5},
+  "encryption": {"primary_key": "k1", "keys": {"k1": {"algorithm": "xchacha20-poly1305",
+    "key": "MDEyMzQ1Njc4OWFiY2RlZjAxMjM0NTY3ODlhYmNkZWY="}}},
+  "oidc": {"flow_ttl": 600, "providers": {"example": {"display_name": "Example",
+    "issuer": "https://accounts.example.com", "client_id": "goweb", "client_secret": "secret"}}},
+  "lockout": {"account_threshold": 5, "ip_threshold": 50, "lock_duration": 900, "window": 900, "base_delay": 1,
+    "max_delay": 30},
+  "rate_limit": {"store": "memory", "rules": {"api": {"algorithm": "token_bucket", "requests": 60, "window": 60,
+    "key": "ip"}}},
+  "csrf": {"cookie_name": "goweb_csrf"},
+  "headers": {"csp": {"directives": {"default-src": "'self'"}}, "hsts_max_age": 31536000},
+  "password": {"argon2": {"memory": 65536, "iterations": 3, "parallelism": 2, "salt_length": 16, "key_length": 32},
+    "policy": {"min_length": 12, "max_length": 128, "min_strength": 3}, "max_concurrency": 4, "queue_timeout": 2}
+}`
+
+func writeConfig(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func loadProblems(t *testing.T, path string) []string {
+	t.Helper()
+	_, err := config.LoadConfig(path)
+
+	var cfgErr *config.ValidationError
+	if !errors.As(err, &cfgErr) {
+		t.Fatalf("expected a validation error, got %v", err)
+	}
+	return cfgErr.Problems
+}
+
+func TestLoadConfig(t *testing.T) {
+	cfg, err := config.LoadConfig(writeConfig(t, validConfig))
+	assert.NoError(t, err)
+	assert.Equal(t, 5432, cfg.Db.Port)
+}
+
+func TestLoadConfigRepoConfig(t *testing.T) {
+	// No secret is committed, they come from the environment.
+	problems := loadProblems(t, "../../config.json")
+	assert.Equal(t, []string{
+		"jwt.secret: is required",
+		"encryption.primary_key: is required",
+		"encryption.keys: must not be empty",
+	}, problems)
+
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("ENCRYPTION_PRIMARY_KEY", "k1")
+	t.Setenv("ENCRYPTION_KEY_K1", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	_, err := config.LoadConfig("../../config.json")
+	assert.NoError(t, err)
+}
+
+func TestLoadConfigProductionSecrets(t *testing.T) {
+	t.Setenv("ENV", "production")
+
+	t.Setenv("JWT_SECRET", "dev-only-secret-change-me-in-production")
+	problems := loadProblems(t, writeConfig(t, validConfig))
+	assert.Equal(t, []string{"jwt.secret: must not be a development secret in production"}, problems)
+
+	t.Setenv("JWT_SECRET", "too-short")
+	problems = loadProblems(t, writeConfig(t, validConfig))
+	if assert.Len(t, problems, 1) {
+		assert.True(t, strings.HasPrefix(problems[0], "jwt.secret: must be at least 32 characters long"))
+	}
+
+	t.Setenv("JWT_SECRET", "0123456789abcdef0123456789abcdef")
+	t.Setenv("ENCRYPTION_KEY_K1", "Nq7VQ2qYz3xHk0m8e1cB5tW9rLp4sJd6gAf2hUo0yEI=")
+	problems = loadProblems(t, writeConfig(t, validConfig))
+	assert.Equal(t, []string{"encryption.keys[k1].key: must not be a development secret in production"}, problems)
+
+	t.Setenv("ENCRYPTION_KEY_K1", "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA=")
+	_, err := config.LoadConfig(writeConfig(t, validConfig))
+	assert.NoError(t, err)
+}
+
+func TestLoadConfigMissingValues(t *testing.T) {
+	problems := loadProblems(t, writeConfig(t, `{"db": {"ping_timeout": 0}}`))
+
+	assert.Contains(t, problems, "server.port: must be 1-65535")
+	assert.Contains(t, problems, "db.driver: is required")
+	assert.Contains(t, problems, "db.ping_timeout: must be at least 1")
+	assert.Contains(t, problems, "db.ssl_mode: is required")
+}
+
+func TestLoadConfigEnv(t *testing.T) {
+	t.Setenv("POSTGRES_PORT", "abc")
+	t.Setenv("SESSION_SECURE", "yes")
+	t.Setenv("PORT", "70000")
+
+	problems := loadProblems(t, writeConfig(t, validConfig))
+
+	assert.ElementsMatch(t, []string{
+		`db.port: must be 1-65535 (from env POSTGRES_PORT="abc")`,
+		`session.secure: must be true or false (from env SESSION_SECURE="yes")`,
+		`server.port: must be 1-65535 (from env PORT="70000")`,
+	}, problems)
+}
+
+func TestLoadConfigUnknownKeys(t *testing.T) {
+	contents := validConfig[:len(validConfig)-1] + `, "db_pass": "x", "worker": {"concurrency": 1, "threads": 4}}`
+	problems := loadProblems(t, writeConfig(t, contents))
+
+	assert.Contains(t, problems, "db_pass: unknown key")
+	assert.Contains(t, problems, "worker.threads: unknown key")
+}
+
+func TestLoadConfigWrongType(t *testing.T) {
+	contents := validConfig[:len(validConfig)-1] + `, "server": {"port": "8080"}}`
+	problems := loadProblems(t, writeConfig(t, contents))
+
+	assert.Contains(t, problems, "server.port: cannot be a string")
+}
+
+func TestLoadConfigOIDCSecretEnv(t *testing.T) {
+	t.Setenv("OIDC_EXAMPLE_CLIENT_SECRET", "from-env")
+
+	cfg, err := config.LoadConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "from-env", cfg.OIDC.Providers["example"].ClientSecret)
+	assert.Equal(t, "*", cfg.Redacted().OIDC.Providers["example"].ClientSecret)
+	assert.Equal(t, "from-env", cfg.OIDC.Providers["example"].ClientSecret, "redacting must not change the config")
+}
+
+func TestLoadConfigEncryptionKeyEnv(t *testing.T) {
+	const key = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	t.Setenv("ENCRYPTION_KEY_K1", key)
+
+	cfg, err := config.LoadConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, key, cfg.Encryption.Keys["k1"].Key)
+	assert.Equal(t, "*", cfg.Redacted().Encryption.Keys["k1"].Key)
+	assert.Equal(t, key, cfg.Encryption.Keys["k1"].Key, "redacting must not change the config")
+}
+
+func TestLoadConfigEncryptionKeyOnlyInEnv(t *testing.T) {
+	const key = "ZmVkY2JhOTg3NjU0MzIxMGZlZGNiYTk4NzY1NDMyMTA="
+	t.Setenv("ENCRYPTION_PRIMARY_KEY", "k2")
+	t.Setenv("ENCRYPTION_KEY_K2", key)
+
+	cfg, err := config.LoadConfig(writeConfig(t, validConfig))
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "k2", cfg.Encryption.PrimaryKey)
+	assert.Equal(t, config.EncryptionKeyConfig{Algorithm: config.DefaultEncryptionAlgorithm, Key: key},
+		cfg.Encryption.Keys["k2"])
+	assert.Contains(t, cfg.Encryption.Keys, "k1", "the keys of the config file are kept")
+}
+
+func TestLoadConfigInvalidOIDCProvider(t *testing.T) {
+	contents := strings.Replace(validConfig, `"client_id": "goweb", `, "", 1)
+	problems := loadProblems(t, writeConfig(t, contents))
+	if assert.Len(t, problems, 1) {
+		assert.Contains(t, problems[0], "client_id")
+	}
+}
+
+func TestLoadConfigInvalidRateLimitRule(t *testing.T) {
+	contents := strings.Replace(validConfig, `"algorithm": "token_bucket"`, `"algorithm": "leaky_bucket"`, 1)
+	problems := loadProblems(t, writeConfig(t, contents))
+	if assert.Len(t, problems, 1) {
+		assert.Contains(t, problems[0], "algorithm")
+	}
+}
+
+func TestLoadConfigInvalidTrustedProxy(t *testing.T) {
+	contents := strings.Replace(validConfig, `"shutdown_timeout": 5}`,
+		`"shutdown_timeout": 5, "trusted_proxies": ["10.0.0.0/8", "proxy.internal"]}`, 1)
+	problems := loadProblems(t, writeConfig(t, contents))
+	if assert.Len(t, problems, 1) {
+		assert.Contains(t, problems[0], "server.trusted_proxies[1]")
+	}
+}
+
+func TestLoadConfigLockTimeout(t *testing.T) {
+	tests := []struct {
+		name        string
+		lockTimeout int
+		valid       bool
+	}{
+		{"Disabled", 0, true},
+		{"Equal to job timeout", 30, false},
+		{"Longer than job timeout", 31, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			contents := strings.Replace(validConfig, `"job_timeout": 30`,
+				fmt.Sprintf(`"job_timeout": 30, "lock_timeout": %d`, tt.lockTimeout), 1)
+			path := writeConfig(t, contents)
+			if tt.valid {
+				_, err := config.LoadConfig(path)
+				assert.NoError(t, err)
+				return
+			}
+			assert.Equal(t, []string{"worker.lock_timeout: must be greater than JobTimeout"}, loadProblems(t, path))
+		})
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := config.Config{Db: config.DBConfig{Pass: "secret"}}
+
+	redacted := cfg.Redacted()
+	assert.Equal(t, "*", redacted.Db.Pass)
+	assert.Empty(t, redacted.Mail.Pass)
+	assert.Equal(t, "secret", cfg.Db.Pass)
+}
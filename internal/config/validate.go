@@ -0,0 +1,190 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/go-playground/validator/v10"
+)
+
+// ValidationError lists every problem found while loading a config.
+type ValidationError struct {
+	Path     string
+	Problems []string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid config %s: %s", e.Path, strings.Join(e.Problems, "; "))
+}
+
+// Validates cfg against the validate struct tags. Problems with a value that
+// came from the environment name the variable it was read from.
+func validate(cfg *Config, sources map[string]string) []string {
+	err := validation.New().Struct(cfg)
+	if err == nil {
+		return nil
+	}
+
+	var errs validator.ValidationErrors
+	if !errors.As(err, &errs) {
+		return []string{err.Error()}
+	}
+
+	problems := make([]string, 0, len(errs))
+	for _, e := range errs {
+		// Namespace is made of json names prefixed with the name of the root struct.
+		_, path, _ := strings.Cut(e.Namespace(), ".")
+
+		msg := fmt.Sprintf("is invalid (%s)", e.Tag())
+		if e.Tag() == "required" || strings.HasPrefix(e.Tag(), "required_") {
+			msg = "is required"
+		} else if field, ok := structField(e.StructNamespace()); ok {
+			msg = ruleMessage(field, msg)
+		}
+
+		problems = append(problems, problem(path, msg, sources[path]))
+	}
+
+	return problems
+}
+
+// Secrets of the example files, which are public.
+var devSecrets = []string{
+	"dev-only-secret-change-me-in-production",
+	"Nq7VQ2qYz3xHk0m8e1cB5tW9rLp4sJd6gAf2hUo0yEI=",
+}
+
+// Returns the problems that keep a development config from running in
+// production. The values are left out of them since they are secrets.
+func productionProblems(cfg *Config) []string {
+	if cfg.App.Env != "production" {
+		return nil
+	}
+
+	var problems []string
+	if slices.Contains(devSecrets, cfg.JWT.Secret) {
+		problems = append(problems, "jwt.secret: must not be a development secret in production")
+	}
+	for _, id := range slices.Sorted(maps.Keys(cfg.Encryption.Keys)) {
+		if slices.Contains(devSecrets, cfg.Encryption.Keys[id].Key) {
+			problems = append(problems, fmt.Sprintf("encryption.keys[%s].key: must not be a development secret in production", id))
+		}
+	}
+	return problems
+}
+
+func problem(path, msg, source string) string {
+	if source != "" {
+		return fmt.Sprintf("%s: %s (from %s)", path, msg, source)
+	}
+	return fmt.Sprintf("%s: %s", path, msg)
+}
+
+// Looks up a field of Config by its Go namespace such as Config.Db.Port.
+func structField(namespace string) (reflect.StructField, bool) {
+	names := strings.Split(namespace, ".")[1:]
+
+	t := reflect.TypeOf(Config{})
+	var field reflect.StructField
+	for _, name := range names {
+		f, ok := t.FieldByName(name)
+		if !ok {
+			return reflect.StructField{}, false
+		}
+		field, t = f, f.Type
+	}
+
+	return field, len(names) > 0
+}
+
+// Describes the constraint of a field from its validate tag, falling back to
+// fallback when the tag has no constraint on the value.
+func ruleMessage(field reflect.StructField, fallback string) string {
+	rules := make(map[string]string)
+	for _, rule := range strings.Split(field.Tag.Get("validate"), ",") {
+		name, param, _ := strings.Cut(rule, "=")
+		rules[name] = param
+	}
+
+	minVal, hasMin := rules["min"]
+	maxVal, hasMax := rules["max"]
+	isNumber := field.Type.Kind() == reflect.Int
+	isCollection := field.Type.Kind() == reflect.Map || field.Type.Kind() == reflect.Slice
+
+	switch {
+	case hasMin && isCollection && minVal == "1":
+		return "must not be empty"
+	case hasMin && isCollection:
+		return fmt.Sprintf("must have at least %s entries", minVal)
+	case hasMin && hasMax && isNumber:
+		return fmt.Sprintf("must be %s-%s", minVal, maxVal)
+	case hasMin && isNumber:
+		return fmt.Sprintf("must be at least %s", minVal)
+	case hasMin:
+		return fmt.Sprintf("must be at least %s characters long", minVal)
+	case hasMax && isNumber:
+		return fmt.Sprintf("must be at most %s", maxVal)
+	}
+
+	if param, ok := rules["gte"]; ok {
+		return fmt.Sprintf("must be at least %s", param)
+	}
+	if param, ok := rules["gtfield"]; ok {
+		return fmt.Sprintf("must be greater than %s", param)
+	}
+	if param, ok := rules["gtefield"]; ok {
+		return fmt.Sprintf("must not be less than %s", param)
+	}
+	if param, ok := rules["oneof"]; ok {
+		return fmt.Sprintf("must be one of %s", strings.Join(strings.Fields(param), ", "))
+	}
+	if _, ok := rules["url"]; ok {
+		return "must be a valid URL"
+	}
+
+	return fallback
+}
+
+// Returns the path of every key in data that does not map to a field of t,
+// so that a misspelled key is reported instead of silently ignored.
+func unknownKeys(data []byte, t reflect.Type, prefix string) []string {
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]reflect.StructField, t.NumField())
+	for i := range t.NumField() {
+		f := t.Field(i)
+		fields[jsonName(f)] = f
+	}
+
+	var problems []string
+	for key, value := range raw {
+		field, ok := fields[key]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s%s: unknown key", prefix, key))
+			continue
+		}
+		if field.Type.Kind() == reflect.Struct {
+			problems = append(problems, unknownKeys(value, field.Type, prefix+key+".")...)
+		}
+	}
+
+	slices.Sort(problems)
+	return problems
+}
+
+func jsonName(field reflect.StructField) string {
+	name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+	if name == "" {
+		return field.Name
+	}
+	return name
+}
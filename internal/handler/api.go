@@ -7,6 +7,8 @@ import (
 	"time"
 
 	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
 	"github.com/ferdiebergado/goweb/internal/pkg/message"
 	"github.com/ferdiebergado/goweb/internal/service"
 )
@@ -18,14 +20,20 @@ type APIResponse[T any] struct {
 }
 
 type APIHandler struct {
-	Base BaseAPIHandler
-	User UserAPIHandler
+	Base      BaseAPIHandler
+	User      UserAPIHandler
+	Token     TokenAPIHandler
+	APIKey    APIKeyAPIHandler
+	TwoFactor TwoFactorAPIHandler
 }
 
-func NewAPIHandler(svc service.Service) *APIHandler {
+func NewAPIHandler(svc service.Service, cfg *config.Config) *APIHandler {
 	return &APIHandler{
-		Base: *NewBaseAPIHandler(svc.Base),
-		User: *NewUserAPIHandler(svc.User),
+		Base:      *NewBaseAPIHandler(svc.Base),
+		User:      *NewUserAPIHandler(svc.User, &cfg.Session),
+		Token:     *NewTokenAPIHandler(svc.Token),
+		APIKey:    *NewAPIKeyAPIHandler(svc.APIKey),
+		TwoFactor: *NewTwoFactorAPIHandler(svc.TwoFactor, &cfg.Session),
 	}
 }
 
@@ -52,17 +60,19 @@ func (h *BaseAPIHandler) HandleHealth(w http.ResponseWriter, r *http.Request) {
 
 type UserAPIHandler struct {
 	service service.UserService
+	cfg     *config.SessionConfig
 }
 
-func NewUserAPIHandler(userService service.UserService) *UserAPIHandler {
+func NewUserAPIHandler(userService service.UserService, cfg *config.SessionConfig) *UserAPIHandler {
 	return &UserAPIHandler{
 		service: userService,
+		cfg:     cfg,
 	}
 }
 
 type RegisterUserRequest struct {
 	Email           string `json:"email,omitempty" validate:"required,email"`
-	Password        string `json:"password,omitempty" validate:"required"`
+	Password        string `json:"password,omitempty" validate:"required,password"`
 	PasswordConfirm string `json:"password_confirm,omitempty" validate:"required,eqfield=Password"`
 }
 
@@ -82,10 +92,11 @@ func (h *UserAPIHandler) HandleUserRegister(w http.ResponseWriter, r *http.Reque
 	user, err := h.service.RegisterUser(r.Context(), params)
 	if err != nil {
 		if errors.Is(err, service.ErrDuplicateUser) {
-			unprocessableError(w, r, err)
+			// The wrapped error may carry database details that must not reach the client.
+			errorResponse(w, r, http.StatusUnprocessableEntity, err, service.ErrDuplicateUser.Error())
 			return
 		}
-		response.ServerError(w, r, err)
+		serverError(w, r, err)
 		return
 	}
 
@@ -101,3 +112,612 @@ func (h *UserAPIHandler) HandleUserRegister(w http.ResponseWriter, r *http.Reque
 
 	response.JSON(w, r, http.StatusCreated, res)
 }
+
+type LoginUserRequest struct {
+	Email    string `json:"email,omitempty" validate:"required,email"`
+	Password string `json:"password,omitempty" validate:"required"`
+}
+
+// LoginUserResponse carries a challenge token instead of a session when the
+// user has to complete the second step of signing in.
+type LoginUserResponse struct {
+	ID                string    `json:"id"`
+	Email             string    `json:"email"`
+	ExpiresAt         time.Time `json:"expires_at"`
+	TwoFactorRequired bool      `json:"two_factor_required,omitempty"`
+	ChallengeToken    string    `json:"challenge_token,omitempty"`
+}
+
+func (h *UserAPIHandler) HandleUserLogin(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[LoginUserRequest](r.Context())
+	params := service.LoginUserParams{
+		Email:     req.Email,
+		Password:  req.Password,
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	}
+	result, err := h.service.LoginUser(r.Context(), params)
+	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			tooManyRequestsError(w, r, err, throttled.RetryAfter)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) {
+			unauthorizedError(w, r, err)
+			return
+		}
+		if errors.Is(err, service.ErrUserNotVerified) || errors.Is(err, service.ErrUserDisabled) {
+			forbiddenError(w, r, err)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	if result.ChallengeToken != "" {
+		w.Header().Set("Cache-Control", "no-store")
+		response.JSON(w, r, http.StatusOK, APIResponse[*LoginUserResponse]{
+			Message: message.Get("twoFactorRequired"),
+			Data: &LoginUserResponse{
+				ID:                result.User.ID,
+				Email:             result.User.Email,
+				ExpiresAt:         result.ExpiresAt,
+				TwoFactorRequired: true,
+				ChallengeToken:    result.ChallengeToken,
+			},
+		})
+		return
+	}
+
+	loginSucceeded(w, r, h.cfg, result)
+}
+
+// Sets the session cookie and writes the login response.
+func loginSucceeded(w http.ResponseWriter, r *http.Request, cfg *config.SessionConfig,
+	result *service.LoginUserResult) {
+	setSessionCookie(w, cfg, result)
+
+	res := APIResponse[*LoginUserResponse]{
+		Message: message.Get("loginSuccess"),
+		Data: &LoginUserResponse{
+			ID:        result.User.ID,
+			Email:     result.User.Email,
+			ExpiresAt: result.ExpiresAt,
+		},
+	}
+
+	response.JSON(w, r, http.StatusOK, res)
+}
+
+func setSessionCookie(w http.ResponseWriter, cfg *config.SessionConfig, result *service.LoginUserResult) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     cfg.CookieName,
+		Value:    result.Token,
+		Path:     "/",
+		Expires:  result.ExpiresAt,
+		MaxAge:   int(time.Until(result.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   cfg.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+func (h *UserAPIHandler) HandleUserLogout(w http.ResponseWriter, r *http.Request) {
+	if cookie, err := r.Cookie(h.cfg.CookieName); err == nil {
+		if err := h.service.LogoutUser(r.Context(), cookie.Value); err != nil {
+			serverError(w, r, err)
+			return
+		}
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     h.cfg.CookieName,
+		Value:    "",
+		Path:     "/",
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cfg.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("logoutSuccess")})
+}
+
+type CurrentUserResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (h *UserAPIHandler) HandleCurrentUser(w http.ResponseWriter, r *http.Request) {
+	user, _ := FromUserContext(r.Context())
+	response.JSON(w, r, http.StatusOK, APIResponse[*CurrentUserResponse]{
+		Data: &CurrentUserResponse{
+			ID:         user.ID,
+			Email:      user.Email,
+			VerifiedAt: user.VerifiedAt,
+			CreatedAt:  user.CreatedAt,
+		},
+	})
+}
+
+type UserResponse struct {
+	ID         string     `json:"id"`
+	Email      string     `json:"email"`
+	VerifiedAt *time.Time `json:"verified_at"`
+	DisabledAt *time.Time `json:"disabled_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+func (h *UserAPIHandler) HandleListUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.ListUsers(r.Context())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	res := make([]UserResponse, 0, len(users))
+	for _, u := range users {
+		res = append(res, UserResponse{
+			ID:         u.ID,
+			Email:      u.Email,
+			VerifiedAt: u.VerifiedAt,
+			DisabledAt: u.DisabledAt,
+			CreatedAt:  u.CreatedAt,
+		})
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[[]UserResponse]{Data: res})
+}
+
+type ForgotPasswordRequest struct {
+	Email string `json:"email,omitempty" validate:"required,email"`
+}
+
+func (h *UserAPIHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[ForgotPasswordRequest](r.Context())
+	if err := h.service.RequestPasswordReset(r.Context(), req.Email); err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("resetSent")})
+}
+
+type ResetPasswordRequest struct {
+	Token           string `json:"token,omitempty" validate:"required"`
+	Password        string `json:"password,omitempty" validate:"required,password"`
+	PasswordConfirm string `json:"password_confirm,omitempty" validate:"required,eqfield=Password"`
+}
+
+func (h *UserAPIHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[ResetPasswordRequest](r.Context())
+	params := service.ResetPasswordParams{
+		Token:    req.Token,
+		Password: req.Password,
+	}
+	if err := h.service.ResetPassword(r.Context(), params); err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			unprocessableError(w, r, err)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("resetSuccess")})
+}
+
+type TokenAPIHandler struct {
+	service service.TokenService
+}
+
+func NewTokenAPIHandler(tokenService service.TokenService) *TokenAPIHandler {
+	return &TokenAPIHandler{service: tokenService}
+}
+
+// Grant types accepted by the token endpoint
+const (
+	GrantPassword     = "password"
+	GrantRefreshToken = "refresh_token"
+)
+
+type TokenRequest struct {
+	GrantType    string `json:"grant_type,omitempty" validate:"required,oneof=password refresh_token"`
+	Email        string `json:"email,omitempty" validate:"required_if=GrantType password,omitempty,email"`
+	Password     string `json:"password,omitempty" validate:"required_if=GrantType password"`
+	RefreshToken string `json:"refresh_token,omitempty" validate:"required_if=GrantType refresh_token"`
+	// Required with the password grant when the user has 2FA enabled
+	Code string `json:"code,omitempty"`
+}
+
+type TokenResponse struct {
+	AccessToken           string    `json:"access_token"`
+	TokenType             string    `json:"token_type"`
+	ExpiresIn             int       `json:"expires_in"`
+	RefreshToken          string    `json:"refresh_token"`
+	RefreshTokenExpiresAt time.Time `json:"refresh_token_expires_at"`
+}
+
+// HandleToken issues a token pair for the credentials of a user or exchanges
+// a refresh token for a new pair.
+func (h *TokenAPIHandler) HandleToken(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[TokenRequest](r.Context())
+
+	var pair *service.TokenPair
+	var err error
+	if req.GrantType == GrantRefreshToken {
+		pair, err = h.service.RefreshTokens(r.Context(), req.RefreshToken)
+	} else {
+		pair, err = h.service.IssueTokens(r.Context(), service.IssueTokensParams{
+			Email:     req.Email,
+			Password:  req.Password,
+			Code:      req.Code,
+			IPAddress: clientIP(r),
+		})
+	}
+
+	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			tooManyRequestsError(w, r, err, throttled.RetryAfter)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidCredentials) || errors.Is(err, service.ErrInvalidToken) ||
+			errors.Is(err, service.ErrTwoFactorRequired) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			unauthorizedError(w, r, err)
+			return
+		}
+		if errors.Is(err, service.ErrUserNotVerified) || errors.Is(err, service.ErrUserDisabled) {
+			forbiddenError(w, r, err)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	// Tokens must not be stored by intermediaries.
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusOK, APIResponse[*TokenResponse]{
+		Data: &TokenResponse{
+			AccessToken:           pair.AccessToken,
+			TokenType:             bearerScheme,
+			ExpiresIn:             int(time.Until(pair.AccessTokenExpiresAt).Round(time.Second).Seconds()),
+			RefreshToken:          pair.RefreshToken,
+			RefreshTokenExpiresAt: pair.RefreshTokenExpiresAt,
+		},
+	})
+}
+
+type RevokeTokenRequest struct {
+	RefreshToken string `json:"refresh_token,omitempty" validate:"required"`
+}
+
+// HandleRevokeToken signs out a bearer token client. Access tokens already
+// issued stay valid until they expire.
+func (h *TokenAPIHandler) HandleRevokeToken(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[RevokeTokenRequest](r.Context())
+	if err := h.service.RevokeRefreshToken(r.Context(), req.RefreshToken); err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("logoutSuccess")})
+}
+
+// APIKeyAPIHandler lets users manage their own API keys. Keys cannot be
+// managed with an API key so that a scoped key cannot mint a broader one.
+type APIKeyAPIHandler struct {
+	service service.APIKeyService
+}
+
+func NewAPIKeyAPIHandler(apiKeyService service.APIKeyService) *APIKeyAPIHandler {
+	return &APIKeyAPIHandler{service: apiKeyService}
+}
+
+type CreateAPIKeyRequest struct {
+	Name      string     `json:"name,omitempty" validate:"required,max=100"`
+	Scopes    []string   `json:"scopes,omitempty" validate:"omitempty,dive,required"`
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+}
+
+type APIKeyResponse struct {
+	ID         string     `json:"id"`
+	Name       string     `json:"name"`
+	Prefix     string     `json:"prefix"`
+	Scopes     []string   `json:"scopes"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	LastUsedAt *time.Time `json:"last_used_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+}
+
+// CreateAPIKeyResponse is the only response that includes the key itself.
+type CreateAPIKeyResponse struct {
+	APIKeyResponse
+	Key string `json:"key"`
+}
+
+func newAPIKeyResponse(k *model.APIKey) APIKeyResponse {
+	scopes := k.Scopes
+	if scopes == nil {
+		scopes = []string{}
+	}
+	return APIKeyResponse{
+		ID:         k.ID,
+		Name:       k.Name,
+		Prefix:     k.Prefix,
+		Scopes:     scopes,
+		ExpiresAt:  k.ExpiresAt,
+		LastUsedAt: k.LastUsedAt,
+		CreatedAt:  k.CreatedAt,
+	}
+}
+
+// Returns the user of the request unless it was authenticated with an API
+// key, in which case a 403 has been written. Used by endpoints that manage
+// credentials, which a key must not be able to do on behalf of the user.
+func interactiveUser(w http.ResponseWriter, r *http.Request) (*model.User, bool) {
+	if _, isKey := FromAPIKeyContext(r.Context()); isKey {
+		response.JSON(w, r, http.StatusForbidden, APIResponse[any]{Message: message.Get("forbidden")})
+		return nil, false
+	}
+	user, _ := FromUserContext(r.Context())
+	return user, true
+}
+
+func (h *APIKeyAPIHandler) HandleCreateAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, req, _ := FromParamsContext[CreateAPIKeyRequest](r.Context())
+	result, err := h.service.CreateAPIKey(r.Context(), service.CreateAPIKeyParams{
+		UserID:    user.ID,
+		Name:      req.Name,
+		Scopes:    req.Scopes,
+		ExpiresAt: req.ExpiresAt,
+	})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidScope) || errors.Is(err, service.ErrInvalidExpiry) {
+			unprocessableError(w, r, err)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusCreated, APIResponse[*CreateAPIKeyResponse]{
+		Message: message.Get("apiKeyCreated"),
+		Data: &CreateAPIKeyResponse{
+			APIKeyResponse: newAPIKeyResponse(result.APIKey),
+			Key:            result.Key,
+		},
+	})
+}
+
+func (h *APIKeyAPIHandler) HandleListAPIKeys(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	keys, err := h.service.ListAPIKeys(r.Context(), user.ID)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+
+	res := make([]APIKeyResponse, 0, len(keys))
+	for i := range keys {
+		res = append(res, newAPIKeyResponse(&keys[i]))
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[[]APIKeyResponse]{Data: res})
+}
+
+func (h *APIKeyAPIHandler) HandleRevokeAPIKey(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	if err := h.service.RevokeAPIKey(r.Context(), user.ID, r.PathValue("id")); err != nil {
+		if errors.Is(err, service.ErrAPIKeyNotFound) {
+			errorResponse(w, r, http.StatusNotFound, err, service.ErrAPIKeyNotFound.Error())
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("apiKeyRevoked")})
+}
+
+// TwoFactorAPIHandler enrolls users in TOTP two-factor authentication and
+// completes the second step of signing in.
+type TwoFactorAPIHandler struct {
+	service service.TwoFactorService
+	cfg     *config.SessionConfig
+}
+
+func NewTwoFactorAPIHandler(twoFactorService service.TwoFactorService, cfg *config.SessionConfig) *TwoFactorAPIHandler {
+	return &TwoFactorAPIHandler{
+		service: twoFactorService,
+		cfg:     cfg,
+	}
+}
+
+type TwoFactorEnrollmentResponse struct {
+	Secret string `json:"secret"`
+	URI    string `json:"uri"`
+}
+
+// HandleEnroll starts an enrollment. The QR code of the returned URI is
+// served by HandleQRCode.
+func (h *TwoFactorAPIHandler) HandleEnroll(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	enrollment, err := h.service.BeginEnrollment(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			errorResponse(w, r, http.StatusConflict, err, err.Error())
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusOK, APIResponse[*TwoFactorEnrollmentResponse]{
+		Data: &TwoFactorEnrollmentResponse{
+			Secret: enrollment.Secret,
+			URI:    enrollment.URI,
+		},
+	})
+}
+
+func (h *TwoFactorAPIHandler) HandleQRCode(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	png, err := h.service.EnrollmentQRCode(r.Context(), user)
+	if err != nil {
+		if errors.Is(err, service.ErrTwoFactorNotEnrolled) {
+			errorResponse(w, r, http.StatusNotFound, err, err.Error())
+			return
+		}
+		if errors.Is(err, service.ErrTwoFactorEnabled) {
+			errorResponse(w, r, http.StatusConflict, err, err.Error())
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	// The image encodes the secret.
+	w.Header().Set("Cache-Control", "no-store")
+	w.Header().Set(HeaderContentType, "image/png")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(png); err != nil {
+		slog.Error("failed to write qr code", "reason", err)
+	}
+}
+
+type TwoFactorCodeRequest struct {
+	Code string `json:"code,omitempty" validate:"required,max=32"`
+}
+
+type RecoveryCodesResponse struct {
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+func (h *TwoFactorAPIHandler) HandleConfirm(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, req, _ := FromParamsContext[TwoFactorCodeRequest](r.Context())
+	codes, err := h.service.ConfirmEnrollment(r.Context(), service.TwoFactorCodeParams{
+		User:      user,
+		Code:      req.Code,
+		IPAddress: clientIP(r),
+	})
+	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			tooManyRequestsError(w, r, err, throttled.RetryAfter)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			unprocessableError(w, r, err)
+			return
+		}
+		if errors.Is(err, service.ErrTwoFactorNotEnrolled) || errors.Is(err, service.ErrTwoFactorEnabled) {
+			errorResponse(w, r, http.StatusConflict, err, err.Error())
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	response.JSON(w, r, http.StatusOK, APIResponse[*RecoveryCodesResponse]{
+		Message: message.Get("twoFactorEnabled"),
+		Data:    &RecoveryCodesResponse{RecoveryCodes: codes},
+	})
+}
+
+func (h *TwoFactorAPIHandler) HandleDisable(w http.ResponseWriter, r *http.Request) {
+	user, ok := interactiveUser(w, r)
+	if !ok {
+		return
+	}
+
+	_, req, _ := FromParamsContext[TwoFactorCodeRequest](r.Context())
+	err := h.service.DisableTwoFactor(r.Context(), service.TwoFactorCodeParams{
+		User:      user,
+		Code:      req.Code,
+		IPAddress: clientIP(r),
+	})
+	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			tooManyRequestsError(w, r, err, throttled.RetryAfter)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			unprocessableError(w, r, err)
+			return
+		}
+		if errors.Is(err, service.ErrTwoFactorNotEnabled) {
+			errorResponse(w, r, http.StatusConflict, err, err.Error())
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, APIResponse[any]{Message: message.Get("twoFactorDisabled")})
+}
+
+type CompleteLoginRequest struct {
+	ChallengeToken string `json:"challenge_token,omitempty" validate:"required"`
+	Code           string `json:"code,omitempty" validate:"required,max=32"`
+}
+
+// HandleCompleteLogin exchanges the challenge returned by the login endpoint
+// and a TOTP or recovery code for a session.
+func (h *TwoFactorAPIHandler) HandleCompleteLogin(w http.ResponseWriter, r *http.Request) {
+	_, req, _ := FromParamsContext[CompleteLoginRequest](r.Context())
+	result, err := h.service.CompleteLogin(r.Context(), service.CompleteLoginParams{
+		ChallengeToken: req.ChallengeToken,
+		Code:           req.Code,
+		UserAgent:      r.UserAgent(),
+		IPAddress:      clientIP(r),
+	})
+	if err != nil {
+		var throttled *service.ThrottledError
+		if errors.As(err, &throttled) {
+			tooManyRequestsError(w, r, err, throttled.RetryAfter)
+			return
+		}
+		if errors.Is(err, service.ErrInvalidToken) || errors.Is(err, service.ErrInvalidTwoFactorCode) {
+			unauthorizedError(w, r, err)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	loginSucceeded(w, r, h.cfg, result)
+}
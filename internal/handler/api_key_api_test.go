@@ -0,0 +1,97 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const keysUrl = "/api/keys"
+
+func newAPIKeyRouter(t *testing.T, setup func(*mock.MockAPIKeyService)) *goexpress.Router {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockAPIKeyService(ctrl)
+	setup(mockService)
+
+	h := handler.NewAPIKeyAPIHandler(mockService)
+	r := goexpress.New()
+	r.Post(keysUrl, h.HandleCreateAPIKey,
+		handler.DecodeJSON[handler.CreateAPIKeyRequest](), handler.ValidateInput[handler.CreateAPIKeyRequest](validate))
+	r.Delete(keysUrl+"/{id}", h.HandleRevokeAPIKey)
+	return r
+}
+
+func newKeyRequest(t *testing.T, method, url string, body any) *http.Request {
+	t.Helper()
+	var buf bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&buf).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	req := httptest.NewRequest(method, url, &buf)
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	return req.WithContext(handler.NewUserContext(req.Context(), &model.User{Model: model.Model{ID: "1"}}))
+}
+
+func TestAPIKeyHandlerHandleCreateAPIKey(t *testing.T) {
+	r := newAPIKeyRouter(t, func(m *mock.MockAPIKeyService) {
+		m.EXPECT().CreateAPIKey(gomock.Any(), service.CreateAPIKeyParams{
+			UserID: "1",
+			Name:   "ci",
+			Scopes: []string{model.PermUsersRead},
+		}).Return(&service.CreateAPIKeyResult{
+			APIKey: &model.APIKey{ID: "2", Name: "ci", Prefix: "gwk_12345678", Scopes: []string{model.PermUsersRead}},
+			Key:    "gwk_12345678_secret",
+		}, nil)
+	})
+
+	req := newKeyRequest(t, http.MethodPost, keysUrl,
+		handler.CreateAPIKeyRequest{Name: "ci", Scopes: []string{model.PermUsersRead}})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+
+	var apiRes handler.APIResponse[handler.CreateAPIKeyResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, "gwk_12345678_secret", apiRes.Data.Key)
+	assert.Equal(t, "gwk_12345678", apiRes.Data.Prefix)
+}
+
+func TestAPIKeyHandlerHandleCreateAPIKeyWithAPIKey(t *testing.T) {
+	r := newAPIKeyRouter(t, func(*mock.MockAPIKeyService) {})
+
+	req := newKeyRequest(t, http.MethodPost, keysUrl, handler.CreateAPIKeyRequest{Name: "ci"})
+	req = req.WithContext(handler.NewAPIKeyContext(req.Context(), &model.APIKey{ID: "2"}))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestAPIKeyHandlerHandleRevokeAPIKeyNotFound(t *testing.T) {
+	r := newAPIKeyRouter(t, func(m *mock.MockAPIKeyService) {
+		m.EXPECT().RevokeAPIKey(gomock.Any(), "1", "3").Return(service.ErrAPIKeyNotFound)
+	})
+
+	req := newKeyRequest(t, http.MethodDelete, keysUrl+"/3", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/ferdiebergado/goexpress"
 	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
 	"github.com/ferdiebergado/goweb/internal/pkg/security"
 	"github.com/ferdiebergado/goweb/internal/repository"
 	"github.com/ferdiebergado/goweb/internal/service"
@@ -15,29 +16,41 @@ import (
 type App struct {
 	cfg       *config.Config
 	db        *sql.DB
+	keyring   *security.Keyring
 	router    *goexpress.Router
 	validater *validator.Validate
 	template  *Template
 	hasher    security.Hasher
+	jwt       *security.JWT
+	csrf      *CSRFMiddleware
+	headers   *SecurityHeaders
+	clientIP  *ClientIP
 }
 
 type AppDependencies struct {
 	Config    *config.Config
 	DB        *sql.DB
+	Keyring   *security.Keyring
 	Router    *goexpress.Router
 	Validator *validator.Validate
 	Template  *Template
 	Hasher    security.Hasher
+	JWT       *security.JWT
 }
 
 func NewApp(deps *AppDependencies) *App {
 	app := &App{
 		cfg:       deps.Config,
 		db:        deps.DB,
+		keyring:   deps.Keyring,
 		router:    deps.Router,
 		validater: deps.Validator,
 		template:  deps.Template,
 		hasher:    deps.Hasher,
+		jwt:       deps.JWT,
+		csrf:      NewCSRFMiddleware(deps.Config),
+		headers:   NewSecurityHeaders(&deps.Config.Headers),
+		clientIP:  NewClientIP(&deps.Config.Server),
 	}
 	app.SetupMiddlewares()
 	return app
@@ -49,21 +62,41 @@ func (a *App) Router() *goexpress.Router {
 
 func (a *App) SetupMiddlewares() {
 	a.router.Use(goexpress.RecoverFromPanic)
+	a.router.Use(a.clientIP.Handle)
 	a.router.Use(goexpress.LogRequest)
+	a.router.Use(a.headers.Handle)
+	a.router.Use(a.csrf.Protect)
 }
 
-func (a *App) SetupRoutes() {
+func (a *App) SetupRoutes() error {
 	if a.cfg.App.Env == "development" {
 		const prefix = "/assets/"
 		a.router.Handle("GET "+prefix, http.StripPrefix(prefix, http.FileServer(http.Dir("web/assets/"))))
 	}
 
-	repo := repository.NewRepository(a.db)
-	svc := service.NewService(repo, a.hasher)
+	repo := repository.NewRepository(a.db, a.keyring)
+	svc := service.NewService(repo, a.hasher, a.jwt, a.cfg)
 
-	htmlHandler := NewHandler(a.template)
-	apiHandler := NewAPIHandler(*svc)
+	htmlHandler := NewHandler(a.template, *svc, a.cfg)
+	apiHandler := NewAPIHandler(*svc, a.cfg)
 
-	mountRoutes(a.router, htmlHandler)
-	mountAPIRoutes(a.router, apiHandler, a.validater)
+	auth := NewAuthMiddleware(*svc, &a.cfg.Session)
+	a.template.AddRequestFuncs(auth.TemplateFuncs)
+	a.template.AddRequestFuncs(a.csrf.TemplateFuncs)
+	a.template.AddRequestFuncs(a.headers.TemplateFuncs)
+
+	var store ratelimit.Store
+	if a.cfg.RateLimit.Store == "postgres" {
+		store = repository.NewRateLimitStore(a.db)
+	} else {
+		store = ratelimit.NewMemoryStore()
+	}
+	rl, err := NewRateLimiter(store, &a.cfg.RateLimit)
+	if err != nil {
+		return err
+	}
+
+	mountRoutes(a.router, htmlHandler, auth, rl)
+	mountAPIRoutes(a.router, apiHandler, a.validater, auth, rl)
+	return nil
 }
@@ -0,0 +1,82 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// Returns the router of an app with all its middlewares and routes, on a
+// stub database.
+func newTestApp(t *testing.T) (*goexpress.Router, sqlmock.Sqlmock) {
+	t.Helper()
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	app := handler.NewApp(&handler.AppDependencies{
+		Config: &config.Config{
+			App:       config.EnvConfig{URL: "http://localhost:8888"},
+			Session:   config.SessionConfig{CookieName: sessionCookie, Lifetime: 3600},
+			CSRF:      config.CSRFConfig{CookieName: csrfCookie},
+			RateLimit: config.RateLimitConfig{Store: "memory"},
+		},
+		DB:        db,
+		Router:    goexpress.New(),
+		Validator: validation.New(),
+		Template:  newTemplate(t),
+	})
+	if err := app.SetupRoutes(); err != nil {
+		t.Fatal(err)
+	}
+	return app.Router(), mock
+}
+
+// Mobile apps call the token endpoints without cookies and CSRF tokens.
+func TestAppTokenEndpointsWithoutCSRFToken(t *testing.T) {
+	r, mock := newTestApp(t)
+
+	tests := []struct {
+		name   string
+		url    string
+		body   any
+		status int
+	}{
+		{"Refresh", "/api/auth/token",
+			handler.TokenRequest{GrantType: handler.GrantRefreshToken, RefreshToken: "unknown"}, http.StatusUnauthorized},
+		{"Revoke", "/api/auth/token/revoke", handler.RevokeTokenRequest{RefreshToken: "unknown"}, http.StatusOK},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock.ExpectQuery(repository.FindRefreshTokenByHashQuery).
+				WithArgs(sqlmock.AnyArg()).
+				WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "token_hash", "expires_at",
+					"revoked_at", "created_at"}))
+
+			body, err := json.Marshal(tt.body)
+			if err != nil {
+				t.Fatal(err)
+			}
+			req := httptest.NewRequest(http.MethodPost, tt.url, bytes.NewReader(body))
+			req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.status, rr.Code, rr.Body.String())
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"errors"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+)
+
+const (
+	apiPrefix    = "/api"
+	loginPath    = "/auth/login"
+	bearerScheme = "Bearer"
+	apiKeyScheme = "ApiKey"
+)
+
+// AuthMiddleware resolves the user of the API key, bearer token or session
+// cookie, stores it in the request context and checks its permissions.
+type AuthMiddleware struct {
+	service service.UserService
+	authz   service.AuthorizationService
+	tokens  service.TokenService
+	apiKeys service.APIKeyService
+	cfg     *config.SessionConfig
+}
+
+func NewAuthMiddleware(svc service.Service, cfg *config.SessionConfig) *AuthMiddleware {
+	return &AuthMiddleware{
+		service: svc.User,
+		authz:   svc.Authorization,
+		tokens:  svc.Token,
+		apiKeys: svc.APIKey,
+		cfg:     cfg,
+	}
+}
+
+// RequireAuth rejects requests without a valid session. HTML requests are
+// redirected to the login page while API requests get a 401 JSON response.
+func (m *AuthMiddleware) RequireAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, ok, err := m.authenticate(r)
+		if err != nil {
+			response.ServerError(w, r, err)
+			return
+		}
+
+		if !ok {
+			unauthenticated(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// RequirePermission rejects requests whose user lacks permission, or whose
+// API key is not scoped to it, with a 403. It authenticates the request
+// itself so it can be used without RequireAuth.
+func (m *AuthMiddleware) RequirePermission(permission string) goexpress.Middleware {
+	return func(next http.Handler) http.Handler {
+		return m.RequireAuth(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			user, _ := FromUserContext(r.Context())
+
+			ok, err := m.authz.Can(r.Context(), user, permission)
+			if err != nil {
+				response.ServerError(w, r, err)
+				return
+			}
+
+			// A key can only narrow the permissions of its user.
+			if key, isKey := FromAPIKeyContext(r.Context()); isKey && !key.HasScope(permission) {
+				ok = false
+			}
+
+			if !ok {
+				slog.Warn("permission denied", "user", user.ID, "permission", permission)
+				if isAPIRequest(r) {
+					response.JSON(w, r, http.StatusForbidden, APIResponse[any]{Message: message.Get("forbidden")})
+					return
+				}
+				http.Error(w, http.StatusText(http.StatusForbidden), http.StatusForbidden)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		}))
+	}
+}
+
+// Redirects HTML requests to the login page and answers API requests with 401.
+func unauthenticated(w http.ResponseWriter, r *http.Request) {
+	if isAPIRequest(r) {
+		response.JSON(w, r, http.StatusUnauthorized, APIResponse[any]{Message: message.Get("unauthenticated")})
+		return
+	}
+	http.Redirect(w, r, loginPath, http.StatusSeeOther)
+}
+
+// TemplateFuncs are the template funcs that depend on the user of the request.
+func (m *AuthMiddleware) TemplateFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"currentUser": func() *model.User {
+			user, _ := FromUserContext(r.Context())
+			return user
+		},
+		"can": func(permission string) (bool, error) {
+			user, _ := FromUserContext(r.Context())
+			return m.authz.Can(r.Context(), user, permission)
+		},
+	}
+}
+
+// OptionalAuth stores the user in the context when the request has a valid
+// session and lets every request through.
+func (m *AuthMiddleware) OptionalAuth(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r, _, err := m.authenticate(r)
+		if err != nil {
+			response.ServerError(w, r, err)
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// Returns r with the authenticated user, and the API key used if any, in its
+// context. The Authorization header takes precedence over the session cookie.
+// ok is false when the credentials are missing or invalid.
+func (m *AuthMiddleware) authenticate(r *http.Request) (_ *http.Request, ok bool, err error) {
+	if _, ok := FromUserContext(r.Context()); ok {
+		return r, true, nil
+	}
+
+	var user *model.User
+	ctx := r.Context()
+	if key, ok := authorizationCredentials(r, apiKeyScheme); ok {
+		var apiKey *model.APIKey
+		user, apiKey, err = m.apiKeys.AuthenticateAPIKey(ctx, key)
+		if err == nil {
+			ctx = NewAPIKeyContext(ctx, apiKey)
+		}
+	} else if token, ok := authorizationCredentials(r, bearerScheme); ok {
+		user, err = m.tokens.AuthenticateAccessToken(ctx, token)
+	} else if cookie, cookieErr := r.Cookie(m.cfg.CookieName); cookieErr == nil && cookie.Value != "" {
+		user, err = m.service.AuthenticateSession(ctx, cookie.Value)
+	} else {
+		return r, false, nil
+	}
+
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidToken) {
+			slog.Debug("invalid credentials")
+			return r, false, nil
+		}
+		return r, false, err
+	}
+
+	return r.WithContext(NewUserContext(ctx, user)), true, nil
+}
+
+// Returns the credentials of the Authorization header if it uses scheme.
+// Schemes are case-insensitive.
+func authorizationCredentials(r *http.Request, scheme string) (string, bool) {
+	prefix, credentials, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(prefix, scheme) {
+		return "", false
+	}
+	credentials = strings.TrimSpace(credentials)
+	return credentials, credentials != ""
+}
+
+// Reports whether r targets the JSON API. RequestURI is used since route
+// groups strip their prefix from URL.Path.
+func isAPIRequest(r *http.Request) bool {
+	return r.RequestURI == apiPrefix || strings.HasPrefix(r.RequestURI, apiPrefix+"/")
+}
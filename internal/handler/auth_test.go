@@ -0,0 +1,310 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+// Responds with the email of the user in the context.
+func echoUser(w http.ResponseWriter, r *http.Request) {
+	user, ok := handler.FromUserContext(r.Context())
+	if !ok {
+		_, _ = w.Write([]byte("anonymous"))
+		return
+	}
+	_, _ = w.Write([]byte(user.Email))
+}
+
+type authMocks struct {
+	user   *mock.MockUserService
+	authz  *mock.MockAuthorizationService
+	token  *mock.MockTokenService
+	apiKey *mock.MockAPIKeyService
+}
+
+func newAuthRouter(t *testing.T, setup func(*authMocks)) *goexpress.Router {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	m := &authMocks{
+		user:   mock.NewMockUserService(ctrl),
+		authz:  mock.NewMockAuthorizationService(ctrl),
+		token:  mock.NewMockTokenService(ctrl),
+		apiKey: mock.NewMockAPIKeyService(ctrl),
+	}
+	setup(m)
+
+	svc := service.Service{User: m.user, Authorization: m.authz, Token: m.token, APIKey: m.apiKey}
+	auth := handler.NewAuthMiddleware(svc, sessionCfg)
+	r := goexpress.New()
+	r.Get("/dashboard", echoUser, auth.RequireAuth)
+	r.Get("/optional", echoUser, auth.OptionalAuth)
+	r.Get("/admin/users", echoUser, auth.RequirePermission(model.PermUsersRead))
+	r.Group("/api", func(gr *goexpress.Router) *goexpress.Router {
+		gr.Get("/auth/me", echoUser, auth.RequireAuth)
+		gr.Get("/users", echoUser, auth.RequirePermission(model.PermUsersRead))
+		return gr
+	})
+	return r
+}
+
+func TestAuthMiddleware_RequireAuthRedirectsHTML(t *testing.T) {
+	r := newAuthRouter(t, func(*authMocks) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/auth/login", rr.Header().Get("Location"))
+}
+
+func TestAuthMiddleware_RequireAuthRejectsAPI(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(nil, service.ErrInvalidToken)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Equal(t, handler.MimeJSONUTF8, rr.Header().Get(handler.HeaderContentType))
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, message.Get("unauthenticated"), apiRes.Message)
+}
+
+func TestAuthMiddleware_RequireAuthStoresUser(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(&model.User{Email: testEmail}, nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, testEmail, rr.Body.String())
+}
+
+func TestAuthMiddleware_OptionalAuth(t *testing.T) {
+	var tests = []struct {
+		name   string
+		cookie bool
+		want   string
+	}{
+		{"Without session", false, "anonymous"},
+		{"With session", true, testEmail},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAuthRouter(t, func(m *authMocks) {
+				if tt.cookie {
+					m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(&model.User{Email: testEmail}, nil)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/optional", nil)
+			if tt.cookie {
+				req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+			}
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Equal(t, tt.want, rr.Body.String())
+		})
+	}
+}
+
+func TestAuthMiddleware_ServiceError(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(nil, assert.AnError)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestAuthMiddleware_RequirePermission(t *testing.T) {
+	var tests = []struct {
+		name     string
+		path     string
+		allowed  bool
+		wantCode int
+	}{
+		{"HTML allowed", "/admin/users", true, http.StatusOK},
+		{"HTML denied", "/admin/users", false, http.StatusForbidden},
+		{"API allowed", "/api/users", true, http.StatusOK},
+		{"API denied", "/api/users", false, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{Email: testEmail}
+			r := newAuthRouter(t, func(m *authMocks) {
+				m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(user, nil)
+				m.authz.EXPECT().Can(gomock.Any(), user, model.PermUsersRead).Return(tt.allowed, nil)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantCode, rr.Code)
+			if tt.allowed {
+				assert.Equal(t, testEmail, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_RequirePermissionForbiddenAPI(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.user.EXPECT().AuthenticateSession(gomock.Any(), testToken).Return(&model.User{Email: testEmail}, nil)
+		m.authz.EXPECT().Can(gomock.Any(), gomock.Any(), model.PermUsersRead).Return(false, nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+	assert.Equal(t, handler.MimeJSONUTF8, rr.Header().Get(handler.HeaderContentType))
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, message.Get("forbidden"), apiRes.Message)
+}
+
+func TestAuthMiddleware_RequirePermissionUnauthenticated(t *testing.T) {
+	r := newAuthRouter(t, func(*authMocks) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/admin/users", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/auth/login", rr.Header().Get("Location"))
+}
+
+func TestAuthMiddleware_Bearer(t *testing.T) {
+	var tests = []struct {
+		name     string
+		err      error
+		wantCode int
+	}{
+		{"Valid token", nil, http.StatusOK},
+		{"Invalid token", service.ErrInvalidToken, http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newAuthRouter(t, func(m *authMocks) {
+				var user *model.User
+				if tt.err == nil {
+					user = &model.User{Email: testEmail}
+				}
+				m.token.EXPECT().AuthenticateAccessToken(gomock.Any(), testToken).Return(user, tt.err)
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantCode, rr.Code)
+			if tt.err == nil {
+				assert.Equal(t, testEmail, rr.Body.String())
+			}
+		})
+	}
+}
+
+func TestAuthMiddleware_BearerTakesPrecedenceOverCookie(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.token.EXPECT().AuthenticateAccessToken(gomock.Any(), "access").Return(&model.User{Email: testEmail}, nil)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.Header.Set("Authorization", "bearer access")
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, testEmail, rr.Body.String())
+}
+
+func TestAuthMiddleware_APIKey(t *testing.T) {
+	const key = "gwk_12345678_secret"
+
+	var tests = []struct {
+		name     string
+		scopes   []string
+		path     string
+		wantCode int
+	}{
+		{"Authenticates", nil, "/api/auth/me", http.StatusOK},
+		{"Unscoped key", nil, "/api/users", http.StatusOK},
+		{"Scoped key", []string{model.PermUsersRead}, "/api/users", http.StatusOK},
+		{"Key not scoped to permission", []string{model.PermUsersWrite}, "/api/users", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{Email: testEmail}
+			r := newAuthRouter(t, func(m *authMocks) {
+				m.apiKey.EXPECT().AuthenticateAPIKey(gomock.Any(), key).Return(user, &model.APIKey{Scopes: tt.scopes}, nil)
+				if tt.path == "/api/users" {
+					m.authz.EXPECT().Can(gomock.Any(), user, model.PermUsersRead).Return(true, nil)
+				}
+			})
+
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "ApiKey "+key)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			assert.Equal(t, tt.wantCode, rr.Code)
+		})
+	}
+}
+
+func TestAuthMiddleware_InvalidAPIKey(t *testing.T) {
+	r := newAuthRouter(t, func(m *authMocks) {
+		m.apiKey.EXPECT().AuthenticateAPIKey(gomock.Any(), "revoked").Return(nil, nil, service.ErrInvalidToken)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/me", nil)
+	req.Header.Set("Authorization", "ApiKey revoked")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
@@ -1,10 +1,19 @@
 package handler
 
-import "context"
+import (
+	"context"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
 
 type ctxKey int
 
-const paramsCtxKey ctxKey = 1
+const (
+	paramsCtxKey ctxKey = iota + 1
+	userCtxKey
+	apiKeyCtxKey
+	clientIPCtxKey
+)
 
 func NewParamsContext[T any](ctx context.Context, t T) context.Context {
 	return context.WithValue(ctx, paramsCtxKey, t)
@@ -15,3 +24,26 @@ func FromParamsContext[T any](ctx context.Context) (any, T, bool) {
 	t, ok := ctxVal.(T)
 	return ctxVal, t, ok
 }
+
+// NewUserContext returns a copy of ctx carrying the authenticated user.
+func NewUserContext(ctx context.Context, user *model.User) context.Context {
+	return context.WithValue(ctx, userCtxKey, user)
+}
+
+// FromUserContext returns the authenticated user, if any.
+func FromUserContext(ctx context.Context) (*model.User, bool) {
+	user, ok := ctx.Value(userCtxKey).(*model.User)
+	return user, ok && user != nil
+}
+
+// NewAPIKeyContext returns a copy of ctx carrying the API key the request was
+// authenticated with.
+func NewAPIKeyContext(ctx context.Context, key *model.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyCtxKey, key)
+}
+
+// FromAPIKeyContext returns the API key of the request, if any.
+func FromAPIKeyContext(ctx context.Context) (*model.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyCtxKey).(*model.APIKey)
+	return key, ok && key != nil
+}
@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"slices"
+	"strings"
+
+	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+)
+
+const (
+	CSRFHeader    = "X-CSRF-Token"
+	CSRFFormField = "csrf_token"
+	csrfTokenLen  = 32
+)
+
+var errCSRF = errors.New(message.Get("csrfFailed"))
+
+type csrfCtxKey struct{}
+
+// CSRFMiddleware protects cookie authenticated requests from cross-site
+// forgery with a double-submit token. The token is kept in a cookie and has
+// to be sent back with every unsafe request, in the X-CSRF-Token header or
+// the csrf_token form field. Cross-site requests are also rejected by their
+// Origin and Sec-Fetch-Site headers.
+//
+// Requests to the API that carry a bearer token or an API key are exempt
+// since browsers never add the Authorization header on their own. So are the
+// CSP reports that browsers send, which change nothing. API requests without
+// the session cookie, like those of mobile apps to the token endpoints,
+// cannot act as a signed in user so only their origin is checked; that still
+// stops a cross-site sign in.
+type CSRFMiddleware struct {
+	cfg           *config.CSRFConfig
+	sessionCookie string
+	secure        bool
+	origins       []string
+}
+
+func NewCSRFMiddleware(cfg *config.Config) *CSRFMiddleware {
+	origins := make([]string, 0, len(cfg.CSRF.TrustedOrigins)+1)
+	for _, o := range append([]string{cfg.App.URL}, cfg.CSRF.TrustedOrigins...) {
+		if origin := originOf(o); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+
+	return &CSRFMiddleware{
+		cfg:           &cfg.CSRF,
+		sessionCookie: cfg.Session.CookieName,
+		secure:        cfg.Session.Secure,
+		origins:       origins,
+	}
+}
+
+// Protect issues the token to clients without one and checks unsafe
+// requests.
+func (m *CSRFMiddleware) Protect(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, ok := m.cookieToken(r)
+		if !ok {
+			var err error
+			if token, err = newCSRFToken(); err != nil {
+				response.ServerError(w, r, err)
+				return
+			}
+			m.setCookie(w, token)
+		}
+
+		r = r.WithContext(context.WithValue(r.Context(), csrfCtxKey{}, token))
+		// The response depends on the cookie of the request.
+		w.Header().Add("Vary", "Cookie")
+
+		if isSafeMethod(r.Method) || m.exempt(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := m.verifyOrigin(r)
+		if err == nil && m.needsToken(r) {
+			err = m.verifyToken(r, token)
+		}
+		if err != nil {
+			forbiddenError(w, r, fmt.Errorf("%w: %w", errCSRF, err))
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func isSafeMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodTrace:
+		return true
+	}
+	return false
+}
+
+func (m *CSRFMiddleware) exempt(r *http.Request) bool {
+	if r.URL.Path == cspReportPath {
+		return true
+	}
+	if !isAPIRequest(r) {
+		return false
+	}
+	_, bearer := authorizationCredentials(r, bearerScheme)
+	_, apiKey := authorizationCredentials(r, apiKeyScheme)
+	return bearer || apiKey
+}
+
+// Reports whether r has to carry the token, which API requests only do when
+// they are authenticated by the session cookie.
+func (m *CSRFMiddleware) needsToken(r *http.Request) bool {
+	if !isAPIRequest(r) {
+		return true
+	}
+	_, err := r.Cookie(m.sessionCookie)
+	return err == nil
+}
+
+// Rejects requests that browsers sent from another site.
+func (m *CSRFMiddleware) verifyOrigin(r *http.Request) error {
+	if site := r.Header.Get("Sec-Fetch-Site"); site != "" && site != "same-origin" && site != "none" {
+		if !m.trustedOrigin(r) {
+			return fmt.Errorf("cross-site request from %q", r.Header.Get("Origin"))
+		}
+	}
+
+	if origin := r.Header.Get("Origin"); origin != "" && !m.trustedOrigin(r) {
+		return fmt.Errorf("untrusted origin %q", origin)
+	}
+	return nil
+}
+
+func (m *CSRFMiddleware) verifyToken(r *http.Request, token []byte) error {
+	sent := r.Header.Get(CSRFHeader)
+	if sent == "" {
+		sent = r.PostFormValue(CSRFFormField)
+	}
+	if sent == "" {
+		return errors.New("missing token")
+	}
+
+	if !validCSRFToken(sent, token) {
+		return errors.New("invalid token")
+	}
+	return nil
+}
+
+// Reports whether the Origin of r is the app, a trusted origin or the host
+// that r was sent to.
+func (m *CSRFMiddleware) trustedOrigin(r *http.Request) bool {
+	origin := originOf(r.Header.Get("Origin"))
+	if origin == "" {
+		return false
+	}
+	if slices.Contains(m.origins, origin) {
+		return true
+	}
+
+	u, _ := url.Parse(origin)
+	return u.Host == r.Host
+}
+
+// Returns the scheme and host of rawURL, or "" if it has none.
+func originOf(rawURL string) string {
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return ""
+	}
+	return strings.ToLower(u.Scheme + "://" + u.Host)
+}
+
+func (m *CSRFMiddleware) cookieToken(r *http.Request) ([]byte, bool) {
+	cookie, err := r.Cookie(m.cfg.CookieName)
+	if err != nil {
+		return nil, false
+	}
+	token, err := base64.RawURLEncoding.DecodeString(cookie.Value)
+	if err != nil || len(token) != csrfTokenLen {
+		return nil, false
+	}
+	return token, true
+}
+
+// The cookie lives as long as the browser session. It is HttpOnly since
+// scripts read the token from the page.
+func (m *CSRFMiddleware) setCookie(w http.ResponseWriter, token []byte) {
+	http.SetCookie(w, &http.Cookie{
+		Name:     m.cfg.CookieName,
+		Value:    base64.RawURLEncoding.EncodeToString(token),
+		Path:     "/",
+		HttpOnly: true,
+		Secure:   m.secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+}
+
+// TemplateFuncs expose the token of the request to the templates.
+func (m *CSRFMiddleware) TemplateFuncs(r *http.Request) template.FuncMap {
+	token := func() (string, error) {
+		t, ok := r.Context().Value(csrfCtxKey{}).([]byte)
+		if !ok {
+			return "", nil
+		}
+		return maskCSRFToken(t)
+	}
+
+	return template.FuncMap{
+		"csrfToken": token,
+		"csrfField": func() (template.HTML, error) {
+			t, err := token()
+			if err != nil {
+				return "", err
+			}
+			// The token is base64url encoded so it needs no escaping.
+			return template.HTML(`<input type="hidden" name="` + CSRFFormField + `" value="` + t + `" />`), nil // #nosec G203
+		},
+	}
+}
+
+func newCSRFToken() ([]byte, error) {
+	token := make([]byte, csrfTokenLen)
+	if _, err := rand.Read(token); err != nil {
+		return nil, fmt.Errorf("generate csrf token: %w", err)
+	}
+	return token, nil
+}
+
+// Returns the token XORed with a random pad, followed by the pad, so that
+// the token in every page is different and cannot be recovered by
+// compression side channels such as BREACH.
+func maskCSRFToken(token []byte) (string, error) {
+	masked := make([]byte, 2*csrfTokenLen)
+	pad := masked[csrfTokenLen:]
+	if _, err := rand.Read(pad); err != nil {
+		return "", fmt.Errorf("generate csrf pad: %w", err)
+	}
+	subtle.XORBytes(masked[:csrfTokenLen], token, pad)
+	return base64.RawURLEncoding.EncodeToString(masked), nil
+}
+
+func validCSRFToken(sent string, token []byte) bool {
+	masked, err := base64.RawURLEncoding.DecodeString(sent)
+	if err != nil || len(masked) != 2*csrfTokenLen {
+		return false
+	}
+
+	unmasked := make([]byte, csrfTokenLen)
+	subtle.XORBytes(unmasked, masked[:csrfTokenLen], masked[csrfTokenLen:])
+	return subtle.ConstantTimeCompare(unmasked, token) == 1
+}
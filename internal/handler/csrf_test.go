@@ -0,0 +1,201 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	csrfCookie    = "goweb_csrf"
+	sessionCookie = "goweb_session"
+)
+
+func newCSRFHandler() (*handler.CSRFMiddleware, http.Handler) {
+	m := handler.NewCSRFMiddleware(&config.Config{
+		App:     config.EnvConfig{URL: "http://localhost:8888"},
+		Session: config.SessionConfig{CookieName: sessionCookie},
+		CSRF:    config.CSRFConfig{CookieName: csrfCookie, TrustedOrigins: []string{"https://admin.example.com"}},
+	})
+
+	return m, m.Protect(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token, err := m.TemplateFuncs(r)["csrfToken"].(func() (string, error))()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		_, _ = w.Write([]byte(token))
+	}))
+}
+
+// Returns the cookie and a token as a page would get them.
+func csrfToken(t *testing.T, h http.Handler) (*http.Cookie, string) {
+	t.Helper()
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/register", nil))
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	cookies := rr.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != csrfCookie {
+		t.Fatalf("expected the csrf cookie, got %v", cookies)
+	}
+	assert.True(t, cookies[0].HttpOnly)
+	return cookies[0], rr.Body.String()
+}
+
+func TestCSRFMiddleware_IssuesTokenOnce(t *testing.T) {
+	_, h := newCSRFHandler()
+	cookie, token := csrfToken(t, h)
+
+	req := httptest.NewRequest(http.MethodGet, "/auth/register", nil)
+	req.AddCookie(cookie)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+
+	assert.Empty(t, rr.Result().Cookies(), "the cookie is kept")
+	assert.NotEqual(t, token, rr.Body.String(), "every page gets a differently masked token")
+}
+
+func TestCSRFMiddleware_Protect(t *testing.T) {
+	_, h := newCSRFHandler()
+	cookie, token := csrfToken(t, h)
+	_, otherToken := csrfToken(t, h)
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		status int
+	}{
+		{"Valid token", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, token)
+		}, http.StatusOK},
+		{"Same origin", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, token)
+			req.Header.Set("Origin", "http://localhost:8888")
+			req.Header.Set("Sec-Fetch-Site", "same-origin")
+		}, http.StatusOK},
+		{"Trusted origin", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, token)
+			req.Header.Set("Origin", "https://admin.example.com")
+			req.Header.Set("Sec-Fetch-Site", "cross-site")
+		}, http.StatusOK},
+		{"Missing token", func(*http.Request) {}, http.StatusForbidden},
+		{"Token of another client", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, otherToken)
+		}, http.StatusForbidden},
+		{"Malformed token", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, "token")
+		}, http.StatusForbidden},
+		{"Cross origin", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, token)
+			req.Header.Set("Origin", "https://evil.example.com")
+		}, http.StatusForbidden},
+		{"Cross site", func(req *http.Request) {
+			req.Header.Set(handler.CSRFHeader, token)
+			req.Header.Set("Sec-Fetch-Site", "cross-site")
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader("{}"))
+			req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+			req.AddCookie(cookie)
+			req.AddCookie(&http.Cookie{Name: sessionCookie, Value: testToken})
+			tt.setup(req)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
+
+func TestCSRFMiddleware_FormField(t *testing.T) {
+	_, h := newCSRFHandler()
+	cookie, token := csrfToken(t, h)
+
+	form := url.Values{handler.CSRFFormField: {token}}
+	req := httptest.NewRequest(http.MethodPost, "/account/security", strings.NewReader(form.Encode()))
+	req.Header.Set(handler.HeaderContentType, "application/x-www-form-urlencoded")
+	req.AddCookie(cookie)
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
+
+func TestCSRFMiddleware_ExemptsAPIAuthorization(t *testing.T) {
+	_, h := newCSRFHandler()
+
+	for _, authz := range []string{"Bearer access-token", "ApiKey gw_key"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/keys", strings.NewReader("{}"))
+		req.Header.Set("Authorization", authz)
+
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		assert.Equal(t, http.StatusOK, rr.Code, authz)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/account/security", nil)
+	req.Header.Set("Authorization", "Bearer access-token")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "only the API accepts bearer tokens")
+}
+
+func TestCSRFMiddleware_APIWithoutSession(t *testing.T) {
+	_, h := newCSRFHandler()
+
+	tests := []struct {
+		name   string
+		setup  func(req *http.Request)
+		status int
+	}{
+		{"No cookies", func(*http.Request) {}, http.StatusOK},
+		{"Same origin", func(req *http.Request) {
+			req.Header.Set("Origin", "http://localhost:8888")
+			req.Header.Set("Sec-Fetch-Site", "same-origin")
+		}, http.StatusOK},
+		{"Cross origin", func(req *http.Request) {
+			req.Header.Set("Origin", "https://evil.example.com")
+		}, http.StatusForbidden},
+		{"Cross site", func(req *http.Request) {
+			req.Header.Set("Sec-Fetch-Site", "cross-site")
+		}, http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader("{}"))
+			req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+			tt.setup(req)
+
+			rr := httptest.NewRecorder()
+			h.ServeHTTP(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/account/security", nil)
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusForbidden, rr.Code, "pages always need the token")
+}
+
+func TestCSRFMiddleware_ExemptsCSPReports(t *testing.T) {
+	_, h := newCSRFHandler()
+
+	req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader("{}"))
+	req.Header.Set(handler.HeaderContentType, "application/csp-report")
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusOK, rr.Code)
+}
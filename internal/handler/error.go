@@ -1,11 +1,14 @@
 package handler
 
 import (
-	"fmt"
+	"errors"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
 )
 
 func badRequestError(w http.ResponseWriter, r *http.Request, err error) {
@@ -13,12 +16,39 @@ func badRequestError(w http.ResponseWriter, r *http.Request, err error) {
 
 }
 
+func unauthorizedError(w http.ResponseWriter, r *http.Request, err error) {
+	errorResponse(w, r, http.StatusUnauthorized, err, err.Error())
+}
+
+func forbiddenError(w http.ResponseWriter, r *http.Request, err error) {
+	errorResponse(w, r, http.StatusForbidden, err, err.Error())
+}
+
 func unprocessableError(w http.ResponseWriter, r *http.Request, err error) {
 	errorResponse(w, r, http.StatusUnprocessableEntity, err, err.Error())
 }
 
+// tooManyRequestsError tells the client how long to wait in whole seconds,
+// rounded up so that it does not come back too early.
+func tooManyRequestsError(w http.ResponseWriter, r *http.Request, err error, retryAfter time.Duration) {
+	w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(retryAfter)))
+	errorResponse(w, r, http.StatusTooManyRequests, err, err.Error())
+}
+
+// serverError is response.ServerError, except that the client is asked to
+// come back later when the passwords could not be hashed for load.
+func serverError(w http.ResponseWriter, r *http.Request, err error) {
+	var busy *security.BusyError
+	if errors.As(err, &busy) {
+		w.Header().Set("Retry-After", strconv.Itoa(ceilSeconds(busy.RetryAfter)))
+		errorResponse(w, r, http.StatusServiceUnavailable, err, busy.Error())
+		return
+	}
+	response.ServerError(w, r, err)
+}
+
 func errorResponse(w http.ResponseWriter, r *http.Request, status int, err error, msg string) {
-	slog.Error("server error", "reason", err, "request", fmt.Sprint(r))
+	slog.Error("server error", "reason", err, requestAttr(r))
 
 	if r.Header.Get(HeaderContentType) == MimeJSONUTF8 {
 		res := APIResponse[any]{
@@ -29,3 +59,17 @@ func errorResponse(w http.ResponseWriter, r *http.Request, status int, err error
 	}
 	http.Error(w, http.StatusText(status), status)
 }
+
+// HeaderRequestID is the header the reverse proxy identifies requests with.
+const HeaderRequestID = "X-Request-ID"
+
+// Returns what identifies r in the logs. The headers are left out since they
+// carry the session cookie, the bearer token and the CSRF token.
+func requestAttr(r *http.Request) slog.Attr {
+	return slog.Group("request",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"remote_addr", r.RemoteAddr,
+		"request_id", r.Header.Get(HeaderRequestID),
+	)
+}
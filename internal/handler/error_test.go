@@ -0,0 +1,32 @@
+package handler
+
+import (
+	"bytes"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestErrorResponseLogsNoCredentials(t *testing.T) {
+	var logs bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(slog.NewTextHandler(&logs, nil)))
+
+	req := httptest.NewRequest(http.MethodPost, "/api/keys", nil)
+	req.Header.Set("Cookie", "goweb_session=session-secret")
+	req.Header.Set("Authorization", "Bearer bearer-secret")
+	req.Header.Set(CSRFHeader, "csrf-secret")
+	req.Header.Set(HeaderRequestID, "req-1")
+	rr := httptest.NewRecorder()
+
+	unauthorizedError(rr, req, errors.New("unauthenticated"))
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.NotContains(t, logs.String(), "secret")
+	assert.Contains(t, logs.String(), "request.method=POST request.path=/api/keys")
+	assert.Contains(t, logs.String(), "request.request_id=req-1")
+}
@@ -1,7 +1,15 @@
 package handler
 
 import (
+	"crypto/subtle"
+	"errors"
 	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
 )
 
 const (
@@ -11,14 +19,18 @@ const (
 )
 
 type Handler struct {
-	Base BaseHandler
-	User UserHandler
+	Base    BaseHandler
+	User    UserHandler
+	Account AccountHandler
+	OIDC    OIDCHandler
 }
 
-func NewHandler(tmpl *Template) *Handler {
+func NewHandler(tmpl *Template, svc service.Service, cfg *config.Config) *Handler {
 	return &Handler{
-		Base: *NewBaseHandler(tmpl),
-		User: *NewUserHandler(tmpl),
+		Base:    *NewBaseHandler(tmpl),
+		User:    *NewUserHandler(tmpl, svc.User, svc.OIDC),
+		Account: *NewAccountHandler(tmpl, svc.TwoFactor),
+		OIDC:    *NewOIDCHandler(tmpl, svc.OIDC, cfg),
 	}
 }
 
@@ -33,19 +45,225 @@ func NewBaseHandler(t *Template) *BaseHandler {
 }
 
 func (h *BaseHandler) HandleDashboard(w http.ResponseWriter, r *http.Request) {
-	h.template.Render(w, r, "dashboard", nil)
+	user, _ := FromUserContext(r.Context())
+	h.template.Render(w, r, "dashboard", user)
 }
 
 type UserHandler struct {
 	template *Template
+	service  service.UserService
+	oidc     service.OIDCService
 }
 
-func NewUserHandler(t *Template) *UserHandler {
+func NewUserHandler(t *Template, userService service.UserService, oidcService service.OIDCService) *UserHandler {
 	return &UserHandler{
 		template: t,
+		service:  userService,
+		oidc:     oidcService,
 	}
 }
 
 func (h *UserHandler) HandleRegister(w http.ResponseWriter, r *http.Request) {
 	h.template.Render(w, r, "register", nil)
 }
+
+func (h *UserHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	if _, ok := FromUserContext(r.Context()); ok {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	h.template.Render(w, r, "login", LoginData{Providers: h.oidc.Providers()})
+}
+
+// LoginData lists the identity providers that the user can sign in with.
+// Error is set when signing in with one of them failed.
+type LoginData struct {
+	Providers []service.OIDCProvider
+	Error     string
+}
+
+// HandleTwoFactor renders the second step of signing in. The challenge token
+// is kept by the browser between the two steps.
+func (h *UserHandler) HandleTwoFactor(w http.ResponseWriter, r *http.Request) {
+	if _, ok := FromUserContext(r.Context()); ok {
+		http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+		return
+	}
+	h.template.Render(w, r, "two_factor", nil)
+}
+
+func (h *UserHandler) HandleForgotPassword(w http.ResponseWriter, r *http.Request) {
+	h.template.Render(w, r, "forgot_password", nil)
+}
+
+func (h *UserHandler) HandleResetPassword(w http.ResponseWriter, r *http.Request) {
+	h.template.Render(w, r, "reset_password", nil)
+}
+
+func (h *UserHandler) HandleAdminUsers(w http.ResponseWriter, r *http.Request) {
+	users, err := h.service.ListUsers(r.Context())
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	h.template.Render(w, r, "admin/users", users)
+}
+
+type VerifyData struct {
+	Verified bool
+	Message  string
+}
+
+func (h *UserHandler) HandleVerify(w http.ResponseWriter, r *http.Request) {
+	data := VerifyData{Verified: true, Message: message.Get("verifySuccess")}
+
+	if err := h.service.VerifyEmail(r.Context(), r.URL.Query().Get("token")); err != nil {
+		if !errors.Is(err, service.ErrInvalidToken) {
+			serverError(w, r, err)
+			return
+		}
+		data = VerifyData{Verified: false, Message: message.Get("verifyFailed")}
+	}
+
+	h.template.Render(w, r, "verify", data)
+}
+
+type AccountHandler struct {
+	template  *Template
+	twoFactor service.TwoFactorService
+}
+
+func NewAccountHandler(t *Template, twoFactorService service.TwoFactorService) *AccountHandler {
+	return &AccountHandler{
+		template:  t,
+		twoFactor: twoFactorService,
+	}
+}
+
+type SecurityData struct {
+	Email            string
+	TwoFactorEnabled bool
+}
+
+func (h *AccountHandler) HandleSecurity(w http.ResponseWriter, r *http.Request) {
+	user, _ := FromUserContext(r.Context())
+	enabled, err := h.twoFactor.TwoFactorEnabled(r.Context(), user.ID)
+	if err != nil {
+		serverError(w, r, err)
+		return
+	}
+	h.template.Render(w, r, "account/security", SecurityData{Email: user.Email, TwoFactorEnabled: enabled})
+}
+
+// Name of the cookie that binds the state of an authorization request to the
+// browser that started it
+const oidcStateCookie = "oidc_state"
+
+// OIDCHandler signs users in with an OpenID provider. The state is kept in a
+// cookie so that a callback can only be completed by the browser that was
+// sent to the provider.
+type OIDCHandler struct {
+	template *Template
+	service  service.OIDCService
+	cfg      *config.SessionConfig
+}
+
+func NewOIDCHandler(t *Template, oidcService service.OIDCService, cfg *config.Config) *OIDCHandler {
+	return &OIDCHandler{
+		template: t,
+		service:  oidcService,
+		cfg:      &cfg.Session,
+	}
+}
+
+// HandleLogin redirects to the provider in the path.
+func (h *OIDCHandler) HandleLogin(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	login, err := h.service.BeginOIDCLogin(r.Context(), provider)
+	if err != nil {
+		if errors.Is(err, service.ErrUnknownProvider) {
+			http.NotFound(w, r)
+			return
+		}
+		serverError(w, r, err)
+		return
+	}
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    login.State,
+		Path:     oidcPath(provider),
+		Expires:  login.ExpiresAt,
+		MaxAge:   int(time.Until(login.ExpiresAt).Seconds()),
+		HttpOnly: true,
+		Secure:   h.cfg.Secure,
+		// Lax so that the cookie is sent on the redirect back from the
+		// provider.
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	http.Redirect(w, r, login.AuthURL, http.StatusFound)
+}
+
+// HandleCallback completes the sign in that the provider redirected back
+// from. Users with two-factor authentication continue on the second step
+// with the challenge token in the fragment, which is not sent to servers.
+func (h *OIDCHandler) HandleCallback(w http.ResponseWriter, r *http.Request) {
+	provider := r.PathValue("provider")
+	q := r.URL.Query()
+
+	http.SetCookie(w, &http.Cookie{
+		Name:     oidcStateCookie,
+		Value:    "",
+		Path:     oidcPath(provider),
+		MaxAge:   -1,
+		HttpOnly: true,
+		Secure:   h.cfg.Secure,
+		SameSite: http.SameSiteLaxMode,
+	})
+
+	state := q.Get("state")
+	cookie, err := r.Cookie(oidcStateCookie)
+	if err != nil || state == "" || q.Has("error") ||
+		subtle.ConstantTimeCompare([]byte(cookie.Value), []byte(state)) != 1 {
+		h.renderError(w, r, message.Get("oidcFailed"))
+		return
+	}
+
+	result, err := h.service.CompleteOIDCLogin(r.Context(), service.CompleteOIDCLoginParams{
+		Provider:  provider,
+		State:     state,
+		Code:      q.Get("code"),
+		UserAgent: r.UserAgent(),
+		IPAddress: clientIP(r),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, service.ErrInvalidToken), errors.Is(err, service.ErrOIDCLoginFailed),
+			errors.Is(err, service.ErrUnknownProvider):
+			h.renderError(w, r, message.Get("oidcFailed"))
+		case errors.Is(err, service.ErrOIDCEmailNotVerified), errors.Is(err, service.ErrUserDisabled):
+			h.renderError(w, r, err.Error())
+		default:
+			serverError(w, r, err)
+		}
+		return
+	}
+
+	if result.ChallengeToken != "" {
+		fragment := url.Values{"challenge": {result.ChallengeToken}}.Encode()
+		http.Redirect(w, r, "/auth/two-factor#"+fragment, http.StatusSeeOther)
+		return
+	}
+
+	setSessionCookie(w, h.cfg, result)
+	http.Redirect(w, r, "/dashboard", http.StatusSeeOther)
+}
+
+func (h *OIDCHandler) renderError(w http.ResponseWriter, r *http.Request, msg string) {
+	h.template.Render(w, r, "login", LoginData{Providers: h.service.Providers(), Error: msg})
+}
+
+func oidcPath(provider string) string {
+	return "/auth/oidc/" + provider
+}
@@ -1,6 +1,7 @@
 package handler_test
 
 import (
+	"context"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -8,13 +9,16 @@ import (
 	"github.com/ferdiebergado/goexpress"
 	"github.com/ferdiebergado/goweb/internal/config"
 	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
 )
 
-func TestHandlerHandleDashboard(t *testing.T) {
-	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
-	rr := httptest.NewRecorder()
-
+func newTemplate(t *testing.T) *handler.Template {
+	t.Helper()
 	mockCfg := config.TemplateConfig{
 		Path:         "../../web/templates",
 		LayoutFile:   "layout.html",
@@ -25,7 +29,15 @@ func TestHandlerHandleDashboard(t *testing.T) {
 	if err != nil {
 		t.Fatalf("cant parse template: %v", err)
 	}
-	h := handler.NewBaseHandler(tmpl)
+	return tmpl
+}
+
+func TestHandlerHandleDashboard(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+	req = req.WithContext(handler.NewUserContext(req.Context(), &model.User{Email: testEmail}))
+	rr := httptest.NewRecorder()
+
+	h := handler.NewBaseHandler(newTemplate(t))
 
 	r := goexpress.New()
 	r.Get("/dashboard", h.HandleDashboard)
@@ -36,4 +48,76 @@ func TestHandlerHandleDashboard(t *testing.T) {
 
 	assert.Equal(t, http.StatusOK, res.StatusCode, "Status code should match")
 	assert.Contains(t, rr.Body.String(), "Dashboard", "Body should contain the same text")
+	assert.Contains(t, rr.Body.String(), testEmail)
+}
+
+func TestUserHandlerHandleVerify(t *testing.T) {
+	const url = "/auth/verify"
+
+	var tests = []struct {
+		name string
+		err  error
+		msg  string
+	}{
+		{"Valid token", nil, message.Get("verifySuccess")},
+		{"Invalid token", service.ErrInvalidToken, message.Get("verifyFailed")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockService := mock.NewMockUserService(ctrl)
+			mockService.EXPECT().VerifyEmail(context.Background(), testToken).Return(tt.err)
+
+			h := handler.NewUserHandler(newTemplate(t), mockService, nil)
+			r := goexpress.New()
+			r.Get(url, h.HandleVerify)
+
+			req := httptest.NewRequest(http.MethodGet, url+"?token="+testToken, nil)
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, req)
+
+			res := rr.Result()
+			defer res.Body.Close()
+
+			assert.Equal(t, http.StatusOK, res.StatusCode)
+			assert.Contains(t, rr.Body.String(), tt.msg)
+		})
+	}
+}
+
+func TestTemplateCanHidesAdminLink(t *testing.T) {
+	var tests = []struct {
+		name    string
+		allowed bool
+	}{
+		{"Allowed", true},
+		{"Denied", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			user := &model.User{Email: testEmail}
+			ctrl := gomock.NewController(t)
+			mockAuthz := mock.NewMockAuthorizationService(ctrl)
+			mockAuthz.EXPECT().Can(gomock.Any(), user, model.PermUsersRead).Return(tt.allowed, nil)
+
+			tmpl := newTemplate(t)
+			auth := handler.NewAuthMiddleware(service.Service{Authorization: mockAuthz}, sessionCfg)
+			tmpl.AddRequestFuncs(auth.TemplateFuncs)
+			h := handler.NewBaseHandler(tmpl)
+
+			req := httptest.NewRequest(http.MethodGet, "/dashboard", nil)
+			req = req.WithContext(handler.NewUserContext(req.Context(), user))
+			rr := httptest.NewRecorder()
+			h.HandleDashboard(rr, req)
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			if tt.allowed {
+				assert.Contains(t, rr.Body.String(), `href="/admin/users"`)
+			} else {
+				assert.NotContains(t, rr.Body.String(), `href="/admin/users"`)
+			}
+		})
+	}
 }
@@ -0,0 +1,155 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const testState = "state"
+
+func newOIDCRouter(t *testing.T, setup func(*mock.MockOIDCService)) *goexpress.Router {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockOIDCService(ctrl)
+	setup(mockService)
+
+	h := handler.NewOIDCHandler(newTemplate(t), mockService, &config.Config{Session: *sessionCfg})
+	r := goexpress.New()
+	r.Get("/auth/oidc/{provider}", h.HandleLogin)
+	r.Get("/auth/oidc/{provider}/callback", h.HandleCallback)
+	return r
+}
+
+func newCallbackRequest(state string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/auth/oidc/example/callback?code=code&state="+testState, nil)
+	req.AddCookie(&http.Cookie{Name: "oidc_state", Value: state})
+	return req
+}
+
+func TestOIDCHandlerHandleLogin(t *testing.T) {
+	const authURL = "https://id.example.com/authorize?state=" + testState
+	r := newOIDCRouter(t, func(m *mock.MockOIDCService) {
+		m.EXPECT().BeginOIDCLogin(gomock.Any(), "example").Return(&service.OIDCLogin{
+			AuthURL:   authURL,
+			State:     testState,
+			ExpiresAt: time.Now().Add(10 * time.Minute),
+		}, nil)
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/oidc/example", nil))
+
+	assert.Equal(t, http.StatusFound, rr.Code)
+	assert.Equal(t, authURL, rr.Header().Get("Location"))
+
+	cookies := rr.Result().Cookies()
+	assert.Len(t, cookies, 1)
+	assert.Equal(t, testState, cookies[0].Value)
+	assert.Equal(t, "/auth/oidc/example", cookies[0].Path)
+	assert.True(t, cookies[0].HttpOnly)
+}
+
+func TestOIDCHandlerHandleLoginUnknownProvider(t *testing.T) {
+	r := newOIDCRouter(t, func(m *mock.MockOIDCService) {
+		m.EXPECT().BeginOIDCLogin(gomock.Any(), "nope").Return(nil, service.ErrUnknownProvider)
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/auth/oidc/nope", nil))
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestOIDCHandlerHandleCallback(t *testing.T) {
+	r := newOIDCRouter(t, func(m *mock.MockOIDCService) {
+		m.EXPECT().CompleteOIDCLogin(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ any, params service.CompleteOIDCLoginParams) (*service.LoginUserResult, error) {
+				assert.Equal(t, "example", params.Provider)
+				assert.Equal(t, testState, params.State)
+				assert.Equal(t, "code", params.Code)
+				return &service.LoginUserResult{
+					User:      &model.User{Model: model.Model{ID: "1"}, Email: testEmail},
+					Token:     testToken,
+					ExpiresAt: time.Now().Add(time.Hour),
+				}, nil
+			})
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, newCallbackRequest(testState))
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/dashboard", rr.Header().Get("Location"))
+
+	var session *http.Cookie
+	for _, c := range rr.Result().Cookies() {
+		if c.Name == sessionCfg.CookieName {
+			session = c
+		}
+	}
+	if assert.NotNil(t, session) {
+		assert.Equal(t, testToken, session.Value)
+	}
+}
+
+func TestOIDCHandlerHandleCallbackTwoFactor(t *testing.T) {
+	r := newOIDCRouter(t, func(m *mock.MockOIDCService) {
+		m.EXPECT().CompleteOIDCLogin(gomock.Any(), gomock.Any()).Return(&service.LoginUserResult{
+			User:           &model.User{Model: model.Model{ID: "1"}, Email: testEmail},
+			ChallengeToken: "challenge",
+			ExpiresAt:      time.Now().Add(5 * time.Minute),
+		}, nil)
+	})
+
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, newCallbackRequest(testState))
+
+	assert.Equal(t, http.StatusSeeOther, rr.Code)
+	assert.Equal(t, "/auth/two-factor#challenge=challenge", rr.Header().Get("Location"))
+	for _, c := range rr.Result().Cookies() {
+		assert.NotEqual(t, sessionCfg.CookieName, c.Name)
+	}
+}
+
+func TestOIDCHandlerHandleCallbackFailed(t *testing.T) {
+	var tests = []struct {
+		name   string
+		cookie string
+		err    error
+		msg    string
+	}{
+		{"State mismatch", "other", nil, message.Get("oidcFailed")},
+		{"Invalid flow", testState, service.ErrInvalidToken, message.Get("oidcFailed")},
+		{"Email not verified", testState, service.ErrOIDCEmailNotVerified, service.ErrOIDCEmailNotVerified.Error()},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newOIDCRouter(t, func(m *mock.MockOIDCService) {
+				m.EXPECT().Providers().Return([]service.OIDCProvider{{Name: "example", DisplayName: "Example"}})
+				if tt.err != nil {
+					m.EXPECT().CompleteOIDCLogin(gomock.Any(), gomock.Any()).Return(nil, tt.err)
+				}
+			})
+
+			rr := httptest.NewRecorder()
+			r.ServeHTTP(rr, newCallbackRequest(tt.cookie))
+
+			assert.Equal(t, http.StatusOK, rr.Code)
+			assert.Contains(t, rr.Body.String(), tt.msg)
+			assert.Contains(t, rr.Body.String(), "Sign in with Example")
+		})
+	}
+}
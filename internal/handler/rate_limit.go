@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
+)
+
+const (
+	rateLimitKeyIP     = "ip"
+	rateLimitKeyUser   = "user"
+	rateLimitKeyAPIKey = "api_key"
+)
+
+var errRateLimited = errors.New(message.Get("rateLimited"))
+
+// RateLimiter enforces the rate limit rules of the config on the routes that
+// name them. Every response of a limited route carries the RateLimit-* headers
+// of its rule.
+type RateLimiter struct {
+	limiters map[string]*ratelimit.Limiter
+	keys     map[string]string
+	now      func() time.Time
+}
+
+func NewRateLimiter(store ratelimit.Store, cfg *config.RateLimitConfig) (*RateLimiter, error) {
+	rl := &RateLimiter{
+		limiters: make(map[string]*ratelimit.Limiter, len(cfg.Rules)),
+		keys:     make(map[string]string, len(cfg.Rules)),
+		now:      time.Now,
+	}
+
+	for name, rule := range cfg.Rules {
+		limiter, err := ratelimit.NewLimiter(store, ratelimit.Limit{
+			Algorithm: rule.Algorithm,
+			Requests:  rule.Requests,
+			Window:    time.Duration(rule.Window) * time.Second,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("rate limit %s: %w", name, err)
+		}
+		rl.limiters[name] = limiter
+		rl.keys[name] = rule.Key
+	}
+
+	return rl, nil
+}
+
+// Limit applies the rule called name. Routes whose rule is not configured
+// are not limited. Rules keyed by user or API key have to come after the
+// middleware that authenticates the request.
+func (rl *RateLimiter) Limit(name string) goexpress.Middleware {
+	limiter, ok := rl.limiters[name]
+	if !ok {
+		slog.Warn("rate limit not configured", "name", name)
+		return func(next http.Handler) http.Handler {
+			return next
+		}
+	}
+	keyKind := rl.keys[name]
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := name + ":" + rateLimitKey(r, keyKind)
+
+			res, err := limiter.Allow(r.Context(), key, rl.now())
+			if err != nil {
+				response.ServerError(w, r, err)
+				return
+			}
+
+			setRateLimitHeaders(w, limiter.Limit(), res)
+
+			if !res.Allowed {
+				tooManyRequestsError(w, r, errRateLimited, res.RetryAfter)
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// Returns what identifies the client for kind, falling back from the API key
+// to the user and from the user to the address.
+func rateLimitKey(r *http.Request, kind string) string {
+	if kind == rateLimitKeyAPIKey {
+		if key, ok := FromAPIKeyContext(r.Context()); ok {
+			return rateLimitKeyAPIKey + ":" + key.ID
+		}
+		kind = rateLimitKeyUser
+	}
+
+	if kind == rateLimitKeyUser {
+		if user, ok := FromUserContext(r.Context()); ok {
+			return rateLimitKeyUser + ":" + user.ID
+		}
+	}
+
+	return rateLimitKeyIP + ":" + clientIP(r)
+}
+
+// Sets the headers of the IETF RateLimit header fields draft. Times are in
+// whole seconds, rounded up.
+func setRateLimitHeaders(w http.ResponseWriter, limit ratelimit.Limit, res ratelimit.Result) {
+	h := w.Header()
+	h.Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+	h.Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+	h.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(res.Reset)))
+	h.Set("RateLimit-Policy", fmt.Sprintf("%d;w=%d", limit.Requests, ceilSeconds(limit.Window)))
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
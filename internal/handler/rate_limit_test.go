@@ -0,0 +1,117 @@
+package handler_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func newRateLimited(t *testing.T, rule config.RateLimitRule) http.Handler {
+	t.Helper()
+	rl, err := handler.NewRateLimiter(ratelimit.NewMemoryStore(), &config.RateLimitConfig{
+		Store: "memory",
+		Rules: map[string]config.RateLimitRule{"test": rule},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return rl.Limit("test")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+}
+
+func newLimitedRequest(remoteAddr string) *http.Request {
+	req := httptest.NewRequest(http.MethodGet, "/api/health", nil)
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	req.RemoteAddr = remoteAddr
+	return req
+}
+
+func TestRateLimiter_Limit(t *testing.T) {
+	h := newRateLimited(t, config.RateLimitRule{Algorithm: ratelimit.TokenBucket, Requests: 2, Window: 60, Key: "ip"})
+
+	for _, remaining := range []string{"1", "0"} {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, newLimitedRequest("192.0.2.1:1234"))
+
+		assert.Equal(t, http.StatusNoContent, rr.Code)
+		assert.Equal(t, "2", rr.Header().Get("RateLimit-Limit"))
+		assert.Equal(t, remaining, rr.Header().Get("RateLimit-Remaining"))
+		assert.Equal(t, "2;w=60", rr.Header().Get("RateLimit-Policy"))
+		assert.Empty(t, rr.Header().Get("Retry-After"))
+	}
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, newLimitedRequest("192.0.2.1:5678"))
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "30", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "60", rr.Header().Get("RateLimit-Reset"))
+
+	var apiRes handler.APIResponse[any]
+	if err := json.NewDecoder(rr.Body).Decode(&apiRes); err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, message.Get("rateLimited"), apiRes.Message)
+
+	rr = httptest.NewRecorder()
+	h.ServeHTTP(rr, newLimitedRequest("198.51.100.1:1234"))
+	assert.Equal(t, http.StatusNoContent, rr.Code, "other addresses have their own limit")
+}
+
+func TestRateLimiter_LimitKeys(t *testing.T) {
+	h := newRateLimited(t, config.RateLimitRule{Algorithm: ratelimit.SlidingWindow, Requests: 1, Window: 60,
+		Key: "api_key"})
+	user := &model.User{Model: model.Model{ID: "1"}}
+
+	serve := func(req *http.Request) int {
+		rr := httptest.NewRecorder()
+		h.ServeHTTP(rr, req)
+		return rr.Code
+	}
+
+	withUser := func(remoteAddr string) *http.Request {
+		req := newLimitedRequest(remoteAddr)
+		return req.WithContext(handler.NewUserContext(req.Context(), user))
+	}
+
+	withKey := func(id string) *http.Request {
+		req := withUser("192.0.2.1:1234")
+		return req.WithContext(handler.NewAPIKeyContext(req.Context(), &model.APIKey{ID: id, UserID: user.ID}))
+	}
+
+	assert.Equal(t, http.StatusNoContent, serve(withKey("a")))
+	assert.Equal(t, http.StatusTooManyRequests, serve(withKey("a")))
+	assert.Equal(t, http.StatusNoContent, serve(withKey("b")), "each key has its own limit")
+
+	assert.Equal(t, http.StatusNoContent, serve(withUser("192.0.2.1:1234")), "sessions fall back to the user")
+	assert.Equal(t, http.StatusTooManyRequests, serve(withUser("198.51.100.1:1234")), "the user is limited on any address")
+
+	assert.Equal(t, http.StatusNoContent, serve(newLimitedRequest("192.0.2.1:1234")), "anonymous requests fall back to the address")
+	assert.Equal(t, http.StatusTooManyRequests, serve(newLimitedRequest("192.0.2.1:1234")))
+}
+
+func TestRateLimiter_LimitNotConfigured(t *testing.T) {
+	rl, err := handler.NewRateLimiter(ratelimit.NewMemoryStore(), &config.RateLimitConfig{Store: "memory"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	h := rl.Limit("missing")(http.HandlerFunc(func(w http.ResponseWriter, _ *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, newLimitedRequest("192.0.2.1:1234"))
+
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+}
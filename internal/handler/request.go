@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+const HeaderForwardedFor = "X-Forwarded-For"
+
+// ClientIP resolves the address of the client that a request came from. The
+// peer is the client unless it is one of the trusted proxies, in which case
+// X-Forwarded-For is read from the right, past the trusted proxies, since
+// anything to the left of them may have been made up by the client.
+type ClientIP struct {
+	proxies []netip.Prefix
+}
+
+func NewClientIP(cfg *config.ServerConfig) *ClientIP {
+	proxies := make([]netip.Prefix, 0, len(cfg.TrustedProxies))
+	for _, p := range cfg.TrustedProxies {
+		prefix, err := parsePrefix(p)
+		if err != nil {
+			slog.Warn("ignored trusted proxy", "proxy", p, "reason", err)
+			continue
+		}
+		proxies = append(proxies, prefix)
+	}
+	return &ClientIP{proxies: proxies}
+}
+
+// Parses an address or a CIDR range.
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		prefix, err := netip.ParsePrefix(s)
+		return prefix.Masked(), err
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	addr = addr.Unmap()
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// Handle stores the address of the client for clientIP.
+func (c *ClientIP) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), clientIPCtxKey, c.resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func (c *ClientIP) resolve(r *http.Request) string {
+	ip := peerIP(r)
+	if len(c.proxies) == 0 || !c.trusted(ip) {
+		return ip
+	}
+
+	hops := strings.Split(strings.Join(r.Header.Values(HeaderForwardedFor), ","), ",")
+	for i := len(hops) - 1; i >= 0; i-- {
+		hop := strings.TrimSpace(hops[i])
+		if _, err := netip.ParseAddr(hop); err != nil {
+			// The last trusted proxy is the best that is known.
+			return ip
+		}
+		ip = hop
+		if !c.trusted(ip) {
+			return ip
+		}
+	}
+	return ip
+}
+
+func (c *ClientIP) trusted(ip string) bool {
+	addr, err := netip.ParseAddr(ip)
+	if err != nil {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range c.proxies {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the address of the client as resolved by ClientIP, or of the peer
+// that sent the request when it did not run.
+func clientIP(r *http.Request) string {
+	if ip, ok := r.Context().Value(clientIPCtxKey).(string); ok {
+		return ip
+	}
+	return peerIP(r)
+}
+
+// Returns the address of the peer that sent the request.
+func peerIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
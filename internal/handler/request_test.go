@@ -0,0 +1,60 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClientIP(t *testing.T) {
+	cfg := &config.ServerConfig{TrustedProxies: []string{"10.0.0.0/8", "192.0.2.10"}}
+
+	tests := []struct {
+		name       string
+		remoteAddr string
+		forwarded  []string
+		want       string
+	}{
+		{"Direct client", "198.51.100.1:1234", nil, "198.51.100.1"},
+		{"Untrusted peer", "198.51.100.1:1234", []string{"203.0.113.9"}, "198.51.100.1"},
+		{"Trusted proxy", "192.0.2.10:1234", []string{"203.0.113.9"}, "203.0.113.9"},
+		{"Spoofed hops", "10.0.0.1:1234", []string{"1.1.1.1, 203.0.113.9, 10.0.0.2"}, "203.0.113.9"},
+		{"Repeated header", "10.0.0.1:1234", []string{"1.1.1.1", "203.0.113.9"}, "203.0.113.9"},
+		{"Only proxies", "10.0.0.1:1234", []string{"10.0.0.2"}, "10.0.0.2"},
+		{"Invalid hop", "10.0.0.1:1234", []string{"203.0.113.9, unknown"}, "10.0.0.1"},
+		{"No header", "10.0.0.1:1234", nil, "10.0.0.1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/", nil)
+			req.RemoteAddr = tt.remoteAddr
+			for _, v := range tt.forwarded {
+				req.Header.Add(HeaderForwardedFor, v)
+			}
+
+			var got string
+			NewClientIP(cfg).Handle(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+				got = clientIP(r)
+			})).ServeHTTP(httptest.NewRecorder(), req)
+
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestClientIPWithoutTrustedProxies(t *testing.T) {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = "192.0.2.10:1234"
+	req.Header.Set(HeaderForwardedFor, "203.0.113.9")
+
+	var got string
+	NewClientIP(&config.ServerConfig{}).Handle(http.HandlerFunc(func(_ http.ResponseWriter, r *http.Request) {
+		got = clientIP(r)
+	})).ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, "192.0.2.10", got, "the header is ignored unless proxies are configured")
+}
@@ -1,21 +1,64 @@
 package handler
 
 import (
+	"expvar"
+
 	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/model"
 	"github.com/go-playground/validator/v10"
 )
 
-func mountAPIRoutes(r *goexpress.Router, h *APIHandler, v *validator.Validate) {
-	r.Group("/api", func(gr *goexpress.Router) *goexpress.Router {
+func mountAPIRoutes(r *goexpress.Router, h *APIHandler, v *validator.Validate, auth *AuthMiddleware,
+	rl *RateLimiter) {
+	r.Group(apiPrefix, func(gr *goexpress.Router) *goexpress.Router {
 		gr.Get("/health", h.Base.HandleHealth)
-		gr.Post("/auth/register", h.User.HandleUserRegister,
+		gr.Post("/auth/register", h.User.HandleUserRegister, rl.Limit("auth.register"),
 			DecodeJSON[RegisterUserRequest](), ValidateInput[RegisterUserRequest](v))
+		gr.Post("/auth/login", h.User.HandleUserLogin, rl.Limit("auth.login"),
+			DecodeJSON[LoginUserRequest](), ValidateInput[LoginUserRequest](v))
+		gr.Post("/auth/login/2fa", h.TwoFactor.HandleCompleteLogin, rl.Limit("auth.login"),
+			DecodeJSON[CompleteLoginRequest](), ValidateInput[CompleteLoginRequest](v))
+		gr.Post("/auth/logout", h.User.HandleUserLogout)
+		gr.Post("/auth/token", h.Token.HandleToken, rl.Limit("auth.token"),
+			DecodeJSON[TokenRequest](), ValidateInput[TokenRequest](v))
+		gr.Post("/auth/token/revoke", h.Token.HandleRevokeToken,
+			DecodeJSON[RevokeTokenRequest](), ValidateInput[RevokeTokenRequest](v))
+		gr.Get("/auth/me", h.User.HandleCurrentUser, auth.RequireAuth)
+		gr.Post("/auth/2fa/enroll", h.TwoFactor.HandleEnroll, auth.RequireAuth)
+		gr.Get("/auth/2fa/qr.png", h.TwoFactor.HandleQRCode, auth.RequireAuth)
+		gr.Post("/auth/2fa/confirm", h.TwoFactor.HandleConfirm, auth.RequireAuth, rl.Limit("auth.two_factor"),
+			DecodeJSON[TwoFactorCodeRequest](), ValidateInput[TwoFactorCodeRequest](v))
+		gr.Post("/auth/2fa/disable", h.TwoFactor.HandleDisable, auth.RequireAuth, rl.Limit("auth.two_factor"),
+			DecodeJSON[TwoFactorCodeRequest](), ValidateInput[TwoFactorCodeRequest](v))
+		gr.Get("/users", h.User.HandleListUsers, auth.RequirePermission(model.PermUsersRead))
+		// The key routes refuse API keys, so they are limited per user.
+		gr.Post("/keys", h.APIKey.HandleCreateAPIKey, auth.RequireAuth, rl.Limit("api_keys"),
+			DecodeJSON[CreateAPIKeyRequest](), ValidateInput[CreateAPIKeyRequest](v))
+		gr.Get("/keys", h.APIKey.HandleListAPIKeys, auth.RequireAuth, rl.Limit("api_keys"))
+		gr.Delete("/keys/{id}", h.APIKey.HandleRevokeAPIKey, auth.RequireAuth, rl.Limit("api_keys"))
+		gr.Post("/auth/forgot-password", h.User.HandleForgotPassword, rl.Limit("auth.forgot_password"),
+			DecodeJSON[ForgotPasswordRequest](), ValidateInput[ForgotPasswordRequest](v))
+		gr.Post("/auth/reset-password", h.User.HandleResetPassword, rl.Limit("auth.reset_password"),
+			DecodeJSON[ResetPasswordRequest](), ValidateInput[ResetPasswordRequest](v))
 
 		return gr
-	})
+	}, rl.Limit("api"))
 }
 
-func mountRoutes(r *goexpress.Router, h *Handler) {
-	r.Get("/dashboard", h.Base.HandleDashboard)
-	r.Get("/auth/register", h.User.HandleRegister)
+// Public pages use OptionalAuth so that the layout can adapt to the user.
+func mountRoutes(r *goexpress.Router, h *Handler, auth *AuthMiddleware, rl *RateLimiter) {
+	r.Get("/dashboard", h.Base.HandleDashboard, auth.RequireAuth)
+	r.Get("/admin/users", h.User.HandleAdminUsers, auth.RequirePermission(model.PermUsersRead))
+	r.Get("/auth/register", h.User.HandleRegister, auth.OptionalAuth)
+	r.Get(loginPath, h.User.HandleLogin, auth.OptionalAuth)
+	r.Get("/auth/two-factor", h.User.HandleTwoFactor, auth.OptionalAuth)
+	r.Get("/auth/oidc/{provider}", h.OIDC.HandleLogin)
+	r.Get("/auth/oidc/{provider}/callback", h.OIDC.HandleCallback)
+	r.Get("/account/security", h.Account.HandleSecurity, auth.RequireAuth)
+	r.Get("/auth/verify", h.User.HandleVerify, auth.OptionalAuth)
+	r.Get("/auth/forgot-password", h.User.HandleForgotPassword, auth.OptionalAuth)
+	r.Get("/auth/reset-password", h.User.HandleResetPassword, auth.OptionalAuth)
+	r.Post(cspReportPath, HandleCSPReport, rl.Limit("csp_report"))
+	// The expvar metrics, such as the queue of password hashes
+	r.Get("/debug/vars", expvar.Handler().ServeHTTP, auth.RequirePermission(model.PermMetricsRead))
 }
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+const (
+	cspReportPath = "/csp-report"
+	// Reports are small, anything bigger is not a browser reporting.
+	maxCSPReportSize = 64 << 10
+	cspNonceLen      = 16
+	noncePlaceholder = "{nonce}"
+)
+
+type cspNonceCtxKey struct{}
+
+// SecurityHeaders sets the security headers of the config on every
+// response. The Content-Security-Policy allows scripts with the nonce of the
+// request, which the templates get from cspNonce.
+type SecurityHeaders struct {
+	headers   map[string]string
+	cspHeader string
+	csp       string
+}
+
+func NewSecurityHeaders(cfg *config.SecurityHeadersConfig) *SecurityHeaders {
+	h := &SecurityHeaders{
+		headers: map[string]string{"X-Content-Type-Options": "nosniff"},
+	}
+
+	if cfg.HSTSMaxAge > 0 {
+		hsts := "max-age=" + strconv.Itoa(cfg.HSTSMaxAge)
+		if cfg.HSTSIncludeSubdomains {
+			hsts += "; includeSubDomains"
+		}
+		if cfg.HSTSPreload {
+			hsts += "; preload"
+		}
+		h.headers["Strict-Transport-Security"] = hsts
+	}
+
+	for name, value := range map[string]string{
+		"Referrer-Policy":              cfg.ReferrerPolicy,
+		"Permissions-Policy":           cfg.PermissionsPolicy,
+		"Cross-Origin-Opener-Policy":   cfg.CrossOriginOpenerPolicy,
+		"Cross-Origin-Embedder-Policy": cfg.CrossOriginEmbedderPolicy,
+	} {
+		if value != "" {
+			h.headers[name] = value
+		}
+	}
+
+	if len(cfg.CSP.Directives) > 0 {
+		h.cspHeader = "Content-Security-Policy"
+		if cfg.CSP.ReportOnly {
+			h.cspHeader = "Content-Security-Policy-Report-Only"
+		}
+		h.csp = buildCSP(cfg.CSP.Directives)
+	}
+
+	return h
+}
+
+// Returns the policy with the nonce placeholder in script-src. Directives are
+// sorted so that the header is the same on every start.
+func buildCSP(directives map[string]string) string {
+	d := make(map[string]string, len(directives)+1)
+	for name, sources := range directives {
+		d[strings.ToLower(name)] = strings.TrimSpace(sources)
+	}
+
+	scriptSrc, ok := d["script-src"]
+	if !ok {
+		// Scripts fall back to default-src, which a script-src with only the
+		// nonce would override.
+		scriptSrc = d["default-src"]
+	}
+	d["script-src"] = strings.TrimSpace(scriptSrc + " 'nonce-" + noncePlaceholder + "'")
+	d["report-uri"] = cspReportPath
+
+	names := make([]string, 0, len(d))
+	for name := range d {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	policy := make([]string, 0, len(names))
+	for _, name := range names {
+		policy = append(policy, strings.TrimSpace(name+" "+d[name]))
+	}
+	return strings.Join(policy, "; ")
+}
+
+func (h *SecurityHeaders) Handle(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		header := w.Header()
+		for name, value := range h.headers {
+			header.Set(name, value)
+		}
+
+		if h.csp != "" {
+			nonce, err := newCSPNonce()
+			if err != nil {
+				response.ServerError(w, r, err)
+				return
+			}
+			header.Set(h.cspHeader, strings.ReplaceAll(h.csp, noncePlaceholder, nonce))
+			r = r.WithContext(context.WithValue(r.Context(), cspNonceCtxKey{}, nonce))
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+func newCSPNonce() (string, error) {
+	b := make([]byte, cspNonceLen)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("generate csp nonce: %w", err)
+	}
+	return base64.RawStdEncoding.EncodeToString(b), nil
+}
+
+// TemplateFuncs expose the nonce of the request to the templates.
+func (h *SecurityHeaders) TemplateFuncs(r *http.Request) template.FuncMap {
+	return template.FuncMap{
+		"cspNonce": func() string {
+			nonce, _ := r.Context().Value(cspNonceCtxKey{}).(string)
+			return nonce
+		},
+	}
+}
+
+// HandleCSPReport logs the violations that browsers report, in either the
+// report-uri or the Reporting API format.
+func HandleCSPReport(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxCSPReportSize))
+	if err != nil {
+		badRequestError(w, r, fmt.Errorf("read csp report: %w", err))
+		return
+	}
+
+	var reports []json.RawMessage
+	if strings.HasPrefix(r.Header.Get(HeaderContentType), "application/reports+json") {
+		var batch []struct {
+			Type string          `json:"type"`
+			Body json.RawMessage `json:"body"`
+		}
+		if err := json.Unmarshal(body, &batch); err != nil {
+			badRequestError(w, r, fmt.Errorf("decode csp report: %w", err))
+			return
+		}
+		for _, report := range batch {
+			if report.Type == "csp-violation" {
+				reports = append(reports, report.Body)
+			}
+		}
+	} else {
+		var report struct {
+			Body json.RawMessage `json:"csp-report"`
+		}
+		if err := json.Unmarshal(body, &report); err != nil {
+			badRequestError(w, r, fmt.Errorf("decode csp report: %w", err))
+			return
+		}
+		if report.Body == nil {
+			badRequestError(w, r, errors.New("csp report without a csp-report member"))
+			return
+		}
+		reports = append(reports, report.Body)
+	}
+
+	for _, report := range reports {
+		slog.Warn("content security policy violation",
+			"report", report,
+			"user_agent", r.UserAgent(),
+			"ip", clientIP(r))
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
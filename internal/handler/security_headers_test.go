@@ -0,0 +1,93 @@
+package handler_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/stretchr/testify/assert"
+)
+
+var headersCfg = config.SecurityHeadersConfig{
+	CSP: config.CSPConfig{Directives: map[string]string{
+		"default-src": "'self'",
+		"object-src":  "'none'",
+	}},
+	HSTSMaxAge:                31536000,
+	HSTSIncludeSubdomains:     true,
+	ReferrerPolicy:            "strict-origin-when-cross-origin",
+	CrossOriginOpenerPolicy:   "same-origin",
+	CrossOriginEmbedderPolicy: "credentialless",
+}
+
+func serveWithHeaders(cfg config.SecurityHeadersConfig) *httptest.ResponseRecorder {
+	sh := handler.NewSecurityHeaders(&cfg)
+	h := sh.Handle(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(sh.TemplateFuncs(r)["cspNonce"].(func() string)()))
+	}))
+
+	rr := httptest.NewRecorder()
+	h.ServeHTTP(rr, httptest.NewRequest(http.MethodGet, "/dashboard", nil))
+	return rr
+}
+
+func TestSecurityHeaders(t *testing.T) {
+	rr := serveWithHeaders(headersCfg)
+	nonce := rr.Body.String()
+
+	assert.NotEmpty(t, nonce)
+	assert.Equal(t, "default-src 'self'; object-src 'none'; report-uri /csp-report; script-src 'self' 'nonce-"+nonce+"'",
+		rr.Header().Get("Content-Security-Policy"))
+	assert.Equal(t, "max-age=31536000; includeSubDomains", rr.Header().Get("Strict-Transport-Security"))
+	assert.Equal(t, "nosniff", rr.Header().Get("X-Content-Type-Options"))
+	assert.Equal(t, "strict-origin-when-cross-origin", rr.Header().Get("Referrer-Policy"))
+	assert.Equal(t, "same-origin", rr.Header().Get("Cross-Origin-Opener-Policy"))
+	assert.Equal(t, "credentialless", rr.Header().Get("Cross-Origin-Embedder-Policy"))
+	assert.NotContains(t, rr.Header(), "Permissions-Policy", "empty policies are left out")
+
+	assert.NotEqual(t, nonce, serveWithHeaders(headersCfg).Body.String(), "every request gets its own nonce")
+}
+
+func TestSecurityHeaders_ReportOnly(t *testing.T) {
+	cfg := headersCfg
+	cfg.CSP.ReportOnly = true
+	cfg.HSTSMaxAge = 0
+
+	rr := serveWithHeaders(cfg)
+	assert.Empty(t, rr.Header().Get("Content-Security-Policy"))
+	assert.Contains(t, rr.Header().Get("Content-Security-Policy-Report-Only"), "'nonce-"+rr.Body.String()+"'")
+	assert.Empty(t, rr.Header().Get("Strict-Transport-Security"))
+}
+
+func TestHandleCSPReport(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		status      int
+	}{
+		{"report-uri", "application/csp-report",
+			`{"csp-report": {"document-uri": "http://localhost:8888/", "violated-directive": "script-src"}}`,
+			http.StatusNoContent},
+		{"Reporting API", "application/reports+json",
+			`[{"type": "csp-violation", "body": {"documentURL": "http://localhost:8888/"}}]`,
+			http.StatusNoContent},
+		{"Not a report", "application/csp-report", `{"document-uri": "http://localhost:8888/"}`,
+			http.StatusBadRequest},
+		{"Malformed", "application/csp-report", `{`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, "/csp-report", strings.NewReader(tt.body))
+			req.Header.Set(handler.HeaderContentType, tt.contentType)
+
+			rr := httptest.NewRecorder()
+			handler.HandleCSPReport(rr, req)
+			assert.Equal(t, tt.status, rr.Code)
+		})
+	}
+}
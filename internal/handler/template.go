@@ -13,14 +13,20 @@ import (
 
 	"github.com/ferdiebergado/gopherkit/http/response"
 	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
 )
 
 const suffix = ".html"
 
 type templateMap map[string]*template.Template
 
+// RequestFuncs returns template funcs whose results depend on the request
+// being rendered, such as the current user.
+type RequestFuncs func(r *http.Request) template.FuncMap
+
 type Template struct {
-	templates templateMap
+	templates    templateMap
+	requestFuncs []RequestFuncs
 }
 
 func NewTemplate(cfg config.TemplateConfig) (*Template, error) {
@@ -41,6 +47,13 @@ func NewTemplate(cfg config.TemplateConfig) (*Template, error) {
 	}, nil
 }
 
+// AddRequestFuncs registers funcs that are bound to the request on every
+// Render. Their names must also be declared in funcMap so that the templates
+// parse. It must be called before the first Render.
+func (t *Template) AddRequestFuncs(funcs RequestFuncs) {
+	t.requestFuncs = append(t.requestFuncs, funcs)
+}
+
 func (t *Template) Render(w http.ResponseWriter, r *http.Request, name string, data any) {
 	tmpl, ok := t.templates[name]
 	if !ok {
@@ -48,6 +61,20 @@ func (t *Template) Render(w http.ResponseWriter, r *http.Request, name string, d
 		return
 	}
 
+	if len(t.requestFuncs) > 0 {
+		// The parsed templates are never executed so that they can be cloned
+		// and given the funcs of this request.
+		clone, err := tmpl.Clone()
+		if err != nil {
+			response.ServerError(w, r, fmt.Errorf("clone template: %w", err))
+			return
+		}
+		for _, funcs := range t.requestFuncs {
+			clone.Funcs(funcs(r))
+		}
+		tmpl = clone
+	}
+
 	var buf bytes.Buffer
 	if err := tmpl.Execute(&buf, data); err != nil {
 		response.ServerError(w, r, fmt.Errorf("execute template: %w", err))
@@ -133,5 +160,11 @@ func funcMap() template.FuncMap {
 		"css": func(s string) template.CSS {
 			return template.CSS(s) // #nosec G203 -- No user input
 		},
+		// Placeholders replaced on Render by the funcs of the request.
+		"currentUser": func() *model.User { return nil },
+		"can":         func(string) (bool, error) { return false, nil },
+		"csrfToken":   func() (string, error) { return "", nil },
+		"csrfField":   func() (template.HTML, error) { return "", nil },
+		"cspNonce":    func() string { return "" },
 	}
 }
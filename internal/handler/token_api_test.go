@@ -0,0 +1,98 @@
+package handler_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const tokenUrl = "/api/auth/token"
+
+func postToken(t *testing.T, setup func(*mock.MockTokenService), body handler.TokenRequest) *httptest.ResponseRecorder {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockTokenService(ctrl)
+	setup(mockService)
+
+	tokenHandler := handler.NewTokenAPIHandler(mockService)
+	r := goexpress.New()
+	r.Post(tokenUrl, tokenHandler.HandleToken,
+		handler.DecodeJSON[handler.TokenRequest](), handler.ValidateInput[handler.TokenRequest](validate))
+
+	reqJSON, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, tokenUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+	return rr
+}
+
+func TestTokenHandlerHandleTokenPassword(t *testing.T) {
+	pair := &service.TokenPair{
+		AccessToken:           "access",
+		AccessTokenExpiresAt:  time.Now().Add(15 * time.Minute),
+		RefreshToken:          "refresh",
+		RefreshTokenExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	rr := postToken(t, func(m *mock.MockTokenService) {
+		m.EXPECT().IssueTokens(gomock.Any(), service.IssueTokensParams{
+			Email:     testEmail,
+			Password:  testPass,
+			IPAddress: "192.0.2.1",
+		}).Return(pair, nil)
+	}, handler.TokenRequest{GrantType: handler.GrantPassword, Email: testEmail, Password: testPass})
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+
+	var apiRes handler.APIResponse[handler.TokenResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, pair.AccessToken, apiRes.Data.AccessToken)
+	assert.Equal(t, "Bearer", apiRes.Data.TokenType)
+	assert.Equal(t, 900, apiRes.Data.ExpiresIn)
+	assert.Equal(t, pair.RefreshToken, apiRes.Data.RefreshToken)
+}
+
+func TestTokenHandlerHandleTokenRefreshInvalid(t *testing.T) {
+	rr := postToken(t, func(m *mock.MockTokenService) {
+		m.EXPECT().RefreshTokens(gomock.Any(), "reused").Return(nil, service.ErrInvalidToken)
+	}, handler.TokenRequest{GrantType: handler.GrantRefreshToken, RefreshToken: "reused"})
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+}
+
+func TestTokenHandlerHandleTokenInvalidInput(t *testing.T) {
+	var tests = []struct {
+		name string
+		body handler.TokenRequest
+	}{
+		{"Unknown grant", handler.TokenRequest{GrantType: "client_credentials"}},
+		{"Password grant without password", handler.TokenRequest{GrantType: handler.GrantPassword, Email: testEmail}},
+		{"Refresh grant without token", handler.TokenRequest{GrantType: handler.GrantRefreshToken}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rr := postToken(t, func(*mock.MockTokenService) {}, tt.body)
+			assert.Equal(t, http.StatusBadRequest, rr.Code)
+		})
+	}
+}
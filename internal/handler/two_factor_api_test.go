@@ -0,0 +1,156 @@
+package handler_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/handler"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/ferdiebergado/goweb/internal/service/mock"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const twoFactorUrl = "/api/auth/2fa"
+
+func newTwoFactorRouter(t *testing.T, setup func(*mock.MockTwoFactorService)) *goexpress.Router {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockTwoFactorService(ctrl)
+	setup(mockService)
+
+	h := handler.NewTwoFactorAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Get(twoFactorUrl+"/qr.png", h.HandleQRCode)
+	r.Post(twoFactorUrl+"/confirm", h.HandleConfirm,
+		handler.DecodeJSON[handler.TwoFactorCodeRequest](), handler.ValidateInput[handler.TwoFactorCodeRequest](validate))
+	r.Post("/api/auth/login/2fa", h.HandleCompleteLogin,
+		handler.DecodeJSON[handler.CompleteLoginRequest](), handler.ValidateInput[handler.CompleteLoginRequest](validate))
+	return r
+}
+
+func TestTwoFactorHandlerHandleQRCode(t *testing.T) {
+	png := []byte("\x89PNG")
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().EnrollmentQRCode(gomock.Any(), gomock.Any()).Return(png, nil)
+	})
+
+	req := newKeyRequest(t, http.MethodGet, twoFactorUrl+"/qr.png", nil)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "image/png", rr.Header().Get(handler.HeaderContentType))
+	assert.Equal(t, "no-store", rr.Header().Get("Cache-Control"))
+	assert.Equal(t, png, rr.Body.Bytes())
+}
+
+func TestTwoFactorHandlerHandleConfirm(t *testing.T) {
+	codes := []string{"abcd-efgh", "ijkl-mnop"}
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().ConfirmEnrollment(gomock.Any(), gomock.Any()).
+			DoAndReturn(func(_ context.Context, params service.TwoFactorCodeParams) ([]string, error) {
+				assert.Equal(t, "1", params.User.ID)
+				assert.Equal(t, "123456", params.Code)
+				return codes, nil
+			})
+	})
+
+	req := newKeyRequest(t, http.MethodPost, twoFactorUrl+"/confirm", handler.TwoFactorCodeRequest{Code: "123456"})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	var apiRes handler.APIResponse[handler.RecoveryCodesResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, codes, apiRes.Data.RecoveryCodes)
+}
+
+func TestTwoFactorHandlerHandleConfirmThrottled(t *testing.T) {
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().ConfirmEnrollment(gomock.Any(), gomock.Any()).
+			Return(nil, &service.ThrottledError{RetryAfter: time.Minute})
+	})
+
+	req := newKeyRequest(t, http.MethodPost, twoFactorUrl+"/confirm", handler.TwoFactorCodeRequest{Code: "000000"})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "60", rr.Header().Get("Retry-After"))
+}
+
+func TestTwoFactorHandlerHandleConfirmWithAPIKey(t *testing.T) {
+	r := newTwoFactorRouter(t, func(*mock.MockTwoFactorService) {})
+
+	req := newKeyRequest(t, http.MethodPost, twoFactorUrl+"/confirm", handler.TwoFactorCodeRequest{Code: "123456"})
+	req = req.WithContext(handler.NewAPIKeyContext(req.Context(), &model.APIKey{ID: "2"}))
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusForbidden, rr.Code)
+}
+
+func TestTwoFactorHandlerHandleCompleteLogin(t *testing.T) {
+	expiresAt := time.Now().Add(time.Hour)
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().CompleteLogin(gomock.Any(), gomock.Any()).
+			Return(&service.LoginUserResult{
+				User:      &model.User{Model: model.Model{ID: "1"}, Email: testEmail},
+				Token:     testToken,
+				ExpiresAt: expiresAt,
+			}, nil)
+	})
+
+	req := newKeyRequest(t, http.MethodPost, "/api/auth/login/2fa",
+		handler.CompleteLoginRequest{ChallengeToken: "challenge", Code: "123456"})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+
+	cookies := rr.Result().Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, sessionCfg.CookieName, cookies[0].Name)
+		assert.Equal(t, testToken, cookies[0].Value)
+	}
+}
+
+func TestTwoFactorHandlerHandleCompleteLoginInvalidCode(t *testing.T) {
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().CompleteLogin(gomock.Any(), gomock.Any()).Return(nil, service.ErrInvalidTwoFactorCode)
+	})
+
+	req := newKeyRequest(t, http.MethodPost, "/api/auth/login/2fa",
+		handler.CompleteLoginRequest{ChallengeToken: "challenge", Code: "000000"})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusUnauthorized, rr.Code)
+	assert.Empty(t, rr.Result().Cookies())
+}
+
+func TestTwoFactorHandlerHandleCompleteLoginThrottled(t *testing.T) {
+	r := newTwoFactorRouter(t, func(m *mock.MockTwoFactorService) {
+		m.EXPECT().CompleteLogin(gomock.Any(), gomock.Any()).
+			Return(nil, &service.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+	})
+
+	req := newKeyRequest(t, http.MethodPost, "/api/auth/login/2fa",
+		handler.CompleteLoginRequest{ChallengeToken: "challenge", Code: "000000"})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+}
@@ -4,15 +4,20 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"testing"
 	"time"
 
 	"github.com/ferdiebergado/goexpress"
+	"github.com/ferdiebergado/goweb/internal/config"
 	"github.com/ferdiebergado/goweb/internal/handler"
 	"github.com/ferdiebergado/goweb/internal/model"
 	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/ferdiebergado/goweb/internal/repository"
 	"github.com/ferdiebergado/goweb/internal/service"
 	"github.com/ferdiebergado/goweb/internal/service/mock"
 	"github.com/go-playground/validator/v10"
@@ -22,6 +27,9 @@ import (
 
 const (
 	regUrl         = "/api/auth/register"
+	loginUrl       = "/api/auth/login"
+	logoutUrl      = "/api/auth/logout"
+	testToken      = "token"
 	testEmail      = "abc@example.com"
 	testPass       = "test"
 	testPassHashed = "hashed"
@@ -29,8 +37,14 @@ const (
 
 var validate *validator.Validate
 
+var sessionCfg = &config.SessionConfig{
+	CookieName: "goweb_session",
+	Lifetime:   3600,
+	Secure:     true,
+}
+
 func TestMain(t *testing.M) {
-	validate = validator.New()
+	validate = validation.New()
 	t.Run()
 }
 
@@ -55,7 +69,7 @@ func TestUserHandlerHandleUserRegisterSuccess(t *testing.T) {
 	}
 
 	mockService.EXPECT().RegisterUser(handler.NewParamsContext(context.Background(), regRequest), regParams).Return(user, nil)
-	userHandler := handler.NewUserAPIHandler(mockService)
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
 	r := goexpress.New()
 	r.Post(regUrl, userHandler.HandleUserRegister,
 		handler.DecodeJSON[handler.RegisterUserRequest](), handler.ValidateInput[handler.RegisterUserRequest](validate))
@@ -94,7 +108,7 @@ func TestUserHandlerHandleUserRegisterInvalidInput(t *testing.T) {
 
 	ctrl := gomock.NewController(t)
 	mockService := mock.NewMockUserService(ctrl)
-	userHandler := handler.NewUserAPIHandler(mockService)
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
 	r := goexpress.New()
 	r.Post(regUrl, userHandler.HandleUserRegister,
 		handler.DecodeJSON[handler.RegisterUserRequest](), handler.ValidateInput[handler.RegisterUserRequest](validate))
@@ -104,6 +118,8 @@ func TestUserHandlerHandleUserRegisterInvalidInput(t *testing.T) {
 		regRequest handler.RegisterUserRequest
 	}{
 		{"Empty email", handler.RegisterUserRequest{Password: testPass, PasswordConfirm: testPass}},
+		{"Password contains email", handler.RegisterUserRequest{Email: testEmail, Password: "x" + testEmail,
+			PasswordConfirm: "x" + testEmail}},
 	}
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
@@ -148,8 +164,9 @@ func TestUserHandlerHandleUserRegisterDuplicateUser(t *testing.T) {
 		Password: testPass,
 	}
 
-	mockService.EXPECT().RegisterUser(handler.NewParamsContext(context.Background(), regRequest), regParams).Return(nil, service.ErrDuplicateUser)
-	userHandler := handler.NewUserAPIHandler(mockService)
+	mockService.EXPECT().RegisterUser(handler.NewParamsContext(context.Background(), regRequest), regParams).
+		Return(nil, fmt.Errorf("user with email %s already exists: %w: %w", testEmail, service.ErrDuplicateUser, repository.ErrUniqueViolation))
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
 	r := goexpress.New()
 	r.Post(regUrl, userHandler.HandleUserRegister,
 		handler.DecodeJSON[handler.RegisterUserRequest](), handler.ValidateInput[handler.RegisterUserRequest](validate))
@@ -177,3 +194,268 @@ func TestUserHandlerHandleUserRegisterDuplicateUser(t *testing.T) {
 
 	assert.Equal(t, service.ErrDuplicateUser.Error(), apiRes.Message)
 }
+
+func TestUserHandlerHandleUserLoginSuccess(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	loginRequest := handler.LoginUserRequest{
+		Email:    testEmail,
+		Password: testPass,
+	}
+
+	result := &service.LoginUserResult{
+		User: &model.User{
+			Model: model.Model{ID: "1", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+			Email: testEmail,
+		},
+		Token:     testToken,
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockService.EXPECT().LoginUser(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params service.LoginUserParams) (*service.LoginUserResult, error) {
+			assert.Equal(t, testEmail, params.Email)
+			assert.Equal(t, testPass, params.Password)
+			assert.NotEmpty(t, params.IPAddress)
+			return result, nil
+		})
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(loginUrl, userHandler.HandleUserLogin,
+		handler.DecodeJSON[handler.LoginUserRequest](), handler.ValidateInput[handler.LoginUserRequest](validate))
+
+	reqJSON, err := json.Marshal(loginRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, loginUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	res := rr.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cookies := res.Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, sessionCfg.CookieName, cookies[0].Name)
+		assert.Equal(t, testToken, cookies[0].Value)
+		assert.True(t, cookies[0].HttpOnly, "Session cookie should be HttpOnly")
+		assert.True(t, cookies[0].Secure, "Session cookie should be Secure")
+	}
+
+	var apiRes handler.APIResponse[handler.LoginUserResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+
+	assert.Equal(t, message.Get("loginSuccess"), apiRes.Message)
+	assert.Equal(t, result.User.ID, apiRes.Data.ID)
+	assert.Equal(t, testEmail, apiRes.Data.Email)
+}
+
+func TestUserHandlerHandleUserLoginTwoFactorRequired(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	mockService.EXPECT().LoginUser(gomock.Any(), gomock.Any()).Return(&service.LoginUserResult{
+		User:           &model.User{Model: model.Model{ID: "1"}, Email: testEmail},
+		ChallengeToken: "challenge",
+		ExpiresAt:      time.Now().Add(5 * time.Minute),
+	}, nil)
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(loginUrl, userHandler.HandleUserLogin,
+		handler.DecodeJSON[handler.LoginUserRequest](), handler.ValidateInput[handler.LoginUserRequest](validate))
+
+	reqJSON, err := json.Marshal(handler.LoginUserRequest{Email: testEmail, Password: testPass})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, loginUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Result().Cookies(), "no session before the second factor")
+
+	var apiRes handler.APIResponse[handler.LoginUserResponse]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.True(t, apiRes.Data.TwoFactorRequired)
+	assert.Equal(t, "challenge", apiRes.Data.ChallengeToken)
+}
+
+func TestUserHandlerHandleUserLoginInvalidCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	loginRequest := handler.LoginUserRequest{
+		Email:    testEmail,
+		Password: testPass,
+	}
+
+	mockService.EXPECT().LoginUser(gomock.Any(), gomock.Any()).Return(nil, service.ErrInvalidCredentials)
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(loginUrl, userHandler.HandleUserLogin,
+		handler.DecodeJSON[handler.LoginUserRequest](), handler.ValidateInput[handler.LoginUserRequest](validate))
+
+	reqJSON, err := json.Marshal(loginRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, loginUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	res := rr.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusUnauthorized, res.StatusCode)
+	assert.Empty(t, res.Cookies(), "No cookie should be set on failed login")
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+
+	assert.Equal(t, service.ErrInvalidCredentials.Error(), apiRes.Message)
+}
+
+func TestUserHandlerHandleUserLoginThrottled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	mockService.EXPECT().LoginUser(gomock.Any(), gomock.Any()).
+		Return(nil, &service.ThrottledError{RetryAfter: 1500 * time.Millisecond})
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(loginUrl, userHandler.HandleUserLogin,
+		handler.DecodeJSON[handler.LoginUserRequest](), handler.ValidateInput[handler.LoginUserRequest](validate))
+
+	reqJSON, err := json.Marshal(handler.LoginUserRequest{Email: testEmail, Password: testPass})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, loginUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, service.ErrTooManyLoginAttempts.Error(), apiRes.Message)
+}
+
+func TestUserHandlerHandleUserLoginHasherBusy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	mockService.EXPECT().LoginUser(gomock.Any(), gomock.Any()).
+		Return(nil, fmt.Errorf("hasher verify: %w", &security.BusyError{RetryAfter: 2 * time.Second}))
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(loginUrl, userHandler.HandleUserLogin,
+		handler.DecodeJSON[handler.LoginUserRequest](), handler.ValidateInput[handler.LoginUserRequest](validate))
+
+	reqJSON, err := json.Marshal(handler.LoginUserRequest{Email: testEmail, Password: testPass})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, loginUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusServiceUnavailable, rr.Code)
+	assert.Equal(t, "2", rr.Header().Get("Retry-After"))
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+	assert.Equal(t, security.ErrHasherBusy.Error(), apiRes.Message)
+}
+
+func TestUserHandlerHandleUserLogout(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	mockService.EXPECT().LogoutUser(gomock.Any(), testToken).Return(nil)
+
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(logoutUrl, userHandler.HandleUserLogout)
+
+	req := httptest.NewRequest(http.MethodPost, logoutUrl, nil)
+	req.AddCookie(&http.Cookie{Name: sessionCfg.CookieName, Value: testToken})
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	res := rr.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusOK, res.StatusCode)
+
+	cookies := res.Cookies()
+	if assert.Len(t, cookies, 1) {
+		assert.Equal(t, sessionCfg.CookieName, cookies[0].Name)
+		assert.Empty(t, cookies[0].Value)
+		assert.Negative(t, cookies[0].MaxAge, "Session cookie should be expired")
+	}
+}
+
+func TestUserHandlerHandleResetPasswordInvalidToken(t *testing.T) {
+	const resetUrl = "/api/auth/reset-password"
+
+	ctrl := gomock.NewController(t)
+	mockService := mock.NewMockUserService(ctrl)
+	resetRequest := handler.ResetPasswordRequest{
+		Token:           testToken,
+		Password:        testPass,
+		PasswordConfirm: testPass,
+	}
+	params := service.ResetPasswordParams{
+		Token:    testToken,
+		Password: testPass,
+	}
+
+	mockService.EXPECT().ResetPassword(gomock.Any(), params).Return(service.ErrInvalidToken)
+	userHandler := handler.NewUserAPIHandler(mockService, sessionCfg)
+	r := goexpress.New()
+	r.Post(resetUrl, userHandler.HandleResetPassword,
+		handler.DecodeJSON[handler.ResetPasswordRequest](), handler.ValidateInput[handler.ResetPasswordRequest](validate))
+
+	reqJSON, err := json.Marshal(resetRequest)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, resetUrl, bytes.NewBuffer(reqJSON))
+	req.Header.Set(handler.HeaderContentType, handler.MimeJSONUTF8)
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	res := rr.Result()
+	defer res.Body.Close()
+
+	assert.Equal(t, http.StatusUnprocessableEntity, res.StatusCode)
+
+	var apiRes handler.APIResponse[any]
+	if err := json.Unmarshal(rr.Body.Bytes(), &apiRes); err != nil {
+		t.Fatal(message.Get("jsonFailed"), err)
+	}
+
+	assert.Equal(t, service.ErrInvalidToken.Error(), apiRes.Message)
+}
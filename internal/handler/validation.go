@@ -3,8 +3,11 @@ package handler
 import (
 	"fmt"
 	"net/http"
+	"strings"
 
 	"github.com/ferdiebergado/gopherkit/http/response"
+	"github.com/ferdiebergado/goweb/internal/pkg/password"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
 	"github.com/go-playground/validator/v10"
 )
 
@@ -23,8 +26,9 @@ func validationError(w http.ResponseWriter, r *http.Request, err error) {
 	response.JSON(w, r, http.StatusBadRequest, res)
 }
 
+// Aliases such as password are described by the tag that failed.
 func validationMessage(e validator.FieldError) string {
-	switch e.Tag() {
+	switch e.ActualTag() {
 	case "required":
 		return fmt.Sprintf("%s is required", e.Field())
 	case "email":
@@ -47,7 +51,34 @@ func validationMessage(e validator.FieldError) string {
 		return fmt.Sprintf("%s must contain only letters and numbers", e.Field())
 	case "eqfield":
 		return fmt.Sprintf("%s should match %s", e.Field(), e.Param())
+	case validation.PasswordClassesTag:
+		return fmt.Sprintf("%s must contain %s", e.Field(), describeClasses(strings.Fields(e.Param())))
+	case validation.PasswordStrengthTag:
+		return fmt.Sprintf("%s is too easy to guess", e.Field())
+	case validation.PasswordEmailTag:
+		return fmt.Sprintf("%s must not contain the email address", e.Field())
+	case validation.PasswordBreachedTag:
+		return fmt.Sprintf("%s has appeared in a data breach", e.Field())
 	default:
 		return fmt.Sprintf("%s is invalid", e.Field())
 	}
 }
+
+var classNames = map[string]string{
+	password.ClassLower:  "a lowercase letter",
+	password.ClassUpper:  "an uppercase letter",
+	password.ClassDigit:  "a digit",
+	password.ClassSymbol: "a symbol",
+}
+
+// Returns the classes as in "a lowercase letter, a digit and a symbol".
+func describeClasses(classes []string) string {
+	names := make([]string, len(classes))
+	for i, class := range classes {
+		names[i] = classNames[class]
+	}
+	if len(names) < 2 {
+		return strings.Join(names, "")
+	}
+	return strings.Join(names[:len(names)-1], ", ") + " and " + names[len(names)-1]
+}
@@ -0,0 +1,350 @@
+package db
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// Arbitrary key of the advisory lock held while migrating so that replicas
+// starting at the same time apply migrations one after the other.
+const migrationLockID int64 = 7_204_592_106
+
+// Same layout as the schema_migrations table of golang-migrate so that
+// databases migrated with the CLI keep working.
+const (
+	createVersionTableQuery = `
+CREATE TABLE IF NOT EXISTS schema_migrations (
+	version BIGINT NOT NULL PRIMARY KEY,
+	dirty BOOLEAN NOT NULL
+)
+`
+	selectVersionQuery = `SELECT version, dirty FROM schema_migrations LIMIT 1`
+	deleteVersionQuery = `DELETE FROM schema_migrations`
+	insertVersionQuery = `INSERT INTO schema_migrations (version, dirty) VALUES ($1, $2)`
+	lockQuery          = `SELECT pg_advisory_lock($1)`
+	unlockQuery        = `SELECT pg_advisory_unlock($1)`
+)
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+var ErrDirty = errors.New("database is dirty")
+var ErrNoChange = errors.New("no change")
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type MigrationStatus struct {
+	Migration
+	Applied bool
+}
+
+type Status struct {
+	Version    int64
+	Dirty      bool
+	Migrations []MigrationStatus
+}
+
+// Migrator applies the migrations found in an fs.FS. Each migration runs in a
+// transaction together with the version update, so statements that cannot run
+// inside a transaction block are not supported.
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+func NewMigrator(db *sql.DB, fsys fs.FS) (*Migrator, error) {
+	migrations, err := readMigrations(fsys)
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db: db, migrations: migrations}, nil
+}
+
+func readMigrations(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, fmt.Errorf("read migrations: %w", err)
+	}
+
+	byVersion := make(map[int64]*Migration)
+	for _, entry := range entries {
+		matches := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || matches == nil {
+			continue
+		}
+
+		version, err := strconv.ParseInt(matches[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("parse version of %s: %w", entry.Name(), err)
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &Migration{Version: version, Name: matches[2]}
+			byVersion[version] = m
+		} else if m.Name != matches[2] {
+			return nil, fmt.Errorf("duplicate migration version %d: %s and %s", version, m.Name, matches[2])
+		}
+
+		contents, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, fmt.Errorf("read migration %s: %w", entry.Name(), err)
+		}
+
+		if matches[3] == "up" {
+			m.Up = string(contents)
+		} else {
+			m.Down = string(contents)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, *m)
+	}
+	slices.SortFunc(migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+
+	return migrations, nil
+}
+
+// Up applies at most steps pending migrations, or all of them when steps is
+// not positive.
+func (m *Migrator) Up(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var pending []Migration
+		for _, mg := range m.migrations {
+			if mg.Version > version {
+				pending = append(pending, mg)
+			}
+		}
+
+		if len(pending) == 0 {
+			return ErrNoChange
+		}
+
+		if steps > 0 && steps < len(pending) {
+			pending = pending[:steps]
+		}
+
+		for _, mg := range pending {
+			slog.Info("Applying migration", "version", mg.Version, "name", mg.Name)
+			if err := apply(ctx, conn, mg.Up, mg.Version); err != nil {
+				return fmt.Errorf("migrate up to %d_%s: %w", mg.Version, mg.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Down reverts at most steps applied migrations, or all of them when steps is
+// not positive.
+func (m *Migrator) Down(ctx context.Context, steps int) error {
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		version, err := currentVersion(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		var applied []Migration
+		for _, mg := range m.migrations {
+			if mg.Version <= version {
+				applied = append(applied, mg)
+			}
+		}
+
+		if len(applied) == 0 {
+			return ErrNoChange
+		}
+
+		n := len(applied)
+		if steps > 0 && steps < n {
+			n = steps
+		}
+
+		for i := len(applied) - 1; i >= len(applied)-n; i-- {
+			mg := applied[i]
+
+			var prev int64
+			if i > 0 {
+				prev = applied[i-1].Version
+			}
+
+			slog.Info("Reverting migration", "version", mg.Version, "name", mg.Name)
+			if err := apply(ctx, conn, mg.Down, prev); err != nil {
+				return fmt.Errorf("migrate down from %d_%s: %w", mg.Version, mg.Name, err)
+			}
+		}
+
+		return nil
+	})
+}
+
+// Force sets the version without running any migration and clears the dirty
+// flag. A version of 0 marks the database as having no migrations applied.
+func (m *Migrator) Force(ctx context.Context, version int64) error {
+	if version != 0 && !slices.ContainsFunc(m.migrations, func(mg Migration) bool { return mg.Version == version }) {
+		return fmt.Errorf("unknown migration version %d", version)
+	}
+
+	return m.withLock(ctx, func(conn *sql.Conn) error {
+		return inTx(ctx, conn, func(tx *sql.Tx) error {
+			return setVersion(ctx, tx, version)
+		})
+	})
+}
+
+func (m *Migrator) Status(ctx context.Context) (*Status, error) {
+	var status Status
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		if err := conn.QueryRowContext(ctx, selectVersionQuery).Scan(&status.Version, &status.Dirty); err != nil &&
+			!errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("read schema version: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, mg := range m.migrations {
+		status.Migrations = append(status.Migrations, MigrationStatus{Migration: mg, Applied: mg.Version <= status.Version})
+	}
+
+	return &status, nil
+}
+
+// Runs fn on a single connection holding the migration lock.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return fmt.Errorf("acquire connection: %w", err)
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, lockQuery, migrationLockID); err != nil {
+		return fmt.Errorf("acquire migration lock: %w", err)
+	}
+	defer func() {
+		// Not tied to ctx so that the lock is released even after a cancellation.
+		if _, err := conn.ExecContext(context.WithoutCancel(ctx), unlockQuery, migrationLockID); err != nil {
+			slog.Error("failed to release migration lock", "reason", err)
+		}
+	}()
+
+	if _, err := conn.ExecContext(ctx, createVersionTableQuery); err != nil {
+		return fmt.Errorf("create schema_migrations table: %w", err)
+	}
+
+	return fn(conn)
+}
+
+func currentVersion(ctx context.Context, conn *sql.Conn) (int64, error) {
+	var version int64
+	var dirty bool
+	if err := conn.QueryRowContext(ctx, selectVersionQuery).Scan(&version, &dirty); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return 0, nil
+		}
+		return 0, fmt.Errorf("read schema version: %w", err)
+	}
+
+	if dirty {
+		return 0, fmt.Errorf("%w at version %d, fix it manually then force the version", ErrDirty, version)
+	}
+
+	return version, nil
+}
+
+func apply(ctx context.Context, conn *sql.Conn, query string, version int64) error {
+	return inTx(ctx, conn, func(tx *sql.Tx) error {
+		if strings.TrimSpace(query) != "" {
+			if _, err := tx.ExecContext(ctx, query); err != nil {
+				return err
+			}
+		}
+		return setVersion(ctx, tx, version)
+	})
+}
+
+func setVersion(ctx context.Context, tx *sql.Tx, version int64) error {
+	if _, err := tx.ExecContext(ctx, deleteVersionQuery); err != nil {
+		return fmt.Errorf("clear schema version: %w", err)
+	}
+
+	if version == 0 {
+		return nil
+	}
+
+	if _, err := tx.ExecContext(ctx, insertVersionQuery, version, false); err != nil {
+		return fmt.Errorf("set schema version: %w", err)
+	}
+
+	return nil
+}
+
+func inTx(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if err := fn(tx); err != nil {
+		return errors.Join(err, tx.Rollback())
+	}
+
+	return tx.Commit()
+}
+
+// NewMigration creates empty up and down files in dir for a migration named
+// name, numbered after the last migration found there.
+func NewMigration(dir, name string) ([]string, error) {
+	if !regexp.MustCompile(`^\w+$`).MatchString(name) {
+		return nil, fmt.Errorf("invalid migration name %q: use letters, digits and underscores", name)
+	}
+
+	migrations, err := readMigrations(os.DirFS(dir))
+	if err != nil {
+		return nil, err
+	}
+
+	var next int64 = 1
+	if len(migrations) > 0 {
+		next = migrations[len(migrations)-1].Version + 1
+	}
+
+	var files []string
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(dir, fmt.Sprintf("%06d_%s.%s.sql", next, name, direction))
+		f, err := os.OpenFile(path, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err != nil {
+			return nil, fmt.Errorf("create migration: %w", err)
+		}
+		f.Close()
+		files = append(files, path)
+	}
+
+	return files, nil
+}
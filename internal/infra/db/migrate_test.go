@@ -0,0 +1,152 @@
+package db
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+var testMigrations = fstest.MapFS{
+	"000001_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INT)")},
+	"000001_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+	"000002_create_posts.up.sql":   {Data: []byte("CREATE TABLE posts (id INT)")},
+	"000002_create_posts.down.sql": {Data: []byte("DROP TABLE posts")},
+	"README.md":                    {Data: []byte("ignored")},
+}
+
+func newTestMigrator(t *testing.T) (*Migrator, sqlmock.Sqlmock) {
+	t.Helper()
+	conn, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	m, err := NewMigrator(conn, testMigrations)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return m, mock
+}
+
+func expectLock(mock sqlmock.Sqlmock, version int64, dirty bool) {
+	mock.ExpectExec(lockQuery).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(createVersionTableQuery).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	rows := sqlmock.NewRows([]string{"version", "dirty"})
+	if version > 0 {
+		rows.AddRow(version, dirty)
+	}
+	mock.ExpectQuery(selectVersionQuery).WillReturnRows(rows)
+}
+
+func expectApply(mock sqlmock.Sqlmock, query string, version int64) {
+	mock.ExpectBegin()
+	mock.ExpectExec(query).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(deleteVersionQuery).WillReturnResult(sqlmock.NewResult(0, 1))
+	if version > 0 {
+		mock.ExpectExec(insertVersionQuery).WithArgs(version, false).WillReturnResult(sqlmock.NewResult(0, 1))
+	}
+	mock.ExpectCommit()
+}
+
+func expectUnlock(mock sqlmock.Sqlmock) {
+	mock.ExpectExec(unlockQuery).WithArgs(migrationLockID).WillReturnResult(sqlmock.NewResult(0, 0))
+}
+
+func TestReadMigrations(t *testing.T) {
+	migrations, err := readMigrations(testMigrations)
+	assert.NoError(t, err)
+	assert.Len(t, migrations, 2)
+	assert.Equal(t, int64(1), migrations[0].Version)
+	assert.Equal(t, "create_users", migrations[0].Name)
+	assert.Equal(t, "DROP TABLE users", migrations[0].Down)
+	assert.Equal(t, int64(2), migrations[1].Version)
+}
+
+func TestReadMigrationsDuplicateVersion(t *testing.T) {
+	_, err := readMigrations(fstest.MapFS{
+		"000001_a.up.sql": {Data: []byte("")},
+		"000001_b.up.sql": {Data: []byte("")},
+	})
+	assert.Error(t, err)
+}
+
+func TestMigrator_Up(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 0, false)
+	expectApply(mock, "CREATE TABLE users (id INT)", 1)
+	expectApply(mock, "CREATE TABLE posts (id INT)", 2)
+	expectUnlock(mock)
+
+	assert.NoError(t, m.Up(context.Background(), 0))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpSteps(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 0, false)
+	expectApply(mock, "CREATE TABLE users (id INT)", 1)
+	expectUnlock(mock)
+
+	assert.NoError(t, m.Up(context.Background(), 1))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpNoChange(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 2, false)
+	expectUnlock(mock)
+
+	assert.ErrorIs(t, m.Up(context.Background(), 0), ErrNoChange)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpDirty(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 1, true)
+	expectUnlock(mock)
+
+	assert.ErrorIs(t, m.Up(context.Background(), 0), ErrDirty)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_UpRollsBackFailedMigration(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 0, false)
+	mock.ExpectBegin()
+	mock.ExpectExec("CREATE TABLE users (id INT)").WillReturnError(errors.New("syntax error"))
+	mock.ExpectRollback()
+	expectUnlock(mock)
+
+	assert.Error(t, m.Up(context.Background(), 0))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_Down(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	expectLock(mock, 2, false)
+	expectApply(mock, "DROP TABLE posts", 1)
+	expectApply(mock, "DROP TABLE users", 0)
+	expectUnlock(mock)
+
+	assert.NoError(t, m.Down(context.Background(), 0))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMigrator_ForceUnknownVersion(t *testing.T) {
+	m, mock := newTestMigrator(t)
+
+	assert.Error(t, m.Force(context.Background(), 3))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
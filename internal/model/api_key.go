@@ -0,0 +1,24 @@
+package model
+
+import (
+	"slices"
+	"time"
+)
+
+type APIKey struct {
+	ID     string
+	UserID string
+	Name   string
+	Prefix string
+	// Permissions the key is limited to. A key without scopes has every
+	// permission of its user.
+	Scopes     []string
+	ExpiresAt  *time.Time
+	LastUsedAt *time.Time
+	CreatedAt  time.Time
+}
+
+// HasScope reports whether the key may be used for permission.
+func (k *APIKey) HasScope(permission string) bool {
+	return len(k.Scopes) == 0 || slices.Contains(k.Scopes, permission)
+}
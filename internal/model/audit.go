@@ -0,0 +1,20 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Actions recorded in the audit log
+const (
+	AuditLoginLocked = "login.locked"
+)
+
+type AuditEntry struct {
+	ID        string
+	UserID    string
+	Action    string
+	IPAddress string
+	Details   json.RawMessage
+	CreatedAt time.Time
+}
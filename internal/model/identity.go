@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Identity links the subject of an OpenID provider to a user.
+type Identity struct {
+	ID        string
+	UserID    string
+	Provider  string
+	Subject   string
+	Email     string
+	CreatedAt time.Time
+}
+
+// OIDCFlow is an authorization request waiting for the provider to redirect
+// the user back.
+type OIDCFlow struct {
+	ID           string
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+	CreatedAt    time.Time
+}
@@ -0,0 +1,25 @@
+package model
+
+import (
+	"encoding/json"
+	"time"
+)
+
+const (
+	JobPending = "pending"
+	JobRunning = "running"
+	JobDone    = "done"
+	JobDead    = "dead"
+)
+
+type Job struct {
+	ID        string
+	Kind      string
+	Payload   json.RawMessage
+	Status    string
+	Attempts  int
+	LastError string
+	RunAt     time.Time
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
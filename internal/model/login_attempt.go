@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+// Scopes that failed sign in attempts are counted in
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+type LoginAttempt struct {
+	Scope        string
+	Key          string
+	Failures     int
+	LastFailedAt time.Time
+	LockedUntil  *time.Time
+}
@@ -0,0 +1,20 @@
+package model
+
+// Roles and permissions seeded by the migrations.
+const (
+	RoleAdmin = "admin"
+
+	PermUsersRead   = "users:read"
+	PermUsersWrite  = "users:write"
+	PermRolesAssign = "roles:assign"
+	PermMetricsRead = "metrics:read"
+)
+
+// Permissions lists every permission seeded by the migrations.
+var Permissions = []string{PermUsersRead, PermUsersWrite, PermRolesAssign, PermMetricsRead}
+
+type Role struct {
+	Model
+	Name        string
+	Description string
+}
@@ -0,0 +1,12 @@
+package model
+
+import "time"
+
+type Session struct {
+	Model
+	UserID    string
+	TokenHash string
+	UserAgent string
+	IPAddress string
+	ExpiresAt time.Time
+}
@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+type TOTP struct {
+	UserID string
+	// Encrypted at rest by the repository
+	Secret    string
+	EnabledAt *time.Time
+	LastStep  int64
+	CreatedAt time.Time
+}
+
+type RecoveryCode struct {
+	ID        string
+	UserID    string
+	CodeHash  string
+	UsedAt    *time.Time
+	CreatedAt time.Time
+}
+
+type LoginChallenge struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	Attempts  int
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
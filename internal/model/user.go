@@ -1,7 +1,11 @@
 package model
 
+import "time"
+
 type User struct {
 	Model
 	Email        string
 	PasswordHash string
+	VerifiedAt   *time.Time
+	DisabledAt   *time.Time
 }
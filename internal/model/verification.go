@@ -0,0 +1,11 @@
+package model
+
+import "time"
+
+type Verification struct {
+	ID        string
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+	CreatedAt time.Time
+}
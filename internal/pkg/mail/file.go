@@ -0,0 +1,49 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+)
+
+// FileMailer writes each message as an .eml file into a directory so that
+// mail can be inspected locally without an outbound mail server.
+type FileMailer struct {
+	dir  string
+	from string
+}
+
+var _ Mailer = (*FileMailer)(nil)
+
+func NewFileMailer(dir, from string) *FileMailer {
+	return &FileMailer{dir: dir, from: from}
+}
+
+// Send implements Mailer.
+func (m *FileMailer) Send(_ context.Context, msg *Message) error {
+	body, err := msg.Bytes(m.from)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(m.dir, 0o750); err != nil {
+		return fmt.Errorf("create mail directory %s: %w", m.dir, err)
+	}
+
+	suffix, err := security.GenerateRandomBytes(4)
+	if err != nil {
+		return fmt.Errorf("generate file name: %w", err)
+	}
+
+	name := fmt.Sprintf("%s-%x.eml", time.Now().Format("20060102T150405.000000000"), suffix)
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, body, 0o600); err != nil {
+		return fmt.Errorf("write mail file %s: %w", path, err)
+	}
+
+	return nil
+}
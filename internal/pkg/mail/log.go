@@ -0,0 +1,23 @@
+package mail
+
+import (
+	"context"
+	"log/slog"
+)
+
+// LogMailer writes messages to the logger instead of delivering them.
+type LogMailer struct {
+	from string
+}
+
+var _ Mailer = (*LogMailer)(nil)
+
+func NewLogMailer(from string) *LogMailer {
+	return &LogMailer{from: from}
+}
+
+// Send implements Mailer.
+func (m *LogMailer) Send(_ context.Context, msg *Message) error {
+	slog.Info("mail", "from", m.from, "to", msg.To, "subject", msg.Subject, "body", msg.Text)
+	return nil
+}
@@ -0,0 +1,40 @@
+//go:generate mockgen -destination=mock/mailer_mock.go -package=mock . Mailer
+package mail
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+const (
+	DriverSMTP = "smtp"
+	DriverFile = "file"
+	DriverLog  = "log"
+)
+
+type Message struct {
+	To      []string `json:"to"`
+	Subject string   `json:"subject"`
+	Text    string   `json:"text,omitempty"`
+	HTML    string   `json:"html,omitempty"`
+}
+
+type Mailer interface {
+	Send(ctx context.Context, msg *Message) error
+}
+
+// New returns the Mailer for the driver selected in the config.
+func New(cfg config.MailConfig) (Mailer, error) {
+	switch cfg.Driver {
+	case DriverSMTP:
+		return NewSMTPMailer(cfg), nil
+	case DriverFile:
+		return NewFileMailer(cfg.Dir, cfg.From), nil
+	case DriverLog, "":
+		return NewLogMailer(cfg.From), nil
+	default:
+		return nil, fmt.Errorf("unsupported mail driver: %s", cfg.Driver)
+	}
+}
@@ -0,0 +1,80 @@
+package mail_test
+
+import (
+	"context"
+	"io"
+	netmail "net/mail"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+const testFrom = "GoWeb <no-reply@example.com>"
+
+func TestNew(t *testing.T) {
+	var tests = []struct {
+		driver string
+		want   any
+	}{
+		{mail.DriverSMTP, &mail.SMTPMailer{}},
+		{mail.DriverFile, &mail.FileMailer{}},
+		{mail.DriverLog, &mail.LogMailer{}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.driver, func(t *testing.T) {
+			mailer, err := mail.New(config.MailConfig{Driver: tt.driver, From: testFrom})
+			assert.NoError(t, err)
+			assert.IsType(t, tt.want, mailer)
+		})
+	}
+
+	_, err := mail.New(config.MailConfig{Driver: "pigeon"})
+	assert.Error(t, err, "Unknown drivers should be rejected")
+}
+
+func TestFileMailer_Send(t *testing.T) {
+	dir := t.TempDir()
+	mailer := mail.NewFileMailer(dir, testFrom)
+
+	msg := &mail.Message{
+		To:      []string{"abc@example.com"},
+		Subject: "Hello",
+		Text:    "Hello, world!",
+		HTML:    "<p>Hello, world!</p>",
+	}
+
+	err := mailer.Send(context.Background(), msg)
+	assert.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.eml"))
+	assert.NoError(t, err)
+	if !assert.Len(t, files, 1) {
+		return
+	}
+
+	f, err := os.Open(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	parsed, err := netmail.ReadMessage(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.Equal(t, testFrom, parsed.Header.Get("From"))
+	assert.Equal(t, "abc@example.com", parsed.Header.Get("To"))
+	assert.Equal(t, "Hello", parsed.Header.Get("Subject"))
+	assert.Contains(t, parsed.Header.Get("Content-Type"), "multipart/alternative")
+
+	body, err := io.ReadAll(parsed.Body)
+	assert.NoError(t, err)
+	assert.Contains(t, string(body), "Hello, world!")
+	assert.Contains(t, string(body), "<p>Hello, world!</p>")
+}
@@ -0,0 +1,79 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net/textproto"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+)
+
+// Bytes encodes the message in RFC 5322 format with a text and, when present,
+// an html alternative.
+func (m *Message) Bytes(from string) ([]byte, error) {
+	var buf bytes.Buffer
+
+	id, err := security.GenerateRandomBytes(16)
+	if err != nil {
+		return nil, fmt.Errorf("generate message id: %w", err)
+	}
+
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at != -1 {
+		domain = strings.Trim(from[at+1:], "> ")
+	}
+
+	writer := multipart.NewWriter(&buf)
+	headers := []string{
+		"From: " + from,
+		"To: " + strings.Join(m.To, ", "),
+		"Subject: " + mime.QEncoding.Encode("utf-8", m.Subject),
+		"Date: " + time.Now().Format(time.RFC1123Z),
+		fmt.Sprintf("Message-ID: <%x@%s>", id, domain),
+		"MIME-Version: 1.0",
+		"Content-Type: multipart/alternative; boundary=" + writer.Boundary(),
+	}
+
+	var out bytes.Buffer
+	out.WriteString(strings.Join(headers, "\r\n"))
+	out.WriteString("\r\n\r\n")
+
+	if err := writePart(writer, "text/plain", m.Text); err != nil {
+		return nil, err
+	}
+
+	if m.HTML != "" {
+		if err := writePart(writer, "text/html", m.HTML); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := writer.Close(); err != nil {
+		return nil, fmt.Errorf("close multipart writer: %w", err)
+	}
+
+	out.Write(buf.Bytes())
+	return out.Bytes(), nil
+}
+
+func writePart(writer *multipart.Writer, contentType, body string) error {
+	part, err := writer.CreatePart(textproto.MIMEHeader{
+		"Content-Type":              {contentType + "; charset=utf-8"},
+		"Content-Transfer-Encoding": {"quoted-printable"},
+	})
+	if err != nil {
+		return fmt.Errorf("create %s part: %w", contentType, err)
+	}
+
+	qp := quotedprintable.NewWriter(part)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return fmt.Errorf("write %s part: %w", contentType, err)
+	}
+
+	return qp.Close()
+}
@@ -0,0 +1,58 @@
+package mail
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"strconv"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+// SMTPMailer delivers messages to an SMTP server, upgrading the connection
+// with STARTTLS when the server supports it.
+type SMTPMailer struct {
+	addr string
+	host string
+	user string
+	pass string
+	from string
+}
+
+var _ Mailer = (*SMTPMailer)(nil)
+
+func NewSMTPMailer(cfg config.MailConfig) *SMTPMailer {
+	return &SMTPMailer{
+		addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port)),
+		host: cfg.Host,
+		user: cfg.User,
+		pass: cfg.Pass,
+		from: cfg.From,
+	}
+}
+
+// Send implements Mailer.
+func (m *SMTPMailer) Send(_ context.Context, msg *Message) error {
+	from, err := mail.ParseAddress(m.from)
+	if err != nil {
+		return fmt.Errorf("parse sender address %q: %w", m.from, err)
+	}
+
+	body, err := msg.Bytes(m.from)
+	if err != nil {
+		return err
+	}
+
+	var auth smtp.Auth
+	if m.user != "" {
+		auth = smtp.PlainAuth("", m.user, m.pass, m.host)
+	}
+
+	if err := smtp.SendMail(m.addr, auth, from.Address, msg.To, body); err != nil {
+		return fmt.Errorf("smtp send to %v: %w", msg.To, err)
+	}
+
+	return nil
+}
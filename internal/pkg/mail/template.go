@@ -0,0 +1,138 @@
+package mail
+
+import (
+	"bytes"
+	"fmt"
+	htmltemplate "html/template"
+	"io/fs"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	texttemplate "text/template"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+const (
+	htmlSuffix = ".html"
+	textSuffix = ".txt"
+	layoutName = "layout"
+)
+
+// Template renders email bodies from a layout, partials and pages laid out the
+// same way as the web templates. Every template exists in an html and a text
+// variant distinguished by the file suffix, so the layout file is configured
+// without a suffix.
+type Template struct {
+	html map[string]*htmltemplate.Template
+	text map[string]*texttemplate.Template
+}
+
+func NewTemplate(cfg config.TemplateConfig) (*Template, error) {
+	layoutFile := filepath.Join(cfg.Path, cfg.LayoutFile)
+
+	htmlLayout, err := htmltemplate.New(layoutName).ParseFiles(layoutFile + htmlSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("parse html layout: %w", err)
+	}
+
+	// Unlike html/template, text/template loses the body of a root template
+	// redefined by the file it parses when cloned, so the root is the file.
+	textLayout, err := texttemplate.ParseFiles(layoutFile + textSuffix)
+	if err != nil {
+		return nil, fmt.Errorf("parse text layout: %w", err)
+	}
+
+	t := &Template{
+		html: make(map[string]*htmltemplate.Template),
+		text: make(map[string]*texttemplate.Template),
+	}
+
+	// Parse all partial templates into the layout templates
+	err = walkTemplates(cfg.Path, cfg.PartialsPath, func(path, _ string) error {
+		if strings.HasSuffix(path, htmlSuffix) {
+			_, err := htmlLayout.ParseFiles(path)
+			return err
+		}
+		_, err := textLayout.ParseFiles(path)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load mail partials: %w", err)
+	}
+
+	// Parse the pages on top of a copy of the layout templates
+	err = walkTemplates(cfg.Path, cfg.PagesPath, func(path, name string) error {
+		if strings.HasSuffix(path, htmlSuffix) {
+			layout, err := htmlLayout.Clone()
+			if err != nil {
+				return err
+			}
+			t.html[name], err = layout.ParseFiles(path)
+			return err
+		}
+		layout, err := textLayout.Clone()
+		if err != nil {
+			return err
+		}
+		t.text[name], err = layout.ParseFiles(path)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("load mail pages: %w", err)
+	}
+
+	return t, nil
+}
+
+// Compose renders the named template into a message addressed to the recipients.
+func (t *Template) Compose(to []string, subject, name string, data any) (*Message, error) {
+	textTmpl, ok := t.text[name]
+	if !ok {
+		return nil, fmt.Errorf("mail template does not exist: %s", name)
+	}
+
+	var text bytes.Buffer
+	if err := textTmpl.ExecuteTemplate(&text, layoutName, data); err != nil {
+		return nil, fmt.Errorf("execute text template %s: %w", name, err)
+	}
+
+	msg := &Message{
+		To:      to,
+		Subject: subject,
+		Text:    text.String(),
+	}
+
+	if htmlTmpl, ok := t.html[name]; ok {
+		var html bytes.Buffer
+		if err := htmlTmpl.ExecuteTemplate(&html, layoutName, data); err != nil {
+			return nil, fmt.Errorf("execute html template %s: %w", name, err)
+		}
+		msg.HTML = html.String()
+	}
+
+	return msg, nil
+}
+
+// Walks dir under root calling fn with the path and name of each html or text template.
+func walkTemplates(root, dir string, fn func(path, name string) error) error {
+	return fs.WalkDir(os.DirFS(root), dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			return nil
+		}
+		ext := filepath.Ext(path)
+		if ext != htmlSuffix && ext != textSuffix {
+			return nil
+		}
+		name := strings.TrimSuffix(strings.TrimPrefix(path, dir+"/"), ext)
+		if err := fn(filepath.Join(root, path), name); err != nil {
+			return fmt.Errorf("parse %s: %w", path, err)
+		}
+		slog.Debug("parsed mail template", "path", path, "name", name)
+		return nil
+	})
+}
@@ -0,0 +1,36 @@
+package mail_test
+
+import (
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/mail"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTemplate_Compose(t *testing.T) {
+	tmpl, err := mail.NewTemplate(config.TemplateConfig{
+		Path:         "../../../web/templates/mail",
+		LayoutFile:   "layout",
+		PartialsPath: "partials",
+		PagesPath:    "pages",
+	})
+	if err != nil {
+		t.Fatalf("cant parse mail template: %v", err)
+	}
+
+	const link = "http://localhost/auth/verify?token=abc"
+	data := struct{ Link string }{Link: link}
+
+	msg, err := tmpl.Compose([]string{"abc@example.com"}, "Verify", "verify_email", data)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"abc@example.com"}, msg.To)
+	assert.Equal(t, "Verify", msg.Subject)
+	assert.Contains(t, msg.Text, link)
+	assert.Contains(t, msg.Text, "The GoWeb Team", "Text body should include the footer partial")
+	assert.Contains(t, msg.HTML, `href="http://localhost/auth/verify?token=abc"`)
+	assert.Contains(t, msg.HTML, "The GoWeb Team", "HTML body should include the footer partial")
+
+	_, err = tmpl.Compose([]string{"abc@example.com"}, "Missing", "missing", data)
+	assert.Error(t, err)
+}
@@ -1,8 +1,26 @@
 package message
 
 var messages = map[string]string{
-	"regSuccess": "Thank you for registering. Please check your email for the verification link.",
-	"jsonfailed": "failed to decode json",
+	"regSuccess":        "Thank you for registering. Please check your email for the verification link.",
+	"jsonfailed":        "failed to decode json",
+	"loginSuccess":      "You are now logged in.",
+	"logoutSuccess":     "You have been logged out.",
+	"verifySubject":     "Verify your email address",
+	"verifySuccess":     "Your email address has been verified. You may now log in.",
+	"verifyFailed":      "The verification link is invalid or has expired.",
+	"resetSubject":      "Reset your password",
+	"resetSent":         "If an account exists for that email, a password reset link has been sent to it.",
+	"resetSuccess":      "Your password has been reset. You may now log in.",
+	"unauthenticated":   "You must be logged in to access this resource.",
+	"forbidden":         "You do not have permission to access this resource.",
+	"apiKeyCreated":     "API key created. Copy it now, it will not be shown again.",
+	"apiKeyRevoked":     "API key revoked.",
+	"twoFactorRequired": "Enter the code from your authenticator app to continue.",
+	"twoFactorEnabled":  "Two-factor authentication enabled. Store your recovery codes somewhere safe, they will not be shown again.",
+	"twoFactorDisabled": "Two-factor authentication disabled.",
+	"oidcFailed":        "Signing in with the identity provider failed. Please try again.",
+	"rateLimited":       "Too many requests. Please try again later.",
+	"csrfFailed":        "The request could not be verified. Please reload the page and try again.",
 }
 
 func Get(key string) string {
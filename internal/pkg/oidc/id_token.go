@@ -0,0 +1,213 @@
+package oidc
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"slices"
+	"strings"
+	"time"
+)
+
+var ErrInvalidIDToken = errors.New("oidc: invalid id token")
+
+// Allowed difference between the clock of the provider and ours
+const clockSkew = time.Minute
+
+// Signing algorithms accepted for ID tokens. Symmetric algorithms are not
+// supported since the client secret is optional.
+const (
+	AlgRS256 = "RS256"
+	AlgES256 = "ES256"
+	AlgEdDSA = "EdDSA"
+)
+
+// Claims of an ID token that are used to identify the user.
+type Claims struct {
+	Issuer          string   `json:"iss"`
+	Subject         string   `json:"sub"`
+	Audience        audience `json:"aud"`
+	AuthorizedParty string   `json:"azp"`
+	Expiry          int64    `json:"exp"`
+	IssuedAt        int64    `json:"iat"`
+	Nonce           string   `json:"nonce"`
+	Email           string   `json:"email"`
+	EmailVerified   boolean  `json:"email_verified"`
+	Name            string   `json:"name"`
+}
+
+// audience is either a single string or an array of strings.
+type audience []string
+
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var many []string
+	if err := json.Unmarshal(data, &many); err != nil {
+		return err
+	}
+	*a = many
+	return nil
+}
+
+// boolean also accepts the "true" and "false" strings that some providers
+// send for email_verified.
+type boolean bool
+
+func (b *boolean) UnmarshalJSON(data []byte) error {
+	switch string(data) {
+	case "true", `"true"`:
+		*b = true
+	case "false", `"false"`, "null":
+		*b = false
+	default:
+		return fmt.Errorf("invalid boolean %s", data)
+	}
+	return nil
+}
+
+type idTokenHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// VerifyIDToken checks the signature of raw against the keys of the provider
+// and validates its claims. nonce must be the one sent with the authorization
+// request.
+func (p *Provider) VerifyIDToken(ctx context.Context, raw, nonce string, now time.Time) (*Claims, error) {
+	m, err := p.Metadata(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return nil, fmt.Errorf("%w: malformed token", ErrInvalidIDToken)
+	}
+
+	var header idTokenHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return nil, fmt.Errorf("%w: header: %w", ErrInvalidIDToken, err)
+	}
+
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, fmt.Errorf("%w: signature: %w", ErrInvalidIDToken, err)
+	}
+
+	p.mu.Lock()
+	keys := p.keys
+	p.mu.Unlock()
+
+	key, err := keys.key(ctx, header.Kid)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	if err := verifySignature(header.Alg, key, []byte(parts[0]+"."+parts[1]), sig); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	var claims Claims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return nil, fmt.Errorf("%w: claims: %w", ErrInvalidIDToken, err)
+	}
+
+	if err := p.validateClaims(&claims, m.Issuer, nonce, now); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidIDToken, err)
+	}
+
+	return &claims, nil
+}
+
+// Validates the claims as required by OpenID Connect Core section 3.1.3.7.
+func (p *Provider) validateClaims(c *Claims, issuer, nonce string, now time.Time) error {
+	if c.Issuer != issuer {
+		return fmt.Errorf("issuer %q does not match", c.Issuer)
+	}
+
+	if !slices.Contains(c.Audience, p.cfg.ClientID) {
+		return errors.New("token was not issued for this client")
+	}
+
+	if len(c.Audience) > 1 && c.AuthorizedParty != p.cfg.ClientID {
+		return errors.New("token was issued for another party")
+	}
+
+	if c.Subject == "" {
+		return errors.New("missing subject")
+	}
+
+	if c.Expiry == 0 || !now.Before(time.Unix(c.Expiry, 0).Add(clockSkew)) {
+		return errors.New("token has expired")
+	}
+
+	if c.IssuedAt != 0 && time.Unix(c.IssuedAt, 0).After(now.Add(clockSkew)) {
+		return errors.New("token was issued in the future")
+	}
+
+	if subtle.ConstantTimeCompare([]byte(c.Nonce), []byte(nonce)) != 1 {
+		return errors.New("nonce does not match")
+	}
+
+	return nil
+}
+
+func verifySignature(alg string, key crypto.PublicKey, input, sig []byte) error {
+	switch alg {
+	case AlgRS256:
+		pub, ok := key.(*rsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match algorithm")
+		}
+		sum := sha256.Sum256(input)
+		if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, sum[:], sig); err != nil {
+			return errors.New("signature mismatch")
+		}
+	case AlgES256:
+		pub, ok := key.(*ecdsa.PublicKey)
+		if !ok {
+			return errors.New("key does not match algorithm")
+		}
+		if len(sig) != 64 {
+			return errors.New("signature mismatch")
+		}
+		sum := sha256.Sum256(input)
+		r := new(big.Int).SetBytes(sig[:32])
+		s := new(big.Int).SetBytes(sig[32:])
+		if !ecdsa.Verify(pub, sum[:], r, s) {
+			return errors.New("signature mismatch")
+		}
+	case AlgEdDSA:
+		pub, ok := key.(ed25519.PublicKey)
+		if !ok {
+			return errors.New("key does not match algorithm")
+		}
+		if !ed25519.Verify(pub, input, sig) {
+			return errors.New("signature mismatch")
+		}
+	default:
+		return fmt.Errorf("unsupported algorithm %q", alg)
+	}
+	return nil
+}
+
+func decodeSegment(s string, v any) error {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, v)
+}
@@ -0,0 +1,152 @@
+package oidc_test
+
+import (
+	"context"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/oidc"
+	"github.com/ferdiebergado/goweb/internal/pkg/oidc/oidctest"
+	"github.com/stretchr/testify/assert"
+)
+
+const redirectURL = "http://localhost/auth/oidc/test/callback"
+
+func newProvider(t *testing.T) (*oidc.Provider, *oidctest.Provider) {
+	t.Helper()
+	fake := oidctest.NewProvider(t)
+	p := oidc.NewProvider(oidc.Config{
+		Issuer:       fake.Issuer(),
+		ClientID:     oidctest.ClientID,
+		ClientSecret: oidctest.ClientSecret,
+		RedirectURL:  redirectURL,
+		Scopes:       []string{"email"},
+	}, fake.Server.Client())
+	return p, fake
+}
+
+func TestProvider_Flow(t *testing.T) {
+	p, fake := newProvider(t)
+	ctx := context.Background()
+
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	u, err := url.Parse(authURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "openid email", u.Query().Get("scope"))
+	assert.Equal(t, oidc.S256Challenge(verifier), u.Query().Get("code_challenge"))
+
+	code, state := fake.Authorize(t, authURL)
+	assert.Equal(t, "state", state)
+
+	token, err := p.Exchange(ctx, code, verifier)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, "nonce", time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, fake.User.Subject, claims.Subject)
+	assert.Equal(t, fake.User.Email, claims.Email)
+	assert.True(t, bool(claims.EmailVerified))
+}
+
+func TestProvider_ExchangeWrongVerifier(t *testing.T) {
+	p, fake := newProvider(t)
+	ctx := context.Background()
+
+	authURL, err := p.AuthCodeURL(ctx, "state", "nonce", "verifier")
+	if err != nil {
+		t.Fatal(err)
+	}
+	code, _ := fake.Authorize(t, authURL)
+
+	_, err = p.Exchange(ctx, code, "another verifier")
+	assert.ErrorIs(t, err, oidc.ErrExchange)
+}
+
+func TestProvider_DiscoveryIssuerMismatch(t *testing.T) {
+	fake := oidctest.NewProvider(t)
+	p := oidc.NewProvider(oidc.Config{Issuer: fake.Issuer() + "/", ClientID: oidctest.ClientID},
+		fake.Server.Client())
+
+	_, err := p.Metadata(context.Background())
+	assert.ErrorIs(t, err, oidc.ErrDiscovery)
+}
+
+func TestProvider_VerifyIDTokenInvalid(t *testing.T) {
+	p, fake := newProvider(t)
+	valid := fake.Claims(fake.User, "nonce")
+
+	tests := []struct {
+		name   string
+		modify func(claims map[string]any)
+		nonce  string
+	}{
+		{"wrong nonce", func(map[string]any) {}, "other"},
+		{"wrong audience", func(c map[string]any) { c["aud"] = "other-client" }, "nonce"},
+		{"multiple audiences without azp", func(c map[string]any) {
+			c["aud"] = []string{oidctest.ClientID, "other-client"}
+		}, "nonce"},
+		{"wrong issuer", func(c map[string]any) { c["iss"] = "https://evil.example.com" }, "nonce"},
+		{"expired", func(c map[string]any) { c["exp"] = time.Now().Add(-time.Hour).Unix() }, "nonce"},
+		{"missing subject", func(c map[string]any) { delete(c, "sub") }, "nonce"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			claims := make(map[string]any, len(valid))
+			for k, v := range valid {
+				claims[k] = v
+			}
+			tt.modify(claims)
+
+			_, err := p.VerifyIDToken(context.Background(), fake.SignIDToken(t, claims), tt.nonce, time.Now())
+			assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+		})
+	}
+}
+
+func TestProvider_VerifyIDTokenTampered(t *testing.T) {
+	p, fake := newProvider(t)
+	token := fake.SignIDToken(t, fake.Claims(fake.User, "nonce"))
+	other := fake.SignIDToken(t, fake.Claims(oidctest.User{Subject: "attacker"}, "nonce"))
+
+	// Payload of one token with the signature of another
+	header, _, _ := cut(token)
+	_, payload, _ := cut(other)
+	_, _, sig := cut(token)
+
+	_, err := p.VerifyIDToken(context.Background(), header+"."+payload+"."+sig, "nonce", time.Now())
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func TestProvider_VerifyIDTokenAlgNone(t *testing.T) {
+	p, fake := newProvider(t)
+	token := fake.SignIDToken(t, fake.Claims(fake.User, "nonce"))
+	_, payload, _ := cut(token)
+
+	// {"alg":"none","kid":"test-key"}
+	const header = "eyJhbGciOiJub25lIiwia2lkIjoidGVzdC1rZXkifQ"
+	_, err := p.VerifyIDToken(context.Background(), header+"."+payload+".", "nonce", time.Now())
+	assert.ErrorIs(t, err, oidc.ErrInvalidIDToken)
+}
+
+func cut(token string) (header, payload, sig string) {
+	var rest string
+	header, rest, _ = strings.Cut(token, ".")
+	payload, sig, _ = strings.Cut(rest, ".")
+	return header, payload, sig
+}
@@ -0,0 +1,69 @@
+package password
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// BreachList looks passwords up in a local copy of the Pwned Passwords range
+// files: a directory with a file per SHA-1 prefix of five hex digits, named
+// like 5BAA6.txt, with a SUFFIX:COUNT line per breached hash that starts with
+// the prefix. Only the file of the prefix is read, as with the range API.
+type BreachList struct {
+	dir string
+}
+
+func NewBreachList(dir string) (*BreachList, error) {
+	info, err := os.Stat(dir)
+	if err != nil {
+		return nil, fmt.Errorf("open breached passwords: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("breached passwords %s is not a directory", dir)
+	}
+	return &BreachList{dir: dir}, nil
+}
+
+// Contains reports whether password is in the list. Hashes with a count of
+// zero are padding and do not count.
+func (b *BreachList) Contains(password string) (bool, error) {
+	sum := sha1.Sum([]byte(password)) // #nosec G401 -- the list is of SHA-1 hashes
+	hash := strings.ToUpper(hex.EncodeToString(sum[:]))
+	prefix, suffix := hash[:5], hash[5:]
+
+	f, err := os.Open(filepath.Join(b.dir, prefix+".txt"))
+	if err != nil {
+		// No file, no breached hash with the prefix.
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, fmt.Errorf("open breached passwords %s: %w", prefix, err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		s, count, _ := strings.Cut(scanner.Text(), ":")
+		if !strings.EqualFold(strings.TrimSpace(s), suffix) {
+			continue
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(count))
+		if err != nil {
+			return false, fmt.Errorf("parse breached passwords %s: invalid count %q", prefix, count)
+		}
+		return n > 0, nil
+	}
+	if err := scanner.Err(); err != nil {
+		return false, fmt.Errorf("read breached passwords %s: %w", prefix, err)
+	}
+
+	return false, nil
+}
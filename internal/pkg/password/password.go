@@ -0,0 +1,88 @@
+// Package password implements the checks of the password policy: character
+// classes, a zxcvbn-style strength estimate, whether a password contains the
+// email of its owner and whether it is in a local list of breached passwords.
+package password
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// Character classes that a policy can require.
+const (
+	ClassLower  = "lower"
+	ClassUpper  = "upper"
+	ClassDigit  = "digit"
+	ClassSymbol = "symbol"
+)
+
+// Policy is what a password has to satisfy. Zero values disable a check, a
+// password must never contain the email of its owner.
+type Policy struct {
+	MinLength int
+	MaxLength int
+	// Classes of characters that a password needs at least one of
+	Classes []string
+	// Minimum score of Strength, from 0 to 4
+	MinStrength int
+	// Breached passwords are rejected when set.
+	Breached *BreachList
+}
+
+// HasClass reports whether s contains a character of class.
+func HasClass(s, class string) (bool, error) {
+	var is func(rune) bool
+	switch class {
+	case ClassLower:
+		is = unicode.IsLower
+	case ClassUpper:
+		is = unicode.IsUpper
+	case ClassDigit:
+		is = unicode.IsDigit
+	case ClassSymbol:
+		is = func(r rune) bool {
+			return !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.IsSpace(r)
+		}
+	default:
+		return false, fmt.Errorf("unknown character class %q", class)
+	}
+	return strings.ContainsFunc(s, is), nil
+}
+
+// ContainsEmail reports whether password contains email or its local part,
+// ignoring case. Local parts shorter than three characters are too likely to
+// appear by chance to count.
+func ContainsEmail(password, email string) bool {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return false
+	}
+
+	password = strings.ToLower(password)
+	if strings.Contains(password, email) {
+		return true
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	return len(local) >= 3 && strings.Contains(password, local)
+}
+
+// EmailInputs returns the parts of email that Strength should consider
+// guessable: the email, its local part and the words of the local part.
+func EmailInputs(email string) []string {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if email == "" {
+		return nil
+	}
+
+	local, _, _ := strings.Cut(email, "@")
+	inputs := []string{email, local}
+	words := strings.FieldsFunc(local, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	if len(words) > 1 {
+		inputs = append(inputs, words...)
+	}
+	return inputs
+}
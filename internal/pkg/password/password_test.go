@@ -0,0 +1,127 @@
+package password_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/password"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHasClass(t *testing.T) {
+	tests := []struct {
+		class string
+		has   string
+		lacks string
+	}{
+		{password.ClassLower, "ABCd", "ABC1"},
+		{password.ClassUpper, "abcD", "abc1"},
+		{password.ClassDigit, "abc1", "abc!"},
+		{password.ClassSymbol, "abc!", "abc1 "},
+	}
+	for _, tt := range tests {
+		t.Run(tt.class, func(t *testing.T) {
+			ok, err := password.HasClass(tt.has, tt.class)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = password.HasClass(tt.lacks, tt.class)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+
+	_, err := password.HasClass("abc", "emoji")
+	assert.Error(t, err)
+}
+
+func TestContainsEmail(t *testing.T) {
+	const email = "Jane.Doe@example.com"
+
+	assert.True(t, password.ContainsEmail("xx"+email+"xx", email))
+	assert.True(t, password.ContainsEmail("JANE.DOE-2024", email))
+	assert.False(t, password.ContainsEmail("jane-doe-2024", email))
+	assert.False(t, password.ContainsEmail("anything", ""))
+	// Too short a local part to count
+	assert.False(t, password.ContainsEmail("joyful", "jo@example.com"))
+}
+
+func TestStrength(t *testing.T) {
+	tests := []struct {
+		password string
+		maxScore int
+		minScore int
+	}{
+		{"password", 0, 0},
+		{"P@ssw0rd", 0, 0},
+		{"drowssap", 0, 0},
+		{"qwerty123", 0, 0},
+		{"asdfghjkl", 1, 0},
+		{"abcdefgh", 0, 0},
+		{"aaaaaaaaaaaa", 0, 0},
+		{"abcabcabcabc", 0, 0},
+		{"Summer2024", 1, 0},
+		{"x7#Kq9!vLp2@", 4, 4},
+		{"correcthorsebatterystaple", 4, 4},
+	}
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			score := password.Strength(tt.password).Score
+			assert.LessOrEqual(t, score, tt.maxScore)
+			assert.GreaterOrEqual(t, score, tt.minScore)
+		})
+	}
+}
+
+func TestStrengthUserInputs(t *testing.T) {
+	const pass = "zqmwvhkbrtx"
+
+	without := password.Strength(pass)
+	with := password.Strength(pass, password.EmailInputs("zqmwvhkbrtx@example.com")...)
+	assert.Equal(t, 4, without.Score)
+	assert.Equal(t, 0, with.Score)
+	assert.Less(t, with.Guesses, without.Guesses)
+}
+
+func TestBreachList(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1 of "password" is 5BAA61E4C9B93F3F0682250B6CF8331B7EE68FD8, of
+	// "letmein" B7A875FC1EA228B9061041B7CEC4BD3C52AB3CE3.
+	rangeFile := "003D68EB55068C33ACE09247EE4C639306B:3\n" +
+		"1E4C9B93F3F0682250B6CF8331B7EE68FD8:9545824\n"
+	if err := os.WriteFile(filepath.Join(dir, "5BAA6.txt"), []byte(rangeFile), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	padding := "5FC1EA228B9061041B7CEC4BD3C52AB3CE3:0\n"
+	if err := os.WriteFile(filepath.Join(dir, "B7A87.txt"), []byte(padding), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := password.NewBreachList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		password string
+		breached bool
+	}{
+		{"Breached", "password", true},
+		{"Padding", "letmein", false},
+		{"No range file", "x7#Kq9!vLp2@", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			breached, err := list.Contains(tt.password)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.breached, breached)
+		})
+	}
+}
+
+func TestNewBreachListMissingDir(t *testing.T) {
+	_, err := password.NewBreachList(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
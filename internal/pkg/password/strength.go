@@ -0,0 +1,540 @@
+package password
+
+import (
+	_ "embed"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Common passwords and words, the most common first.
+//
+//go:embed common.txt
+var commonList string
+
+type dictionary struct {
+	ranks   map[string]int
+	longest int
+}
+
+var common = sync.OnceValue(func() *dictionary {
+	words := strings.Fields(commonList)
+	d := &dictionary{ranks: make(map[string]int, len(words))}
+	for i, w := range words {
+		if _, ok := d.ranks[w]; !ok {
+			d.ranks[w] = i + 1
+			d.longest = max(d.longest, utf8.RuneCountInString(w))
+		}
+	}
+	return d
+})
+
+// The constants of zxcvbn.
+const (
+	bruteforceCardinality           = 10
+	minSubmatchGuessesSingleChar    = 10
+	minSubmatchGuessesMultiChar     = 50
+	minGuessesBeforeGrowingSequence = 10000
+	minYearSpace                    = 20
+	maxSequenceDelta                = 5
+	keyboardStartingPositions       = 94
+	keyboardAverageDegree           = 4.6
+)
+
+// Guesses below which a password gets the score of the index.
+var scoreThresholds = [...]float64{1e3, 1e6, 1e8, 1e10}
+
+// Rows of a QWERTY keyboard without shift.
+var keyboardRows = []string{"`1234567890-=", "qwertyuiop[]\\", "asdfghjkl;'", "zxcvbnm,./"}
+
+// Letters that l33t speak substitutes.
+var l33tTable = map[rune][]rune{
+	'4': {'a'}, '@': {'a'},
+	'8': {'b'},
+	'(': {'c'}, '{': {'c'}, '[': {'c'}, '<': {'c'},
+	'3': {'e'},
+	'6': {'g'}, '9': {'g'},
+	'1': {'i', 'l'}, '!': {'i'}, '|': {'i', 'l'},
+	'0': {'o'},
+	'$': {'s'}, '5': {'s'},
+	'+': {'t'}, '7': {'t', 'l'},
+	'%': {'x'},
+	'2': {'z'},
+}
+
+// Estimate is the result of Strength.
+type Estimate struct {
+	// Guesses an attacker needs to find the password
+	Guesses float64
+	// From 0, too guessable, to 4, very unguessable
+	Score int
+}
+
+// A part of the password from rune i to rune j inclusive.
+type match struct {
+	i, j    int
+	guesses float64
+}
+
+// Strength estimates the guesses needed to find password the way zxcvbn does:
+// the password is split into the least guessable sequence of common words,
+// keyboard patterns, repeats, sequences, years and brute forced runs.
+// userInputs are words that an attacker likely knows, such as the email.
+//
+// The dictionary is much smaller than the one of zxcvbn, uncommon words are
+// assumed to be brute forced.
+func Strength(password string, userInputs ...string) Estimate {
+	runes := []rune(password)
+	if len(runes) == 0 {
+		return Estimate{Guesses: 1}
+	}
+
+	inputs := make(map[string]int, len(userInputs))
+	for i, in := range userInputs {
+		in = strings.ToLower(in)
+		if _, ok := inputs[in]; !ok && in != "" {
+			inputs[in] = i + 1
+		}
+	}
+
+	guesses := mostGuessable(runes, findMatches(runes, inputs))
+	return Estimate{Guesses: guesses, Score: score(guesses)}
+}
+
+func score(guesses float64) int {
+	// zxcvbn gives a little room above each threshold.
+	for i, threshold := range scoreThresholds {
+		if guesses < threshold+5 {
+			return i
+		}
+	}
+	return len(scoreThresholds)
+}
+
+func findMatches(runes []rune, inputs map[string]int) []match {
+	var matches []match
+	matches = append(matches, dictionaryMatches(runes, inputs)...)
+	matches = append(matches, reversedMatches(runes, inputs)...)
+	matches = append(matches, l33tMatches(runes, inputs)...)
+	matches = append(matches, spatialMatches(runes)...)
+	matches = append(matches, repeatMatches(runes, inputs)...)
+	matches = append(matches, sequenceMatches(runes)...)
+	matches = append(matches, yearMatches(runes)...)
+	return matches
+}
+
+// Returns the guesses of the least guessable sequence of matches and brute
+// forced runs that covers runes, like most_guessable_match_sequence of zxcvbn.
+// Longer sequences pay for the order of their parts and for each extra part.
+func mostGuessable(runes []rune, matches []match) float64 {
+	n := len(runes)
+
+	type step struct {
+		pi, g      float64
+		bruteforce bool
+	}
+	// The best sequence of each length ending at each position
+	optimal := make([]map[int]step, n)
+
+	update := func(m match, l int, bruteforce bool) {
+		pi := m.guesses
+		if l > 1 {
+			pi *= optimal[m.i-1][l-1].pi
+		}
+		g := factorial(l)*pi + math.Pow(minGuessesBeforeGrowingSequence, float64(l-1))
+		for other, s := range optimal[m.j] {
+			if other <= l && s.g <= g {
+				return
+			}
+		}
+		optimal[m.j][l] = step{pi: pi, g: g, bruteforce: bruteforce}
+	}
+
+	byEnd := make([][]match, n)
+	for _, m := range matches {
+		byEnd[m.j] = append(byEnd[m.j], m)
+	}
+
+	for k := range n {
+		optimal[k] = make(map[int]step)
+
+		for _, m := range byEnd[k] {
+			if length := m.j - m.i + 1; length < n {
+				if length == 1 {
+					m.guesses = max(m.guesses, minSubmatchGuessesSingleChar)
+				} else {
+					m.guesses = max(m.guesses, minSubmatchGuessesMultiChar)
+				}
+			}
+			if m.i == 0 {
+				update(m, 1, false)
+				continue
+			}
+			for l := range optimal[m.i-1] {
+				update(m, l+1, false)
+			}
+		}
+
+		// Two brute forced runs in a row are one longer run.
+		update(match{i: 0, j: k, guesses: bruteforceGuesses(k + 1)}, 1, true)
+		for i := 1; i <= k; i++ {
+			m := match{i: i, j: k, guesses: bruteforceGuesses(k - i + 1)}
+			for l, s := range optimal[i-1] {
+				if !s.bruteforce {
+					update(m, l+1, true)
+				}
+			}
+		}
+	}
+
+	best := math.Inf(1)
+	for _, s := range optimal[n-1] {
+		best = min(best, s.g)
+	}
+	return best
+}
+
+func bruteforceGuesses(length int) float64 {
+	guesses := math.Pow(bruteforceCardinality, float64(length))
+	if length == 1 {
+		return max(guesses, minSubmatchGuessesSingleChar+1)
+	}
+	return max(guesses, minSubmatchGuessesMultiChar+1)
+}
+
+func lowerRunes(runes []rune) []rune {
+	lower := make([]rune, len(runes))
+	for i, r := range runes {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+// Returns the rank of word in the user inputs or the common words, the user
+// inputs first.
+func rank(word string, inputs map[string]int) (int, bool) {
+	if r, ok := inputs[word]; ok {
+		return r, true
+	}
+	r, ok := common().ranks[word]
+	return r, ok
+}
+
+func dictionaryMatches(runes []rune, inputs map[string]int) []match {
+	lower := lowerRunes(runes)
+
+	longest := common().longest
+	for in := range inputs {
+		longest = max(longest, utf8.RuneCountInString(in))
+	}
+
+	var matches []match
+	for i := range lower {
+		for j := i; j < min(i+longest, len(lower)); j++ {
+			r, ok := rank(string(lower[i:j+1]), inputs)
+			if !ok {
+				continue
+			}
+			matches = append(matches, match{i: i, j: j, guesses: float64(r) * upperVariations(runes[i:j+1])})
+		}
+	}
+	return matches
+}
+
+// Words typed backwards take twice the guesses.
+func reversedMatches(runes []rune, inputs map[string]int) []match {
+	n := len(runes)
+	reversed := make([]rune, n)
+	for i, r := range runes {
+		reversed[n-1-i] = r
+	}
+
+	var matches []match
+	for _, m := range dictionaryMatches(reversed, inputs) {
+		matches = append(matches, match{i: n - 1 - m.j, j: n - 1 - m.i, guesses: m.guesses * 2})
+	}
+	return matches
+}
+
+// Finds words with l33t substitutions. Characters that stand for more than
+// one letter are tried with each of them.
+func l33tMatches(runes []rune, inputs map[string]int) []match {
+	lower := lowerRunes(runes)
+
+	var present []rune
+	for r := range l33tTable {
+		if strings.ContainsRune(string(lower), r) {
+			present = append(present, r)
+		}
+	}
+	if len(present) == 0 {
+		return nil
+	}
+
+	var matches []match
+	for _, sub := range l33tSubstitutions(present) {
+		subbed := make([]rune, len(lower))
+		for i, r := range lower {
+			if letter, ok := sub[r]; ok {
+				subbed[i] = letter
+			} else {
+				subbed[i] = r
+			}
+		}
+
+		for _, m := range dictionaryMatches(subbed, inputs) {
+			token := lower[m.i : m.j+1]
+			// Single characters and words without substitutions are found
+			// by the other matchers.
+			if m.i == m.j || string(token) == string(subbed[m.i:m.j+1]) {
+				continue
+			}
+			m.guesses = m.guesses * upperVariations(runes[m.i:m.j+1]) * l33tVariations(token, sub)
+			matches = append(matches, m)
+		}
+	}
+	return matches
+}
+
+// Returns each way of mapping the l33t characters in present to letters.
+func l33tSubstitutions(present []rune) []map[rune]rune {
+	subs := []map[rune]rune{{}}
+	for _, r := range present {
+		var next []map[rune]rune
+		for _, sub := range subs {
+			for _, letter := range l33tTable[r] {
+				extended := make(map[rune]rune, len(sub)+1)
+				for k, v := range sub {
+					extended[k] = v
+				}
+				extended[r] = letter
+				next = append(next, extended)
+			}
+		}
+		subs = next
+	}
+	return subs
+}
+
+// Finds runs of at least three keys next to each other on a keyboard row.
+func spatialMatches(runes []rune) []match {
+	lower := lowerRunes(runes)
+
+	type key struct{ row, col int }
+	keys := make(map[rune]key)
+	for row, keysOfRow := range keyboardRows {
+		for col, r := range keysOfRow {
+			keys[r] = key{row: row, col: col}
+		}
+	}
+
+	adjacent := func(a, b rune) (int, bool) {
+		ka, okA := keys[a]
+		kb, okB := keys[b]
+		if !okA || !okB || ka.row != kb.row || (kb.col-ka.col != 1 && kb.col-ka.col != -1) {
+			return 0, false
+		}
+		return kb.col - ka.col, true
+	}
+
+	var matches []match
+	for i := 0; i < len(lower)-2; {
+		j := i
+		turns, direction := 0, 0
+		for j+1 < len(lower) {
+			d, ok := adjacent(lower[j], lower[j+1])
+			if !ok {
+				break
+			}
+			if d != direction {
+				turns++
+				direction = d
+			}
+			j++
+		}
+
+		if j-i+1 >= 3 {
+			guesses := spatialGuesses(j-i+1, turns) * upperVariations(runes[i:j+1])
+			matches = append(matches, match{i: i, j: j, guesses: guesses})
+			i = j
+			continue
+		}
+		i++
+	}
+	return matches
+}
+
+func spatialGuesses(length, turns int) float64 {
+	var guesses float64
+	for i := 2; i <= length; i++ {
+		for j := 1; j <= min(turns, i-1); j++ {
+			guesses += binomial(i-1, j-1) * keyboardStartingPositions * math.Pow(keyboardAverageDegree, float64(j))
+		}
+	}
+	return guesses
+}
+
+// Finds the longest repeats of a base, the guesses are those of the base
+// times the repeats.
+func repeatMatches(runes []rune, inputs map[string]int) []match {
+	n := len(runes)
+
+	var matches []match
+	for i := 0; i < n-1; {
+		bestLength, bestPeriod := 0, 0
+		for period := 1; i+2*period <= n; period++ {
+			repeats := 1
+			for i+(repeats+1)*period <= n &&
+				string(runes[i+repeats*period:i+(repeats+1)*period]) == string(runes[i:i+period]) {
+				repeats++
+			}
+			if repeats >= 2 && repeats*period > bestLength {
+				bestLength, bestPeriod = repeats*period, period
+			}
+		}
+
+		if bestLength == 0 {
+			i++
+			continue
+		}
+
+		base := runes[i : i+bestPeriod]
+		baseGuesses := mostGuessable(base, findMatches(base, inputs))
+		repeats := bestLength / bestPeriod
+		matches = append(matches, match{i: i, j: i + bestLength - 1, guesses: baseGuesses * float64(repeats)})
+		i += bestLength
+	}
+	return matches
+}
+
+// Finds runs of at least three characters with the same small step, like
+// abc, 9753 or ZYX.
+func sequenceMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i < len(runes)-2; {
+		delta := runes[i+1] - runes[i]
+		j := i + 1
+		for j+1 < len(runes) && runes[j+1]-runes[j] == delta {
+			j++
+		}
+
+		if j-i+1 >= 3 && delta != 0 && delta >= -maxSequenceDelta && delta <= maxSequenceDelta {
+			matches = append(matches, match{i: i, j: j, guesses: sequenceGuesses(runes[i:j+1], delta > 0)})
+		}
+		i = j
+	}
+	return matches
+}
+
+func sequenceGuesses(token []rune, ascending bool) float64 {
+	var base float64
+	switch first := token[0]; {
+	case strings.ContainsRune("aAzZ019", first):
+		// Obvious starts
+		base = 4
+	case unicode.IsDigit(first):
+		base = 10
+	default:
+		base = 26
+	}
+	if !ascending {
+		base *= 2
+	}
+	return base * float64(len(token))
+}
+
+// Finds years from 1900 to 2099, recent ones being the likeliest.
+func yearMatches(runes []rune) []match {
+	var matches []match
+	for i := 0; i+4 <= len(runes); i++ {
+		year, err := strconv.Atoi(string(runes[i : i+4]))
+		if err != nil || year < 1900 || year > 2099 {
+			continue
+		}
+		space := max(math.Abs(float64(year-time.Now().Year())), minYearSpace)
+		matches = append(matches, match{i: i, j: i + 3, guesses: space})
+	}
+	return matches
+}
+
+// Returns the ways to capitalize a word with the same number of upper case
+// letters. Capitalizing the first or last letter or all of them is obvious.
+func upperVariations(token []rune) float64 {
+	var upper, lower int
+	for _, r := range token {
+		switch {
+		case unicode.IsUpper(r):
+			upper++
+		case unicode.IsLower(r):
+			lower++
+		}
+	}
+
+	if upper == 0 {
+		return 1
+	}
+	if lower == 0 || (upper == 1 && (unicode.IsUpper(token[0]) || unicode.IsUpper(token[len(token)-1]))) {
+		return 2
+	}
+
+	var variations float64
+	for i := 1; i <= min(upper, lower); i++ {
+		variations += binomial(upper+lower, i)
+	}
+	return variations
+}
+
+// Returns the ways to apply the substitutions of sub to token with the same
+// number of substituted characters.
+func l33tVariations(token []rune, sub map[rune]rune) float64 {
+	variations := 1.0
+	for l33t, letter := range sub {
+		var subbed, unsubbed int
+		for _, r := range token {
+			switch r {
+			case l33t:
+				subbed++
+			case letter:
+				unsubbed++
+			}
+		}
+
+		if subbed == 0 {
+			continue
+		}
+		if unsubbed == 0 {
+			variations *= 2
+			continue
+		}
+
+		var v float64
+		for i := 1; i <= min(subbed, unsubbed); i++ {
+			v += binomial(subbed+unsubbed, i)
+		}
+		variations *= v
+	}
+	return variations
+}
+
+func binomial(n, k int) float64 {
+	if k > n {
+		return 0
+	}
+	r := 1.0
+	for d := 1; d <= k; d++ {
+		r = r * float64(n-k+d) / float64(d)
+	}
+	return r
+}
+
+func factorial(n int) float64 {
+	f := 1.0
+	for i := 2; i <= n; i++ {
+		f *= float64(i)
+	}
+	return f
+}
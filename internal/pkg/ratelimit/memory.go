@@ -0,0 +1,57 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// How often expired keys are removed from a MemoryStore
+const sweepInterval = time.Minute
+
+type memoryEntry struct {
+	state     State
+	expiresAt time.Time
+}
+
+// MemoryStore keeps the state in the process. Limits are per replica.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]*memoryEntry
+	lastSweep time.Time
+}
+
+var _ Store = (*MemoryStore)(nil)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{entries: make(map[string]*memoryEntry)}
+}
+
+func (m *MemoryStore) Update(_ context.Context, key string, expiresAt time.Time, fn func(*State)) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(m.lastSweep) >= sweepInterval {
+		m.sweep(now)
+	}
+
+	entry, ok := m.entries[key]
+	if !ok || now.After(entry.expiresAt) {
+		entry = &memoryEntry{}
+		m.entries[key] = entry
+	}
+
+	fn(&entry.state)
+	entry.expiresAt = expiresAt
+	return nil
+}
+
+func (m *MemoryStore) sweep(now time.Time) {
+	for key, entry := range m.entries {
+		if now.After(entry.expiresAt) {
+			delete(m.entries, key)
+		}
+	}
+	m.lastSweep = now
+}
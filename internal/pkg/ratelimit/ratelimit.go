@@ -0,0 +1,176 @@
+// Package ratelimit implements the token bucket and sliding window rate
+// limiting algorithms on top of a Store that keeps their state, so that the
+// same limits can be enforced by a single process or across replicas.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+const (
+	// TokenBucket allows bursts of up to Requests that refill evenly over
+	// Window.
+	TokenBucket = "token_bucket"
+	// SlidingWindow allows Requests in any Window, estimated from the counts
+	// of the current and the previous fixed window.
+	SlidingWindow = "sliding_window"
+)
+
+type Limit struct {
+	Algorithm string
+	Requests  int
+	Window    time.Duration
+}
+
+// State is what a Store keeps per key. For a token bucket Count is the number
+// of tokens left at Time. For a sliding window Count and Previous are the
+// requests in the window starting at Time and in the one before it.
+type State struct {
+	Count    float64
+	Previous float64
+	Time     time.Time
+}
+
+// Store applies fn to the state of key atomically. A key seen for the first
+// time starts with the zero State. The state may be discarded after
+// expiresAt.
+type Store interface {
+	Update(ctx context.Context, key string, expiresAt time.Time, fn func(*State)) error
+}
+
+// Result is the outcome of a request against a limit.
+type Result struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Time until the limit is fully available again
+	Reset time.Duration
+	// Time until the next request is allowed, zero when Allowed
+	RetryAfter time.Duration
+}
+
+type Limiter struct {
+	store Store
+	limit Limit
+}
+
+func NewLimiter(store Store, limit Limit) (*Limiter, error) {
+	if limit.Algorithm != TokenBucket && limit.Algorithm != SlidingWindow {
+		return nil, fmt.Errorf("unknown rate limit algorithm %q", limit.Algorithm)
+	}
+	if limit.Requests < 1 || limit.Window <= 0 {
+		return nil, fmt.Errorf("invalid rate limit of %d requests per %s", limit.Requests, limit.Window)
+	}
+	return &Limiter{store: store, limit: limit}, nil
+}
+
+// Limit returns the limit that the limiter enforces.
+func (l *Limiter) Limit() Limit {
+	return l.limit
+}
+
+// Allow counts a request for key at now.
+func (l *Limiter) Allow(ctx context.Context, key string, now time.Time) (Result, error) {
+	var res Result
+	// A sliding window needs the previous window, a bucket refills within one.
+	expiresAt := now.Add(2 * l.limit.Window)
+
+	err := l.store.Update(ctx, key, expiresAt, func(s *State) {
+		if l.limit.Algorithm == TokenBucket {
+			res = l.takeToken(s, now)
+		} else {
+			res = l.slide(s, now)
+		}
+	})
+	if err != nil {
+		return Result{}, fmt.Errorf("update rate limit %s: %w", key, err)
+	}
+
+	return res, nil
+}
+
+func (l *Limiter) takeToken(s *State, now time.Time) Result {
+	capacity := float64(l.limit.Requests)
+	perSecond := capacity / l.limit.Window.Seconds()
+
+	elapsed := max(now.Sub(s.Time).Seconds(), 0)
+	tokens := math.Min(capacity, s.Count+elapsed*perSecond)
+	if s.Time.IsZero() {
+		tokens = capacity
+	}
+
+	res := Result{Limit: l.limit.Requests}
+	if tokens >= 1 {
+		tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = seconds((1 - tokens) / perSecond)
+	}
+
+	s.Count = tokens
+	s.Time = now
+
+	res.Remaining = int(tokens)
+	res.Reset = seconds((capacity - tokens) / perSecond)
+	return res
+}
+
+func (l *Limiter) slide(s *State, now time.Time) Result {
+	window := l.limit.Window
+	start := now.Truncate(window)
+
+	if !s.Time.Equal(start) {
+		if s.Time.Equal(start.Add(-window)) {
+			s.Previous = s.Count
+		} else {
+			s.Previous = 0
+		}
+		s.Count = 0
+		s.Time = start
+	}
+
+	limit := float64(l.limit.Requests)
+	elapsed := float64(now.Sub(start)) / float64(window)
+	estimate := s.Previous*(1-elapsed) + s.Count
+
+	res := Result{Limit: l.limit.Requests}
+	if estimate+1 <= limit {
+		s.Count++
+		estimate++
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.slideRetryAfter(s, elapsed)
+	}
+
+	res.Remaining = max(int(limit-estimate), 0)
+	// The previous window stops counting at the end of the current one, the
+	// current one at the end of the next.
+	res.Reset = start.Add(window).Sub(now)
+	if s.Count > 0 {
+		res.Reset += window
+	}
+	return res
+}
+
+// Returns how long until the estimate leaves room for one more request.
+func (l *Limiter) slideRetryAfter(s *State, elapsed float64) time.Duration {
+	window := l.limit.Window.Seconds()
+	room := float64(l.limit.Requests) - 1
+
+	// Still within this window once enough of the previous one slid out.
+	if s.Count <= room && s.Previous > 0 {
+		needed := 1 - (room-s.Count)/s.Previous
+		return seconds((needed - elapsed) * window)
+	}
+
+	// Otherwise this window has to slide out of the next one.
+	needed := 1 - room/s.Count
+	return seconds((1-elapsed)*window + needed*window)
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(math.Ceil(s * float64(time.Second)))
+}
@@ -0,0 +1,108 @@
+package ratelimit_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+const testKey = "ip:192.0.2.1"
+
+func newLimiter(t *testing.T, algorithm string, requests int, window time.Duration) *ratelimit.Limiter {
+	t.Helper()
+	l, err := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), ratelimit.Limit{
+		Algorithm: algorithm,
+		Requests:  requests,
+		Window:    window,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return l
+}
+
+func allow(t *testing.T, l *ratelimit.Limiter, now time.Time) ratelimit.Result {
+	t.Helper()
+	res, err := l.Allow(context.Background(), testKey, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return res
+}
+
+func TestTokenBucket(t *testing.T) {
+	l := newLimiter(t, ratelimit.TokenBucket, 3, time.Minute)
+	now := time.Now()
+
+	for i := range 3 {
+		res := allow(t, l, now)
+		assert.True(t, res.Allowed)
+		assert.Equal(t, 2-i, res.Remaining)
+	}
+
+	res := allow(t, l, now)
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	assert.Equal(t, 20*time.Second, res.RetryAfter, "one token refills every 20s")
+	assert.Equal(t, time.Minute, res.Reset)
+
+	res = allow(t, l, now.Add(20*time.Second))
+	assert.True(t, res.Allowed)
+	assert.False(t, allow(t, l, now.Add(21*time.Second)).Allowed)
+
+	// The bucket does not fill beyond its capacity.
+	res = allow(t, l, now.Add(time.Hour))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 2, res.Remaining)
+}
+
+func TestSlidingWindow(t *testing.T) {
+	l := newLimiter(t, ratelimit.SlidingWindow, 4, time.Minute)
+	start := time.Now().Truncate(time.Minute)
+
+	for range 4 {
+		assert.True(t, allow(t, l, start.Add(30*time.Second)).Allowed)
+	}
+
+	res := allow(t, l, start.Add(45*time.Second))
+	assert.False(t, res.Allowed)
+	assert.Equal(t, 0, res.Remaining)
+	// The estimate drops to 3 once a quarter of the next window passed.
+	assert.Equal(t, 30*time.Second, res.RetryAfter)
+
+	// Half of the previous window still counts.
+	next := start.Add(time.Minute)
+	res = allow(t, l, next.Add(30*time.Second))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 1, res.Remaining)
+	assert.True(t, allow(t, l, next.Add(30*time.Second)).Allowed)
+	assert.False(t, allow(t, l, next.Add(30*time.Second)).Allowed)
+
+	// Windows further back are forgotten.
+	res = allow(t, l, start.Add(5*time.Minute))
+	assert.True(t, res.Allowed)
+	assert.Equal(t, 3, res.Remaining)
+}
+
+func TestLimiterKeysAreSeparate(t *testing.T) {
+	l := newLimiter(t, ratelimit.TokenBucket, 1, time.Minute)
+	now := time.Now()
+
+	assert.True(t, allow(t, l, now).Allowed)
+	res, err := l.Allow(context.Background(), "ip:192.0.2.2", now)
+	assert.NoError(t, err)
+	assert.True(t, res.Allowed)
+}
+
+func TestNewLimiterInvalid(t *testing.T) {
+	store := ratelimit.NewMemoryStore()
+
+	_, err := ratelimit.NewLimiter(store, ratelimit.Limit{Algorithm: "leaky", Requests: 1, Window: time.Second})
+	assert.Error(t, err)
+
+	_, err = ratelimit.NewLimiter(store, ratelimit.Limit{Algorithm: ratelimit.TokenBucket, Window: time.Second})
+	assert.Error(t, err)
+}
@@ -0,0 +1,56 @@
+package security
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+)
+
+// EncryptedString encrypts a string with a keyring when it is written to the
+// database and decrypts it when it is read, so that repositories can encrypt
+// a column by wrapping its value:
+//
+//	db.ExecContext(ctx, query, keyring.EncryptedString(&secret, context))
+//	row.Scan(keyring.EncryptedString(&secret, context))
+//
+// The context binds the value to where it is stored, see Keyring.
+type EncryptedString struct {
+	keyring *Keyring
+	context string
+	s       *string
+}
+
+var (
+	_ driver.Valuer = EncryptedString{}
+	_ sql.Scanner   = EncryptedString{}
+)
+
+// EncryptedString returns s as an EncryptedString bound to context.
+func (k *Keyring) EncryptedString(s *string, context string) EncryptedString {
+	return EncryptedString{keyring: k, context: context, s: s}
+}
+
+// Value implements driver.Valuer.
+func (e EncryptedString) Value() (driver.Value, error) {
+	return e.keyring.Encrypt([]byte(*e.s), e.context)
+}
+
+// Scan implements sql.Scanner.
+func (e EncryptedString) Scan(src any) error {
+	var encrypted string
+	switch v := src.(type) {
+	case string:
+		encrypted = v
+	case []byte:
+		encrypted = string(v)
+	default:
+		return fmt.Errorf("scan encrypted string: unsupported type %T", src)
+	}
+
+	plaintext, err := e.keyring.Decrypt(encrypted, e.context)
+	if err != nil {
+		return err
+	}
+	*e.s = string(plaintext)
+	return nil
+}
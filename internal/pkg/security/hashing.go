@@ -6,7 +6,9 @@ import (
 	"encoding/base64"
 	"errors"
 	"fmt"
+	"slices"
 	"strings"
+	"time"
 
 	"golang.org/x/crypto/argon2"
 )
@@ -14,81 +16,187 @@ import (
 type Hasher interface {
 	Hash(plain string) (string, error)
 	Verify(plain, hashed string) (bool, error)
+	// NeedsRehash reports whether hashed is weaker than what Hash produces
+	// now, so that it should be replaced once the plain text is known.
+	NeedsRehash(hashed string) bool
 }
 
-type Argon2Hasher struct{}
+// Argon2Params are the cost parameters of Argon2id. Memory is in KiB.
+type Argon2Params struct {
+	Memory      uint32
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// DefaultArgon2Params are used by an Argon2Hasher without parameters.
+var DefaultArgon2Params = Argon2Params{
+	Memory:      64 * 1024, // 64 MB
+	Iterations:  3,
+	Parallelism: 2,
+	SaltLength:  16, // 16 bytes
+	KeyLength:   32, // 32 bytes
+}
+
+// Argon2Hasher hashes with Params, or DefaultArgon2Params if they are zero.
+// Hashes made with other parameters are still verified since the parameters
+// are stored in the hash.
+type Argon2Hasher struct {
+	Params Argon2Params
+}
 
 var _ Hasher = (*Argon2Hasher)(nil)
 
-// Parameters for the Argon2ID algorithm
-const (
-	Memory      = 64 * 1024 // 64 MB
-	Iterations  = 3
-	Parallelism = 2
-	SaltLength  = 16 // 16 bytes
-	KeyLength   = 32 // 32 bytes
-)
+func NewArgon2Hasher(params Argon2Params) *Argon2Hasher {
+	return &Argon2Hasher{Params: params}
+}
+
+func (h *Argon2Hasher) params() Argon2Params {
+	if h.Params == (Argon2Params{}) {
+		return DefaultArgon2Params
+	}
+	return h.Params
+}
 
 // Hash implements Hasher.
 func (h *Argon2Hasher) Hash(plain string) (string, error) {
+	p := h.params()
+
 	// Generate a random salt
-	salt, err := GenerateRandomBytes(SaltLength)
+	salt, err := GenerateRandomBytes(p.SaltLength)
 	if err != nil {
 		return "", fmt.Errorf("generate salt: %w", err)
 	}
 
 	// Hash the password
-	hash := argon2.IDKey([]byte(plain), salt, Iterations, Memory, Parallelism, KeyLength)
+	hash := argon2.IDKey([]byte(plain), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
 
 	// Encode the salt and hash for storage
 	saltBase64 := base64.RawStdEncoding.EncodeToString(salt)
 	hashBase64 := base64.RawStdEncoding.EncodeToString(hash)
 
 	// Return the formatted password hash
-	return fmt.Sprintf("$argon2id$v=19$m=%d,t=%d,p=%d$%s$%s",
-		Memory, Iterations, Parallelism, saltBase64, hashBase64), nil
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, p.Memory, p.Iterations, p.Parallelism, saltBase64, hashBase64), nil
 }
 
 // Verify implements Hasher.
 func (h *Argon2Hasher) Verify(plain string, hashed string) (bool, error) {
+	p, salt, expectedHash, err := decodeArgon2Hash(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	// Compute the hash with the same parameters
+	computedHash := argon2.IDKey([]byte(plain), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+
+	// Constant time comparison to prevent timing attacks
+	if subtle.ConstantTimeCompare(computedHash, expectedHash) == 1 {
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// NeedsRehash implements Hasher. Hashes that cannot be decoded need one too.
+func (h *Argon2Hasher) NeedsRehash(hashed string) bool {
+	stored, _, _, err := decodeArgon2Hash(hashed)
+	if err != nil {
+		return true
+	}
+
+	p := h.params()
+	return stored.Memory < p.Memory ||
+		stored.Iterations < p.Iterations ||
+		stored.Parallelism < p.Parallelism ||
+		stored.SaltLength < p.SaltLength ||
+		stored.KeyLength < p.KeyLength
+}
+
+// Returns the parameters, salt and key of a hash in the PHC string format.
+func decodeArgon2Hash(hashed string) (Argon2Params, []byte, []byte, error) {
+	var p Argon2Params
+
 	parts := strings.Split(hashed, "$")
-	if len(parts) != 6 {
-		return false, errors.New("invalid hash format")
+	if len(parts) != 6 || parts[1] != "argon2id" {
+		return p, nil, nil, errors.New("invalid hash format")
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("unsupported argon2 version %q", parts[2])
 	}
 
 	// Extract parameters and the salt/hash values
-	var memory uint32
-	var iterations uint32
-	var parallelism uint8
-	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism)
+	_, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism)
 	if err != nil {
-		return false, fmt.Errorf("failed to parse hash parameters: %w", err)
+		return p, nil, nil, fmt.Errorf("failed to parse hash parameters: %w", err)
 	}
 
 	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode salt: %w", err)
+		return p, nil, nil, fmt.Errorf("failed to decode salt: %w", err)
 	}
 
-	expectedHash, err := base64.RawStdEncoding.DecodeString(parts[5])
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
 	if err != nil {
-		return false, fmt.Errorf("failed to decode hash: %w", err)
+		return p, nil, nil, fmt.Errorf("failed to decode hash: %w", err)
 	}
 
-	// Check if len(expectedHash) can safely fit in a uint32
-	hashLen := len(expectedHash)
-
-	if hashLen > int(^uint32(0)) { // ^uint32(0) gives the max value of uint32
-		return false, errors.New("expected hash length exceeds uint32 limits")
+	// Check if len(key) can safely fit in a uint32
+	if len(key) > int(^uint32(0)) || len(salt) > int(^uint32(0)) { // ^uint32(0) gives the max value of uint32
+		return p, nil, nil, errors.New("expected hash length exceeds uint32 limits")
 	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
 
-	// Compute the hash with the same parameters
-	computedHash := argon2.IDKey([]byte(plain), salt, iterations, memory, parallelism, uint32(hashLen))
+	return p, salt, key, nil
+}
 
-	// Constant time comparison to prevent timing attacks
-	if subtle.ConstantTimeCompare(computedHash, expectedHash) == 1 {
-		return true, nil
+// Runs of each measurement in CalibrateArgon2, the median is used.
+const calibrationRuns = 3
+
+// CalibrateArgon2 returns the parameters whose hashes take close to, but not
+// more than, target on this machine. As much memory up to maxMemory is used
+// as the target allows since memory is what makes guessing expensive on GPUs.
+// Memory is halved while a single iteration is too slow, then iterations are
+// added while they fit.
+func CalibrateArgon2(target time.Duration, maxMemory uint32, parallelism uint8) (Argon2Params, time.Duration) {
+	p := DefaultArgon2Params
+	p.Parallelism = max(parallelism, 1)
+	p.Iterations = 1
+	// Argon2 needs at least 8 KiB per lane.
+	minMemory := 8 * uint32(p.Parallelism)
+	p.Memory = max(maxMemory, minMemory)
+
+	elapsed := measureArgon2(p)
+	for elapsed > target && p.Memory/2 >= minMemory {
+		p.Memory /= 2
+		elapsed = measureArgon2(p)
 	}
 
-	return false, nil
+	// The time grows linearly with the iterations.
+	if extra := int64(target/max(elapsed, 1)) - 1; extra > 0 {
+		p.Iterations += uint32(min(extra, 1<<16))
+		elapsed = measureArgon2(p)
+		for elapsed > target && p.Iterations > 1 {
+			p.Iterations--
+			elapsed = measureArgon2(p)
+		}
+	}
+
+	return p, elapsed
+}
+
+func measureArgon2(p Argon2Params) time.Duration {
+	salt := make([]byte, p.SaltLength)
+	runs := make([]time.Duration, calibrationRuns)
+	for i := range runs {
+		start := time.Now()
+		argon2.IDKey([]byte("calibrate"), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+		runs[i] = time.Since(start)
+	}
+	slices.Sort(runs)
+	return runs[len(runs)/2]
 }
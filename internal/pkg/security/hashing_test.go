@@ -3,6 +3,7 @@ package security_test
 import (
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/ferdiebergado/goweb/internal/pkg/security"
 	"github.com/stretchr/testify/assert"
@@ -18,3 +19,69 @@ func TestArgon2Hasher_Hash(t *testing.T) {
 	assert.NotEmpty(t, hashed, "Hashed password should not be empty")
 	assert.True(t, strings.HasPrefix(hashed, "$argon2id$"), "Hashed password should have the correct prefix")
 }
+
+func TestArgon2Hasher_Params(t *testing.T) {
+	hasher := security.NewArgon2Hasher(security.Argon2Params{
+		Memory: 1024, Iterations: 2, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+	})
+
+	hashed, err := hasher.Hash("securepassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$v=19$m=1024,t=2,p=1$"))
+
+	ok, err := (&security.Argon2Hasher{}).Verify("securepassword", hashed)
+	assert.NoError(t, err)
+	assert.True(t, ok, "hashes are verified with their own parameters")
+}
+
+func TestArgon2Hasher_NeedsRehash(t *testing.T) {
+	weak := security.NewArgon2Hasher(security.Argon2Params{
+		Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+	})
+	hashed, err := weak.Hash("securepassword")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assert.False(t, weak.NeedsRehash(hashed))
+
+	stronger := *weak
+	stronger.Params.Iterations = 2
+	assert.True(t, stronger.NeedsRehash(hashed), "more iterations")
+
+	stronger = *weak
+	stronger.Params.Memory = 2048
+	assert.True(t, stronger.NeedsRehash(hashed), "more memory")
+
+	weaker := *weak
+	weaker.Params.Memory = 512
+	assert.False(t, weaker.NeedsRehash(hashed), "hashes are never downgraded")
+
+	assert.True(t, weak.NeedsRehash("$2b$10$abcdefghijklmnopqrstuv"), "other algorithms")
+}
+
+func TestArgon2Hasher_VerifyInvalidHash(t *testing.T) {
+	hasher := &security.Argon2Hasher{}
+
+	for _, hashed := range []string{
+		"",
+		"$argon2i$v=19$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=16$m=1024,t=1,p=1$c2FsdA$a2V5",
+		"$argon2id$v=19$m=x$c2FsdA$a2V5",
+	} {
+		_, err := hasher.Verify("securepassword", hashed)
+		assert.Error(t, err, hashed)
+	}
+}
+
+func TestCalibrateArgon2(t *testing.T) {
+	p, elapsed := security.CalibrateArgon2(5*time.Millisecond, 4096, 1)
+
+	assert.LessOrEqual(t, p.Memory, uint32(4096))
+	assert.GreaterOrEqual(t, p.Memory, uint32(8))
+	assert.GreaterOrEqual(t, p.Iterations, uint32(1))
+	assert.Equal(t, uint8(1), p.Parallelism)
+	assert.Positive(t, elapsed)
+}
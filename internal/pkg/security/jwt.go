@@ -0,0 +1,196 @@
+package security
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+)
+
+// Signing algorithms supported for JWTs
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+)
+
+var (
+	ErrTokenInvalid = errors.New("invalid token")
+	ErrTokenExpired = errors.New("token has expired")
+)
+
+// Claims are the registered JWT claims used by the app.
+type Claims struct {
+	Issuer    string `json:"iss"`
+	Subject   string `json:"sub"`
+	ID        string `json:"jti,omitempty"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Typ string `json:"typ"`
+	Kid string `json:"kid,omitempty"`
+}
+
+// JWT signs and verifies compact JWS tokens with a single algorithm. Tokens
+// signed with any other algorithm are rejected, so a token cannot pick the
+// key it is verified with.
+type JWT struct {
+	alg        string
+	keyID      string
+	issuer     string
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// NewJWT returns a JWT using the algorithm and key of cfg.
+func NewJWT(cfg config.JWTConfig) (*JWT, error) {
+	j := &JWT{alg: cfg.Algorithm, keyID: cfg.KeyID, issuer: cfg.Issuer}
+
+	switch cfg.Algorithm {
+	case AlgHS256:
+		if cfg.Secret == "" {
+			return nil, errors.New("jwt secret is required for HS256")
+		}
+		j.secret = []byte(cfg.Secret)
+	case AlgEdDSA:
+		key, err := ParseEd25519PrivateKey(cfg.PrivateKey)
+		if err != nil {
+			return nil, err
+		}
+		j.privateKey = key
+		j.publicKey = key.Public().(ed25519.PublicKey)
+	default:
+		return nil, fmt.Errorf("unsupported jwt algorithm: %s", cfg.Algorithm)
+	}
+
+	return j, nil
+}
+
+// ParseEd25519PrivateKey accepts a PKCS#8 PEM block or a base64 encoded
+// 32-byte seed.
+func ParseEd25519PrivateKey(s string) (ed25519.PrivateKey, error) {
+	s = strings.TrimSpace(s)
+
+	if strings.HasPrefix(s, "-----BEGIN") {
+		block, _ := pem.Decode([]byte(s))
+		if block == nil {
+			return nil, errors.New("decode ed25519 private key: invalid PEM")
+		}
+		key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+		if err != nil {
+			return nil, fmt.Errorf("parse ed25519 private key: %w", err)
+		}
+		edKey, ok := key.(ed25519.PrivateKey)
+		if !ok {
+			return nil, errors.New("parse ed25519 private key: not an ed25519 key")
+		}
+		return edKey, nil
+	}
+
+	seed, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("decode ed25519 private key: %w", err)
+	}
+	if len(seed) != ed25519.SeedSize {
+		return nil, fmt.Errorf("ed25519 seed must be %d bytes, got %d", ed25519.SeedSize, len(seed))
+	}
+	return ed25519.NewKeyFromSeed(seed), nil
+}
+
+// Sign returns the token for claims. The issuer is set from the config.
+func (j *JWT) Sign(claims Claims) (string, error) {
+	claims.Issuer = j.issuer
+
+	header, err := json.Marshal(jwtHeader{Alg: j.alg, Typ: "JWT", Kid: j.keyID})
+	if err != nil {
+		return "", fmt.Errorf("encode jwt header: %w", err)
+	}
+
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("encode jwt claims: %w", err)
+	}
+
+	signingInput := encodeSegment(header) + "." + encodeSegment(payload)
+	return signingInput + "." + encodeSegment(j.sign([]byte(signingInput))), nil
+}
+
+// Parse verifies the signature, issuer and expiry of token as of now and
+// returns its claims.
+func (j *JWT) Parse(token string, now time.Time) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrTokenInvalid
+	}
+
+	headerJSON, err := decodeSegment(parts[0])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	var header jwtHeader
+	if err := json.Unmarshal(headerJSON, &header); err != nil || header.Alg != j.alg {
+		return nil, ErrTokenInvalid
+	}
+
+	sig, err := decodeSegment(parts[2])
+	if err != nil || !j.verify([]byte(parts[0]+"."+parts[1]), sig) {
+		return nil, ErrTokenInvalid
+	}
+
+	payload, err := decodeSegment(parts[1])
+	if err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrTokenInvalid
+	}
+
+	if claims.Issuer != j.issuer || claims.Subject == "" {
+		return nil, ErrTokenInvalid
+	}
+
+	if now.Unix() >= claims.ExpiresAt {
+		return nil, ErrTokenExpired
+	}
+
+	return &claims, nil
+}
+
+func (j *JWT) sign(input []byte) []byte {
+	if j.alg == AlgEdDSA {
+		return ed25519.Sign(j.privateKey, input)
+	}
+	mac := hmac.New(sha256.New, j.secret)
+	mac.Write(input)
+	return mac.Sum(nil)
+}
+
+func (j *JWT) verify(input, sig []byte) bool {
+	if j.alg == AlgEdDSA {
+		return ed25519.Verify(j.publicKey, input, sig)
+	}
+	return hmac.Equal(j.sign(input), sig)
+}
+
+func encodeSegment(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeSegment(s string) ([]byte, error) {
+	return base64.RawURLEncoding.DecodeString(s)
+}
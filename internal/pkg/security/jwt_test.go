@@ -0,0 +1,84 @@
+package security_test
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/base64"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+)
+
+const testSecret = "0123456789abcdef0123456789abcdef"
+
+func newEdDSAConfig(t *testing.T) config.JWTConfig {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return config.JWTConfig{
+		Algorithm:  security.AlgEdDSA,
+		PrivateKey: base64.StdEncoding.EncodeToString(key.Seed()),
+		Issuer:     "goweb",
+	}
+}
+
+func TestJWT_SignAndParse(t *testing.T) {
+	var tests = []struct {
+		name string
+		cfg  config.JWTConfig
+	}{
+		{"HS256", config.JWTConfig{Algorithm: security.AlgHS256, Secret: testSecret, Issuer: "goweb"}},
+		{"EdDSA", newEdDSAConfig(t)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			j, err := security.NewJWT(tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			now := time.Now()
+			token, err := j.Sign(security.Claims{Subject: "1", IssuedAt: now.Unix(), ExpiresAt: now.Add(time.Minute).Unix()})
+			assert.NoError(t, err)
+
+			claims, err := j.Parse(token, now)
+			assert.NoError(t, err)
+			assert.Equal(t, "1", claims.Subject)
+			assert.Equal(t, "goweb", claims.Issuer)
+
+			_, err = j.Parse(token, now.Add(time.Minute))
+			assert.ErrorIs(t, err, security.ErrTokenExpired)
+
+			parts := strings.Split(token, ".")
+			forged := parts[0] + "." + base64.RawURLEncoding.EncodeToString([]byte(`{"iss":"goweb","sub":"2","exp":9999999999}`)) +
+				"." + parts[2]
+			_, err = j.Parse(forged, now)
+			assert.ErrorIs(t, err, security.ErrTokenInvalid)
+		})
+	}
+}
+
+func TestJWT_ParseRejectsOtherAlgorithm(t *testing.T) {
+	hs, err := security.NewJWT(config.JWTConfig{Algorithm: security.AlgHS256, Secret: testSecret, Issuer: "goweb"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	ed, err := security.NewJWT(newEdDSAConfig(t))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	token, err := hs.Sign(security.Claims{Subject: "1", ExpiresAt: now.Add(time.Minute).Unix()})
+	assert.NoError(t, err)
+
+	_, err = ed.Parse(token, now)
+	assert.ErrorIs(t, err, security.ErrTokenInvalid)
+}
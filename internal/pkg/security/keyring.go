@@ -0,0 +1,211 @@
+package security
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/chacha20poly1305"
+)
+
+// Algorithms of the keys of a Keyring
+const (
+	AlgAES256GCM         = "aes-256-gcm"
+	AlgXChaCha20Poly1305 = "xchacha20-poly1305"
+)
+
+// Prefix of the values encrypted by a Keyring, followed by the key ID, the
+// wrapped data key and the sealed data, separated by colons.
+const envelopePrefix = "enc:v1:"
+
+// Length in bytes of the keys of either algorithm
+const CipherKeyLength = 32
+
+var ErrDecrypt = errors.New("decrypt: message authentication failed")
+var ErrUnknownKey = errors.New("decrypt: unknown encryption key")
+
+var envelopeEncoding = base64.RawURLEncoding
+
+// EncryptionKey is a key of a Keyring. Key is 32 bytes for either algorithm.
+type EncryptionKey struct {
+	ID        string
+	Algorithm string
+	Key       []byte
+}
+
+// Keyring encrypts values for storage with envelope encryption: each value
+// is sealed with a random data key, which is sealed in turn with a key of the
+// keyring. The ID of that key is part of the encrypted value so that every
+// key of the keyring can decrypt while new values use the primary one.
+//
+// Every value is bound to a context, such as the table, column and row it is
+// stored in, which has to be the same to decrypt it. A value copied to
+// another row fails to decrypt.
+type Keyring struct {
+	primary string
+	keys    map[string]*keyringKey
+}
+
+type keyringKey struct {
+	algorithm string
+	aead      cipher.AEAD
+}
+
+func NewKeyring(primary string, keys []EncryptionKey) (*Keyring, error) {
+	k := &Keyring{primary: primary, keys: make(map[string]*keyringKey, len(keys))}
+	for _, key := range keys {
+		if key.ID == "" || strings.ContainsAny(key.ID, ":\x00") {
+			return nil, fmt.Errorf("invalid encryption key ID %q", key.ID)
+		}
+		if _, ok := k.keys[key.ID]; ok {
+			return nil, fmt.Errorf("duplicate encryption key %s", key.ID)
+		}
+
+		aead, err := newAEAD(key.Algorithm, key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %s: %w", key.ID, err)
+		}
+		k.keys[key.ID] = &keyringKey{algorithm: key.Algorithm, aead: aead}
+	}
+
+	if _, ok := k.keys[primary]; !ok {
+		return nil, fmt.Errorf("primary encryption key %q is not in the keyring", primary)
+	}
+	return k, nil
+}
+
+func newAEAD(algorithm string, key []byte) (cipher.AEAD, error) {
+	if len(key) != CipherKeyLength {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", CipherKeyLength, len(key))
+	}
+
+	switch algorithm {
+	case AlgAES256GCM:
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+		return cipher.NewGCM(block)
+	case AlgXChaCha20Poly1305:
+		return chacha20poly1305.NewX(key)
+	default:
+		return nil, fmt.Errorf("unknown algorithm %q", algorithm)
+	}
+}
+
+// Encrypt returns plaintext encrypted with the primary key and bound to
+// context.
+func (k *Keyring) Encrypt(plaintext []byte, context string) (string, error) {
+	key := k.keys[k.primary]
+	header := envelopePrefix + k.primary
+	aad := envelopeAAD(header, context)
+
+	dataKey, err := GenerateRandomBytes(CipherKeyLength)
+	if err != nil {
+		return "", fmt.Errorf("generate data key: %w", err)
+	}
+	dataAEAD, err := newAEAD(key.algorithm, dataKey)
+	if err != nil {
+		return "", err
+	}
+
+	wrapped, err := seal(key.aead, dataKey, aad)
+	if err != nil {
+		return "", err
+	}
+	sealed, err := seal(dataAEAD, plaintext, aad)
+	if err != nil {
+		return "", err
+	}
+
+	return header + ":" + envelopeEncoding.EncodeToString(wrapped) + ":" + envelopeEncoding.EncodeToString(sealed), nil
+}
+
+// Decrypt returns the plaintext of a value returned by Encrypt with any key
+// of the keyring and the same context.
+func (k *Keyring) Decrypt(encrypted, context string) ([]byte, error) {
+	rest, ok := strings.CutPrefix(encrypted, envelopePrefix)
+	if !ok {
+		return nil, ErrUnknownKey
+	}
+
+	parts := strings.Split(rest, ":")
+	if len(parts) != 3 {
+		return nil, ErrDecrypt
+	}
+	id := parts[0]
+	key, ok := k.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, id)
+	}
+	aad := envelopeAAD(envelopePrefix+id, context)
+
+	wrapped, err := envelopeEncoding.DecodeString(parts[1])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	sealed, err := envelopeEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+
+	dataKey, err := open(key.aead, wrapped, aad)
+	if err != nil {
+		return nil, err
+	}
+	dataAEAD, err := newAEAD(key.algorithm, dataKey)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return open(dataAEAD, sealed, aad)
+}
+
+// Returns the additional data that the sealed values are authenticated with.
+// The header is included so that a value cannot be moved to another key, the
+// context so that it cannot be moved to another row. Key IDs cannot contain
+// a NUL, so the header and the context cannot run into each other.
+func envelopeAAD(header, context string) []byte {
+	return []byte(header + "\x00" + context)
+}
+
+// KeyID returns the ID of the key that encrypted is encrypted with, empty for
+// values that were not encrypted by a Keyring.
+func KeyID(encrypted string) string {
+	rest, ok := strings.CutPrefix(encrypted, envelopePrefix)
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, ":")
+	return id
+}
+
+// NeedsRotation reports whether encrypted is not encrypted with the primary
+// key, so that it should be encrypted again.
+func (k *Keyring) NeedsRotation(encrypted string) bool {
+	return KeyID(encrypted) != k.primary
+}
+
+// Returns the nonce and the sealed plaintext.
+func seal(aead cipher.AEAD, plaintext, aad []byte) ([]byte, error) {
+	nonce, err := GenerateRandomBytes(uint32(aead.NonceSize()))
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+	return aead.Seal(nonce, nonce, plaintext, aad), nil
+}
+
+func open(aead cipher.AEAD, sealed, aad []byte) ([]byte, error) {
+	nonceSize := aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return nil, ErrDecrypt
+	}
+
+	plaintext, err := aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], aad)
+	if err != nil {
+		return nil, ErrDecrypt
+	}
+	return plaintext, nil
+}
@@ -0,0 +1,162 @@
+package security_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+)
+
+// The context of the values of the tests, as a repository would bind them
+// to their row.
+const testContext = "user_totp.secret/1"
+
+func newTestKey(t *testing.T, id, algorithm string) security.EncryptionKey {
+	t.Helper()
+	key, err := security.GenerateRandomBytes(security.CipherKeyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return security.EncryptionKey{ID: id, Algorithm: algorithm, Key: key}
+}
+
+func TestKeyring_EncryptDecrypt(t *testing.T) {
+	for _, algorithm := range []string{security.AlgAES256GCM, security.AlgXChaCha20Poly1305} {
+		t.Run(algorithm, func(t *testing.T) {
+			k, err := security.NewKeyring("k1", []security.EncryptionKey{newTestKey(t, "k1", algorithm)})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			encrypted, err := k.Encrypt([]byte("secret"), testContext)
+			assert.NoError(t, err)
+			assert.NotContains(t, encrypted, "secret")
+			assert.Equal(t, "k1", security.KeyID(encrypted))
+
+			again, err := k.Encrypt([]byte("secret"), testContext)
+			assert.NoError(t, err)
+			assert.NotEqual(t, encrypted, again, "every value must get its own data key")
+
+			plaintext, err := k.Decrypt(encrypted, testContext)
+			assert.NoError(t, err)
+			assert.Equal(t, "secret", string(plaintext))
+		})
+	}
+}
+
+func TestKeyring_Rotation(t *testing.T) {
+	k1 := newTestKey(t, "k1", security.AlgAES256GCM)
+	k2 := newTestKey(t, "k2", security.AlgXChaCha20Poly1305)
+
+	old, err := security.NewKeyring("k1", []security.EncryptionKey{k1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	encrypted, err := old.Encrypt([]byte("secret"), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	k, err := security.NewKeyring("k2", []security.EncryptionKey{k1, k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plaintext, err := k.Decrypt(encrypted, testContext)
+	assert.NoError(t, err, "keys other than the primary still decrypt")
+	assert.Equal(t, "secret", string(plaintext))
+	assert.True(t, k.NeedsRotation(encrypted))
+
+	rotated, err := k.Encrypt(plaintext, testContext)
+	assert.NoError(t, err)
+	assert.False(t, k.NeedsRotation(rotated))
+
+	_, err = old.Decrypt(rotated, testContext)
+	assert.ErrorIs(t, err, security.ErrUnknownKey)
+
+	_, err = k.Decrypt("c2VjcmV0", testContext)
+	assert.ErrorIs(t, err, security.ErrUnknownKey, "only values of a keyring decrypt")
+}
+
+func TestKeyring_DecryptTampered(t *testing.T) {
+	k1 := newTestKey(t, "k1", security.AlgAES256GCM)
+	k2 := newTestKey(t, "k2", security.AlgAES256GCM)
+	k, err := security.NewKeyring("k1", []security.EncryptionKey{k1, k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := k.Encrypt([]byte("secret"), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The key ID is authenticated, the value cannot claim another key.
+	_, err = k.Decrypt(strings.Replace(encrypted, ":k1:", ":k2:", 1), testContext)
+	assert.ErrorIs(t, err, security.ErrDecrypt)
+
+	_, err = k.Decrypt(encrypted[:len(encrypted)-4], testContext)
+	assert.ErrorIs(t, err, security.ErrDecrypt)
+}
+
+func TestNewKeyringInvalid(t *testing.T) {
+	k1 := newTestKey(t, "k1", security.AlgAES256GCM)
+
+	tests := []struct {
+		name    string
+		primary string
+		keys    []security.EncryptionKey
+	}{
+		{"Missing primary", "k2", []security.EncryptionKey{k1}},
+		{"Duplicate ID", "k1", []security.EncryptionKey{k1, k1}},
+		{"Unknown algorithm", "k1", []security.EncryptionKey{{ID: "k1", Algorithm: "rot13", Key: k1.Key}}},
+		{"Short key", "k1", []security.EncryptionKey{{ID: "k1", Algorithm: security.AlgAES256GCM, Key: k1.Key[:16]}}},
+		{"Colon in ID", "k:1", []security.EncryptionKey{{ID: "k:1", Algorithm: security.AlgAES256GCM, Key: k1.Key}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := security.NewKeyring(tt.primary, tt.keys)
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestKeyring_DecryptOtherContext(t *testing.T) {
+	k, err := security.NewKeyring("k1", []security.EncryptionKey{newTestKey(t, "k1", security.AlgAES256GCM)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	encrypted, err := k.Encrypt([]byte("secret"), testContext)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A value copied to another row does not decrypt.
+	_, err = k.Decrypt(encrypted, "user_totp.secret/2")
+	assert.ErrorIs(t, err, security.ErrDecrypt)
+}
+
+func TestEncryptedString(t *testing.T) {
+	k, err := security.NewKeyring("k1", []security.EncryptionKey{newTestKey(t, "k1", security.AlgAES256GCM)})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	secret := "secret"
+	value, err := k.EncryptedString(&secret, testContext).Value()
+	assert.NoError(t, err)
+	encrypted, ok := value.(string)
+	if !assert.True(t, ok) {
+		return
+	}
+	assert.Equal(t, "k1", security.KeyID(encrypted))
+
+	var s string
+	assert.NoError(t, k.EncryptedString(&s, testContext).Scan([]byte(encrypted)))
+	assert.Equal(t, "secret", s)
+
+	assert.ErrorIs(t, k.EncryptedString(&s, "user_totp.secret/2").Scan(encrypted), security.ErrDecrypt)
+	assert.Error(t, k.EncryptedString(&s, testContext).Scan(42))
+}
@@ -0,0 +1,105 @@
+package security
+
+import (
+	"errors"
+	"expvar"
+	"time"
+)
+
+var ErrHasherBusy = errors.New("the server is busy, try again later")
+
+// BusyError is returned by a LimitedHasher that could not get a slot within
+// its queue timeout.
+type BusyError struct {
+	RetryAfter time.Duration
+}
+
+func (e *BusyError) Error() string {
+	return ErrHasherBusy.Error()
+}
+
+func (e *BusyError) Unwrap() error {
+	return ErrHasherBusy
+}
+
+// Metrics of the LimitedHasher, published with expvar.
+var (
+	hashQueueDepth = expvar.NewInt("password_hash_queue_depth")
+	hashActive     = expvar.NewInt("password_hash_active")
+	hashRejected   = expvar.NewInt("password_hash_rejected")
+)
+
+// LimitedHasher runs at most a fixed number of hashes at once since each one
+// can take 64 MB with Argon2id, so that a burst of sign ins cannot exhaust the
+// memory. The others wait for a slot up to the queue timeout and then fail
+// with a *BusyError.
+type LimitedHasher struct {
+	hasher       Hasher
+	slots        chan struct{}
+	queueTimeout time.Duration
+}
+
+var _ Hasher = (*LimitedHasher)(nil)
+
+func NewLimitedHasher(hasher Hasher, maxConcurrency int, queueTimeout time.Duration) *LimitedHasher {
+	return &LimitedHasher{
+		hasher:       hasher,
+		slots:        make(chan struct{}, max(maxConcurrency, 1)),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Hash implements Hasher.
+func (h *LimitedHasher) Hash(plain string) (string, error) {
+	if err := h.acquire(); err != nil {
+		return "", err
+	}
+	defer h.release()
+
+	return h.hasher.Hash(plain)
+}
+
+// Verify implements Hasher.
+func (h *LimitedHasher) Verify(plain, hashed string) (bool, error) {
+	if err := h.acquire(); err != nil {
+		return false, err
+	}
+	defer h.release()
+
+	return h.hasher.Verify(plain, hashed)
+}
+
+// NeedsRehash implements Hasher. It only decodes the hash so it is not
+// limited.
+func (h *LimitedHasher) NeedsRehash(hashed string) bool {
+	return h.hasher.NeedsRehash(hashed)
+}
+
+func (h *LimitedHasher) acquire() error {
+	select {
+	case h.slots <- struct{}{}:
+		hashActive.Add(1)
+		return nil
+	default:
+	}
+
+	hashQueueDepth.Add(1)
+	defer hashQueueDepth.Add(-1)
+
+	timer := time.NewTimer(h.queueTimeout)
+	defer timer.Stop()
+
+	select {
+	case h.slots <- struct{}{}:
+		hashActive.Add(1)
+		return nil
+	case <-timer.C:
+		hashRejected.Add(1)
+		return &BusyError{RetryAfter: h.queueTimeout}
+	}
+}
+
+func (h *LimitedHasher) release() {
+	hashActive.Add(-1)
+	<-h.slots
+}
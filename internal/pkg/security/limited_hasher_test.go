@@ -0,0 +1,71 @@
+package security_test
+
+import (
+	"expvar"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+)
+
+// Hashes block until released.
+type blockingHasher struct {
+	started chan struct{}
+	release chan struct{}
+}
+
+func (h *blockingHasher) Hash(plain string) (string, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return "hashed", nil
+}
+
+func (h *blockingHasher) Verify(plain, hashed string) (bool, error) {
+	h.started <- struct{}{}
+	<-h.release
+	return true, nil
+}
+
+func (h *blockingHasher) NeedsRehash(string) bool {
+	return false
+}
+
+func TestLimitedHasher(t *testing.T) {
+	inner := &blockingHasher{started: make(chan struct{}, 2), release: make(chan struct{})}
+	hasher := security.NewLimitedHasher(inner, 1, 50*time.Millisecond)
+
+	done := make(chan error)
+	go func() {
+		_, err := hasher.Hash("first")
+		done <- err
+	}()
+	<-inner.started
+
+	// The only slot is taken, so the second hash times out in the queue.
+	queued := make(chan error)
+	go func() {
+		_, err := hasher.Verify("second", "hashed")
+		queued <- err
+	}()
+
+	assert.Eventually(t, func() bool {
+		return expvar.Get("password_hash_queue_depth").String() == "1"
+	}, time.Second, time.Millisecond)
+
+	err := <-queued
+	var busy *security.BusyError
+	if assert.ErrorAs(t, err, &busy) {
+		assert.Equal(t, 50*time.Millisecond, busy.RetryAfter)
+	}
+	assert.ErrorIs(t, err, security.ErrHasherBusy)
+	assert.Equal(t, "0", expvar.Get("password_hash_queue_depth").String())
+
+	close(inner.release)
+	assert.NoError(t, <-done)
+
+	// The slot is free again.
+	ok, err := hasher.Verify("third", "hashed")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+}
@@ -54,6 +54,20 @@ func (mr *MockHasherMockRecorder) Hash(plain any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Hash", reflect.TypeOf((*MockHasher)(nil).Hash), plain)
 }
 
+// NeedsRehash mocks base method.
+func (m *MockHasher) NeedsRehash(hashed string) bool {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "NeedsRehash", hashed)
+	ret0, _ := ret[0].(bool)
+	return ret0
+}
+
+// NeedsRehash indicates an expected call of NeedsRehash.
+func (mr *MockHasherMockRecorder) NeedsRehash(hashed any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "NeedsRehash", reflect.TypeOf((*MockHasher)(nil).NeedsRehash), hashed)
+}
+
 // Verify mocks base method.
 func (m *MockHasher) Verify(plain, hashed string) (bool, error) {
 	m.ctrl.T.Helper()
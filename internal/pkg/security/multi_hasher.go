@@ -0,0 +1,205 @@
+package security
+
+import (
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+	"golang.org/x/crypto/scrypt"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash")
+
+// MultiHasher verifies the password hashes of other systems so that their
+// users can be imported as they are. New hashes are always Argon2id, and the
+// others always need a rehash so that they are replaced as users sign in.
+//
+// Supported are Argon2id, bcrypt ($2a$, $2b$ and $2y$) and the scrypt and
+// PBKDF2-SHA256 formats of passlib ($scrypt$ and $pbkdf2-sha256$).
+type MultiHasher struct {
+	Argon2 *Argon2Hasher
+}
+
+var _ Hasher = (*MultiHasher)(nil)
+
+func NewMultiHasher(argon2 *Argon2Hasher) *MultiHasher {
+	return &MultiHasher{Argon2: argon2}
+}
+
+// Hash implements Hasher.
+func (h *MultiHasher) Hash(plain string) (string, error) {
+	return h.Argon2.Hash(plain)
+}
+
+// Verify implements Hasher.
+func (h *MultiHasher) Verify(plain, hashed string) (bool, error) {
+	switch hashAlgorithm(hashed) {
+	case "argon2id":
+		return h.Argon2.Verify(plain, hashed)
+	case "2a", "2b", "2y":
+		return verifyBcrypt(plain, hashed)
+	case "scrypt":
+		return verifyScrypt(plain, hashed)
+	case "pbkdf2-sha256":
+		return verifyPBKDF2(plain, hashed)
+	default:
+		return false, ErrUnsupportedHash
+	}
+}
+
+// NeedsRehash implements Hasher.
+func (h *MultiHasher) NeedsRehash(hashed string) bool {
+	return h.Argon2.NeedsRehash(hashed)
+}
+
+// CheckHash returns an error if hashed is not a hash that Verify supports,
+// without verifying anything.
+func (h *MultiHasher) CheckHash(hashed string) error {
+	var err error
+	switch hashAlgorithm(hashed) {
+	case "argon2id":
+		_, _, _, err = decodeArgon2Hash(hashed)
+	case "2a", "2b", "2y":
+		_, err = bcrypt.Cost([]byte(bcryptHash(hashed)))
+	case "scrypt":
+		_, err = decodeScryptHash(hashed)
+	case "pbkdf2-sha256":
+		_, err = decodePBKDF2Hash(hashed)
+	default:
+		return ErrUnsupportedHash
+	}
+	if err != nil {
+		return fmt.Errorf("%w: %w", ErrUnsupportedHash, err)
+	}
+	return nil
+}
+
+// Returns the identifier between the first two $ of a PHC string.
+func hashAlgorithm(hashed string) string {
+	rest, ok := strings.CutPrefix(hashed, "$")
+	if !ok {
+		return ""
+	}
+	id, _, _ := strings.Cut(rest, "$")
+	return id
+}
+
+// $2y$ is what PHP calls $2b$, the algorithm is the same.
+func bcryptHash(hashed string) string {
+	if rest, ok := strings.CutPrefix(hashed, "$2y$"); ok {
+		return "$2b$" + rest
+	}
+	return hashed
+}
+
+func verifyBcrypt(plain, hashed string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(bcryptHash(hashed)), []byte(plain))
+	// bcrypt refuses passwords over 72 bytes, so such a password cannot be
+	// the one that was hashed.
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) || errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("verify bcrypt hash: %w", err)
+	}
+	return true, nil
+}
+
+type scryptHash struct {
+	logN, r, p int
+	salt, key  []byte
+}
+
+// Decodes $scrypt$ln=<log2 N>,r=<r>,p=<p>$<salt>$<key>.
+func decodeScryptHash(hashed string) (*scryptHash, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 5 {
+		return nil, errors.New("invalid scrypt hash format")
+	}
+
+	var h scryptHash
+	if _, err := fmt.Sscanf(parts[2], "ln=%d,r=%d,p=%d", &h.logN, &h.r, &h.p); err != nil {
+		return nil, fmt.Errorf("parse scrypt parameters: %w", err)
+	}
+	if h.logN < 1 || h.logN > 30 || h.r < 1 || h.p < 1 {
+		return nil, fmt.Errorf("invalid scrypt parameters %q", parts[2])
+	}
+
+	var err error
+	if h.salt, err = decodeAdaptedBase64(parts[3]); err != nil {
+		return nil, fmt.Errorf("decode scrypt salt: %w", err)
+	}
+	if h.key, err = decodeAdaptedBase64(parts[4]); err != nil {
+		return nil, fmt.Errorf("decode scrypt key: %w", err)
+	}
+	if len(h.key) == 0 {
+		return nil, errors.New("empty scrypt key")
+	}
+	return &h, nil
+}
+
+func verifyScrypt(plain, hashed string) (bool, error) {
+	h, err := decodeScryptHash(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	key, err := scrypt.Key([]byte(plain), h.salt, 1<<h.logN, h.r, h.p, len(h.key))
+	if err != nil {
+		return false, fmt.Errorf("compute scrypt key: %w", err)
+	}
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+type pbkdf2Hash struct {
+	iterations int
+	salt, key  []byte
+}
+
+// Decodes $pbkdf2-sha256$<iterations>$<salt>$<key>. The iterations may also
+// be given as i=<iterations>.
+func decodePBKDF2Hash(hashed string) (*pbkdf2Hash, error) {
+	parts := strings.Split(hashed, "$")
+	if len(parts) != 5 {
+		return nil, errors.New("invalid pbkdf2 hash format")
+	}
+
+	var h pbkdf2Hash
+	var err error
+	if h.iterations, err = strconv.Atoi(strings.TrimPrefix(parts[2], "i=")); err != nil || h.iterations < 1 {
+		return nil, fmt.Errorf("invalid pbkdf2 iterations %q", parts[2])
+	}
+	if h.salt, err = decodeAdaptedBase64(parts[3]); err != nil {
+		return nil, fmt.Errorf("decode pbkdf2 salt: %w", err)
+	}
+	if h.key, err = decodeAdaptedBase64(parts[4]); err != nil {
+		return nil, fmt.Errorf("decode pbkdf2 key: %w", err)
+	}
+	if len(h.key) == 0 {
+		return nil, errors.New("empty pbkdf2 key")
+	}
+	return &h, nil
+}
+
+func verifyPBKDF2(plain, hashed string) (bool, error) {
+	h, err := decodePBKDF2Hash(hashed)
+	if err != nil {
+		return false, err
+	}
+
+	key := pbkdf2.Key([]byte(plain), h.salt, h.iterations, len(h.key), sha256.New)
+	return subtle.ConstantTimeCompare(key, h.key) == 1, nil
+}
+
+// Decodes the base64 of passlib, which uses . instead of + and no padding.
+// Standard base64, with or without padding, is accepted too.
+func decodeAdaptedBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(strings.ReplaceAll(s, ".", "+"), "=")
+	return base64.RawStdEncoding.DecodeString(s)
+}
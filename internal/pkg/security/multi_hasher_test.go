@@ -0,0 +1,97 @@
+package security_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func newMultiHasher() *security.MultiHasher {
+	return security.NewMultiHasher(security.NewArgon2Hasher(security.Argon2Params{
+		Memory: 1024, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32,
+	}))
+}
+
+func TestMultiHasher_Verify(t *testing.T) {
+	hasher := newMultiHasher()
+
+	argon2Hash, err := hasher.Hash("password")
+	if err != nil {
+		t.Fatal(err)
+	}
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		hashed string
+	}{
+		{"Argon2id", argon2Hash},
+		{"bcrypt", string(bcryptHash)},
+		{"bcrypt of PHP", "$2y$" + strings.TrimPrefix(string(bcryptHash), "$2a$")},
+		// From the passlib documentation
+		{"scrypt", "$scrypt$ln=16,r=8,p=1$aM15713r3Xsvxbi31lqr1Q$nFNh2CVHVjNldFVKDHDlm4CbdRSCdEBsjjJxD+iCs5E"},
+		// Computed with hashlib.pbkdf2_hmac of Python
+		{"PBKDF2-SHA256", "$pbkdf2-sha256$29000$N2YMIWQsBWBMae09x1jrPQ$lEjTD4tvq5SOB5ctjdSxvnbzU5PQEfMComvCIxNEqq8"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.NoError(t, hasher.CheckHash(tt.hashed))
+
+			ok, err := hasher.Verify("password", tt.hashed)
+			assert.NoError(t, err)
+			assert.True(t, ok)
+
+			ok, err = hasher.Verify("wrong", tt.hashed)
+			assert.NoError(t, err)
+			assert.False(t, ok)
+		})
+	}
+}
+
+func TestMultiHasher_VerifyBcryptTooLong(t *testing.T) {
+	hasher := newMultiHasher()
+
+	bcryptHash, err := bcrypt.GenerateFromPassword([]byte("password"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ok, err := hasher.Verify(strings.Repeat("a", 73), string(bcryptHash))
+	assert.NoError(t, err, "a password too long for bcrypt is a wrong password")
+	assert.False(t, ok)
+}
+
+func TestMultiHasher_HashIsArgon2id(t *testing.T) {
+	hasher := newMultiHasher()
+
+	hashed, err := hasher.Hash("password")
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(hashed, "$argon2id$"))
+	assert.False(t, hasher.NeedsRehash(hashed))
+	assert.True(t, hasher.NeedsRehash("$pbkdf2-sha256$29000$N2YMIWQsBWBMae09x1jrPQ$lEjTD4tvq5SOB5ctjdSxvnbzU5PQEfMComvCIxNEqq8"),
+		"legacy hashes are replaced")
+}
+
+func TestMultiHasher_Unsupported(t *testing.T) {
+	hasher := newMultiHasher()
+
+	for _, hashed := range []string{
+		"",
+		"5f4dcc3b5aa765d61d8327deb882cf99",
+		"$1$saltsalt$qjXMvbEw8oaL.CzflDugX/",
+		"$pbkdf2-sha256$0$c2FsdA$a2V5",
+		"$scrypt$ln=16$c2FsdA$a2V5",
+		"$2b$10$short",
+	} {
+		assert.ErrorIs(t, hasher.CheckHash(hashed), security.ErrUnsupportedHash, hashed)
+		_, err := hasher.Verify("password", hashed)
+		assert.Error(t, err, hashed)
+	}
+}
@@ -0,0 +1,49 @@
+package security
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+)
+
+// Prefix of every API key so that leaked keys are easy to recognize
+const APIKeyPrefix = "gwk_"
+
+// Length in bytes of opaque tokens such as session ids
+const TokenLength = 32
+
+// GenerateToken returns a random url-safe token and its hash for storage.
+func GenerateToken() (token, hash string, err error) {
+	token, err = GenerateRandomBytesEncoded(TokenLength)
+	if err != nil {
+		return "", "", err
+	}
+	return token, HashToken(token), nil
+}
+
+// HashToken returns the hex encoded SHA-256 digest of a token.
+//
+// Tokens are high-entropy random values so a fast hash is enough to keep them
+// from being usable if the database is leaked.
+func HashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// GenerateAPIKey returns a random API key, the part of it that may be shown
+// to identify the key, and its hash for storage.
+func GenerateAPIKey() (key, prefix, hash string, err error) {
+	id, err := GenerateRandomBytes(4)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	secret, err := GenerateRandomBytes(TokenLength)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	prefix = APIKeyPrefix + hex.EncodeToString(id)
+	key = prefix + "_" + base64.RawURLEncoding.EncodeToString(secret)
+	return key, prefix, HashToken(key), nil
+}
@@ -0,0 +1,78 @@
+package security_test
+
+import (
+	"bytes"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/stretchr/testify/assert"
+)
+
+// Base32 of the ASCII seed "12345678901234567890" of RFC 6238 appendix B
+const rfcSecret = "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ"
+
+func TestTOTPCode(t *testing.T) {
+	// The last six digits of the SHA1 test vectors of RFC 6238
+	var tests = []struct {
+		unix int64
+		want string
+	}{
+		{59, "287082"},
+		{1111111109, "081804"},
+		{1234567890, "005924"},
+		{2000000000, "279037"},
+	}
+
+	for _, tt := range tests {
+		code, err := security.TOTPCode(rfcSecret, security.TOTPStep(time.Unix(tt.unix, 0)))
+		assert.NoError(t, err)
+		assert.Equal(t, tt.want, code)
+	}
+}
+
+func TestValidateTOTP(t *testing.T) {
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	previous, err := security.TOTPCode(secret, security.TOTPStep(now)-1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	step, ok, err := security.ValidateTOTP(secret, previous, now, 1)
+	assert.NoError(t, err)
+	assert.True(t, ok, "a code of the previous step is within the skew")
+	assert.Equal(t, security.TOTPStep(now)-1, step)
+
+	_, ok, err = security.ValidateTOTP(secret, previous, now, 0)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	_, ok, _ = security.ValidateTOTP(secret, "12345", now, 1)
+	assert.False(t, ok)
+}
+
+func TestTOTPURI(t *testing.T) {
+	uri := security.TOTPURI("GoWeb", "abc@example.com", rfcSecret)
+
+	u, err := url.Parse(uri)
+	if err != nil {
+		t.Fatal(err)
+	}
+	assert.Equal(t, "otpauth", u.Scheme)
+	assert.Equal(t, "totp", u.Host)
+	assert.Equal(t, "/GoWeb:abc@example.com", u.Path)
+	assert.Equal(t, rfcSecret, u.Query().Get("secret"))
+	assert.Equal(t, "GoWeb", u.Query().Get("issuer"))
+}
+
+func TestQRCodePNG(t *testing.T) {
+	png, err := security.QRCodePNG(security.TOTPURI("GoWeb", "abc@example.com", rfcSecret))
+	assert.NoError(t, err)
+	assert.True(t, bytes.HasPrefix(png, []byte("\x89PNG\r\n\x1a\n")))
+}
@@ -0,0 +1,124 @@
+package validation
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/password"
+	"github.com/go-playground/validator/v10"
+)
+
+// Tags of the checks of the password policy. The password tag is an alias of
+// those that the policy enables, so a failing check is reported under its own
+// tag as the actual tag of the error.
+const (
+	PasswordTag         = "password"
+	PasswordClassesTag  = "password_classes"
+	PasswordStrengthTag = "password_strength"
+	PasswordEmailTag    = "password_email"
+	PasswordBreachedTag = "password_breached"
+)
+
+// WithPasswordPolicy makes the password tag enforce policy.
+func WithPasswordPolicy(policy *password.Policy) Option {
+	return func(validate *validator.Validate) {
+		if policy.Breached != nil {
+			registerBreachedTag(validate, policy.Breached)
+		}
+		registerPasswordAlias(validate, policy)
+	}
+}
+
+func registerPasswordAlias(validate *validator.Validate, policy *password.Policy) {
+	var tags []string
+	if policy != nil {
+		if policy.MinLength > 0 {
+			tags = append(tags, "min="+strconv.Itoa(policy.MinLength))
+		}
+		if policy.MaxLength > 0 {
+			tags = append(tags, "max="+strconv.Itoa(policy.MaxLength))
+		}
+		if len(policy.Classes) > 0 {
+			tags = append(tags, PasswordClassesTag+"="+strings.Join(policy.Classes, " "))
+		}
+	}
+	// The cheap checks go first since only the first failure is reported.
+	tags = append(tags, PasswordEmailTag)
+	if policy != nil {
+		if policy.MinStrength > 0 {
+			tags = append(tags, PasswordStrengthTag+"="+strconv.Itoa(policy.MinStrength))
+		}
+		if policy.Breached != nil {
+			tags = append(tags, PasswordBreachedTag)
+		}
+	}
+
+	validate.RegisterAlias(PasswordTag, strings.Join(tags, ","))
+}
+
+func registerPasswordTags(validate *validator.Validate) {
+	mustRegister(validate, PasswordClassesTag, func(fl validator.FieldLevel) bool {
+		for _, class := range strings.Fields(fl.Param()) {
+			ok, err := password.HasClass(fl.Field().String(), class)
+			if err != nil {
+				panic(fmt.Sprintf("%s: %v", PasswordClassesTag, err))
+			}
+			if !ok {
+				return false
+			}
+		}
+		return true
+	})
+
+	mustRegister(validate, PasswordStrengthTag, func(fl validator.FieldLevel) bool {
+		minScore, err := strconv.Atoi(fl.Param())
+		if err != nil {
+			panic(fmt.Sprintf("%s: invalid score %q", PasswordStrengthTag, fl.Param()))
+		}
+		estimate := password.Strength(fl.Field().String(), password.EmailInputs(siblingEmail(fl))...)
+		return estimate.Score >= minScore
+	})
+
+	mustRegister(validate, PasswordEmailTag, func(fl validator.FieldLevel) bool {
+		return !password.ContainsEmail(fl.Field().String(), siblingEmail(fl))
+	})
+}
+
+// Breached passwords are let through when the list cannot be read so that
+// users are not locked out of registering by a broken disk.
+func registerBreachedTag(validate *validator.Validate, list *password.BreachList) {
+	mustRegister(validate, PasswordBreachedTag, func(fl validator.FieldLevel) bool {
+		breached, err := list.Contains(fl.Field().String())
+		if err != nil {
+			slog.Error("check breached passwords", "reason", err)
+			return true
+		}
+		return !breached
+	})
+}
+
+func mustRegister(validate *validator.Validate, tag string, fn validator.Func) {
+	if err := validate.RegisterValidation(tag, fn); err != nil {
+		panic(fmt.Sprintf("register %s: %v", tag, err))
+	}
+}
+
+// Returns the Email field of the struct the password is in, if any.
+func siblingEmail(fl validator.FieldLevel) string {
+	parent := fl.Parent()
+	for parent.Kind() == reflect.Pointer {
+		parent = parent.Elem()
+	}
+	if parent.Kind() != reflect.Struct {
+		return ""
+	}
+
+	email := parent.FieldByName("Email")
+	if !email.IsValid() || email.Kind() != reflect.String {
+		return ""
+	}
+	return email.String()
+}
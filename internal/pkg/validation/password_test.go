@@ -0,0 +1,78 @@
+package validation_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/password"
+	"github.com/ferdiebergado/goweb/internal/pkg/validation"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+)
+
+type registration struct {
+	Email    string `json:"email" validate:"required,email"`
+	Password string `json:"password" validate:"required,password"`
+}
+
+func TestPasswordPolicy(t *testing.T) {
+	dir := t.TempDir()
+	// SHA-1 of "Breached-Passw0rd-42" starts with BEB41.
+	if err := os.WriteFile(filepath.Join(dir, "BEB41.txt"),
+		[]byte("AD0FA25200920086B72216046B7237B79E8:12\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	list, err := password.NewBreachList(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	validate := validation.New(validation.WithPasswordPolicy(&password.Policy{
+		MinLength:   12,
+		MaxLength:   64,
+		Classes:     []string{password.ClassLower, password.ClassDigit},
+		MinStrength: 3,
+		Breached:    list,
+	}))
+
+	tests := []struct {
+		name     string
+		password string
+		tag      string
+	}{
+		{"Valid", "v7qk-mzr2-tbx9", ""},
+		{"Too short", "v7qk-mzr2", "min"},
+		{"Too long", strings.Repeat("v7qk-mzr2-", 7), "max"},
+		{"Missing class", "VQKM-ZRTB-XWPL", validation.PasswordClassesTag},
+		{"Contains email", "jane.doe-v7qk-mzr2", validation.PasswordEmailTag},
+		{"Too weak", "password1234", validation.PasswordStrengthTag},
+		{"Breached", "Breached-Passw0rd-42", validation.PasswordBreachedTag},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := validate.Struct(registration{Email: "jane.doe@example.com", Password: tt.password})
+			if tt.tag == "" {
+				assert.NoError(t, err)
+				return
+			}
+
+			var errs validator.ValidationErrors
+			if !errors.As(err, &errs) {
+				t.Fatalf("got %v, want validation errors", err)
+			}
+			assert.Len(t, errs, 1)
+			assert.Equal(t, validation.PasswordTag, errs[0].Tag())
+			assert.Equal(t, tt.tag, errs[0].ActualTag())
+		})
+	}
+}
+
+func TestPasswordWithoutPolicy(t *testing.T) {
+	validate := validation.New()
+
+	assert.NoError(t, validate.Struct(registration{Email: "jane.doe@example.com", Password: "x"}))
+	assert.Error(t, validate.Struct(registration{Email: "jane.doe@example.com", Password: "jane.doe1"}))
+}
@@ -7,7 +7,12 @@ import (
 	"github.com/go-playground/validator/v10"
 )
 
-func New() *validator.Validate {
+type Option func(*validator.Validate)
+
+// New returns a validator that names fields after their json tags and knows
+// the password tag. Without WithPasswordPolicy the password tag only checks
+// that the password does not contain the email.
+func New(opts ...Option) *validator.Validate {
 	validate := validator.New()
 
 	// register function to get tag name from json tags.
@@ -19,5 +24,12 @@ func New() *validator.Validate {
 		return name
 	})
 
+	registerPasswordTags(validate)
+	registerPasswordAlias(validate, nil)
+
+	for _, opt := range opts {
+		opt(validate)
+	}
+
 	return validate
 }
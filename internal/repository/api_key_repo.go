@@ -0,0 +1,143 @@
+//go:generate mockgen -destination=mock/api_key_repo_mock.go -package=mock . APIKeyRepo
+package repository
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type APIKeyRepo interface {
+	CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*model.APIKey, error)
+	ListUserAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	FindActiveAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error)
+	TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error
+	RevokeAPIKey(ctx context.Context, userID, id string) (bool, error)
+}
+
+type apiKeyRepo struct {
+	db DBTX
+}
+
+var _ APIKeyRepo = (*apiKeyRepo)(nil)
+
+func NewAPIKeyRepository(db DBTX) APIKeyRepo {
+	return &apiKeyRepo{db: db}
+}
+
+type CreateAPIKeyParams struct {
+	UserID    string
+	Name      string
+	Prefix    string
+	KeyHash   string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+const CreateAPIKeyQuery = `
+INSERT INTO api_keys (user_id, name, prefix, key_hash, scopes, expires_at)
+VALUES ($1, $2, $3, $4, $5, $6)
+RETURNING id, created_at
+`
+
+func (r *apiKeyRepo) CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*model.APIKey, error) {
+	key := model.APIKey{
+		UserID:    params.UserID,
+		Name:      params.Name,
+		Prefix:    params.Prefix,
+		Scopes:    params.Scopes,
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := r.db.QueryRowContext(ctx, CreateAPIKeyQuery, params.UserID, params.Name, params.Prefix,
+		params.KeyHash, strings.Join(params.Scopes, " "), params.ExpiresAt).
+		Scan(&key.ID, &key.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &key, nil
+}
+
+const ListUserAPIKeysQuery = `
+SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at FROM api_keys
+WHERE user_id = $1 AND revoked_at IS NULL
+ORDER BY created_at
+`
+
+func (r *apiKeyRepo) ListUserAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	rows, err := r.db.QueryContext(ctx, ListUserAPIKeysQuery, userID)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var keys []model.APIKey
+	for rows.Next() {
+		var key model.APIKey
+		var scopes string
+		if err := rows.Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopes, &key.ExpiresAt,
+			&key.LastUsedAt, &key.CreatedAt); err != nil {
+			return nil, err
+		}
+		key.Scopes = strings.Fields(scopes)
+		keys = append(keys, key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+const FindActiveAPIKeyByHashQuery = `
+SELECT id, user_id, name, prefix, scopes, expires_at, last_used_at, created_at FROM api_keys
+WHERE key_hash = $1 AND revoked_at IS NULL
+	AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+LIMIT 1
+`
+
+// FindActiveAPIKeyByHash returns the key unless it is revoked or expired.
+func (r *apiKeyRepo) FindActiveAPIKeyByHash(ctx context.Context, keyHash string) (*model.APIKey, error) {
+	var key model.APIKey
+	var scopes string
+	if err := r.db.QueryRowContext(ctx, FindActiveAPIKeyByHashQuery, keyHash).
+		Scan(&key.ID, &key.UserID, &key.Name, &key.Prefix, &scopes, &key.ExpiresAt, &key.LastUsedAt,
+			&key.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	key.Scopes = strings.Fields(scopes)
+	return &key, nil
+}
+
+const TouchAPIKeyQuery = `
+UPDATE api_keys
+SET last_used_at = $2
+WHERE id = $1 AND (last_used_at IS NULL OR last_used_at < $2)
+`
+
+// TouchAPIKey records the last use of a key. It never moves last_used_at
+// back when concurrent requests race.
+func (r *apiKeyRepo) TouchAPIKey(ctx context.Context, id string, usedAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, TouchAPIKeyQuery, id, usedAt)
+	return mapError(err)
+}
+
+const RevokeAPIKeyQuery = `
+UPDATE api_keys
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL
+`
+
+// RevokeAPIKey reports whether a key of the user was revoked.
+func (r *apiKeyRepo) RevokeAPIKey(ctx context.Context, userID, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, RevokeAPIKeyQuery, id, userID)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
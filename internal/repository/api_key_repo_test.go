@@ -0,0 +1,60 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAPIKeyRepo_CreateAPIKey(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateAPIKeyParams{
+		UserID:  "1",
+		Name:    "ci",
+		Prefix:  "gwk_12345678",
+		KeyHash: "hashed",
+		Scopes:  []string{model.PermUsersRead, model.PermUsersWrite},
+	}
+
+	mock.ExpectQuery(repository.CreateAPIKeyQuery).
+		WithArgs(params.UserID, params.Name, params.Prefix, params.KeyHash, "users:read users:write", params.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("2", time.Now()))
+
+	repo := repository.NewAPIKeyRepository(db)
+	key, err := repo.CreateAPIKey(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", key.ID)
+	assert.Equal(t, params.Scopes, key.Scopes)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestAPIKeyRepo_FindActiveAPIKeyByHash(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(repository.FindActiveAPIKeyByHashQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "name", "prefix", "scopes", "expires_at",
+			"last_used_at", "created_at"}).
+			AddRow("2", "1", "ci", "gwk_12345678", "", nil, nil, time.Now()))
+
+	repo := repository.NewAPIKeyRepository(db)
+	key, err := repo.FindActiveAPIKeyByHash(context.Background(), "hashed")
+	assert.NoError(t, err)
+	assert.Empty(t, key.Scopes)
+	assert.True(t, key.HasScope(model.PermUsersRead), "a key without scopes has every permission")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
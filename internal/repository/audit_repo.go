@@ -0,0 +1,58 @@
+//go:generate mockgen -destination=mock/audit_repo_mock.go -package=mock . AuditRepo
+package repository
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type AuditRepo interface {
+	CreateAuditEntry(ctx context.Context, params CreateAuditEntryParams) (*model.AuditEntry, error)
+}
+
+type auditRepo struct {
+	db DBTX
+}
+
+var _ AuditRepo = (*auditRepo)(nil)
+
+func NewAuditRepository(db DBTX) AuditRepo {
+	return &auditRepo{db: db}
+}
+
+// CreateAuditEntryParams leave UserID empty when the action is not tied to a
+// known user.
+type CreateAuditEntryParams struct {
+	UserID    string
+	Action    string
+	IPAddress string
+	Details   json.RawMessage
+}
+
+const CreateAuditEntryQuery = `
+INSERT INTO audit_logs (user_id, action, ip_address, details)
+VALUES (NULLIF($1, '')::uuid, $2, NULLIF($3, ''), $4)
+RETURNING id, created_at
+`
+
+func (r *auditRepo) CreateAuditEntry(ctx context.Context, params CreateAuditEntryParams) (*model.AuditEntry, error) {
+	details := params.Details
+	if details == nil {
+		details = json.RawMessage(`{}`)
+	}
+
+	entry := model.AuditEntry{
+		UserID:    params.UserID,
+		Action:    params.Action,
+		IPAddress: params.IPAddress,
+		Details:   details,
+	}
+	if err := r.db.QueryRowContext(ctx, CreateAuditEntryQuery,
+		params.UserID, params.Action, params.IPAddress, details).
+		Scan(&entry.ID, &entry.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &entry, nil
+}
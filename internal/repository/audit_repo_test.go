@@ -0,0 +1,37 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestAuditRepo_CreateAuditEntry(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateAuditEntryParams{
+		Action:    model.AuditLoginLocked,
+		IPAddress: "192.0.2.1",
+		Details:   json.RawMessage(`{"scope":"ip"}`),
+	}
+
+	mock.ExpectQuery(repository.CreateAuditEntryQuery).
+		WithArgs("", params.Action, params.IPAddress, params.Details).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("1", time.Now()))
+
+	repo := repository.NewAuditRepository(db)
+	entry, err := repo.CreateAuditEntry(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", entry.ID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,58 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Errors returned by repositories in place of driver specific errors so that
+// services can react to them without depending on the database driver.
+var (
+	ErrUniqueViolation      = errors.New("unique violation")
+	ErrForeignKeyViolation  = errors.New("foreign key violation")
+	ErrCheckViolation       = errors.New("check violation")
+	ErrSerializationFailure = errors.New("serialization failure")
+	ErrInvalidText          = errors.New("invalid text representation")
+)
+
+// Postgres SQLSTATE codes, see https://www.postgresql.org/docs/current/errcodes-appendix.html
+const (
+	codeUniqueViolation      = "23505"
+	codeForeignKeyViolation  = "23503"
+	codeCheckViolation       = "23514"
+	codeSerializationFailure = "40001"
+	// Raised for instance when a malformed UUID is compared to a UUID column.
+	codeInvalidText = "22P02"
+)
+
+// mapError translates a Postgres error into one of the repository errors. The
+// original error stays in the chain so its details are not lost.
+func mapError(err error) error {
+	var pgErr *pgconn.PgError
+	if !errors.As(err, &pgErr) {
+		return err
+	}
+
+	var target error
+	switch pgErr.Code {
+	case codeUniqueViolation:
+		target = ErrUniqueViolation
+	case codeForeignKeyViolation:
+		target = ErrForeignKeyViolation
+	case codeCheckViolation:
+		target = ErrCheckViolation
+	case codeSerializationFailure:
+		target = ErrSerializationFailure
+	case codeInvalidText:
+		target = ErrInvalidText
+	default:
+		return err
+	}
+
+	if pgErr.ConstraintName != "" {
+		return fmt.Errorf("%w on %s: %w", target, pgErr.ConstraintName, err)
+	}
+	return fmt.Errorf("%w: %w", target, err)
+}
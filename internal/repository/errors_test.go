@@ -0,0 +1,72 @@
+package repository_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUserRepo_CreateUserMapsPgErrors(t *testing.T) {
+	tests := []struct {
+		code string
+		want error
+	}{
+		{"23505", repository.ErrUniqueViolation},
+		{"23503", repository.ErrForeignKeyViolation},
+		{"23514", repository.ErrCheckViolation},
+		{"40001", repository.ErrSerializationFailure},
+		{"22P02", repository.ErrInvalidText},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.code, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			pgErr := &pgconn.PgError{Code: tt.code, ConstraintName: "users_email_key"}
+			mock.ExpectQuery(repository.CreateUserQuery).
+				WithArgs("abc@example.com", "hashed").
+				WillReturnError(pgErr)
+
+			repo := repository.NewUserRepository(db)
+			_, err = repo.CreateUser(context.Background(), repository.CreateUserParams{
+				Email:        "abc@example.com",
+				PasswordHash: "hashed",
+			})
+			assert.ErrorIs(t, err, tt.want)
+
+			var target *pgconn.PgError
+			assert.True(t, errors.As(err, &target), "original error must stay in the chain")
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestUserRepo_CreateUserPassesOtherErrors(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	pgErr := &pgconn.PgError{Code: "57014"}
+	mock.ExpectQuery(repository.CreateUserQuery).
+		WithArgs("abc@example.com", "hashed").
+		WillReturnError(pgErr)
+
+	repo := repository.NewUserRepository(db)
+	_, err = repo.CreateUser(context.Background(), repository.CreateUserParams{
+		Email:        "abc@example.com",
+		PasswordHash: "hashed",
+	})
+	assert.Same(t, pgErr, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
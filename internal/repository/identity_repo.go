@@ -0,0 +1,108 @@
+//go:generate mockgen -destination=mock/identity_repo_mock.go -package=mock . IdentityRepo
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type IdentityRepo interface {
+	CreateIdentity(ctx context.Context, params CreateIdentityParams) (*model.Identity, error)
+	FindIdentity(ctx context.Context, provider, subject string) (*model.Identity, error)
+	CreateOIDCFlow(ctx context.Context, params CreateOIDCFlowParams) error
+	ConsumeOIDCFlow(ctx context.Context, stateHash string) (*model.OIDCFlow, error)
+}
+
+type identityRepo struct {
+	db DBTX
+}
+
+var _ IdentityRepo = (*identityRepo)(nil)
+
+func NewIdentityRepository(db DBTX) IdentityRepo {
+	return &identityRepo{db: db}
+}
+
+type CreateIdentityParams struct {
+	UserID   string
+	Provider string
+	Subject  string
+	Email    string
+}
+
+const CreateIdentityQuery = `
+INSERT INTO user_identities (user_id, provider, subject, email)
+VALUES ($1, $2, $3, NULLIF($4, ''))
+RETURNING id, created_at
+`
+
+func (r *identityRepo) CreateIdentity(ctx context.Context, params CreateIdentityParams) (*model.Identity, error) {
+	identity := model.Identity{
+		UserID:   params.UserID,
+		Provider: params.Provider,
+		Subject:  params.Subject,
+		Email:    params.Email,
+	}
+	if err := r.db.QueryRowContext(ctx, CreateIdentityQuery,
+		params.UserID, params.Provider, params.Subject, params.Email).
+		Scan(&identity.ID, &identity.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &identity, nil
+}
+
+const FindIdentityQuery = `
+SELECT id, user_id, provider, subject, COALESCE(email, ''), created_at FROM user_identities
+WHERE provider = $1 AND subject = $2
+LIMIT 1
+`
+
+func (r *identityRepo) FindIdentity(ctx context.Context, provider, subject string) (*model.Identity, error) {
+	var identity model.Identity
+	if err := r.db.QueryRowContext(ctx, FindIdentityQuery, provider, subject).
+		Scan(&identity.ID, &identity.UserID, &identity.Provider, &identity.Subject, &identity.Email,
+			&identity.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &identity, nil
+}
+
+type CreateOIDCFlowParams struct {
+	StateHash    string
+	Provider     string
+	Nonce        string
+	CodeVerifier string
+	ExpiresAt    time.Time
+}
+
+const CreateOIDCFlowQuery = `
+INSERT INTO oidc_flows (state_hash, provider, nonce, code_verifier, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+`
+
+func (r *identityRepo) CreateOIDCFlow(ctx context.Context, params CreateOIDCFlowParams) error {
+	_, err := r.db.ExecContext(ctx, CreateOIDCFlowQuery,
+		params.StateHash, params.Provider, params.Nonce, params.CodeVerifier, params.ExpiresAt)
+	return mapError(err)
+}
+
+// Flows are deleted when they are completed so that a state cannot be
+// replayed.
+const ConsumeOIDCFlowQuery = `
+DELETE FROM oidc_flows
+WHERE state_hash = $1
+RETURNING id, state_hash, provider, nonce, code_verifier, expires_at, created_at
+`
+
+// ConsumeOIDCFlow removes and returns the flow with stateHash.
+func (r *identityRepo) ConsumeOIDCFlow(ctx context.Context, stateHash string) (*model.OIDCFlow, error) {
+	var flow model.OIDCFlow
+	if err := r.db.QueryRowContext(ctx, ConsumeOIDCFlowQuery, stateHash).
+		Scan(&flow.ID, &flow.StateHash, &flow.Provider, &flow.Nonce, &flow.CodeVerifier, &flow.ExpiresAt,
+			&flow.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &flow, nil
+}
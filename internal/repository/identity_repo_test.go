@@ -0,0 +1,64 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestIdentityRepo_CreateIdentity(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateIdentityParams{
+		UserID:   "1",
+		Provider: "example",
+		Subject:  "subject-1",
+		Email:    "abc@example.com",
+	}
+
+	mock.ExpectQuery(repository.CreateIdentityQuery).
+		WithArgs(params.UserID, params.Provider, params.Subject, params.Email).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "created_at"}).AddRow("2", time.Now()))
+
+	repo := repository.NewIdentityRepository(db)
+	identity, err := repo.CreateIdentity(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", identity.ID)
+	assert.Equal(t, params.Subject, identity.Subject)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestIdentityRepo_ConsumeOIDCFlow(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	columns := []string{"id", "state_hash", "provider", "nonce", "code_verifier", "expires_at", "created_at"}
+	mock.ExpectQuery(repository.ConsumeOIDCFlowQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows(columns).
+			AddRow("1", "hashed", "example", "nonce", "verifier", time.Now().Add(time.Minute), time.Now()))
+	mock.ExpectQuery(repository.ConsumeOIDCFlowQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows(columns))
+
+	repo := repository.NewIdentityRepository(db)
+	flow, err := repo.ConsumeOIDCFlow(context.Background(), "hashed")
+	assert.NoError(t, err)
+	assert.Equal(t, "verifier", flow.CodeVerifier)
+
+	_, err = repo.ConsumeOIDCFlow(context.Background(), "hashed")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,191 @@
+//go:generate mockgen -destination=mock/job_repo_mock.go -package=mock . JobRepo
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type JobRepo interface {
+	EnqueueJob(ctx context.Context, params EnqueueJobParams) (string, error)
+	ClaimJobs(ctx context.Context, workerID string, limit int) ([]model.Job, error)
+	CompleteJob(ctx context.Context, id, workerID string) (bool, error)
+	RetryJob(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error)
+	KillJob(ctx context.Context, id, workerID string, lastError string) (bool, error)
+	RequeueStaleJobs(ctx context.Context, lockedBefore time.Time, maxAttempts int) (*RequeueStaleJobsResult, error)
+}
+
+type jobRepo struct {
+	db DBTX
+}
+
+var _ JobRepo = (*jobRepo)(nil)
+
+// NewJobRepository returns a JobRepo that runs its queries on db. Passing a
+// *sql.Tx enqueues jobs in the same transaction as the write that produced them.
+func NewJobRepository(db DBTX) JobRepo {
+	return &jobRepo{db: db}
+}
+
+type EnqueueJobParams struct {
+	Kind    string
+	Payload json.RawMessage
+	RunAt   time.Time
+}
+
+const EnqueueJobQuery = `
+INSERT INTO jobs (kind, payload, run_at)
+VALUES ($1, $2, COALESCE($3, CURRENT_TIMESTAMP))
+RETURNING id
+`
+
+func (r *jobRepo) EnqueueJob(ctx context.Context, params EnqueueJobParams) (string, error) {
+	var runAt *time.Time
+	if !params.RunAt.IsZero() {
+		runAt = &params.RunAt
+	}
+
+	var id string
+	if err := r.db.QueryRowContext(ctx, EnqueueJobQuery, params.Kind, params.Payload, runAt).Scan(&id); err != nil {
+		return "", mapError(err)
+	}
+	return id, nil
+}
+
+// Claims due jobs without blocking on rows that other workers have locked.
+const ClaimJobsQuery = `
+UPDATE jobs
+SET status = 'running', attempts = attempts + 1, locked_at = CURRENT_TIMESTAMP, locked_by = $2,
+	updated_at = CURRENT_TIMESTAMP
+WHERE id IN (
+	SELECT id FROM jobs
+	WHERE status = 'pending' AND run_at <= CURRENT_TIMESTAMP
+	ORDER BY run_at
+	LIMIT $1
+	FOR UPDATE SKIP LOCKED
+)
+RETURNING id, kind, payload, status, attempts, run_at, created_at, updated_at
+`
+
+// ClaimJobs locks up to limit due jobs for the worker workerID.
+func (r *jobRepo) ClaimJobs(ctx context.Context, workerID string, limit int) ([]model.Job, error) {
+	rows, err := r.db.QueryContext(ctx, ClaimJobsQuery, limit, workerID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var jobs []model.Job
+	for rows.Next() {
+		var job model.Job
+		if err := rows.Scan(&job.ID, &job.Kind, &job.Payload, &job.Status, &job.Attempts,
+			&job.RunAt, &job.CreatedAt, &job.UpdatedAt); err != nil {
+			return nil, err
+		}
+		jobs = append(jobs, job)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return jobs, nil
+}
+
+// The updates of a claimed job only apply while the worker still holds it, so
+// that a worker whose lock was reaped cannot settle a job claimed again.
+const CompleteJobQuery = `
+UPDATE jobs
+SET status = 'done', locked_at = NULL, locked_by = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND locked_by = $2 AND status = 'running'
+`
+
+// CompleteJob reports false when the worker no longer holds the job.
+func (r *jobRepo) CompleteJob(ctx context.Context, id, workerID string) (bool, error) {
+	return r.settleJob(ctx, CompleteJobQuery, id, workerID)
+}
+
+const RetryJobQuery = `
+UPDATE jobs
+SET status = 'pending', run_at = $3, last_error = $4, locked_at = NULL, locked_by = NULL,
+	updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND locked_by = $2 AND status = 'running'
+`
+
+// RetryJob reports false when the worker no longer holds the job.
+func (r *jobRepo) RetryJob(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error) {
+	return r.settleJob(ctx, RetryJobQuery, id, workerID, runAt, lastError)
+}
+
+const KillJobQuery = `
+UPDATE jobs
+SET status = 'dead', last_error = $3, locked_at = NULL, locked_by = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND locked_by = $2 AND status = 'running'
+`
+
+// KillJob reports false when the worker no longer holds the job.
+func (r *jobRepo) KillJob(ctx context.Context, id, workerID string, lastError string) (bool, error) {
+	return r.settleJob(ctx, KillJobQuery, id, workerID, lastError)
+}
+
+func (r *jobRepo) settleJob(ctx context.Context, query, id, workerID string, args ...any) (bool, error) {
+	res, err := r.db.ExecContext(ctx, query, append([]any{id, workerID}, args...)...)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Last error of a job whose worker died during its last attempt
+const StaleJobError = "worker lock expired"
+
+// Returns jobs left running by a worker that crashed to the queue, unless
+// they have used up their attempts. Those are killed so that a job that
+// crashes its worker every time cannot loop forever.
+const RequeueStaleJobsQuery = `
+UPDATE jobs
+SET status = CASE WHEN attempts >= $2 THEN 'dead' ELSE 'pending' END,
+	last_error = CASE WHEN attempts >= $2 THEN $3 ELSE last_error END,
+	locked_at = NULL, locked_by = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE status = 'running' AND locked_at < $1
+RETURNING status
+`
+
+type RequeueStaleJobsResult struct {
+	Requeued int
+	Killed   int
+}
+
+// RequeueStaleJobs releases the jobs locked before lockedBefore, killing the
+// ones that were attempted maxAttempts times.
+func (r *jobRepo) RequeueStaleJobs(ctx context.Context, lockedBefore time.Time, maxAttempts int) (*RequeueStaleJobsResult, error) {
+	rows, err := r.db.QueryContext(ctx, RequeueStaleJobsQuery, lockedBefore, maxAttempts, StaleJobError)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	result := &RequeueStaleJobsResult{}
+	for rows.Next() {
+		var status string
+		if err := rows.Scan(&status); err != nil {
+			return nil, err
+		}
+		if status == model.JobDead {
+			result.Killed++
+		} else {
+			result.Requeued++
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
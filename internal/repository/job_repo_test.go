@@ -0,0 +1,107 @@
+package repository_test
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJobRepo_EnqueueJob(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.EnqueueJobParams{
+		Kind:    "send_email",
+		Payload: json.RawMessage(`{"to":["test@example.com"]}`),
+	}
+
+	mock.ExpectQuery(repository.EnqueueJobQuery).
+		WithArgs(params.Kind, params.Payload, nil).
+		WillReturnRows(sqlmock.NewRows([]string{"id"}).AddRow("1"))
+
+	repo := repository.NewJobRepository(db)
+	id, err := repo.EnqueueJob(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "1", id)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepo_ClaimJobs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	mock.ExpectQuery(repository.ClaimJobsQuery).
+		WithArgs(1, "host:1:0").
+		WillReturnRows(sqlmock.NewRows([]string{"id", "kind", "payload", "status", "attempts", "run_at", "created_at", "updated_at"}).
+			AddRow("1", "send_email", []byte(`{}`), model.JobRunning, 1, now, now, now))
+
+	repo := repository.NewJobRepository(db)
+	jobs, err := repo.ClaimJobs(context.Background(), "host:1:0", 1)
+	assert.NoError(t, err)
+	assert.Len(t, jobs, 1)
+	assert.Equal(t, "send_email", jobs[0].Kind)
+	assert.Equal(t, model.JobRunning, jobs[0].Status)
+	assert.Equal(t, 1, jobs[0].Attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepo_CompleteJob(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.CompleteJobQuery).
+		WithArgs("1", "host:1:0").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	// Reaped and claimed by another worker
+	mock.ExpectExec(repository.CompleteJobQuery).
+		WithArgs("1", "host:1:0").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := repository.NewJobRepository(db)
+	ok, err := repo.CompleteJob(context.Background(), "1", "host:1:0")
+	assert.NoError(t, err)
+	assert.True(t, ok)
+
+	ok, err = repo.CompleteJob(context.Background(), "1", "host:1:0")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestJobRepo_RequeueStaleJobs(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	lockedBefore := time.Now().Add(-time.Minute)
+	mock.ExpectQuery(repository.RequeueStaleJobsQuery).
+		WithArgs(lockedBefore, 3, repository.StaleJobError).
+		WillReturnRows(sqlmock.NewRows([]string{"status"}).
+			AddRow(model.JobPending).
+			AddRow(model.JobDead).
+			AddRow(model.JobPending))
+
+	repo := repository.NewJobRepository(db)
+	result, err := repo.RequeueStaleJobs(context.Background(), lockedBefore, 3)
+	assert.NoError(t, err)
+	assert.Equal(t, &repository.RequeueStaleJobsResult{Requeued: 2, Killed: 1}, result)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,110 @@
+//go:generate mockgen -destination=mock/key_rotation_repo_mock.go -package=mock . KeyRotationRepo
+package repository
+
+import (
+	"context"
+	"fmt"
+)
+
+// EncryptedColumn is a column of security.EncryptedString values. Key is the
+// primary key of Table, which batches are ordered by and which binds each
+// value to its row.
+type EncryptedColumn struct {
+	Table  string
+	Key    string
+	Column string
+}
+
+func (c EncryptedColumn) String() string {
+	return c.Table + "." + c.Column
+}
+
+// Context returns the encryption context of the value of the row with key.
+func (c EncryptedColumn) Context(key string) string {
+	return c.String() + "/" + key
+}
+
+var TOTPSecretColumn = EncryptedColumn{Table: "user_totp", Key: "user_id", Column: "secret"}
+
+// EncryptedColumns are the columns that rotate-keys re-encrypts. A column
+// that is encrypted with security.EncryptedString must be added here.
+var EncryptedColumns = []EncryptedColumn{TOTPSecretColumn}
+
+// EncryptedValue is the value of an encrypted column, as stored.
+type EncryptedValue struct {
+	Key       string
+	Encrypted string
+}
+
+type KeyRotationRepo interface {
+	ListEncrypted(ctx context.Context, col EncryptedColumn, after string, limit int) ([]EncryptedValue, error)
+	UpdateEncrypted(ctx context.Context, col EncryptedColumn, key, encrypted string) error
+}
+
+type keyRotationRepo struct {
+	db DBTX
+}
+
+var _ KeyRotationRepo = (*keyRotationRepo)(nil)
+
+func NewKeyRotationRepository(db DBTX) KeyRotationRepo {
+	return &keyRotationRepo{db: db}
+}
+
+// ListEncryptedQuery returns the query of a batch of col, starting after the
+// key of the previous batch unless first is set. The rows are locked so that
+// they cannot change until they are updated.
+func ListEncryptedQuery(col EncryptedColumn, first bool) string {
+	where := col.Column + " IS NOT NULL"
+	if !first {
+		where += " AND " + col.Key + " > $2"
+	}
+	return fmt.Sprintf(`
+SELECT %[1]s, %[2]s FROM %[3]s
+WHERE %[4]s
+ORDER BY %[1]s
+LIMIT $1
+FOR UPDATE
+`, col.Key, col.Column, col.Table, where)
+}
+
+// ListEncrypted returns up to limit non-NULL values of col, after the key
+// after, or from the start if it is empty.
+func (r *keyRotationRepo) ListEncrypted(ctx context.Context, col EncryptedColumn, after string, limit int) ([]EncryptedValue, error) {
+	args := []any{limit}
+	if after != "" {
+		args = append(args, after)
+	}
+
+	rows, err := r.db.QueryContext(ctx, ListEncryptedQuery(col, after == ""), args...)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var values []EncryptedValue
+	for rows.Next() {
+		var v EncryptedValue
+		if err := rows.Scan(&v.Key, &v.Encrypted); err != nil {
+			return nil, err
+		}
+		values = append(values, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return values, nil
+}
+
+func UpdateEncryptedQuery(col EncryptedColumn) string {
+	return fmt.Sprintf(`
+UPDATE %s SET %s = $2
+WHERE %s = $1
+`, col.Table, col.Column, col.Key)
+}
+
+// UpdateEncrypted stores a value that the caller already encrypted.
+func (r *keyRotationRepo) UpdateEncrypted(ctx context.Context, col EncryptedColumn, key, encrypted string) error {
+	_, err := r.db.ExecContext(ctx, UpdateEncryptedQuery(col), key, encrypted)
+	return mapError(err)
+}
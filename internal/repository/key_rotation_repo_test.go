@@ -0,0 +1,59 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+var totpSecretColumn = repository.EncryptedColumn{Table: "user_totp", Key: "user_id", Column: "secret"}
+
+func TestKeyRotationRepo_ListEncrypted(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(repository.ListEncryptedQuery(totpSecretColumn, true)).
+		WithArgs(2).
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret"}).
+			AddRow("1", "enc:v1:k1:a:b").
+			AddRow("2", "enc:v1:k2:c:d"))
+	mock.ExpectQuery(repository.ListEncryptedQuery(totpSecretColumn, false)).
+		WithArgs(2, "2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret"}))
+
+	repo := repository.NewKeyRotationRepository(db)
+	values, err := repo.ListEncrypted(context.Background(), totpSecretColumn, "", 2)
+	assert.NoError(t, err)
+	assert.Equal(t, []repository.EncryptedValue{
+		{Key: "1", Encrypted: "enc:v1:k1:a:b"},
+		{Key: "2", Encrypted: "enc:v1:k2:c:d"},
+	}, values)
+
+	values, err = repo.ListEncrypted(context.Background(), totpSecretColumn, "2", 2)
+	assert.NoError(t, err)
+	assert.Empty(t, values)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestKeyRotationRepo_UpdateEncrypted(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.UpdateEncryptedQuery(totpSecretColumn)).
+		WithArgs("1", "enc:v1:k2:a:b").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewKeyRotationRepository(db)
+	err = repo.UpdateEncrypted(context.Background(), totpSecretColumn, "1", "enc:v1:k2:a:b")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,110 @@
+//go:generate mockgen -destination=mock/login_attempt_repo_mock.go -package=mock . LoginAttemptRepo
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+// LoginAttemptRepo counts failed sign in attempts. Times are passed in rather
+// than taken from the database so that all replicas agree with the caller on
+// when a lock ends.
+type LoginAttemptRepo interface {
+	FindLoginAttempt(ctx context.Context, scope, key string) (*model.LoginAttempt, error)
+	RecordLoginFailure(ctx context.Context, params RecordLoginFailureParams) (*model.LoginAttempt, error)
+	ReleaseLoginFailure(ctx context.Context, scope, key string) error
+	LockLoginAttempt(ctx context.Context, scope, key string, until time.Time) error
+	DeleteLoginAttempt(ctx context.Context, scope, key string) error
+}
+
+type loginAttemptRepo struct {
+	db DBTX
+}
+
+var _ LoginAttemptRepo = (*loginAttemptRepo)(nil)
+
+func NewLoginAttemptRepository(db DBTX) LoginAttemptRepo {
+	return &loginAttemptRepo{db: db}
+}
+
+const FindLoginAttemptQuery = `
+SELECT scope, key, failures, last_failed_at, locked_until FROM login_attempts
+WHERE scope = $1 AND key = $2
+LIMIT 1
+`
+
+func (r *loginAttemptRepo) FindLoginAttempt(ctx context.Context, scope, key string) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	if err := r.db.QueryRowContext(ctx, FindLoginAttemptQuery, scope, key).
+		Scan(&attempt.Scope, &attempt.Key, &attempt.Failures, &attempt.LastFailedAt, &attempt.LockedUntil); err != nil {
+		return nil, mapError(err)
+	}
+	return &attempt, nil
+}
+
+// RecordLoginFailureParams start counting again from one when the last
+// failure happened before WindowStart.
+type RecordLoginFailureParams struct {
+	Scope       string
+	Key         string
+	FailedAt    time.Time
+	WindowStart time.Time
+}
+
+const RecordLoginFailureQuery = `
+INSERT INTO login_attempts (scope, key, failures, last_failed_at)
+VALUES ($1, $2, 1, $3)
+ON CONFLICT (scope, key) DO UPDATE
+SET failures = CASE WHEN login_attempts.last_failed_at < $4 THEN 1 ELSE login_attempts.failures + 1 END,
+	last_failed_at = EXCLUDED.last_failed_at
+RETURNING scope, key, failures, last_failed_at, locked_until
+`
+
+// RecordLoginFailure counts a failure and returns the updated attempt.
+func (r *loginAttemptRepo) RecordLoginFailure(ctx context.Context, params RecordLoginFailureParams) (*model.LoginAttempt, error) {
+	var attempt model.LoginAttempt
+	if err := r.db.QueryRowContext(ctx, RecordLoginFailureQuery,
+		params.Scope, params.Key, params.FailedAt, params.WindowStart).
+		Scan(&attempt.Scope, &attempt.Key, &attempt.Failures, &attempt.LastFailedAt, &attempt.LockedUntil); err != nil {
+		return nil, mapError(err)
+	}
+	return &attempt, nil
+}
+
+// A lock in the meantime may have reset the failures already.
+const ReleaseLoginFailureQuery = `
+UPDATE login_attempts
+SET failures = GREATEST(failures - 1, 0)
+WHERE scope = $1 AND key = $2
+`
+
+// ReleaseLoginFailure takes back a failure recorded for an attempt that did
+// not fail after all.
+func (r *loginAttemptRepo) ReleaseLoginFailure(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(ctx, ReleaseLoginFailureQuery, scope, key)
+	return mapError(err)
+}
+
+// Counting starts over once the lock ends.
+const LockLoginAttemptQuery = `
+UPDATE login_attempts
+SET locked_until = $3, failures = 0
+WHERE scope = $1 AND key = $2
+`
+
+func (r *loginAttemptRepo) LockLoginAttempt(ctx context.Context, scope, key string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, LockLoginAttemptQuery, scope, key, until)
+	return mapError(err)
+}
+
+const DeleteLoginAttemptQuery = `
+DELETE FROM login_attempts
+WHERE scope = $1 AND key = $2
+`
+
+func (r *loginAttemptRepo) DeleteLoginAttempt(ctx context.Context, scope, key string) error {
+	_, err := r.db.ExecContext(ctx, DeleteLoginAttemptQuery, scope, key)
+	return mapError(err)
+}
@@ -0,0 +1,96 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginAttemptRepo_RecordLoginFailure(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	now := time.Now()
+	params := repository.RecordLoginFailureParams{
+		Scope:       model.LoginScopeAccount,
+		Key:         "abc@example.com",
+		FailedAt:    now,
+		WindowStart: now.Add(-15 * time.Minute),
+	}
+
+	mock.ExpectQuery(repository.RecordLoginFailureQuery).
+		WithArgs(params.Scope, params.Key, params.FailedAt, params.WindowStart).
+		WillReturnRows(sqlmock.NewRows([]string{"scope", "key", "failures", "last_failed_at", "locked_until"}).
+			AddRow(params.Scope, params.Key, 2, now, nil))
+
+	repo := repository.NewLoginAttemptRepository(db)
+	attempt, err := repo.RecordLoginFailure(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, attempt.Failures)
+	assert.Nil(t, attempt.LockedUntil)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginAttemptRepo_ReleaseLoginFailure(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.ReleaseLoginFailureQuery).
+		WithArgs(model.LoginScopeAccount, "abc@example.com").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewLoginAttemptRepository(db)
+	assert.NoError(t, repo.ReleaseLoginFailure(context.Background(), model.LoginScopeAccount, "abc@example.com"))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMemoryLoginAttemptRepo(t *testing.T) {
+	ctx := context.Background()
+	repo := repository.NewMemoryLoginAttemptRepository()
+	now := time.Now()
+
+	record := func(at time.Time) *model.LoginAttempt {
+		t.Helper()
+		attempt, err := repo.RecordLoginFailure(ctx, repository.RecordLoginFailureParams{
+			Scope:       model.LoginScopeIP,
+			Key:         "192.0.2.1",
+			FailedAt:    at,
+			WindowStart: at.Add(-time.Minute),
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return attempt
+	}
+
+	assert.Equal(t, 1, record(now).Failures)
+	assert.Equal(t, 2, record(now.Add(30*time.Second)).Failures)
+	assert.Equal(t, 1, record(now.Add(5*time.Minute)).Failures, "failures outside the window are forgotten")
+	assert.Equal(t, 2, record(now.Add(5*time.Minute)).Failures)
+
+	assert.NoError(t, repo.ReleaseLoginFailure(ctx, model.LoginScopeIP, "192.0.2.1"))
+	assert.Equal(t, 2, record(now.Add(5*time.Minute)).Failures, "a released failure is not counted")
+
+	until := now.Add(time.Hour)
+	assert.NoError(t, repo.LockLoginAttempt(ctx, model.LoginScopeIP, "192.0.2.1", until))
+	attempt, err := repo.FindLoginAttempt(ctx, model.LoginScopeIP, "192.0.2.1")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, attempt.Failures)
+	assert.Equal(t, until, *attempt.LockedUntil)
+
+	assert.NoError(t, repo.DeleteLoginAttempt(ctx, model.LoginScopeIP, "192.0.2.1"))
+	_, err = repo.FindLoginAttempt(ctx, model.LoginScopeIP, "192.0.2.1")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
@@ -0,0 +1,102 @@
+//go:generate mockgen -destination=mock/login_challenge_repo_mock.go -package=mock . LoginChallengeRepo
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type LoginChallengeRepo interface {
+	CreateLoginChallenge(ctx context.Context, params CreateLoginChallengeParams) (*model.LoginChallenge, error)
+	FindLoginChallengeByHash(ctx context.Context, tokenHash string) (*model.LoginChallenge, error)
+	IncrementLoginChallengeAttempts(ctx context.Context, id string) (int, error)
+	DeleteLoginChallenge(ctx context.Context, id string) (bool, error)
+}
+
+type loginChallengeRepo struct {
+	db DBTX
+}
+
+var _ LoginChallengeRepo = (*loginChallengeRepo)(nil)
+
+func NewLoginChallengeRepository(db DBTX) LoginChallengeRepo {
+	return &loginChallengeRepo{db: db}
+}
+
+type CreateLoginChallengeParams struct {
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+const CreateLoginChallengeQuery = `
+INSERT INTO login_challenges (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+RETURNING id, attempts, created_at
+`
+
+func (r *loginChallengeRepo) CreateLoginChallenge(ctx context.Context, params CreateLoginChallengeParams) (*model.LoginChallenge, error) {
+	challenge := model.LoginChallenge{
+		UserID:    params.UserID,
+		TokenHash: params.TokenHash,
+		ExpiresAt: params.ExpiresAt,
+	}
+	if err := r.db.QueryRowContext(ctx, CreateLoginChallengeQuery, params.UserID, params.TokenHash, params.ExpiresAt).
+		Scan(&challenge.ID, &challenge.Attempts, &challenge.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &challenge, nil
+}
+
+const FindLoginChallengeByHashQuery = `
+SELECT id, user_id, token_hash, attempts, expires_at, created_at FROM login_challenges
+WHERE token_hash = $1
+LIMIT 1
+`
+
+func (r *loginChallengeRepo) FindLoginChallengeByHash(ctx context.Context, tokenHash string) (*model.LoginChallenge, error) {
+	var challenge model.LoginChallenge
+	if err := r.db.QueryRowContext(ctx, FindLoginChallengeByHashQuery, tokenHash).
+		Scan(&challenge.ID, &challenge.UserID, &challenge.TokenHash, &challenge.Attempts, &challenge.ExpiresAt,
+			&challenge.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &challenge, nil
+}
+
+const IncrementLoginChallengeAttemptsQuery = `
+UPDATE login_challenges
+SET attempts = attempts + 1
+WHERE id = $1
+RETURNING attempts
+`
+
+// IncrementLoginChallengeAttempts returns the number of attempts including
+// the current one.
+func (r *loginChallengeRepo) IncrementLoginChallengeAttempts(ctx context.Context, id string) (int, error) {
+	var attempts int
+	if err := r.db.QueryRowContext(ctx, IncrementLoginChallengeAttemptsQuery, id).Scan(&attempts); err != nil {
+		return 0, mapError(err)
+	}
+	return attempts, nil
+}
+
+const DeleteLoginChallengeQuery = `
+DELETE FROM login_challenges
+WHERE id = $1
+`
+
+// DeleteLoginChallenge reports whether this call consumed the challenge.
+func (r *loginChallengeRepo) DeleteLoginChallenge(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, DeleteLoginChallengeQuery, id)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
@@ -0,0 +1,54 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLoginChallengeRepo_CreateLoginChallenge(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateLoginChallengeParams{
+		UserID:    "1",
+		TokenHash: "hashed",
+		ExpiresAt: time.Now().Add(5 * time.Minute),
+	}
+
+	mock.ExpectQuery(repository.CreateLoginChallengeQuery).
+		WithArgs(params.UserID, params.TokenHash, params.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "attempts", "created_at"}).AddRow("2", 0, time.Now()))
+
+	repo := repository.NewLoginChallengeRepository(db)
+	challenge, err := repo.CreateLoginChallenge(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "2", challenge.ID)
+	assert.Equal(t, params.UserID, challenge.UserID)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestLoginChallengeRepo_IncrementLoginChallengeAttempts(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(repository.IncrementLoginChallengeAttemptsQuery).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"attempts"}).AddRow(3))
+
+	repo := repository.NewLoginChallengeRepository(db)
+	attempts, err := repo.IncrementLoginChallengeAttempts(context.Background(), "2")
+	assert.NoError(t, err)
+	assert.Equal(t, 3, attempts)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,97 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type loginAttemptKey struct {
+	scope string
+	key   string
+}
+
+// memoryLoginAttemptRepo keeps the counters of a single process, for tests
+// and for running without a database.
+type memoryLoginAttemptRepo struct {
+	mu       sync.Mutex
+	attempts map[loginAttemptKey]model.LoginAttempt
+}
+
+var _ LoginAttemptRepo = (*memoryLoginAttemptRepo)(nil)
+
+func NewMemoryLoginAttemptRepository() LoginAttemptRepo {
+	return &memoryLoginAttemptRepo{attempts: make(map[loginAttemptKey]model.LoginAttempt)}
+}
+
+func (r *memoryLoginAttemptRepo) FindLoginAttempt(_ context.Context, scope, key string) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[loginAttemptKey{scope, key}]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) RecordLoginFailure(_ context.Context, params RecordLoginFailureParams) (*model.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := loginAttemptKey{params.Scope, params.Key}
+	attempt, ok := r.attempts[k]
+	if !ok {
+		attempt = model.LoginAttempt{Scope: params.Scope, Key: params.Key}
+	}
+
+	if attempt.LastFailedAt.Before(params.WindowStart) {
+		attempt.Failures = 1
+	} else {
+		attempt.Failures++
+	}
+	attempt.LastFailedAt = params.FailedAt
+	r.attempts[k] = attempt
+
+	return &attempt, nil
+}
+
+func (r *memoryLoginAttemptRepo) ReleaseLoginFailure(_ context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := loginAttemptKey{scope, key}
+	attempt, ok := r.attempts[k]
+	if !ok {
+		return nil
+	}
+	attempt.Failures = max(attempt.Failures-1, 0)
+	r.attempts[k] = attempt
+	return nil
+}
+
+func (r *memoryLoginAttemptRepo) LockLoginAttempt(_ context.Context, scope, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	k := loginAttemptKey{scope, key}
+	attempt, ok := r.attempts[k]
+	if !ok {
+		return nil
+	}
+	attempt.LockedUntil = &until
+	attempt.Failures = 0
+	r.attempts[k] = attempt
+	return nil
+}
+
+func (r *memoryLoginAttemptRepo) DeleteLoginAttempt(_ context.Context, scope, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.attempts, loginAttemptKey{scope, key})
+	return nil
+}
@@ -0,0 +1,102 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: IdentityRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/identity_repo_mock.go -package=mock . IdentityRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockIdentityRepo is a mock of IdentityRepo interface.
+type MockIdentityRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockIdentityRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockIdentityRepoMockRecorder is the mock recorder for MockIdentityRepo.
+type MockIdentityRepoMockRecorder struct {
+	mock *MockIdentityRepo
+}
+
+// NewMockIdentityRepo creates a new mock instance.
+func NewMockIdentityRepo(ctrl *gomock.Controller) *MockIdentityRepo {
+	mock := &MockIdentityRepo{ctrl: ctrl}
+	mock.recorder = &MockIdentityRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockIdentityRepo) EXPECT() *MockIdentityRepoMockRecorder {
+	return m.recorder
+}
+
+// ConsumeOIDCFlow mocks base method.
+func (m *MockIdentityRepo) ConsumeOIDCFlow(ctx context.Context, stateHash string) (*model.OIDCFlow, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeOIDCFlow", ctx, stateHash)
+	ret0, _ := ret[0].(*model.OIDCFlow)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeOIDCFlow indicates an expected call of ConsumeOIDCFlow.
+func (mr *MockIdentityRepoMockRecorder) ConsumeOIDCFlow(ctx, stateHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeOIDCFlow", reflect.TypeOf((*MockIdentityRepo)(nil).ConsumeOIDCFlow), ctx, stateHash)
+}
+
+// CreateIdentity mocks base method.
+func (m *MockIdentityRepo) CreateIdentity(ctx context.Context, params repository.CreateIdentityParams) (*model.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateIdentity", ctx, params)
+	ret0, _ := ret[0].(*model.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateIdentity indicates an expected call of CreateIdentity.
+func (mr *MockIdentityRepoMockRecorder) CreateIdentity(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateIdentity", reflect.TypeOf((*MockIdentityRepo)(nil).CreateIdentity), ctx, params)
+}
+
+// CreateOIDCFlow mocks base method.
+func (m *MockIdentityRepo) CreateOIDCFlow(ctx context.Context, params repository.CreateOIDCFlowParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateOIDCFlow", ctx, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateOIDCFlow indicates an expected call of CreateOIDCFlow.
+func (mr *MockIdentityRepoMockRecorder) CreateOIDCFlow(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateOIDCFlow", reflect.TypeOf((*MockIdentityRepo)(nil).CreateOIDCFlow), ctx, params)
+}
+
+// FindIdentity mocks base method.
+func (m *MockIdentityRepo) FindIdentity(ctx context.Context, provider, subject string) (*model.Identity, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindIdentity", ctx, provider, subject)
+	ret0, _ := ret[0].(*model.Identity)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindIdentity indicates an expected call of FindIdentity.
+func (mr *MockIdentityRepoMockRecorder) FindIdentity(ctx, provider, subject any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindIdentity", reflect.TypeOf((*MockIdentityRepo)(nil).FindIdentity), ctx, provider, subject)
+}
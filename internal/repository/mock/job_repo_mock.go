@@ -0,0 +1,134 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: JobRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/job_repo_mock.go -package=mock . JobRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockJobRepo is a mock of JobRepo interface.
+type MockJobRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockJobRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockJobRepoMockRecorder is the mock recorder for MockJobRepo.
+type MockJobRepoMockRecorder struct {
+	mock *MockJobRepo
+}
+
+// NewMockJobRepo creates a new mock instance.
+func NewMockJobRepo(ctrl *gomock.Controller) *MockJobRepo {
+	mock := &MockJobRepo{ctrl: ctrl}
+	mock.recorder = &MockJobRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockJobRepo) EXPECT() *MockJobRepoMockRecorder {
+	return m.recorder
+}
+
+// ClaimJobs mocks base method.
+func (m *MockJobRepo) ClaimJobs(ctx context.Context, workerID string, limit int) ([]model.Job, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimJobs", ctx, workerID, limit)
+	ret0, _ := ret[0].([]model.Job)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimJobs indicates an expected call of ClaimJobs.
+func (mr *MockJobRepoMockRecorder) ClaimJobs(ctx, workerID, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimJobs", reflect.TypeOf((*MockJobRepo)(nil).ClaimJobs), ctx, workerID, limit)
+}
+
+// CompleteJob mocks base method.
+func (m *MockJobRepo) CompleteJob(ctx context.Context, id, workerID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteJob", ctx, id, workerID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteJob indicates an expected call of CompleteJob.
+func (mr *MockJobRepoMockRecorder) CompleteJob(ctx, id, workerID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteJob", reflect.TypeOf((*MockJobRepo)(nil).CompleteJob), ctx, id, workerID)
+}
+
+// EnqueueJob mocks base method.
+func (m *MockJobRepo) EnqueueJob(ctx context.Context, params repository.EnqueueJobParams) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnqueueJob", ctx, params)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnqueueJob indicates an expected call of EnqueueJob.
+func (mr *MockJobRepoMockRecorder) EnqueueJob(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnqueueJob", reflect.TypeOf((*MockJobRepo)(nil).EnqueueJob), ctx, params)
+}
+
+// KillJob mocks base method.
+func (m *MockJobRepo) KillJob(ctx context.Context, id, workerID, lastError string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "KillJob", ctx, id, workerID, lastError)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// KillJob indicates an expected call of KillJob.
+func (mr *MockJobRepoMockRecorder) KillJob(ctx, id, workerID, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "KillJob", reflect.TypeOf((*MockJobRepo)(nil).KillJob), ctx, id, workerID, lastError)
+}
+
+// RequeueStaleJobs mocks base method.
+func (m *MockJobRepo) RequeueStaleJobs(ctx context.Context, lockedBefore time.Time, maxAttempts int) (*repository.RequeueStaleJobsResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RequeueStaleJobs", ctx, lockedBefore, maxAttempts)
+	ret0, _ := ret[0].(*repository.RequeueStaleJobsResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RequeueStaleJobs indicates an expected call of RequeueStaleJobs.
+func (mr *MockJobRepoMockRecorder) RequeueStaleJobs(ctx, lockedBefore, maxAttempts any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RequeueStaleJobs", reflect.TypeOf((*MockJobRepo)(nil).RequeueStaleJobs), ctx, lockedBefore, maxAttempts)
+}
+
+// RetryJob mocks base method.
+func (m *MockJobRepo) RetryJob(ctx context.Context, id, workerID string, runAt time.Time, lastError string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RetryJob", ctx, id, workerID, runAt, lastError)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RetryJob indicates an expected call of RetryJob.
+func (mr *MockJobRepoMockRecorder) RetryJob(ctx, id, workerID, runAt, lastError any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RetryJob", reflect.TypeOf((*MockJobRepo)(nil).RetryJob), ctx, id, workerID, runAt, lastError)
+}
@@ -0,0 +1,71 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: KeyRotationRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/key_rotation_repo_mock.go -package=mock . KeyRotationRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockKeyRotationRepo is a mock of KeyRotationRepo interface.
+type MockKeyRotationRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyRotationRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockKeyRotationRepoMockRecorder is the mock recorder for MockKeyRotationRepo.
+type MockKeyRotationRepoMockRecorder struct {
+	mock *MockKeyRotationRepo
+}
+
+// NewMockKeyRotationRepo creates a new mock instance.
+func NewMockKeyRotationRepo(ctrl *gomock.Controller) *MockKeyRotationRepo {
+	mock := &MockKeyRotationRepo{ctrl: ctrl}
+	mock.recorder = &MockKeyRotationRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyRotationRepo) EXPECT() *MockKeyRotationRepoMockRecorder {
+	return m.recorder
+}
+
+// ListEncrypted mocks base method.
+func (m *MockKeyRotationRepo) ListEncrypted(ctx context.Context, col repository.EncryptedColumn, after string, limit int) ([]repository.EncryptedValue, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListEncrypted", ctx, col, after, limit)
+	ret0, _ := ret[0].([]repository.EncryptedValue)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListEncrypted indicates an expected call of ListEncrypted.
+func (mr *MockKeyRotationRepoMockRecorder) ListEncrypted(ctx, col, after, limit any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListEncrypted", reflect.TypeOf((*MockKeyRotationRepo)(nil).ListEncrypted), ctx, col, after, limit)
+}
+
+// UpdateEncrypted mocks base method.
+func (m *MockKeyRotationRepo) UpdateEncrypted(ctx context.Context, col repository.EncryptedColumn, key, encrypted string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateEncrypted", ctx, col, key, encrypted)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateEncrypted indicates an expected call of UpdateEncrypted.
+func (mr *MockKeyRotationRepoMockRecorder) UpdateEncrypted(ctx, col, key, encrypted any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateEncrypted", reflect.TypeOf((*MockKeyRotationRepo)(nil).UpdateEncrypted), ctx, col, key, encrypted)
+}
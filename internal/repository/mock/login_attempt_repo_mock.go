@@ -0,0 +1,116 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: LoginAttemptRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/login_attempt_repo_mock.go -package=mock . LoginAttemptRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+	time "time"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginAttemptRepo is a mock of LoginAttemptRepo interface.
+type MockLoginAttemptRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginAttemptRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockLoginAttemptRepoMockRecorder is the mock recorder for MockLoginAttemptRepo.
+type MockLoginAttemptRepoMockRecorder struct {
+	mock *MockLoginAttemptRepo
+}
+
+// NewMockLoginAttemptRepo creates a new mock instance.
+func NewMockLoginAttemptRepo(ctrl *gomock.Controller) *MockLoginAttemptRepo {
+	mock := &MockLoginAttemptRepo{ctrl: ctrl}
+	mock.recorder = &MockLoginAttemptRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginAttemptRepo) EXPECT() *MockLoginAttemptRepoMockRecorder {
+	return m.recorder
+}
+
+// DeleteLoginAttempt mocks base method.
+func (m *MockLoginAttemptRepo) DeleteLoginAttempt(ctx context.Context, scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginAttempt", ctx, scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteLoginAttempt indicates an expected call of DeleteLoginAttempt.
+func (mr *MockLoginAttemptRepoMockRecorder) DeleteLoginAttempt(ctx, scope, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginAttempt", reflect.TypeOf((*MockLoginAttemptRepo)(nil).DeleteLoginAttempt), ctx, scope, key)
+}
+
+// FindLoginAttempt mocks base method.
+func (m *MockLoginAttemptRepo) FindLoginAttempt(ctx context.Context, scope, key string) (*model.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLoginAttempt", ctx, scope, key)
+	ret0, _ := ret[0].(*model.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLoginAttempt indicates an expected call of FindLoginAttempt.
+func (mr *MockLoginAttemptRepoMockRecorder) FindLoginAttempt(ctx, scope, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLoginAttempt", reflect.TypeOf((*MockLoginAttemptRepo)(nil).FindLoginAttempt), ctx, scope, key)
+}
+
+// LockLoginAttempt mocks base method.
+func (m *MockLoginAttemptRepo) LockLoginAttempt(ctx context.Context, scope, key string, until time.Time) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "LockLoginAttempt", ctx, scope, key, until)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// LockLoginAttempt indicates an expected call of LockLoginAttempt.
+func (mr *MockLoginAttemptRepoMockRecorder) LockLoginAttempt(ctx, scope, key, until any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "LockLoginAttempt", reflect.TypeOf((*MockLoginAttemptRepo)(nil).LockLoginAttempt), ctx, scope, key, until)
+}
+
+// RecordLoginFailure mocks base method.
+func (m *MockLoginAttemptRepo) RecordLoginFailure(ctx context.Context, params repository.RecordLoginFailureParams) (*model.LoginAttempt, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RecordLoginFailure", ctx, params)
+	ret0, _ := ret[0].(*model.LoginAttempt)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RecordLoginFailure indicates an expected call of RecordLoginFailure.
+func (mr *MockLoginAttemptRepoMockRecorder) RecordLoginFailure(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RecordLoginFailure", reflect.TypeOf((*MockLoginAttemptRepo)(nil).RecordLoginFailure), ctx, params)
+}
+
+// ReleaseLoginFailure mocks base method.
+func (m *MockLoginAttemptRepo) ReleaseLoginFailure(ctx context.Context, scope, key string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReleaseLoginFailure", ctx, scope, key)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReleaseLoginFailure indicates an expected call of ReleaseLoginFailure.
+func (mr *MockLoginAttemptRepoMockRecorder) ReleaseLoginFailure(ctx, scope, key any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReleaseLoginFailure", reflect.TypeOf((*MockLoginAttemptRepo)(nil).ReleaseLoginFailure), ctx, scope, key)
+}
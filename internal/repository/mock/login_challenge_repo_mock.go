@@ -0,0 +1,103 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: LoginChallengeRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/login_challenge_repo_mock.go -package=mock . LoginChallengeRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockLoginChallengeRepo is a mock of LoginChallengeRepo interface.
+type MockLoginChallengeRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockLoginChallengeRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockLoginChallengeRepoMockRecorder is the mock recorder for MockLoginChallengeRepo.
+type MockLoginChallengeRepoMockRecorder struct {
+	mock *MockLoginChallengeRepo
+}
+
+// NewMockLoginChallengeRepo creates a new mock instance.
+func NewMockLoginChallengeRepo(ctrl *gomock.Controller) *MockLoginChallengeRepo {
+	mock := &MockLoginChallengeRepo{ctrl: ctrl}
+	mock.recorder = &MockLoginChallengeRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLoginChallengeRepo) EXPECT() *MockLoginChallengeRepoMockRecorder {
+	return m.recorder
+}
+
+// CreateLoginChallenge mocks base method.
+func (m *MockLoginChallengeRepo) CreateLoginChallenge(ctx context.Context, params repository.CreateLoginChallengeParams) (*model.LoginChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateLoginChallenge", ctx, params)
+	ret0, _ := ret[0].(*model.LoginChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateLoginChallenge indicates an expected call of CreateLoginChallenge.
+func (mr *MockLoginChallengeRepoMockRecorder) CreateLoginChallenge(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateLoginChallenge", reflect.TypeOf((*MockLoginChallengeRepo)(nil).CreateLoginChallenge), ctx, params)
+}
+
+// DeleteLoginChallenge mocks base method.
+func (m *MockLoginChallengeRepo) DeleteLoginChallenge(ctx context.Context, id string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteLoginChallenge", ctx, id)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// DeleteLoginChallenge indicates an expected call of DeleteLoginChallenge.
+func (mr *MockLoginChallengeRepoMockRecorder) DeleteLoginChallenge(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteLoginChallenge", reflect.TypeOf((*MockLoginChallengeRepo)(nil).DeleteLoginChallenge), ctx, id)
+}
+
+// FindLoginChallengeByHash mocks base method.
+func (m *MockLoginChallengeRepo) FindLoginChallengeByHash(ctx context.Context, tokenHash string) (*model.LoginChallenge, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLoginChallengeByHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*model.LoginChallenge)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLoginChallengeByHash indicates an expected call of FindLoginChallengeByHash.
+func (mr *MockLoginChallengeRepoMockRecorder) FindLoginChallengeByHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLoginChallengeByHash", reflect.TypeOf((*MockLoginChallengeRepo)(nil).FindLoginChallengeByHash), ctx, tokenHash)
+}
+
+// IncrementLoginChallengeAttempts mocks base method.
+func (m *MockLoginChallengeRepo) IncrementLoginChallengeAttempts(ctx context.Context, id string) (int, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "IncrementLoginChallengeAttempts", ctx, id)
+	ret0, _ := ret[0].(int)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// IncrementLoginChallengeAttempts indicates an expected call of IncrementLoginChallengeAttempts.
+func (mr *MockLoginChallengeRepoMockRecorder) IncrementLoginChallengeAttempts(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "IncrementLoginChallengeAttempts", reflect.TypeOf((*MockLoginChallengeRepo)(nil).IncrementLoginChallengeAttempts), ctx, id)
+}
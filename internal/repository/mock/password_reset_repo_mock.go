@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: PasswordResetRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/password_reset_repo_mock.go -package=mock . PasswordResetRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockPasswordResetRepo is a mock of PasswordResetRepo interface.
+type MockPasswordResetRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockPasswordResetRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockPasswordResetRepoMockRecorder is the mock recorder for MockPasswordResetRepo.
+type MockPasswordResetRepoMockRecorder struct {
+	mock *MockPasswordResetRepo
+}
+
+// NewMockPasswordResetRepo creates a new mock instance.
+func NewMockPasswordResetRepo(ctrl *gomock.Controller) *MockPasswordResetRepo {
+	mock := &MockPasswordResetRepo{ctrl: ctrl}
+	mock.recorder = &MockPasswordResetRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockPasswordResetRepo) EXPECT() *MockPasswordResetRepoMockRecorder {
+	return m.recorder
+}
+
+// ConsumePasswordReset mocks base method.
+func (m *MockPasswordResetRepo) ConsumePasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumePasswordReset", ctx, tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumePasswordReset indicates an expected call of ConsumePasswordReset.
+func (mr *MockPasswordResetRepoMockRecorder) ConsumePasswordReset(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumePasswordReset", reflect.TypeOf((*MockPasswordResetRepo)(nil).ConsumePasswordReset), ctx, tokenHash)
+}
+
+// CreatePasswordReset mocks base method.
+func (m *MockPasswordResetRepo) CreatePasswordReset(ctx context.Context, params repository.CreatePasswordResetParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePasswordReset", ctx, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreatePasswordReset indicates an expected call of CreatePasswordReset.
+func (mr *MockPasswordResetRepoMockRecorder) CreatePasswordReset(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePasswordReset", reflect.TypeOf((*MockPasswordResetRepo)(nil).CreatePasswordReset), ctx, params)
+}
+
+// DeleteUserPasswordResets mocks base method.
+func (m *MockPasswordResetRepo) DeleteUserPasswordResets(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserPasswordResets", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserPasswordResets indicates an expected call of DeleteUserPasswordResets.
+func (mr *MockPasswordResetRepoMockRecorder) DeleteUserPasswordResets(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserPasswordResets", reflect.TypeOf((*MockPasswordResetRepo)(nil).DeleteUserPasswordResets), ctx, userID)
+}
@@ -0,0 +1,100 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: RoleRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/role_repo_mock.go -package=mock . RoleRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockRoleRepo is a mock of RoleRepo interface.
+type MockRoleRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockRoleRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockRoleRepoMockRecorder is the mock recorder for MockRoleRepo.
+type MockRoleRepoMockRecorder struct {
+	mock *MockRoleRepo
+}
+
+// NewMockRoleRepo creates a new mock instance.
+func NewMockRoleRepo(ctrl *gomock.Controller) *MockRoleRepo {
+	mock := &MockRoleRepo{ctrl: ctrl}
+	mock.recorder = &MockRoleRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRoleRepo) EXPECT() *MockRoleRepoMockRecorder {
+	return m.recorder
+}
+
+// AssignRole mocks base method.
+func (m *MockRoleRepo) AssignRole(ctx context.Context, userID, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRole", ctx, userID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignRole indicates an expected call of AssignRole.
+func (mr *MockRoleRepoMockRecorder) AssignRole(ctx, userID, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRole", reflect.TypeOf((*MockRoleRepo)(nil).AssignRole), ctx, userID, roleID)
+}
+
+// FindRoleByName mocks base method.
+func (m *MockRoleRepo) FindRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindRoleByName", ctx, name)
+	ret0, _ := ret[0].(*model.Role)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindRoleByName indicates an expected call of FindRoleByName.
+func (mr *MockRoleRepoMockRecorder) FindRoleByName(ctx, name any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindRoleByName", reflect.TypeOf((*MockRoleRepo)(nil).FindRoleByName), ctx, name)
+}
+
+// RevokeRole mocks base method.
+func (m *MockRoleRepo) RevokeRole(ctx context.Context, userID, roleID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRole", ctx, userID, roleID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRole indicates an expected call of RevokeRole.
+func (mr *MockRoleRepoMockRecorder) RevokeRole(ctx, userID, roleID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRole", reflect.TypeOf((*MockRoleRepo)(nil).RevokeRole), ctx, userID, roleID)
+}
+
+// UserHasPermission mocks base method.
+func (m *MockRoleRepo) UserHasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UserHasPermission", ctx, userID, permission)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UserHasPermission indicates an expected call of UserHasPermission.
+func (mr *MockRoleRepoMockRecorder) UserHasPermission(ctx, userID, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UserHasPermission", reflect.TypeOf((*MockRoleRepo)(nil).UserHasPermission), ctx, userID, permission)
+}
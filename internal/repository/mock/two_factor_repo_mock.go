@@ -0,0 +1,158 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: TwoFactorRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/two_factor_repo_mock.go -package=mock . TwoFactorRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTwoFactorRepo is a mock of TwoFactorRepo interface.
+type MockTwoFactorRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockTwoFactorRepoMockRecorder is the mock recorder for MockTwoFactorRepo.
+type MockTwoFactorRepoMockRecorder struct {
+	mock *MockTwoFactorRepo
+}
+
+// NewMockTwoFactorRepo creates a new mock instance.
+func NewMockTwoFactorRepo(ctrl *gomock.Controller) *MockTwoFactorRepo {
+	mock := &MockTwoFactorRepo{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorRepo) EXPECT() *MockTwoFactorRepoMockRecorder {
+	return m.recorder
+}
+
+// AdvanceTOTPStep mocks base method.
+func (m *MockTwoFactorRepo) AdvanceTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AdvanceTOTPStep", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// AdvanceTOTPStep indicates an expected call of AdvanceTOTPStep.
+func (mr *MockTwoFactorRepoMockRecorder) AdvanceTOTPStep(ctx, userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AdvanceTOTPStep", reflect.TypeOf((*MockTwoFactorRepo)(nil).AdvanceTOTPStep), ctx, userID, step)
+}
+
+// CreateRecoveryCode mocks base method.
+func (m *MockTwoFactorRepo) CreateRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateRecoveryCode indicates an expected call of CreateRecoveryCode.
+func (mr *MockTwoFactorRepoMockRecorder) CreateRecoveryCode(ctx, userID, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateRecoveryCode", reflect.TypeOf((*MockTwoFactorRepo)(nil).CreateRecoveryCode), ctx, userID, codeHash)
+}
+
+// DeleteRecoveryCodes mocks base method.
+func (m *MockTwoFactorRepo) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteRecoveryCodes", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteRecoveryCodes indicates an expected call of DeleteRecoveryCodes.
+func (mr *MockTwoFactorRepoMockRecorder) DeleteRecoveryCodes(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteRecoveryCodes", reflect.TypeOf((*MockTwoFactorRepo)(nil).DeleteRecoveryCodes), ctx, userID)
+}
+
+// DeleteTOTP mocks base method.
+func (m *MockTwoFactorRepo) DeleteTOTP(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteTOTP", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteTOTP indicates an expected call of DeleteTOTP.
+func (mr *MockTwoFactorRepoMockRecorder) DeleteTOTP(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteTOTP", reflect.TypeOf((*MockTwoFactorRepo)(nil).DeleteTOTP), ctx, userID)
+}
+
+// EnableTOTP mocks base method.
+func (m *MockTwoFactorRepo) EnableTOTP(ctx context.Context, userID string, step int64) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnableTOTP", ctx, userID, step)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnableTOTP indicates an expected call of EnableTOTP.
+func (mr *MockTwoFactorRepoMockRecorder) EnableTOTP(ctx, userID, step any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnableTOTP", reflect.TypeOf((*MockTwoFactorRepo)(nil).EnableTOTP), ctx, userID, step)
+}
+
+// FindTOTP mocks base method.
+func (m *MockTwoFactorRepo) FindTOTP(ctx context.Context, userID string) (*model.TOTP, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindTOTP", ctx, userID)
+	ret0, _ := ret[0].(*model.TOTP)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindTOTP indicates an expected call of FindTOTP.
+func (mr *MockTwoFactorRepoMockRecorder) FindTOTP(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindTOTP", reflect.TypeOf((*MockTwoFactorRepo)(nil).FindTOTP), ctx, userID)
+}
+
+// SaveTOTPSecret mocks base method.
+func (m *MockTwoFactorRepo) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SaveTOTPSecret", ctx, userID, secret)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SaveTOTPSecret indicates an expected call of SaveTOTPSecret.
+func (mr *MockTwoFactorRepoMockRecorder) SaveTOTPSecret(ctx, userID, secret any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SaveTOTPSecret", reflect.TypeOf((*MockTwoFactorRepo)(nil).SaveTOTPSecret), ctx, userID, secret)
+}
+
+// UseRecoveryCode mocks base method.
+func (m *MockTwoFactorRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UseRecoveryCode", ctx, userID, codeHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UseRecoveryCode indicates an expected call of UseRecoveryCode.
+func (mr *MockTwoFactorRepoMockRecorder) UseRecoveryCode(ctx, userID, codeHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UseRecoveryCode", reflect.TypeOf((*MockTwoFactorRepo)(nil).UseRecoveryCode), ctx, userID, codeHash)
+}
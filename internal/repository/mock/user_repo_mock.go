@@ -42,6 +42,36 @@ func (m *MockUserRepo) EXPECT() *MockUserRepoMockRecorder {
 	return m.recorder
 }
 
+// ClaimUnverifiedUser mocks base method.
+func (m *MockUserRepo) ClaimUnverifiedUser(ctx context.Context, userID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ClaimUnverifiedUser", ctx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ClaimUnverifiedUser indicates an expected call of ClaimUnverifiedUser.
+func (mr *MockUserRepoMockRecorder) ClaimUnverifiedUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ClaimUnverifiedUser", reflect.TypeOf((*MockUserRepo)(nil).ClaimUnverifiedUser), ctx, userID)
+}
+
+// CreateExternalUser mocks base method.
+func (m *MockUserRepo) CreateExternalUser(ctx context.Context, email string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateExternalUser", ctx, email)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateExternalUser indicates an expected call of CreateExternalUser.
+func (mr *MockUserRepoMockRecorder) CreateExternalUser(ctx, email any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateExternalUser", reflect.TypeOf((*MockUserRepo)(nil).CreateExternalUser), ctx, email)
+}
+
 // CreateUser mocks base method.
 func (m *MockUserRepo) CreateUser(ctx context.Context, params repository.CreateUserParams) (*model.User, error) {
 	m.ctrl.T.Helper()
@@ -57,6 +87,35 @@ func (mr *MockUserRepoMockRecorder) CreateUser(ctx, params any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateUser", reflect.TypeOf((*MockUserRepo)(nil).CreateUser), ctx, params)
 }
 
+// DisableUser mocks base method.
+func (m *MockUserRepo) DisableUser(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableUser", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableUser indicates an expected call of DisableUser.
+func (mr *MockUserRepoMockRecorder) DisableUser(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableUser", reflect.TypeOf((*MockUserRepo)(nil).DisableUser), ctx, userID)
+}
+
+// FindActiveUserByID mocks base method.
+func (m *MockUserRepo) FindActiveUserByID(ctx context.Context, id string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindActiveUserByID", ctx, id)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindActiveUserByID indicates an expected call of FindActiveUserByID.
+func (mr *MockUserRepoMockRecorder) FindActiveUserByID(ctx, id any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindActiveUserByID", reflect.TypeOf((*MockUserRepo)(nil).FindActiveUserByID), ctx, id)
+}
+
 // FindUserByEmail mocks base method.
 func (m *MockUserRepo) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	m.ctrl.T.Helper()
@@ -71,3 +130,91 @@ func (mr *MockUserRepoMockRecorder) FindUserByEmail(ctx, email any) *gomock.Call
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserByEmail", reflect.TypeOf((*MockUserRepo)(nil).FindUserByEmail), ctx, email)
 }
+
+// FindUserBySessionTokenHash mocks base method.
+func (m *MockUserRepo) FindUserBySessionTokenHash(ctx context.Context, tokenHash string) (*model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindUserBySessionTokenHash", ctx, tokenHash)
+	ret0, _ := ret[0].(*model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindUserBySessionTokenHash indicates an expected call of FindUserBySessionTokenHash.
+func (mr *MockUserRepoMockRecorder) FindUserBySessionTokenHash(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindUserBySessionTokenHash", reflect.TypeOf((*MockUserRepo)(nil).FindUserBySessionTokenHash), ctx, tokenHash)
+}
+
+// ImportUser mocks base method.
+func (m *MockUserRepo) ImportUser(ctx context.Context, params repository.ImportUserParams) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ImportUser", ctx, params)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ImportUser indicates an expected call of ImportUser.
+func (mr *MockUserRepoMockRecorder) ImportUser(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ImportUser", reflect.TypeOf((*MockUserRepo)(nil).ImportUser), ctx, params)
+}
+
+// ListUsers mocks base method.
+func (m *MockUserRepo) ListUsers(ctx context.Context) ([]model.User, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListUsers", ctx)
+	ret0, _ := ret[0].([]model.User)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListUsers indicates an expected call of ListUsers.
+func (mr *MockUserRepoMockRecorder) ListUsers(ctx any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListUsers", reflect.TypeOf((*MockUserRepo)(nil).ListUsers), ctx)
+}
+
+// MarkUserVerified mocks base method.
+func (m *MockUserRepo) MarkUserVerified(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MarkUserVerified", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MarkUserVerified indicates an expected call of MarkUserVerified.
+func (mr *MockUserRepoMockRecorder) MarkUserVerified(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MarkUserVerified", reflect.TypeOf((*MockUserRepo)(nil).MarkUserVerified), ctx, userID)
+}
+
+// RehashUserPassword mocks base method.
+func (m *MockUserRepo) RehashUserPassword(ctx context.Context, userID, oldHash, newHash string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RehashUserPassword", ctx, userID, oldHash, newHash)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RehashUserPassword indicates an expected call of RehashUserPassword.
+func (mr *MockUserRepoMockRecorder) RehashUserPassword(ctx, userID, oldHash, newHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RehashUserPassword", reflect.TypeOf((*MockUserRepo)(nil).RehashUserPassword), ctx, userID, oldHash, newHash)
+}
+
+// UpdateUserPassword mocks base method.
+func (m *MockUserRepo) UpdateUserPassword(ctx context.Context, userID, passwordHash string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateUserPassword", ctx, userID, passwordHash)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdateUserPassword indicates an expected call of UpdateUserPassword.
+func (mr *MockUserRepoMockRecorder) UpdateUserPassword(ctx, userID, passwordHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateUserPassword", reflect.TypeOf((*MockUserRepo)(nil).UpdateUserPassword), ctx, userID, passwordHash)
+}
@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/repository (interfaces: VerificationRepo)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/verification_repo_mock.go -package=mock . VerificationRepo
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockVerificationRepo is a mock of VerificationRepo interface.
+type MockVerificationRepo struct {
+	ctrl     *gomock.Controller
+	recorder *MockVerificationRepoMockRecorder
+	isgomock struct{}
+}
+
+// MockVerificationRepoMockRecorder is the mock recorder for MockVerificationRepo.
+type MockVerificationRepoMockRecorder struct {
+	mock *MockVerificationRepo
+}
+
+// NewMockVerificationRepo creates a new mock instance.
+func NewMockVerificationRepo(ctrl *gomock.Controller) *MockVerificationRepo {
+	mock := &MockVerificationRepo{ctrl: ctrl}
+	mock.recorder = &MockVerificationRepoMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockVerificationRepo) EXPECT() *MockVerificationRepoMockRecorder {
+	return m.recorder
+}
+
+// ConsumeVerification mocks base method.
+func (m *MockVerificationRepo) ConsumeVerification(ctx context.Context, tokenHash string) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConsumeVerification", ctx, tokenHash)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConsumeVerification indicates an expected call of ConsumeVerification.
+func (mr *MockVerificationRepoMockRecorder) ConsumeVerification(ctx, tokenHash any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConsumeVerification", reflect.TypeOf((*MockVerificationRepo)(nil).ConsumeVerification), ctx, tokenHash)
+}
+
+// CreateVerification mocks base method.
+func (m *MockVerificationRepo) CreateVerification(ctx context.Context, params repository.CreateVerificationParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateVerification", ctx, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// CreateVerification indicates an expected call of CreateVerification.
+func (mr *MockVerificationRepoMockRecorder) CreateVerification(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateVerification", reflect.TypeOf((*MockVerificationRepo)(nil).CreateVerification), ctx, params)
+}
+
+// DeleteUserVerifications mocks base method.
+func (m *MockVerificationRepo) DeleteUserVerifications(ctx context.Context, userID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteUserVerifications", ctx, userID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteUserVerifications indicates an expected call of DeleteUserVerifications.
+func (mr *MockVerificationRepoMockRecorder) DeleteUserVerifications(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteUserVerifications", reflect.TypeOf((*MockVerificationRepo)(nil).DeleteUserVerifications), ctx, userID)
+}
@@ -0,0 +1,66 @@
+//go:generate mockgen -destination=mock/password_reset_repo_mock.go -package=mock . PasswordResetRepo
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type PasswordResetRepo interface {
+	CreatePasswordReset(ctx context.Context, params CreatePasswordResetParams) error
+	ConsumePasswordReset(ctx context.Context, tokenHash string) (string, error)
+	DeleteUserPasswordResets(ctx context.Context, userID string) error
+}
+
+type passwordResetRepo struct {
+	db DBTX
+}
+
+var _ PasswordResetRepo = (*passwordResetRepo)(nil)
+
+func NewPasswordResetRepository(db DBTX) PasswordResetRepo {
+	return &passwordResetRepo{db: db}
+}
+
+type CreatePasswordResetParams struct {
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+const CreatePasswordResetQuery = `
+INSERT INTO password_resets (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+`
+
+func (r *passwordResetRepo) CreatePasswordReset(ctx context.Context, params CreatePasswordResetParams) error {
+	_, err := r.db.ExecContext(ctx, CreatePasswordResetQuery, params.UserID, params.TokenHash, params.ExpiresAt)
+	return mapError(err)
+}
+
+const ConsumePasswordResetQuery = `
+DELETE FROM password_resets
+WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id
+`
+
+// ConsumePasswordReset deletes the unexpired reset of tokenHash and returns
+// its user, or sql.ErrNoRows when there is none. Only one of concurrent
+// calls with the same token gets the user.
+func (r *passwordResetRepo) ConsumePasswordReset(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+	if err := r.db.QueryRowContext(ctx, ConsumePasswordResetQuery, tokenHash).Scan(&userID); err != nil {
+		return "", mapError(err)
+	}
+	return userID, nil
+}
+
+const DeleteUserPasswordResetsQuery = `
+DELETE FROM password_resets
+WHERE user_id = $1
+`
+
+func (r *passwordResetRepo) DeleteUserPasswordResets(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DeleteUserPasswordResetsQuery, userID)
+	return err
+}
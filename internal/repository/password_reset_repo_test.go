@@ -0,0 +1,35 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPasswordResetRepo_ConsumePasswordReset(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(repository.ConsumePasswordResetQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("2"))
+	mock.ExpectQuery(repository.ConsumePasswordResetQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	repo := repository.NewPasswordResetRepository(db)
+	userID, err := repo.ConsumePasswordReset(context.Background(), "hashed")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", userID)
+
+	_, err = repo.ConsumePasswordReset(context.Background(), "hashed")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "A token is only consumed once")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
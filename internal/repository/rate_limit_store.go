@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
+)
+
+// How often a replica removes expired rate limits
+const rateLimitSweepInterval = time.Minute
+
+// rateLimitStore keeps the state of rate limiters in Postgres so that the
+// limits hold across replicas. Each update locks the row of its key.
+type rateLimitStore struct {
+	db *sql.DB
+
+	mu        sync.Mutex
+	lastSweep time.Time
+}
+
+var _ ratelimit.Store = (*rateLimitStore)(nil)
+
+func NewRateLimitStore(db *sql.DB) ratelimit.Store {
+	return &rateLimitStore{db: db}
+}
+
+// Locks the row of the key, creating it first if needed. The state of an
+// expired row is returned as new.
+const LockRateLimitQuery = `
+INSERT INTO rate_limits (key, expires_at)
+VALUES ($1, $2)
+ON CONFLICT (key) DO UPDATE
+SET count = CASE WHEN rate_limits.expires_at < $3 THEN 0 ELSE rate_limits.count END,
+	previous = CASE WHEN rate_limits.expires_at < $3 THEN 0 ELSE rate_limits.previous END,
+	at = CASE WHEN rate_limits.expires_at < $3 THEN NULL ELSE rate_limits.at END
+RETURNING count, previous, at
+`
+
+const UpdateRateLimitQuery = `
+UPDATE rate_limits
+SET count = $2, previous = $3, at = $4, expires_at = $5
+WHERE key = $1
+`
+
+const DeleteExpiredRateLimitsQuery = `
+DELETE FROM rate_limits
+WHERE expires_at < $1
+`
+
+func (s *rateLimitStore) Update(ctx context.Context, key string, expiresAt time.Time,
+	fn func(*ratelimit.State)) (err error) {
+	now := time.Now()
+	if s.sweepDue(now) {
+		if _, err := s.db.ExecContext(ctx, DeleteExpiredRateLimitsQuery, now); err != nil {
+			return fmt.Errorf("delete expired rate limits: %w", mapError(err))
+		}
+	}
+
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("rollback transaction: %w", rbErr))
+			}
+		}
+	}()
+
+	var state ratelimit.State
+	var at sql.NullTime
+	if err := tx.QueryRowContext(ctx, LockRateLimitQuery, key, expiresAt, now).
+		Scan(&state.Count, &state.Previous, &at); err != nil {
+		return mapError(err)
+	}
+	state.Time = at.Time
+
+	fn(&state)
+
+	if _, err := tx.ExecContext(ctx, UpdateRateLimitQuery,
+		key, state.Count, state.Previous, state.Time, expiresAt); err != nil {
+		return mapError(err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit transaction: %w", mapError(err))
+	}
+	return nil
+}
+
+func (s *rateLimitStore) sweepDue(now time.Time) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if now.Sub(s.lastSweep) < rateLimitSweepInterval {
+		return false
+	}
+	s.lastSweep = now
+	return true
+}
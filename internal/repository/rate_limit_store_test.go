@@ -0,0 +1,45 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/pkg/ratelimit"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimitStore_Update(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	const key = "api:ip:192.0.2.1"
+	at := time.Now().Add(-time.Second)
+	expiresAt := time.Now().Add(time.Minute)
+
+	mock.ExpectExec(repository.DeleteExpiredRateLimitsQuery).
+		WithArgs(sqlmock.AnyArg()).
+		WillReturnResult(sqlmock.NewResult(0, 3))
+	mock.ExpectBegin()
+	mock.ExpectQuery(repository.LockRateLimitQuery).
+		WithArgs(key, expiresAt, sqlmock.AnyArg()).
+		WillReturnRows(sqlmock.NewRows([]string{"count", "previous", "at"}).AddRow(2.5, 0, at))
+	mock.ExpectExec(repository.UpdateRateLimitQuery).
+		WithArgs(key, 1.5, 0.0, at, expiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	store := repository.NewRateLimitStore(db)
+	err = store.Update(context.Background(), key, expiresAt, func(s *ratelimit.State) {
+		assert.Equal(t, 2.5, s.Count)
+		assert.Equal(t, at, s.Time)
+		s.Count--
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
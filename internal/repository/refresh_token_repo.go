@@ -0,0 +1,109 @@
+//go:generate mockgen -destination=mock/refresh_token_repo_mock.go -package=mock . RefreshTokenRepo
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type RefreshTokenRepo interface {
+	CreateRefreshToken(ctx context.Context, params CreateRefreshTokenParams) (*model.RefreshToken, error)
+	FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error)
+	RevokeRefreshToken(ctx context.Context, id string) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyID string) error
+	RevokeUserRefreshTokens(ctx context.Context, userID string) error
+}
+
+type refreshTokenRepo struct {
+	db DBTX
+}
+
+var _ RefreshTokenRepo = (*refreshTokenRepo)(nil)
+
+func NewRefreshTokenRepository(db DBTX) RefreshTokenRepo {
+	return &refreshTokenRepo{db: db}
+}
+
+// CreateRefreshTokenParams starts a new family when FamilyID is empty.
+type CreateRefreshTokenParams struct {
+	UserID    string
+	FamilyID  string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+const CreateRefreshTokenQuery = `
+INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at)
+VALUES ($1, COALESCE(NULLIF($2, '')::uuid, gen_random_uuid()), $3, $4)
+RETURNING id, user_id, family_id, expires_at, created_at
+`
+
+func (r *refreshTokenRepo) CreateRefreshToken(ctx context.Context, params CreateRefreshTokenParams) (*model.RefreshToken, error) {
+	token := model.RefreshToken{TokenHash: params.TokenHash}
+	if err := r.db.QueryRowContext(ctx, CreateRefreshTokenQuery,
+		params.UserID, params.FamilyID, params.TokenHash, params.ExpiresAt).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.ExpiresAt, &token.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+const FindRefreshTokenByHashQuery = `
+SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, created_at FROM refresh_tokens
+WHERE token_hash = $1
+LIMIT 1
+`
+
+func (r *refreshTokenRepo) FindRefreshTokenByHash(ctx context.Context, tokenHash string) (*model.RefreshToken, error) {
+	var token model.RefreshToken
+	if err := r.db.QueryRowContext(ctx, FindRefreshTokenByHashQuery, tokenHash).
+		Scan(&token.ID, &token.UserID, &token.FamilyID, &token.TokenHash, &token.ExpiresAt, &token.RevokedAt,
+			&token.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &token, nil
+}
+
+const RevokeRefreshTokenQuery = `
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND revoked_at IS NULL
+`
+
+// RevokeRefreshToken reports whether this call revoked the token, which is
+// false when it was already revoked by a concurrent use.
+func (r *refreshTokenRepo) RevokeRefreshToken(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, RevokeRefreshTokenQuery, id)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const RevokeRefreshTokenFamilyQuery = `
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE family_id = $1 AND revoked_at IS NULL
+`
+
+func (r *refreshTokenRepo) RevokeRefreshTokenFamily(ctx context.Context, familyID string) error {
+	_, err := r.db.ExecContext(ctx, RevokeRefreshTokenFamilyQuery, familyID)
+	return mapError(err)
+}
+
+const RevokeUserRefreshTokensQuery = `
+UPDATE refresh_tokens
+SET revoked_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND revoked_at IS NULL
+`
+
+func (r *refreshTokenRepo) RevokeUserRefreshTokens(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, RevokeUserRefreshTokensQuery, userID)
+	return mapError(err)
+}
@@ -0,0 +1,68 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRefreshTokenRepo_CreateRefreshToken(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateRefreshTokenParams{
+		UserID:    "1",
+		TokenHash: "hashed",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectQuery(repository.CreateRefreshTokenQuery).
+		WithArgs(params.UserID, params.FamilyID, params.TokenHash, params.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "family_id", "expires_at", "created_at"}).
+			AddRow("2", params.UserID, "3", params.ExpiresAt, time.Now()))
+
+	repo := repository.NewRefreshTokenRepository(db)
+	token, err := repo.CreateRefreshToken(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Equal(t, "3", token.FamilyID)
+	assert.Equal(t, params.TokenHash, token.TokenHash)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRefreshTokenRepo_RevokeRefreshToken(t *testing.T) {
+	var tests = []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{"Revoked", 1, true},
+		{"Already revoked", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(repository.RevokeRefreshTokenQuery).
+				WithArgs("1").
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			repo := repository.NewRefreshTokenRepository(db)
+			revoked, err := repo.RevokeRefreshToken(context.Background(), "1")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, revoked)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
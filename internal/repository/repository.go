@@ -1,15 +1,132 @@
 package repository
 
-import "database/sql"
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+)
+
+// DBTX is satisfied by both *sql.DB and *sql.Tx so that repositories can run
+// their queries either directly or inside a transaction.
+type DBTX interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
 
 type Repository struct {
-	Base BaseRepository
-	User UserRepo
+	Base          BaseRepository
+	User          UserRepo
+	Session       SessionRepo
+	Verification  VerificationRepo
+	PasswordReset PasswordResetRepo
+	Job           JobRepo
+	Role          RoleRepo
+	RefreshToken  RefreshTokenRepo
+	APIKey        APIKeyRepo
+	TwoFactor     TwoFactorRepo
+	Challenge     LoginChallengeRepo
+	Identity      IdentityRepo
+	LoginAttempt  LoginAttemptRepo
+	Audit         AuditRepo
+	KeyRotation   KeyRotationRepo
+
+	// nil when the repository is bound to a transaction
+	db      *sql.DB
+	keyring *security.Keyring
 }
 
-func NewRepository(db *sql.DB) *Repository {
+// NewRepository returns the repositories of db. Columns that are stored
+// encrypted are encrypted with keyring.
+func NewRepository(db *sql.DB, keyring *security.Keyring) *Repository {
+	r := newRepository(db, keyring)
+	r.Base = NewBaseRepository(db)
+	r.db = db
+	return r
+}
+
+func newRepository(db DBTX, keyring *security.Keyring) *Repository {
 	return &Repository{
-		Base: NewBaseRepository(db),
-		User: NewUserRepository(db),
+		User:          NewUserRepository(db),
+		Session:       NewSessionRepository(db),
+		Verification:  NewVerificationRepository(db),
+		PasswordReset: NewPasswordResetRepository(db),
+		Job:           NewJobRepository(db),
+		Role:          NewRoleRepository(db),
+		RefreshToken:  NewRefreshTokenRepository(db),
+		APIKey:        NewAPIKeyRepository(db),
+		TwoFactor:     NewTwoFactorRepository(db, keyring),
+		Challenge:     NewLoginChallengeRepository(db),
+		Identity:      NewIdentityRepository(db),
+		LoginAttempt:  NewLoginAttemptRepository(db),
+		Audit:         NewAuditRepository(db),
+		KeyRotation:   NewKeyRotationRepository(db),
+		keyring:       keyring,
+	}
+}
+
+// TxOption customizes the transaction started by WithTx.
+type TxOption func(*sql.TxOptions)
+
+// WithIsolation sets the isolation level of the transaction.
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *sql.TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction.
+func ReadOnly() TxOption {
+	return func(o *sql.TxOptions) {
+		o.ReadOnly = true
 	}
 }
+
+// WithTx calls fn with a Repository whose repos all run on a single
+// transaction. The transaction is committed if fn returns nil and rolled back
+// if it returns an error or panics.
+//
+// A Repository that is already bound to a transaction, or was not created by
+// NewRepository, calls fn with itself so that nested calls join the outer
+// unit of work.
+func (r *Repository) WithTx(ctx context.Context, fn func(*Repository) error, opts ...TxOption) (err error) {
+	if r.db == nil {
+		return fn(r)
+	}
+
+	var txOpts sql.TxOptions
+	for _, opt := range opts {
+		opt(&txOpts)
+	}
+
+	tx, err := r.db.BeginTx(ctx, &txOpts)
+	if err != nil {
+		return fmt.Errorf("begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+
+		if err != nil {
+			if rbErr := tx.Rollback(); rbErr != nil {
+				err = errors.Join(err, fmt.Errorf("rollback transaction: %w", rbErr))
+			}
+			return
+		}
+
+		if err = tx.Commit(); err != nil {
+			err = fmt.Errorf("commit transaction: %w", mapError(err))
+		}
+	}()
+
+	txRepo := newRepository(tx, r.keyring)
+	txRepo.Base = r.Base
+
+	return fn(txRepo)
+}
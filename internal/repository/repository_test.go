@@ -0,0 +1,93 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRepository_WithTxCommit(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(repository.MarkUserVerifiedQuery).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectCommit()
+
+	repo := repository.NewRepository(db, nil)
+	err = repo.WithTx(context.Background(), func(tx *repository.Repository) error {
+		assert.NotSame(t, repo, tx)
+		return tx.User.MarkUserVerified(context.Background(), "1")
+	}, repository.WithIsolation(sql.LevelSerializable))
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_WithTxRollback(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	errFailed := errors.New("failed")
+	repo := repository.NewRepository(db, nil)
+	err = repo.WithTx(context.Background(), func(*repository.Repository) error {
+		return errFailed
+	})
+	assert.ErrorIs(t, err, errFailed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_WithTxPanic(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectRollback()
+
+	repo := repository.NewRepository(db, nil)
+	assert.PanicsWithValue(t, "boom", func() {
+		_ = repo.WithTx(context.Background(), func(*repository.Repository) error {
+			panic("boom")
+		})
+	})
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestRepository_WithTxNested(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectCommit()
+
+	repo := repository.NewRepository(db, nil)
+	err = repo.WithTx(context.Background(), func(tx *repository.Repository) error {
+		return tx.WithTx(context.Background(), func(inner *repository.Repository) error {
+			assert.Same(t, tx, inner)
+			return nil
+		})
+	})
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
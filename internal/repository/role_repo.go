@@ -0,0 +1,78 @@
+//go:generate mockgen -destination=mock/role_repo_mock.go -package=mock . RoleRepo
+package repository
+
+import (
+	"context"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type RoleRepo interface {
+	FindRoleByName(ctx context.Context, name string) (*model.Role, error)
+	AssignRole(ctx context.Context, userID, roleID string) error
+	RevokeRole(ctx context.Context, userID, roleID string) error
+	UserHasPermission(ctx context.Context, userID, permission string) (bool, error)
+}
+
+type roleRepo struct {
+	db DBTX
+}
+
+var _ RoleRepo = (*roleRepo)(nil)
+
+func NewRoleRepository(db DBTX) RoleRepo {
+	return &roleRepo{db: db}
+}
+
+const FindRoleByNameQuery = `
+SELECT id, name, COALESCE(description, ''), created_at, updated_at FROM roles
+WHERE name = $1
+LIMIT 1
+`
+
+func (r *roleRepo) FindRoleByName(ctx context.Context, name string) (*model.Role, error) {
+	var role model.Role
+	if err := r.db.QueryRowContext(ctx, FindRoleByNameQuery, name).
+		Scan(&role.ID, &role.Name, &role.Description, &role.CreatedAt, &role.UpdatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &role, nil
+}
+
+const AssignRoleQuery = `
+INSERT INTO user_roles (user_id, role_id)
+VALUES ($1, $2)
+ON CONFLICT DO NOTHING
+`
+
+func (r *roleRepo) AssignRole(ctx context.Context, userID, roleID string) error {
+	_, err := r.db.ExecContext(ctx, AssignRoleQuery, userID, roleID)
+	return mapError(err)
+}
+
+const RevokeRoleQuery = `
+DELETE FROM user_roles
+WHERE user_id = $1 AND role_id = $2
+`
+
+func (r *roleRepo) RevokeRole(ctx context.Context, userID, roleID string) error {
+	_, err := r.db.ExecContext(ctx, RevokeRoleQuery, userID, roleID)
+	return mapError(err)
+}
+
+const UserHasPermissionQuery = `
+SELECT EXISTS (
+	SELECT 1 FROM user_roles ur
+	JOIN role_permissions rp ON rp.role_id = ur.role_id
+	JOIN permissions p ON p.id = rp.permission_id
+	WHERE ur.user_id = $1 AND p.name = $2
+)
+`
+
+func (r *roleRepo) UserHasPermission(ctx context.Context, userID, permission string) (bool, error) {
+	var ok bool
+	if err := r.db.QueryRowContext(ctx, UserHasPermissionQuery, userID, permission).Scan(&ok); err != nil {
+		return false, mapError(err)
+	}
+	return ok, nil
+}
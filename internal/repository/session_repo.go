@@ -0,0 +1,73 @@
+//go:generate mockgen -destination=mock/session_repo_mock.go -package=mock . SessionRepo
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+)
+
+type SessionRepo interface {
+	CreateSession(ctx context.Context, params CreateSessionParams) (*model.Session, error)
+	DeleteSessionByTokenHash(ctx context.Context, tokenHash string) error
+	DeleteUserSessions(ctx context.Context, userID string) error
+}
+
+type sessionRepo struct {
+	db DBTX
+}
+
+var _ SessionRepo = (*sessionRepo)(nil)
+
+func NewSessionRepository(db DBTX) SessionRepo {
+	return &sessionRepo{db: db}
+}
+
+type CreateSessionParams struct {
+	UserID    string
+	TokenHash string
+	UserAgent string
+	IPAddress string
+	ExpiresAt time.Time
+}
+
+const CreateSessionQuery = `
+INSERT INTO sessions (user_id, token_hash, user_agent, ip_address, expires_at)
+VALUES ($1, $2, $3, $4, $5)
+RETURNING id, user_id, expires_at, created_at, updated_at
+`
+
+func (r *sessionRepo) CreateSession(ctx context.Context, params CreateSessionParams) (*model.Session, error) {
+	session := model.Session{
+		TokenHash: params.TokenHash,
+		UserAgent: params.UserAgent,
+		IPAddress: params.IPAddress,
+	}
+	if err := r.db.QueryRowContext(ctx, CreateSessionQuery,
+		params.UserID, params.TokenHash, params.UserAgent, params.IPAddress, params.ExpiresAt).
+		Scan(&session.ID, &session.UserID, &session.ExpiresAt, &session.CreatedAt, &session.UpdatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &session, nil
+}
+
+const DeleteSessionByTokenHashQuery = `
+DELETE FROM sessions
+WHERE token_hash = $1
+`
+
+func (r *sessionRepo) DeleteSessionByTokenHash(ctx context.Context, tokenHash string) error {
+	_, err := r.db.ExecContext(ctx, DeleteSessionByTokenHashQuery, tokenHash)
+	return err
+}
+
+const DeleteUserSessionsQuery = `
+DELETE FROM sessions
+WHERE user_id = $1
+`
+
+func (r *sessionRepo) DeleteUserSessions(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DeleteUserSessionsQuery, userID)
+	return err
+}
@@ -0,0 +1,58 @@
+package repository_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSessionRepo_CreateSession(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateSessionParams{
+		UserID:    "1",
+		TokenHash: "hashed",
+		UserAgent: "test",
+		IPAddress: "127.0.0.1",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectQuery(repository.CreateSessionQuery).
+		WithArgs(params.UserID, params.TokenHash, params.UserAgent, params.IPAddress, params.ExpiresAt).
+		WillReturnRows(sqlmock.NewRows([]string{"id", "user_id", "expires_at", "created_at", "updated_at"}).
+			AddRow("1", params.UserID, params.ExpiresAt, time.Now(), time.Now()))
+
+	repo := repository.NewSessionRepository(db)
+	session, err := repo.CreateSession(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NotZero(t, session.ID)
+	assert.Equal(t, params.UserID, session.UserID)
+	assert.Equal(t, params.TokenHash, session.TokenHash)
+	assert.Equal(t, params.ExpiresAt, session.ExpiresAt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSessionRepo_DeleteSessionByTokenHash(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.DeleteSessionByTokenHashQuery).
+		WithArgs("hashed").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewSessionRepository(db)
+	err = repo.DeleteSessionByTokenHash(context.Background(), "hashed")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,153 @@
+//go:generate mockgen -destination=mock/two_factor_repo_mock.go -package=mock . TwoFactorRepo
+package repository
+
+import (
+	"context"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+)
+
+type TwoFactorRepo interface {
+	SaveTOTPSecret(ctx context.Context, userID, secret string) error
+	FindTOTP(ctx context.Context, userID string) (*model.TOTP, error)
+	EnableTOTP(ctx context.Context, userID string, step int64) (bool, error)
+	AdvanceTOTPStep(ctx context.Context, userID string, step int64) (bool, error)
+	DeleteTOTP(ctx context.Context, userID string) error
+	CreateRecoveryCode(ctx context.Context, userID, codeHash string) error
+	UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error)
+	DeleteRecoveryCodes(ctx context.Context, userID string) error
+}
+
+type twoFactorRepo struct {
+	db      DBTX
+	keyring *security.Keyring
+}
+
+var _ TwoFactorRepo = (*twoFactorRepo)(nil)
+
+// NewTwoFactorRepository returns a TwoFactorRepo that encrypts the TOTP
+// secrets with keyring.
+func NewTwoFactorRepository(db DBTX, keyring *security.Keyring) TwoFactorRepo {
+	return &twoFactorRepo{db: db, keyring: keyring}
+}
+
+const SaveTOTPSecretQuery = `
+INSERT INTO user_totp (user_id, secret)
+VALUES ($1, $2)
+ON CONFLICT (user_id) DO UPDATE
+SET secret = EXCLUDED.secret, last_step = 0, created_at = CURRENT_TIMESTAMP
+WHERE user_totp.enabled_at IS NULL
+`
+
+// SaveTOTPSecret stores the secret of a pending enrollment, encrypted. The
+// secret of an enabled TOTP is left untouched.
+func (r *twoFactorRepo) SaveTOTPSecret(ctx context.Context, userID, secret string) error {
+	_, err := r.db.ExecContext(ctx, SaveTOTPSecretQuery, userID,
+		r.keyring.EncryptedString(&secret, TOTPSecretColumn.Context(userID)))
+	return mapError(err)
+}
+
+const FindTOTPQuery = `
+SELECT user_id, secret, enabled_at, last_step, created_at FROM user_totp
+WHERE user_id = $1
+`
+
+func (r *twoFactorRepo) FindTOTP(ctx context.Context, userID string) (*model.TOTP, error) {
+	var totp model.TOTP
+	if err := r.db.QueryRowContext(ctx, FindTOTPQuery, userID).
+		Scan(&totp.UserID, r.keyring.EncryptedString(&totp.Secret, TOTPSecretColumn.Context(userID)),
+			&totp.EnabledAt, &totp.LastStep, &totp.CreatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &totp, nil
+}
+
+const EnableTOTPQuery = `
+UPDATE user_totp
+SET enabled_at = CURRENT_TIMESTAMP, last_step = $2
+WHERE user_id = $1 AND enabled_at IS NULL
+`
+
+// EnableTOTP reports whether a pending enrollment was enabled.
+func (r *twoFactorRepo) EnableTOTP(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, EnableTOTPQuery, userID, step)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const AdvanceTOTPStepQuery = `
+UPDATE user_totp
+SET last_step = $2
+WHERE user_id = $1 AND last_step < $2
+`
+
+// AdvanceTOTPStep records step as used. It reports false when a code of the
+// same or a later step was already accepted.
+func (r *twoFactorRepo) AdvanceTOTPStep(ctx context.Context, userID string, step int64) (bool, error) {
+	res, err := r.db.ExecContext(ctx, AdvanceTOTPStepQuery, userID, step)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const DeleteTOTPQuery = `
+DELETE FROM user_totp
+WHERE user_id = $1
+`
+
+func (r *twoFactorRepo) DeleteTOTP(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DeleteTOTPQuery, userID)
+	return mapError(err)
+}
+
+const CreateRecoveryCodeQuery = `
+INSERT INTO recovery_codes (user_id, code_hash)
+VALUES ($1, $2)
+`
+
+func (r *twoFactorRepo) CreateRecoveryCode(ctx context.Context, userID, codeHash string) error {
+	_, err := r.db.ExecContext(ctx, CreateRecoveryCodeQuery, userID, codeHash)
+	return mapError(err)
+}
+
+const UseRecoveryCodeQuery = `
+UPDATE recovery_codes
+SET used_at = CURRENT_TIMESTAMP
+WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL
+`
+
+// UseRecoveryCode reports whether this call used up an unused code of the
+// user with the given hash.
+func (r *twoFactorRepo) UseRecoveryCode(ctx context.Context, userID, codeHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, UseRecoveryCodeQuery, userID, codeHash)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const DeleteRecoveryCodesQuery = `
+DELETE FROM recovery_codes
+WHERE user_id = $1
+`
+
+func (r *twoFactorRepo) DeleteRecoveryCodes(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DeleteRecoveryCodesQuery, userID)
+	return mapError(err)
+}
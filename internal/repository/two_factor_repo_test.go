@@ -0,0 +1,159 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+// argFunc is a sqlmock.Argument that matches the arguments that f accepts.
+type argFunc func(driver.Value) bool
+
+func (f argFunc) Match(v driver.Value) bool {
+	return f(v)
+}
+
+// Returns a keyring for the encrypted columns of the test.
+func newTestKeyring(t *testing.T) *security.Keyring {
+	t.Helper()
+	key, err := security.GenerateRandomBytes(security.CipherKeyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	k, err := security.NewKeyring("k1", []security.EncryptionKey{{ID: "k1", Algorithm: security.AlgAES256GCM, Key: key}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return k
+}
+
+func TestTwoFactorRepo_SaveTOTPSecret(t *testing.T) {
+	k := newTestKeyring(t)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	var stored string
+	mock.ExpectExec(repository.SaveTOTPSecretQuery).
+		WithArgs("1", argFunc(func(v driver.Value) bool {
+			stored, _ = v.(string)
+			return true
+		})).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewTwoFactorRepository(db, k)
+	err = repo.SaveTOTPSecret(context.Background(), "1", "secret")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	assert.Equal(t, "k1", security.KeyID(stored), "the secret must be stored encrypted")
+	plaintext, err := k.Decrypt(stored, repository.TOTPSecretColumn.Context("1"))
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", string(plaintext))
+}
+
+func TestTwoFactorRepo_FindTOTP(t *testing.T) {
+	k := newTestKeyring(t)
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	encrypted, err := k.Encrypt([]byte("secret"), repository.TOTPSecretColumn.Context("1"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	mock.ExpectQuery(repository.FindTOTPQuery).
+		WithArgs("1").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "enabled_at", "last_step", "created_at"}).
+			AddRow("1", encrypted, now, 42, now))
+	// The secret of user 1 copied to user 2
+	mock.ExpectQuery(repository.FindTOTPQuery).
+		WithArgs("2").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id", "secret", "enabled_at", "last_step", "created_at"}).
+			AddRow("2", encrypted, now, 42, now))
+
+	repo := repository.NewTwoFactorRepository(db, k)
+	totp, err := repo.FindTOTP(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "secret", totp.Secret)
+	assert.Equal(t, int64(42), totp.LastStep)
+	assert.NotNil(t, totp.EnabledAt)
+
+	_, err = repo.FindTOTP(context.Background(), "2")
+	assert.ErrorIs(t, err, security.ErrDecrypt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestTwoFactorRepo_AdvanceTOTPStep(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{"new step", 1, true},
+		{"step already used", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(repository.AdvanceTOTPStepQuery).
+				WithArgs("1", int64(42)).
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			repo := repository.NewTwoFactorRepository(db, nil)
+			advanced, err := repo.AdvanceTOTPStep(context.Background(), "1", 42)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, advanced)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
+
+func TestTwoFactorRepo_UseRecoveryCode(t *testing.T) {
+	tests := []struct {
+		name     string
+		affected int64
+		want     bool
+	}{
+		{"unused code", 1, true},
+		{"used or unknown code", 0, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+			if err != nil {
+				t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+			}
+			defer db.Close()
+
+			mock.ExpectExec(repository.UseRecoveryCodeQuery).
+				WithArgs("1", "hash").
+				WillReturnResult(sqlmock.NewResult(0, tt.affected))
+
+			repo := repository.NewTwoFactorRepository(db, nil)
+			used, err := repo.UseRecoveryCode(context.Background(), "1", "hash")
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, used)
+			assert.NoError(t, mock.ExpectationsWereMet())
+		})
+	}
+}
@@ -3,23 +3,33 @@ package repository
 
 import (
 	"context"
-	"database/sql"
+	"time"
 
 	"github.com/ferdiebergado/goweb/internal/model"
 )
 
 type UserRepo interface {
 	CreateUser(ctx context.Context, params CreateUserParams) (*model.User, error)
+	CreateExternalUser(ctx context.Context, email string) (*model.User, error)
+	ImportUser(ctx context.Context, params ImportUserParams) (bool, error)
 	FindUserByEmail(ctx context.Context, email string) (*model.User, error)
+	FindUserBySessionTokenHash(ctx context.Context, tokenHash string) (*model.User, error)
+	FindActiveUserByID(ctx context.Context, id string) (*model.User, error)
+	MarkUserVerified(ctx context.Context, userID string) error
+	ClaimUnverifiedUser(ctx context.Context, userID string) (bool, error)
+	UpdateUserPassword(ctx context.Context, userID, passwordHash string) error
+	RehashUserPassword(ctx context.Context, userID, oldHash, newHash string) (bool, error)
+	ListUsers(ctx context.Context) ([]model.User, error)
+	DisableUser(ctx context.Context, userID string) error
 }
 
 type userRepo struct {
-	db *sql.DB
+	db DBTX
 }
 
 var _ UserRepo = (*userRepo)(nil)
 
-func NewUserRepository(db *sql.DB) UserRepo {
+func NewUserRepository(db DBTX) UserRepo {
 	return &userRepo{db: db}
 }
 
@@ -38,13 +48,57 @@ func (r *userRepo) CreateUser(ctx context.Context, params CreateUserParams) (*mo
 	var user model.User
 	if err := r.db.QueryRowContext(ctx, CreateUserQuery, params.Email, params.PasswordHash).
 		Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return nil, err
+		return nil, mapError(err)
 	}
 	return &user, nil
 }
 
+type ImportUserParams struct {
+	Email        string
+	PasswordHash string
+	VerifiedAt   *time.Time
+}
+
+const ImportUserQuery = `
+INSERT INTO users (email, password_hash, verified_at)
+VALUES ($1, $2, $3)
+ON CONFLICT (email) DO NOTHING
+`
+
+// ImportUser creates a user with the hash of another system. It reports
+// whether the user was created, false meaning that the email is taken.
+func (r *userRepo) ImportUser(ctx context.Context, params ImportUserParams) (bool, error) {
+	res, err := r.db.ExecContext(ctx, ImportUserQuery, params.Email, params.PasswordHash, params.VerifiedAt)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const CreateExternalUserQuery = `
+INSERT INTO users (email, verified_at)
+VALUES ($1, CURRENT_TIMESTAMP)
+RETURNING id, email, verified_at, created_at, updated_at
+`
+
+// CreateExternalUser creates a user without a password whose email was
+// verified by an identity provider.
+func (r *userRepo) CreateExternalUser(ctx context.Context, email string) (*model.User, error) {
+	var user model.User
+	if err := r.db.QueryRowContext(ctx, CreateExternalUserQuery, email).
+		Scan(&user.ID, &user.Email, &user.VerifiedAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &user, nil
+}
+
+// PasswordHash is empty for users without a password.
 const FindUserByEmailQuery = `
-SELECT id, email, created_at, updated_at FROM users
+SELECT id, email, COALESCE(password_hash, ''), verified_at, disabled_at, created_at, updated_at FROM users
 WHERE email = $1
 LIMIT 1
 `
@@ -52,8 +106,164 @@ LIMIT 1
 func (r *userRepo) FindUserByEmail(ctx context.Context, email string) (*model.User, error) {
 	var user model.User
 	if err := r.db.QueryRowContext(ctx, FindUserByEmailQuery, email).
-		Scan(&user.ID, &user.Email, &user.CreatedAt, &user.UpdatedAt); err != nil {
-		return nil, err
+		Scan(&user.ID, &user.Email, &user.PasswordHash, &user.VerifiedAt, &user.DisabledAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &user, nil
+}
+
+// Only active sessions of enabled users resolve to a user.
+const FindUserBySessionTokenHashQuery = `
+SELECT u.id, u.email, u.verified_at, u.disabled_at, u.created_at, u.updated_at FROM sessions s
+JOIN users u ON u.id = s.user_id
+WHERE s.token_hash = $1 AND s.expires_at > CURRENT_TIMESTAMP
+	AND u.disabled_at IS NULL AND u.deleted_at IS NULL
+LIMIT 1
+`
+
+func (r *userRepo) FindUserBySessionTokenHash(ctx context.Context, tokenHash string) (*model.User, error) {
+	var user model.User
+	if err := r.db.QueryRowContext(ctx, FindUserBySessionTokenHashQuery, tokenHash).
+		Scan(&user.ID, &user.Email, &user.VerifiedAt, &user.DisabledAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, mapError(err)
 	}
 	return &user, nil
 }
+
+const FindActiveUserByIDQuery = `
+SELECT id, email, verified_at, disabled_at, created_at, updated_at FROM users
+WHERE id = $1 AND disabled_at IS NULL AND deleted_at IS NULL
+LIMIT 1
+`
+
+// FindActiveUserByID returns the user unless it is disabled or deleted.
+func (r *userRepo) FindActiveUserByID(ctx context.Context, id string) (*model.User, error) {
+	var user model.User
+	if err := r.db.QueryRowContext(ctx, FindActiveUserByIDQuery, id).
+		Scan(&user.ID, &user.Email, &user.VerifiedAt, &user.DisabledAt, &user.CreatedAt, &user.UpdatedAt); err != nil {
+		return nil, mapError(err)
+	}
+	return &user, nil
+}
+
+const MarkUserVerifiedQuery = `
+UPDATE users
+SET verified_at = CURRENT_TIMESTAMP, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (r *userRepo) MarkUserVerified(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, MarkUserVerifiedQuery, userID)
+	return mapError(err)
+}
+
+// The password of an unverified user was set by whoever registered the email,
+// who may not own it. It is removed when the owner proves otherwise.
+const ClaimUnverifiedUserQuery = `
+UPDATE users
+SET verified_at = CURRENT_TIMESTAMP, password_hash = NULL, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1 AND verified_at IS NULL
+`
+
+// ClaimUnverifiedUser marks the user verified and removes its password. It
+// reports false when the user was already verified.
+func (r *userRepo) ClaimUnverifiedUser(ctx context.Context, userID string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, ClaimUnverifiedUserQuery, userID)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+// Changing the password also invalidates any pending password reset tokens.
+const UpdateUserPasswordQuery = `
+WITH resets AS (
+	DELETE FROM password_resets WHERE user_id = $1
+)
+UPDATE users
+SET password_hash = $2, updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (r *userRepo) UpdateUserPassword(ctx context.Context, userID, passwordHash string) error {
+	_, err := r.db.ExecContext(ctx, UpdateUserPasswordQuery, userID, passwordHash)
+	return mapError(err)
+}
+
+// Only replaces oldHash so that a password changed in the meantime is kept.
+// The password stays the same so resets and sessions are left alone.
+const RehashUserPasswordQuery = `
+UPDATE users
+SET password_hash = $3
+WHERE id = $1 AND password_hash = $2
+`
+
+// RehashUserPassword replaces the hash of an unchanged password with one of
+// newer parameters. It reports whether the hash was still oldHash.
+func (r *userRepo) RehashUserPassword(ctx context.Context, userID, oldHash, newHash string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, RehashUserPasswordQuery, userID, oldHash, newHash)
+	if err != nil {
+		return false, mapError(err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return n == 1, nil
+}
+
+const ListUsersQuery = `
+SELECT id, email, verified_at, disabled_at, created_at, updated_at FROM users
+WHERE deleted_at IS NULL
+ORDER BY created_at
+`
+
+func (r *userRepo) ListUsers(ctx context.Context) ([]model.User, error) {
+	rows, err := r.db.QueryContext(ctx, ListUsersQuery)
+	if err != nil {
+		return nil, mapError(err)
+	}
+	defer rows.Close()
+
+	var users []model.User
+	for rows.Next() {
+		var user model.User
+		if err := rows.Scan(&user.ID, &user.Email, &user.VerifiedAt, &user.DisabledAt,
+			&user.CreatedAt, &user.UpdatedAt); err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+// Disabling a user also revokes all of its sessions, refresh tokens and API
+// keys.
+const DisableUserQuery = `
+WITH sessions AS (
+	DELETE FROM sessions WHERE user_id = $1
+), refresh_tokens AS (
+	UPDATE refresh_tokens SET revoked_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND revoked_at IS NULL
+), api_keys AS (
+	UPDATE api_keys SET revoked_at = CURRENT_TIMESTAMP
+	WHERE user_id = $1 AND revoked_at IS NULL
+)
+UPDATE users
+SET disabled_at = COALESCE(disabled_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+WHERE id = $1
+`
+
+func (r *userRepo) DisableUser(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DisableUserQuery, userID)
+	return mapError(err)
+}
@@ -37,3 +37,100 @@ func TestUserRepo_CreateUser(t *testing.T) {
 	assert.NotZero(t, newUser.UpdatedAt)
 	assert.NoError(t, mock.ExpectationsWereMet())
 }
+
+func TestUserRepo_UpdateUserPassword(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.UpdateUserPasswordQuery).
+		WithArgs("1", "hashed").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewUserRepository(db)
+	err = repo.UpdateUserPassword(context.Background(), "1", "hashed")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_ImportUser(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	verifiedAt := time.Now()
+	params := repository.ImportUserParams{Email: "abc@example.com", PasswordHash: "$2b$10$hash", VerifiedAt: &verifiedAt}
+	mock.ExpectExec(repository.ImportUserQuery).
+		WithArgs(params.Email, params.PasswordHash, params.VerifiedAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewUserRepository(db)
+	ok, err := repo.ImportUser(context.Background(), params)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_RehashUserPassword(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.RehashUserPasswordQuery).
+		WithArgs("1", "old", "new").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := repository.NewUserRepository(db)
+	ok, err := repo.RehashUserPassword(context.Background(), "1", "old", "new")
+	assert.NoError(t, err)
+	assert.False(t, ok, "the password was changed in the meantime")
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_DisableUser(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.DisableUserQuery).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewUserRepository(db)
+	err = repo.DisableUser(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestUserRepo_ClaimUnverifiedUser(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectExec(repository.ClaimUnverifiedUserQuery).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 1))
+	mock.ExpectExec(repository.ClaimUnverifiedUserQuery).
+		WithArgs("1").
+		WillReturnResult(sqlmock.NewResult(0, 0))
+
+	repo := repository.NewUserRepository(db)
+	claimed, err := repo.ClaimUnverifiedUser(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.True(t, claimed)
+
+	claimed, err = repo.ClaimUnverifiedUser(context.Background(), "1")
+	assert.NoError(t, err)
+	assert.False(t, claimed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
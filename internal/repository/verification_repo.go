@@ -0,0 +1,65 @@
+//go:generate mockgen -destination=mock/verification_repo_mock.go -package=mock . VerificationRepo
+package repository
+
+import (
+	"context"
+	"time"
+)
+
+type VerificationRepo interface {
+	CreateVerification(ctx context.Context, params CreateVerificationParams) error
+	ConsumeVerification(ctx context.Context, tokenHash string) (string, error)
+	DeleteUserVerifications(ctx context.Context, userID string) error
+}
+
+type verificationRepo struct {
+	db DBTX
+}
+
+var _ VerificationRepo = (*verificationRepo)(nil)
+
+func NewVerificationRepository(db DBTX) VerificationRepo {
+	return &verificationRepo{db: db}
+}
+
+type CreateVerificationParams struct {
+	UserID    string
+	TokenHash string
+	ExpiresAt time.Time
+}
+
+const CreateVerificationQuery = `
+INSERT INTO email_verifications (user_id, token_hash, expires_at)
+VALUES ($1, $2, $3)
+`
+
+func (r *verificationRepo) CreateVerification(ctx context.Context, params CreateVerificationParams) error {
+	_, err := r.db.ExecContext(ctx, CreateVerificationQuery, params.UserID, params.TokenHash, params.ExpiresAt)
+	return mapError(err)
+}
+
+const ConsumeVerificationQuery = `
+DELETE FROM email_verifications
+WHERE token_hash = $1 AND expires_at > CURRENT_TIMESTAMP
+RETURNING user_id
+`
+
+// ConsumeVerification deletes the unexpired verification of tokenHash and
+// returns its user, or sql.ErrNoRows when there is none.
+func (r *verificationRepo) ConsumeVerification(ctx context.Context, tokenHash string) (string, error) {
+	var userID string
+	if err := r.db.QueryRowContext(ctx, ConsumeVerificationQuery, tokenHash).Scan(&userID); err != nil {
+		return "", mapError(err)
+	}
+	return userID, nil
+}
+
+const DeleteUserVerificationsQuery = `
+DELETE FROM email_verifications
+WHERE user_id = $1
+`
+
+func (r *verificationRepo) DeleteUserVerifications(ctx context.Context, userID string) error {
+	_, err := r.db.ExecContext(ctx, DeleteUserVerificationsQuery, userID)
+	return err
+}
@@ -0,0 +1,59 @@
+package repository_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	sqlmock "github.com/DATA-DOG/go-sqlmock"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerificationRepo_CreateVerification(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	params := repository.CreateVerificationParams{
+		UserID:    "1",
+		TokenHash: "hashed",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mock.ExpectExec(repository.CreateVerificationQuery).
+		WithArgs(params.UserID, params.TokenHash, params.ExpiresAt).
+		WillReturnResult(sqlmock.NewResult(0, 1))
+
+	repo := repository.NewVerificationRepository(db)
+	err = repo.CreateVerification(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestVerificationRepo_ConsumeVerification(t *testing.T) {
+	db, mock, err := sqlmock.New(sqlmock.QueryMatcherOption(sqlmock.QueryMatcherEqual))
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	mock.ExpectQuery(repository.ConsumeVerificationQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}).AddRow("2"))
+	mock.ExpectQuery(repository.ConsumeVerificationQuery).
+		WithArgs("hashed").
+		WillReturnRows(sqlmock.NewRows([]string{"user_id"}))
+
+	repo := repository.NewVerificationRepository(db)
+	userID, err := repo.ConsumeVerification(context.Background(), "hashed")
+	assert.NoError(t, err)
+	assert.Equal(t, "2", userID)
+
+	_, err = repo.ConsumeVerification(context.Background(), "hashed")
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,145 @@
+//go:generate mockgen -destination=mock/api_key_service_mock.go -package=mock . APIKeyService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"slices"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// APIKeyService manages the long-lived personal keys used by scripts and
+// integrations.
+type APIKeyService interface {
+	CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*CreateAPIKeyResult, error)
+	ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error)
+	RevokeAPIKey(ctx context.Context, userID, id string) error
+	AuthenticateAPIKey(ctx context.Context, key string) (*model.User, *model.APIKey, error)
+}
+
+type apiKeyService struct {
+	repo *repository.Repository
+	cfg  *config.AuthConfig
+}
+
+var _ APIKeyService = (*apiKeyService)(nil)
+var ErrAPIKeyNotFound = errors.New("api key not found")
+var ErrInvalidScope = errors.New("invalid scope")
+var ErrInvalidExpiry = errors.New("expiry must be in the future")
+
+func NewAPIKeyService(repo *repository.Repository, cfg *config.AuthConfig) APIKeyService {
+	return &apiKeyService{repo: repo, cfg: cfg}
+}
+
+type CreateAPIKeyParams struct {
+	UserID string
+	Name   string
+	// Permissions the key is limited to, all permissions of the user if empty.
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
+// CreateAPIKeyResult carries the only copy of the plain key.
+type CreateAPIKeyResult struct {
+	APIKey *model.APIKey
+	Key    string
+}
+
+func (s *apiKeyService) CreateAPIKey(ctx context.Context, params CreateAPIKeyParams) (*CreateAPIKeyResult, error) {
+	for _, scope := range params.Scopes {
+		if !slices.Contains(model.Permissions, scope) {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidScope, scope)
+		}
+	}
+
+	if params.ExpiresAt != nil && !params.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidExpiry
+	}
+
+	key, prefix, hash, err := security.GenerateAPIKey()
+	if err != nil {
+		return nil, fmt.Errorf("generate api key: %w", err)
+	}
+
+	apiKey, err := s.repo.APIKey.CreateAPIKey(ctx, repository.CreateAPIKeyParams{
+		UserID:    params.UserID,
+		Name:      params.Name,
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    params.Scopes,
+		ExpiresAt: params.ExpiresAt,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create api key for user %s: %w", params.UserID, err)
+	}
+
+	return &CreateAPIKeyResult{APIKey: apiKey, Key: key}, nil
+}
+
+func (s *apiKeyService) ListAPIKeys(ctx context.Context, userID string) ([]model.APIKey, error) {
+	keys, err := s.repo.APIKey.ListUserAPIKeys(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("list api keys of user %s: %w", userID, err)
+	}
+	return keys, nil
+}
+
+// RevokeAPIKey revokes a key of the user. Keys of other users are reported
+// as not found.
+func (s *apiKeyService) RevokeAPIKey(ctx context.Context, userID, id string) error {
+	revoked, err := s.repo.APIKey.RevokeAPIKey(ctx, userID, id)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidText) {
+			return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+		}
+		return fmt.Errorf("revoke api key %s: %w", id, err)
+	}
+
+	if !revoked {
+		return fmt.Errorf("%w: %s", ErrAPIKeyNotFound, id)
+	}
+
+	return nil
+}
+
+// AuthenticateAPIKey returns the owner of key along with the key. The time of
+// use is only written once it is older than the touch interval so that busy
+// keys do not cause a write on every request.
+func (s *apiKeyService) AuthenticateAPIKey(ctx context.Context, key string) (*model.User, *model.APIKey, error) {
+	apiKey, err := s.repo.APIKey.FindActiveAPIKeyByHash(ctx, security.HashToken(key))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("find api key: %w", err)
+	}
+
+	user, err := s.repo.User.FindActiveUserByID(ctx, apiKey.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, ErrInvalidToken
+		}
+		return nil, nil, fmt.Errorf("find user %s: %w", apiKey.UserID, err)
+	}
+
+	now := time.Now()
+	interval := time.Duration(s.cfg.APIKeyTouchInterval) * time.Second
+	if apiKey.LastUsedAt == nil || now.Sub(*apiKey.LastUsedAt) >= interval {
+		// Failing to record the use must not fail the request.
+		if err := s.repo.APIKey.TouchAPIKey(ctx, apiKey.ID, now); err != nil {
+			slog.Error("failed to record api key use", "key", apiKey.ID, "reason", err)
+		} else {
+			apiKey.LastUsedAt = &now
+		}
+	}
+
+	return user, apiKey, nil
+}
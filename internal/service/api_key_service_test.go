@@ -0,0 +1,101 @@
+package service_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var authCfg = &config.AuthConfig{APIKeyTouchInterval: 300}
+
+func TestAPIKeyService_CreateAPIKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockKeyRepo := mock.NewMockAPIKeyRepo(ctrl)
+
+	var hash string
+	mockKeyRepo.EXPECT().CreateAPIKey(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateAPIKeyParams) (*model.APIKey, error) {
+			assert.True(t, strings.HasPrefix(params.Prefix, security.APIKeyPrefix))
+			hash = params.KeyHash
+			return &model.APIKey{ID: "2", UserID: params.UserID, Prefix: params.Prefix}, nil
+		})
+
+	svc := service.NewAPIKeyService(&repository.Repository{APIKey: mockKeyRepo}, authCfg)
+	result, err := svc.CreateAPIKey(context.Background(), service.CreateAPIKeyParams{
+		UserID: "1",
+		Name:   "ci",
+		Scopes: []string{model.PermUsersRead},
+	})
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(result.Key, result.APIKey.Prefix+"_"))
+	assert.Equal(t, security.HashToken(result.Key), hash, "only the hash of the key must be stored")
+}
+
+func TestAPIKeyService_CreateAPIKeyInvalid(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+
+	var tests = []struct {
+		name   string
+		params service.CreateAPIKeyParams
+		want   error
+	}{
+		{"Unknown scope", service.CreateAPIKeyParams{Name: "ci", Scopes: []string{"everything"}}, service.ErrInvalidScope},
+		{"Expired", service.CreateAPIKeyParams{Name: "ci", ExpiresAt: &past}, service.ErrInvalidExpiry},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc := service.NewAPIKeyService(&repository.Repository{}, authCfg)
+			_, err := svc.CreateAPIKey(context.Background(), tt.params)
+			assert.ErrorIs(t, err, tt.want)
+		})
+	}
+}
+
+func TestAPIKeyService_AuthenticateAPIKey(t *testing.T) {
+	const key = "gwk_12345678_secret"
+	recent := time.Now().Add(-time.Minute)
+	stale := time.Now().Add(-time.Hour)
+
+	var tests = []struct {
+		name       string
+		lastUsedAt *time.Time
+		touch      bool
+	}{
+		{"Never used", nil, true},
+		{"Used recently", &recent, false},
+		{"Used long ago", &stale, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockKeyRepo := mock.NewMockAPIKeyRepo(ctrl)
+			mockUserRepo := mock.NewMockUserRepo(ctrl)
+
+			apiKey := &model.APIKey{ID: "2", UserID: "1", LastUsedAt: tt.lastUsedAt}
+			user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+			mockKeyRepo.EXPECT().FindActiveAPIKeyByHash(gomock.Any(), security.HashToken(key)).Return(apiKey, nil)
+			mockUserRepo.EXPECT().FindActiveUserByID(gomock.Any(), apiKey.UserID).Return(user, nil)
+			if tt.touch {
+				mockKeyRepo.EXPECT().TouchAPIKey(gomock.Any(), apiKey.ID, gomock.Any()).Return(nil)
+			}
+
+			svc := service.NewAPIKeyService(&repository.Repository{APIKey: mockKeyRepo, User: mockUserRepo}, authCfg)
+			gotUser, gotKey, err := svc.AuthenticateAPIKey(context.Background(), key)
+			assert.NoError(t, err)
+			assert.Equal(t, user, gotUser)
+			assert.Equal(t, apiKey.ID, gotKey.ID)
+		})
+	}
+}
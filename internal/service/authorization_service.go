@@ -0,0 +1,87 @@
+//go:generate mockgen -destination=mock/authorization_service_mock.go -package=mock . AuthorizationService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+type AuthorizationService interface {
+	Can(ctx context.Context, user *model.User, permission string) (bool, error)
+	AssignRole(ctx context.Context, email, role string) error
+	RevokeRole(ctx context.Context, email, role string) error
+}
+
+type authorizationService struct {
+	repo *repository.Repository
+}
+
+var _ AuthorizationService = (*authorizationService)(nil)
+var ErrRoleNotFound = errors.New("role not found")
+
+func NewAuthorizationService(repo *repository.Repository) AuthorizationService {
+	return &authorizationService{repo: repo}
+}
+
+// Can reports whether one of the roles of user grants permission. Anonymous
+// and disabled users have no permissions.
+func (s *authorizationService) Can(ctx context.Context, user *model.User, permission string) (bool, error) {
+	if user == nil || user.DisabledAt != nil {
+		return false, nil
+	}
+
+	ok, err := s.repo.Role.UserHasPermission(ctx, user.ID, permission)
+	if err != nil {
+		return false, fmt.Errorf("check permission %s of user %s: %w", permission, user.ID, err)
+	}
+	return ok, nil
+}
+
+func (s *authorizationService) AssignRole(ctx context.Context, email, role string) error {
+	user, r, err := s.findUserAndRole(ctx, email, role)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Role.AssignRole(ctx, user.ID, r.ID); err != nil {
+		return fmt.Errorf("assign role %s to user %s: %w", role, user.ID, err)
+	}
+	return nil
+}
+
+func (s *authorizationService) RevokeRole(ctx context.Context, email, role string) error {
+	user, r, err := s.findUserAndRole(ctx, email, role)
+	if err != nil {
+		return err
+	}
+
+	if err := s.repo.Role.RevokeRole(ctx, user.ID, r.ID); err != nil {
+		return fmt.Errorf("revoke role %s from user %s: %w", role, user.ID, err)
+	}
+	return nil
+}
+
+func (s *authorizationService) findUserAndRole(ctx context.Context, email, role string) (*model.User, *model.Role, error) {
+	user, err := s.repo.User.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+		}
+		return nil, nil, fmt.Errorf("find user %s: %w", email, err)
+	}
+
+	r, err := s.repo.Role.FindRoleByName(ctx, role)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil, fmt.Errorf("%w: %s", ErrRoleNotFound, role)
+		}
+		return nil, nil, fmt.Errorf("find role %s: %w", role, err)
+	}
+
+	return user, r, nil
+}
@@ -0,0 +1,75 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+func TestAuthorizationService_Can(t *testing.T) {
+	disabledAt := time.Now()
+
+	var tests = []struct {
+		name  string
+		user  *model.User
+		check bool
+		want  bool
+	}{
+		{"Anonymous", nil, false, false},
+		{"Disabled", &model.User{Model: model.Model{ID: "1"}, DisabledAt: &disabledAt}, false, false},
+		{"Granted", &model.User{Model: model.Model{ID: "1"}}, true, true},
+		{"Not granted", &model.User{Model: model.Model{ID: "1"}}, true, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockRoleRepo := mock.NewMockRoleRepo(ctrl)
+			if tt.check {
+				mockRoleRepo.EXPECT().UserHasPermission(gomock.Any(), "1", model.PermUsersRead).Return(tt.want, nil)
+			}
+
+			svc := service.NewAuthorizationService(&repository.Repository{Role: mockRoleRepo})
+			ok, err := svc.Can(context.Background(), tt.user, model.PermUsersRead)
+			assert.NoError(t, err)
+			assert.Equal(t, tt.want, ok)
+		})
+	}
+}
+
+func TestAuthorizationService_AssignRole(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockRoleRepo := mock.NewMockRoleRepo(ctrl)
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	role := &model.Role{Model: model.Model{ID: "2"}, Name: model.RoleAdmin}
+	mockUserRepo.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	mockRoleRepo.EXPECT().FindRoleByName(gomock.Any(), model.RoleAdmin).Return(role, nil)
+	mockRoleRepo.EXPECT().AssignRole(gomock.Any(), user.ID, role.ID).Return(nil)
+
+	svc := service.NewAuthorizationService(&repository.Repository{User: mockUserRepo, Role: mockRoleRepo})
+	err := svc.AssignRole(context.Background(), testEmail, model.RoleAdmin)
+	assert.NoError(t, err)
+}
+
+func TestAuthorizationService_AssignRoleNotFound(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockRoleRepo := mock.NewMockRoleRepo(ctrl)
+
+	mockUserRepo.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(&model.User{Email: testEmail}, nil)
+	mockRoleRepo.EXPECT().FindRoleByName(gomock.Any(), "nope").Return(nil, sql.ErrNoRows)
+
+	svc := service.NewAuthorizationService(&repository.Repository{User: mockUserRepo, Role: mockRoleRepo})
+	err := svc.AssignRole(context.Background(), testEmail, "nope")
+	assert.ErrorIs(t, err, service.ErrRoleNotFound)
+}
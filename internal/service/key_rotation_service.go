@@ -0,0 +1,84 @@
+//go:generate mockgen -destination=mock/key_rotation_service_mock.go -package=mock . KeyRotationService
+package service
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+type KeyRotationService interface {
+	RotateKeys(ctx context.Context, col repository.EncryptedColumn, batchSize int) (*RotateKeysResult, error)
+}
+
+// RotateKeysResult counts the values of a column that were read and those
+// that were encrypted again.
+type RotateKeysResult struct {
+	Scanned int
+	Rotated int
+}
+
+type keyRotationService struct {
+	repo    *repository.Repository
+	keyring *security.Keyring
+}
+
+var _ KeyRotationService = (*keyRotationService)(nil)
+
+func NewKeyRotationService(repo *repository.Repository, keyring *security.Keyring) KeyRotationService {
+	return &keyRotationService{repo: repo, keyring: keyring}
+}
+
+// RotateKeys encrypts the values of col that are not encrypted with the
+// primary key again, batchSize rows per transaction. A batch that fails is
+// rolled back but the batches before it stay committed, so that it can be
+// run again to resume.
+func (s *keyRotationService) RotateKeys(ctx context.Context, col repository.EncryptedColumn, batchSize int) (*RotateKeysResult, error) {
+	batchSize = max(batchSize, 1)
+	result := &RotateKeysResult{}
+	after := ""
+	for {
+		var values []repository.EncryptedValue
+		var rotated int
+		err := s.repo.WithTx(ctx, func(tx *repository.Repository) error {
+			var err error
+			values, err = tx.KeyRotation.ListEncrypted(ctx, col, after, batchSize)
+			if err != nil {
+				return fmt.Errorf("list %s: %w", col, err)
+			}
+
+			for _, v := range values {
+				if !s.keyring.NeedsRotation(v.Encrypted) {
+					continue
+				}
+
+				context := col.Context(v.Key)
+				plaintext, err := s.keyring.Decrypt(v.Encrypted, context)
+				if err != nil {
+					return fmt.Errorf("decrypt %s of %s: %w", col, v.Key, err)
+				}
+				encrypted, err := s.keyring.Encrypt(plaintext, context)
+				if err != nil {
+					return fmt.Errorf("encrypt %s of %s: %w", col, v.Key, err)
+				}
+				if err := tx.KeyRotation.UpdateEncrypted(ctx, col, v.Key, encrypted); err != nil {
+					return fmt.Errorf("update %s of %s: %w", col, v.Key, err)
+				}
+				rotated++
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result.Scanned += len(values)
+		result.Rotated += rotated
+		if len(values) < batchSize {
+			return result, nil
+		}
+		after = values[len(values)-1].Key
+	}
+}
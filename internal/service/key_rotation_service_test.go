@@ -0,0 +1,118 @@
+package service_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var testColumn = repository.EncryptedColumn{Table: "user_totp", Key: "user_id", Column: "secret"}
+
+func newEncryptionKey(t *testing.T, id string) security.EncryptionKey {
+	t.Helper()
+	key, err := security.GenerateRandomBytes(security.CipherKeyLength)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return security.EncryptionKey{ID: id, Algorithm: security.AlgXChaCha20Poly1305, Key: key}
+}
+
+// Returns a keyring of k1 and k2 whose primary key is k2, and a keyring of
+// only k1.
+func newRotationKeyring(t *testing.T) (*security.Keyring, *security.Keyring) {
+	t.Helper()
+	k1, k2 := newEncryptionKey(t, "k1"), newEncryptionKey(t, "k2")
+
+	old, err := security.NewKeyring("k1", []security.EncryptionKey{k1})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	keyring, err := security.NewKeyring("k2", []security.EncryptionKey{k1, k2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return keyring, old
+}
+
+// Returns plaintext encrypted for the row of testColumn with key.
+func encryptRow(t *testing.T, keyring *security.Keyring, key, plaintext string) string {
+	t.Helper()
+	encrypted, err := keyring.Encrypt([]byte(plaintext), testColumn.Context(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return encrypted
+}
+
+func TestKeyRotationService_RotateKeys(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mock.NewMockKeyRotationRepo(ctrl)
+
+	keyring, old := newRotationKeyring(t)
+
+	gomock.InOrder(
+		mockRepo.EXPECT().ListEncrypted(gomock.Any(), testColumn, "", 2).
+			Return([]repository.EncryptedValue{
+				{Key: "1", Encrypted: encryptRow(t, old, "1", "secret")},
+				{Key: "2", Encrypted: encryptRow(t, keyring, "2", "current")},
+			}, nil),
+		mockRepo.EXPECT().UpdateEncrypted(gomock.Any(), testColumn, "1", gomock.Any()).
+			DoAndReturn(func(_ context.Context, _ repository.EncryptedColumn, _, encrypted string) error {
+				assert.Equal(t, "k2", security.KeyID(encrypted))
+				plaintext, err := keyring.Decrypt(encrypted, testColumn.Context("1"))
+				assert.NoError(t, err)
+				assert.Equal(t, "secret", string(plaintext))
+				return nil
+			}),
+		mockRepo.EXPECT().ListEncrypted(gomock.Any(), testColumn, "2", 2).
+			Return([]repository.EncryptedValue{{Key: "3", Encrypted: encryptRow(t, old, "3", "secret")}}, nil),
+		mockRepo.EXPECT().UpdateEncrypted(gomock.Any(), testColumn, "3", gomock.Any()).Return(nil),
+	)
+
+	svc := service.NewKeyRotationService(&repository.Repository{KeyRotation: mockRepo}, keyring)
+	result, err := svc.RotateKeys(context.Background(), testColumn, 2)
+	assert.NoError(t, err)
+	assert.Equal(t, &service.RotateKeysResult{Scanned: 3, Rotated: 2}, result)
+}
+
+func TestKeyRotationService_RotateKeysUnknownKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mock.NewMockKeyRotationRepo(ctrl)
+
+	_, old := newRotationKeyring(t)
+	// A keyring that no longer has k1
+	keyring, err := security.NewKeyring("k3", []security.EncryptionKey{newEncryptionKey(t, "k3")})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	mockRepo.EXPECT().ListEncrypted(gomock.Any(), testColumn, "", 10).
+		Return([]repository.EncryptedValue{{Key: "1", Encrypted: encryptRow(t, old, "1", "secret")}}, nil)
+	mockRepo.EXPECT().UpdateEncrypted(gomock.Any(), gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	svc := service.NewKeyRotationService(&repository.Repository{KeyRotation: mockRepo}, keyring)
+	result, err := svc.RotateKeys(context.Background(), testColumn, 10)
+	assert.ErrorIs(t, err, security.ErrUnknownKey)
+	assert.Equal(t, 0, result.Rotated)
+}
+
+func TestKeyRotationService_RotateKeysListError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockRepo := mock.NewMockKeyRotationRepo(ctrl)
+	keyring, _ := newRotationKeyring(t)
+
+	errList := errors.New("connection reset")
+	mockRepo.EXPECT().ListEncrypted(gomock.Any(), testColumn, "", 10).Return(nil, errList)
+
+	svc := service.NewKeyRotationService(&repository.Repository{KeyRotation: mockRepo}, keyring)
+	_, err := svc.RotateKeys(context.Background(), testColumn, 10)
+	assert.ErrorIs(t, err, errList)
+}
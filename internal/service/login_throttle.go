@@ -0,0 +1,245 @@
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+var ErrTooManyLoginAttempts = errors.New("too many failed login attempts, try again later")
+
+// ThrottledError is returned instead of checking the credentials while an
+// account or client address has to wait before trying again.
+type ThrottledError struct {
+	RetryAfter time.Duration
+}
+
+func (e *ThrottledError) Error() string {
+	return ErrTooManyLoginAttempts.Error()
+}
+
+func (e *ThrottledError) Unwrap() error {
+	return ErrTooManyLoginAttempts
+}
+
+// loginThrottle guards checkCredentials against password guessing. Failures
+// are counted per account and per client address. Accounts are also delayed
+// progressively, addresses are not since many users can share one.
+type loginThrottle struct {
+	repo *repository.Repository
+	cfg  *config.LockoutConfig
+}
+
+func newLoginThrottle(repo *repository.Repository, cfg *config.LockoutConfig) *loginThrottle {
+	return &loginThrottle{repo: repo, cfg: cfg}
+}
+
+type throttleKey struct {
+	scope     string
+	key       string
+	threshold int
+}
+
+// Returns the keys that an attempt is counted under. Attempts for emails
+// that do not exist are counted too so that a lock does not reveal which do.
+func (t *loginThrottle) keys(email, ipAddress string) []throttleKey {
+	keys := []throttleKey{{
+		scope:     model.LoginScopeAccount,
+		key:       accountKey(email),
+		threshold: t.cfg.AccountThreshold,
+	}}
+	if ipAddress != "" {
+		keys = append(keys, throttleKey{
+			scope:     model.LoginScopeIP,
+			key:       ipAddress,
+			threshold: t.cfg.IPThreshold,
+		})
+	}
+	return keys
+}
+
+func accountKey(email string) string {
+	return strings.ToLower(strings.TrimSpace(email))
+}
+
+// Returns a ThrottledError when the account or address is locked or has not
+// waited long enough since its last failure.
+func (t *loginThrottle) allow(ctx context.Context, email, ipAddress string) error {
+	now := time.Now()
+
+	var wait time.Duration
+	for _, k := range t.keys(email, ipAddress) {
+		attempt, err := t.repo.LoginAttempt.FindLoginAttempt(ctx, k.scope, k.key)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				continue
+			}
+			return fmt.Errorf("find login attempts of %s %s: %w", k.scope, k.key, err)
+		}
+		wait = max(wait, t.retryAfter(attempt, now))
+	}
+
+	if wait > 0 {
+		return &ThrottledError{RetryAfter: wait}
+	}
+	return nil
+}
+
+func (t *loginThrottle) retryAfter(attempt *model.LoginAttempt, now time.Time) time.Duration {
+	if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+		return attempt.LockedUntil.Sub(now)
+	}
+
+	if attempt.Scope != model.LoginScopeAccount || attempt.LastFailedAt.Before(t.windowStart(now)) {
+		return 0
+	}
+
+	return max(attempt.LastFailedAt.Add(t.delay(attempt.Failures)).Sub(now), 0)
+}
+
+// Returns the delay after failures consecutive failures.
+func (t *loginThrottle) delay(failures int) time.Duration {
+	if failures < 1 || t.cfg.BaseDelay == 0 {
+		return 0
+	}
+
+	maxDelay := time.Duration(t.cfg.MaxDelay) * time.Second
+	delay := time.Duration(t.cfg.BaseDelay) * time.Second
+	for i := 1; i < failures && delay < maxDelay; i++ {
+		delay *= 2
+	}
+	return min(delay, maxDelay)
+}
+
+func (t *loginThrottle) windowStart(now time.Time) time.Time {
+	return now.Add(-time.Duration(t.cfg.Window) * time.Second)
+}
+
+// check runs verify as a sign in attempt of email from ipAddress. It refuses
+// with a ThrottledError while the account or address is locked or has to wait
+// out the delay of its last failure. The attempt is counted as a failure of
+// both before verify runs, so that concurrent guesses cannot all pass while
+// none of them has failed yet, and those beyond the threshold are refused. The
+// failure is taken back unless verify returns a wrong password or code, and
+// the account or address that reached its threshold is locked when it does.
+func (t *loginThrottle) check(ctx context.Context, email, ipAddress string, verify func() error) error {
+	if err := t.allow(ctx, email, ipAddress); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var reserved []throttleKey
+	var failures []int
+	for _, k := range t.keys(email, ipAddress) {
+		attempt, err := t.repo.LoginAttempt.RecordLoginFailure(ctx, repository.RecordLoginFailureParams{
+			Scope:       k.scope,
+			Key:         k.key,
+			FailedAt:    now,
+			WindowStart: t.windowStart(now),
+		})
+		if err != nil {
+			err = fmt.Errorf("record login failure of %s %s: %w", k.scope, k.key, err)
+			return errors.Join(err, t.release(ctx, reserved))
+		}
+		reserved = append(reserved, k)
+		failures = append(failures, attempt.Failures)
+
+		if attempt.Failures > k.threshold {
+			err := &ThrottledError{RetryAfter: max(t.delay(attempt.Failures), time.Second)}
+			return errors.Join(err, t.release(ctx, reserved))
+		}
+	}
+
+	if err := verify(); err != nil {
+		if !errors.Is(err, ErrInvalidCredentials) && !errors.Is(err, ErrInvalidTwoFactorCode) {
+			return errors.Join(err, t.release(ctx, reserved))
+		}
+
+		for i, k := range reserved {
+			if failures[i] >= k.threshold {
+				if err := t.lock(ctx, k, failures[i], ipAddress, now); err != nil {
+					return err
+				}
+			}
+		}
+		return err
+	}
+
+	return t.release(ctx, reserved)
+}
+
+// Takes back the failures recorded for an attempt that did not fail.
+func (t *loginThrottle) release(ctx context.Context, keys []throttleKey) error {
+	for _, k := range keys {
+		if err := t.repo.LoginAttempt.ReleaseLoginFailure(ctx, k.scope, k.key); err != nil {
+			return fmt.Errorf("release login failure of %s %s: %w", k.scope, k.key, err)
+		}
+	}
+	return nil
+}
+
+func (t *loginThrottle) lock(ctx context.Context, k throttleKey, failures int, ipAddress string,
+	now time.Time) error {
+	until := now.Add(time.Duration(t.cfg.LockDuration) * time.Second)
+
+	details, err := json.Marshal(map[string]any{
+		"scope":        k.scope,
+		"key":          k.key,
+		"failures":     failures,
+		"locked_until": until,
+	})
+	if err != nil {
+		return fmt.Errorf("marshal audit details: %w", err)
+	}
+
+	return t.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.LoginAttempt.LockLoginAttempt(ctx, k.scope, k.key, until); err != nil {
+			return fmt.Errorf("lock %s %s: %w", k.scope, k.key, err)
+		}
+
+		if _, err := repo.Audit.CreateAuditEntry(ctx, repository.CreateAuditEntryParams{
+			Action:    model.AuditLoginLocked,
+			IPAddress: ipAddress,
+			Details:   details,
+		}); err != nil {
+			return fmt.Errorf("audit lock of %s %s: %w", k.scope, k.key, err)
+		}
+
+		return nil
+	})
+}
+
+// Forgets the failures of the account once its owner signed in.
+func (t *loginThrottle) reset(ctx context.Context, email string) error {
+	if err := t.repo.LoginAttempt.DeleteLoginAttempt(ctx, model.LoginScopeAccount, accountKey(email)); err != nil {
+		return fmt.Errorf("reset login attempts of %s: %w", email, err)
+	}
+	return nil
+}
+
+// checkCredentials verifies the email and password of a sign in as an
+// attempt of check. Only a wrong password counts, other errors like an
+// unverified account do not.
+func (t *loginThrottle) checkCredentials(ctx context.Context, hasher security.Hasher,
+	email, password, ipAddress string) (*model.User, error) {
+	var user *model.User
+	err := t.check(ctx, email, ipAddress, func() error {
+		var err error
+		user, err = checkCredentials(ctx, t.repo.User, hasher, email, password)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,241 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	secMock "github.com/ferdiebergado/goweb/internal/pkg/security/mock"
+)
+
+const testIP = "192.0.2.1"
+
+type throttleMocks struct {
+	user      *mock.MockUserRepo
+	session   *mock.MockSessionRepo
+	twoFactor *mock.MockTwoFactorRepo
+	challenge *mock.MockLoginChallengeRepo
+	audit     *mock.MockAuditRepo
+	hasher    *secMock.MockHasher
+	attempts  repository.LoginAttemptRepo
+}
+
+func newThrottledUserService(t *testing.T, lockout config.LockoutConfig) (service.UserService, *throttleMocks) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	m := &throttleMocks{
+		user:      mock.NewMockUserRepo(ctrl),
+		session:   mock.NewMockSessionRepo(ctrl),
+		twoFactor: mock.NewMockTwoFactorRepo(ctrl),
+		challenge: mock.NewMockLoginChallengeRepo(ctrl),
+		audit:     mock.NewMockAuditRepo(ctrl),
+		hasher:    secMock.NewMockHasher(ctrl),
+		attempts:  repository.NewMemoryLoginAttemptRepository(),
+	}
+
+	repo := &repository.Repository{
+		User:         m.user,
+		Session:      m.session,
+		TwoFactor:    m.twoFactor,
+		Challenge:    m.challenge,
+		LoginAttempt: m.attempts,
+		Audit:        m.audit,
+	}
+	cfg := &config.Config{Session: config.SessionConfig{Lifetime: 3600}, Lockout: lockout}
+	return service.NewUserService(repo, m.hasher, cfg), m
+}
+
+// Expects a login with a wrong password.
+func (m *throttleMocks) expectWrongPassword(email string) {
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), email).Return(nil, sql.ErrNoRows)
+	m.hasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+}
+
+func login(svc service.UserService, email, ip string) error {
+	_, err := svc.LoginUser(context.Background(), service.LoginUserParams{
+		Email:     email,
+		Password:  testPass,
+		IPAddress: ip,
+	})
+	return err
+}
+
+func TestLoginThrottle_ProgressiveDelay(t *testing.T) {
+	svc, m := newThrottledUserService(t, *lockoutCfg)
+
+	m.expectWrongPassword(testEmail)
+	assert.ErrorIs(t, login(svc, testEmail, testIP), service.ErrInvalidCredentials)
+
+	// The credentials are not checked again until the delay passed.
+	err := login(svc, "ABC@example.com ", testIP)
+	var throttled *service.ThrottledError
+	if assert.True(t, errors.As(err, &throttled)) {
+		assert.InDelta(t, time.Second, throttled.RetryAfter, float64(100*time.Millisecond))
+	}
+
+	// Addresses are not delayed.
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeIP, testIP)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, attempt.Failures)
+	m.expectWrongPassword("other@example.com")
+	assert.ErrorIs(t, login(svc, "other@example.com", testIP), service.ErrInvalidCredentials)
+}
+
+func TestLoginThrottle_LocksAccount(t *testing.T) {
+	lockout := *lockoutCfg
+	lockout.BaseDelay = 0
+	svc, m := newThrottledUserService(t, lockout)
+
+	m.audit.EXPECT().CreateAuditEntry(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateAuditEntryParams) (*model.AuditEntry, error) {
+			assert.Equal(t, model.AuditLoginLocked, params.Action)
+			assert.Equal(t, testIP, params.IPAddress)
+
+			var details map[string]any
+			assert.NoError(t, json.Unmarshal(params.Details, &details))
+			assert.Equal(t, model.LoginScopeAccount, details["scope"])
+			assert.Equal(t, testEmail, details["key"])
+			return &model.AuditEntry{}, nil
+		})
+
+	for range lockout.AccountThreshold {
+		m.expectWrongPassword(testEmail)
+		assert.ErrorIs(t, login(svc, testEmail, testIP), service.ErrInvalidCredentials)
+	}
+
+	err := login(svc, testEmail, testIP)
+	var throttled *service.ThrottledError
+	if assert.True(t, errors.As(err, &throttled)) {
+		assert.InDelta(t, 15*time.Minute, throttled.RetryAfter, float64(time.Second))
+	}
+}
+
+func TestLoginThrottle_LocksAddress(t *testing.T) {
+	lockout := *lockoutCfg
+	lockout.AccountThreshold = 100
+	lockout.IPThreshold = 2
+	svc, m := newThrottledUserService(t, lockout)
+
+	m.audit.EXPECT().CreateAuditEntry(gomock.Any(), gomock.Any()).Return(&model.AuditEntry{}, nil)
+
+	m.expectWrongPassword("a@example.com")
+	assert.ErrorIs(t, login(svc, "a@example.com", testIP), service.ErrInvalidCredentials)
+	m.expectWrongPassword("b@example.com")
+	assert.ErrorIs(t, login(svc, "b@example.com", testIP), service.ErrInvalidCredentials)
+
+	assert.ErrorIs(t, login(svc, "c@example.com", testIP), service.ErrTooManyLoginAttempts)
+}
+
+func TestLoginThrottle_SuccessResetsAccount(t *testing.T) {
+	lockout := *lockoutCfg
+	lockout.BaseDelay = 0
+	svc, m := newThrottledUserService(t, lockout)
+
+	m.expectWrongPassword(testEmail)
+	assert.ErrorIs(t, login(svc, testEmail, testIP), service.ErrInvalidCredentials)
+
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	m.hasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	m.hasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(nil, sql.ErrNoRows)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		Return(&model.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	_, err := svc.LoginUser(context.Background(), service.LoginUserParams{
+		Email:     testEmail,
+		Password:  testPass,
+		IPAddress: testIP,
+	})
+	assert.NoError(t, err)
+
+	_, err = m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	assert.ErrorIs(t, err, sql.ErrNoRows)
+}
+
+func TestLoginThrottle_TwoFactorKeepsFailures(t *testing.T) {
+	lockout := *lockoutCfg
+	lockout.BaseDelay = 0
+	svc, m := newThrottledUserService(t, lockout)
+
+	m.expectWrongPassword(testEmail)
+	assert.ErrorIs(t, login(svc, testEmail, testIP), service.ErrInvalidCredentials)
+
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	m.hasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	m.hasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(&model.TOTP{UserID: user.ID, EnabledAt: &verifiedAt}, nil)
+	m.challenge.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).
+		Return(&model.LoginChallenge{ID: "2", UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}, nil)
+
+	result, err := svc.LoginUser(context.Background(), service.LoginUserParams{
+		Email:     testEmail,
+		Password:  testPass,
+		IPAddress: testIP,
+	})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.ChallengeToken)
+
+	// The second factor has not passed yet.
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 1, attempt.Failures)
+	}
+}
+
+func TestLoginThrottle_ConcurrentGuesses(t *testing.T) {
+	lockout := *lockoutCfg
+	lockout.AccountThreshold = 1
+	lockout.BaseDelay = 0
+	svc, m := newThrottledUserService(t, lockout)
+
+	// A second guess arrives while the first one is being checked.
+	var concurrent error
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).
+		DoAndReturn(func(context.Context, string) (*model.User, error) {
+			concurrent = login(svc, testEmail, "192.0.2.2")
+			return nil, sql.ErrNoRows
+		})
+	m.hasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+	m.audit.EXPECT().CreateAuditEntry(gomock.Any(), gomock.Any()).Return(&model.AuditEntry{}, nil)
+
+	assert.ErrorIs(t, login(svc, testEmail, testIP), service.ErrInvalidCredentials)
+	assert.ErrorIs(t, concurrent, service.ErrTooManyLoginAttempts, "the first guess already used up the attempts")
+
+	_, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeIP, "192.0.2.2")
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the refused guess is not counted against its address")
+}
+
+func TestLoginThrottle_SuccessReleasesAddress(t *testing.T) {
+	svc, m := newThrottledUserService(t, *lockoutCfg)
+
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	m.hasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	m.hasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(nil, sql.ErrNoRows)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		Return(&model.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	assert.NoError(t, login(svc, testEmail, testIP))
+
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeIP, testIP)
+	if assert.NoError(t, err) {
+		assert.Equal(t, 0, attempt.Failures, "a sign in that passed is not counted against the address")
+	}
+}
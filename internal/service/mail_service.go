@@ -0,0 +1,172 @@
+//go:generate mockgen -destination=mock/mail_service_mock.go -package=mock . MailService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/mail"
+	"github.com/ferdiebergado/goweb/internal/pkg/message"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// Kind of the job that sends a MailJob
+const JobSendEmail = "send_email"
+
+// Mails that carry a link with a token
+const (
+	MailVerifyEmail   = "verify_email"
+	MailResetPassword = "reset_password"
+)
+
+// MailJob is the payload of a JobSendEmail job. It only names the mail and
+// its user, the token of the link is issued when the mail is sent so that
+// the queue never holds a usable one.
+type MailJob struct {
+	Mail   string `json:"mail"`
+	UserID string `json:"user_id"`
+}
+
+type MailService interface {
+	SendMail(ctx context.Context, job MailJob) error
+}
+
+type mailService struct {
+	repo     *repository.Repository
+	mailer   mail.Mailer
+	mailTmpl *mail.Template
+	cfg      *config.Config
+}
+
+var _ MailService = (*mailService)(nil)
+
+func NewMailService(repo *repository.Repository, mailer mail.Mailer, mailTmpl *mail.Template,
+	cfg *config.Config) MailService {
+	return &mailService{
+		repo:     repo,
+		mailer:   mailer,
+		mailTmpl: mailTmpl,
+		cfg:      cfg,
+	}
+}
+
+// SendMail issues the token of the mail and sends it. Users that were
+// disabled or deleted in the meantime are skipped, as are verifications of
+// users that are already verified.
+func (s *mailService) SendMail(ctx context.Context, job MailJob) error {
+	user, err := s.repo.User.FindActiveUserByID(ctx, job.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			slog.Info("skipped mail of inactive user", "mail", job.Mail, "user", job.UserID)
+			return nil
+		}
+		return fmt.Errorf("find user %s: %w", job.UserID, err)
+	}
+
+	var msg *mail.Message
+	switch job.Mail {
+	case MailVerifyEmail:
+		if user.VerifiedAt != nil {
+			return nil
+		}
+		msg, err = s.verificationMail(ctx, user)
+	case MailResetPassword:
+		msg, err = s.passwordResetMail(ctx, user)
+	default:
+		return fmt.Errorf("unknown mail %q", job.Mail)
+	}
+	if err != nil {
+		return err
+	}
+
+	return s.mailer.Send(ctx, msg)
+}
+
+func (s *mailService) verificationMail(ctx context.Context, user *model.User) (*mail.Message, error) {
+	token, tokenHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate verification token: %w", err)
+	}
+
+	if err := s.repo.Verification.CreateVerification(ctx, repository.CreateVerificationParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Duration(s.cfg.Auth.VerificationTTL) * time.Second),
+	}); err != nil {
+		return nil, fmt.Errorf("create verification for user %s: %w", user.ID, err)
+	}
+
+	data := linkMailData{Email: user.Email, Link: s.tokenURL("/auth/verify", token)}
+	msg, err := s.mailTmpl.Compose([]string{user.Email}, message.Get("verifySubject"), MailVerifyEmail, data)
+	if err != nil {
+		return nil, fmt.Errorf("compose verification email: %w", err)
+	}
+	return msg, nil
+}
+
+// Only the link of the latest mail resets the password.
+func (s *mailService) passwordResetMail(ctx context.Context, user *model.User) (*mail.Message, error) {
+	token, tokenHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate password reset token: %w", err)
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.PasswordReset.DeleteUserPasswordResets(ctx, user.ID); err != nil {
+			return fmt.Errorf("delete password resets of user %s: %w", user.ID, err)
+		}
+
+		if err := repo.PasswordReset.CreatePasswordReset(ctx, repository.CreatePasswordResetParams{
+			UserID:    user.ID,
+			TokenHash: tokenHash,
+			ExpiresAt: time.Now().Add(time.Duration(s.cfg.Auth.PasswordResetTTL) * time.Second),
+		}); err != nil {
+			return fmt.Errorf("create password reset for user %s: %w", user.ID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	data := linkMailData{Email: user.Email, Link: s.tokenURL("/auth/reset-password", token)}
+	msg, err := s.mailTmpl.Compose([]string{user.Email}, message.Get("resetSubject"), MailResetPassword, data)
+	if err != nil {
+		return nil, fmt.Errorf("compose password reset email: %w", err)
+	}
+	return msg, nil
+}
+
+// Data for mail templates that carry a single action link.
+type linkMailData struct {
+	Email string
+	Link  string
+}
+
+// Returns an absolute url to path carrying token as a query parameter.
+func (s *mailService) tokenURL(path, token string) string {
+	return s.cfg.App.URL + path + "?token=" + url.QueryEscape(token)
+}
+
+// Queues the mail for user for delivery by the background workers.
+func enqueueMail(ctx context.Context, jobs repository.JobRepo, mail, userID string) error {
+	payload, err := json.Marshal(MailJob{Mail: mail, UserID: userID})
+	if err != nil {
+		return fmt.Errorf("encode mail job: %w", err)
+	}
+
+	if _, err := jobs.EnqueueJob(ctx, repository.EnqueueJobParams{Kind: JobSendEmail, Payload: payload}); err != nil {
+		return fmt.Errorf("enqueue job: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,134 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/mail"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	mailMock "github.com/ferdiebergado/goweb/internal/pkg/mail/mock"
+)
+
+func newMailTemplate(t *testing.T) *mail.Template {
+	t.Helper()
+	tmpl, err := mail.NewTemplate(config.TemplateConfig{
+		Path:         "../../web/templates/mail",
+		LayoutFile:   "layout",
+		PartialsPath: "partials",
+		PagesPath:    "pages",
+	})
+	if err != nil {
+		t.Fatalf("cant parse mail template: %v", err)
+	}
+	return tmpl
+}
+
+// Expects a single mail to testEmail whose text contains link.
+func expectMail(t *testing.T, mailer *mailMock.MockMailer, link string) {
+	t.Helper()
+	mailer.EXPECT().Send(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, msg *mail.Message) error {
+			assert.Equal(t, []string{testEmail}, msg.To)
+			assert.Contains(t, msg.Text, link)
+			assert.Contains(t, msg.HTML, link)
+			return nil
+		})
+}
+
+func TestMailService_SendMailVerifyEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockVerificationRepo := mock.NewMockVerificationRepo(ctrl)
+	mockMailer := mailMock.NewMockMailer(ctrl)
+	ctx := context.Background()
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	mockUserRepo.EXPECT().FindActiveUserByID(ctx, user.ID).Return(user, nil)
+	mockVerificationRepo.EXPECT().CreateVerification(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateVerificationParams) error {
+			assert.Equal(t, user.ID, params.UserID)
+			assert.NotEmpty(t, params.TokenHash)
+			return nil
+		})
+	expectMail(t, mockMailer, "/auth/verify?token=")
+
+	repo := &repository.Repository{User: mockUserRepo, Verification: mockVerificationRepo}
+	mails := service.NewMailService(repo, mockMailer, newMailTemplate(t), &config.Config{})
+
+	err := mails.SendMail(ctx, service.MailJob{Mail: service.MailVerifyEmail, UserID: user.ID})
+	assert.NoError(t, err)
+}
+
+func TestMailService_SendMailResetPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockResetRepo := mock.NewMockPasswordResetRepo(ctrl)
+	mockMailer := mailMock.NewMockMailer(ctrl)
+	ctx := context.Background()
+
+	now := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, VerifiedAt: &now}
+
+	mockUserRepo.EXPECT().FindActiveUserByID(ctx, user.ID).Return(user, nil)
+	gomock.InOrder(
+		mockResetRepo.EXPECT().DeleteUserPasswordResets(ctx, user.ID).Return(nil),
+		mockResetRepo.EXPECT().CreatePasswordReset(ctx, gomock.Any()).
+			DoAndReturn(func(_ context.Context, params repository.CreatePasswordResetParams) error {
+				assert.Equal(t, user.ID, params.UserID)
+				assert.NotEmpty(t, params.TokenHash)
+				assert.WithinDuration(t, time.Now().Add(time.Hour), params.ExpiresAt, time.Minute)
+				return nil
+			}),
+	)
+	expectMail(t, mockMailer, "/auth/reset-password?token=")
+
+	cfg := &config.Config{Auth: config.AuthConfig{PasswordResetTTL: 3600}}
+	repo := &repository.Repository{User: mockUserRepo, PasswordReset: mockResetRepo}
+	mails := service.NewMailService(repo, mockMailer, newMailTemplate(t), cfg)
+
+	err := mails.SendMail(ctx, service.MailJob{Mail: service.MailResetPassword, UserID: user.ID})
+	assert.NoError(t, err)
+}
+
+func TestMailService_SendMailSkipped(t *testing.T) {
+	now := time.Now()
+
+	var tests = []struct {
+		name string
+		job  service.MailJob
+		user *model.User
+		err  error
+	}{
+		{"Inactive user", service.MailJob{Mail: service.MailResetPassword, UserID: "1"}, nil, sql.ErrNoRows},
+		{"Verified user", service.MailJob{Mail: service.MailVerifyEmail, UserID: "1"},
+			&model.User{Model: model.Model{ID: "1"}, Email: testEmail, VerifiedAt: &now}, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			mockUserRepo := mock.NewMockUserRepo(ctrl)
+			mockMailer := mailMock.NewMockMailer(ctrl)
+			ctx := context.Background()
+
+			mockUserRepo.EXPECT().FindActiveUserByID(ctx, tt.job.UserID).Return(tt.user, tt.err)
+			mockMailer.EXPECT().Send(gomock.Any(), gomock.Any()).Times(0)
+
+			repo := &repository.Repository{User: mockUserRepo}
+			mails := service.NewMailService(repo, mockMailer, newMailTemplate(t), &config.Config{})
+
+			err := mails.SendMail(ctx, tt.job)
+			assert.NoError(t, err)
+		})
+	}
+}
@@ -0,0 +1,85 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/service (interfaces: AuthorizationService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/authorization_service_mock.go -package=mock . AuthorizationService
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockAuthorizationService is a mock of AuthorizationService interface.
+type MockAuthorizationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockAuthorizationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockAuthorizationServiceMockRecorder is the mock recorder for MockAuthorizationService.
+type MockAuthorizationServiceMockRecorder struct {
+	mock *MockAuthorizationService
+}
+
+// NewMockAuthorizationService creates a new mock instance.
+func NewMockAuthorizationService(ctrl *gomock.Controller) *MockAuthorizationService {
+	mock := &MockAuthorizationService{ctrl: ctrl}
+	mock.recorder = &MockAuthorizationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockAuthorizationService) EXPECT() *MockAuthorizationServiceMockRecorder {
+	return m.recorder
+}
+
+// AssignRole mocks base method.
+func (m *MockAuthorizationService) AssignRole(ctx context.Context, email, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AssignRole", ctx, email, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AssignRole indicates an expected call of AssignRole.
+func (mr *MockAuthorizationServiceMockRecorder) AssignRole(ctx, email, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AssignRole", reflect.TypeOf((*MockAuthorizationService)(nil).AssignRole), ctx, email, role)
+}
+
+// Can mocks base method.
+func (m *MockAuthorizationService) Can(ctx context.Context, user *model.User, permission string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Can", ctx, user, permission)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Can indicates an expected call of Can.
+func (mr *MockAuthorizationServiceMockRecorder) Can(ctx, user, permission any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Can", reflect.TypeOf((*MockAuthorizationService)(nil).Can), ctx, user, permission)
+}
+
+// RevokeRole mocks base method.
+func (m *MockAuthorizationService) RevokeRole(ctx context.Context, email, role string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RevokeRole", ctx, email, role)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RevokeRole indicates an expected call of RevokeRole.
+func (mr *MockAuthorizationServiceMockRecorder) RevokeRole(ctx, email, role any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RevokeRole", reflect.TypeOf((*MockAuthorizationService)(nil).RevokeRole), ctx, email, role)
+}
@@ -0,0 +1,58 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/service (interfaces: KeyRotationService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/key_rotation_service_mock.go -package=mock . KeyRotationService
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	repository "github.com/ferdiebergado/goweb/internal/repository"
+	service "github.com/ferdiebergado/goweb/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockKeyRotationService is a mock of KeyRotationService interface.
+type MockKeyRotationService struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyRotationServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockKeyRotationServiceMockRecorder is the mock recorder for MockKeyRotationService.
+type MockKeyRotationServiceMockRecorder struct {
+	mock *MockKeyRotationService
+}
+
+// NewMockKeyRotationService creates a new mock instance.
+func NewMockKeyRotationService(ctrl *gomock.Controller) *MockKeyRotationService {
+	mock := &MockKeyRotationService{ctrl: ctrl}
+	mock.recorder = &MockKeyRotationServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyRotationService) EXPECT() *MockKeyRotationServiceMockRecorder {
+	return m.recorder
+}
+
+// RotateKeys mocks base method.
+func (m *MockKeyRotationService) RotateKeys(ctx context.Context, col repository.EncryptedColumn, batchSize int) (*service.RotateKeysResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RotateKeys", ctx, col, batchSize)
+	ret0, _ := ret[0].(*service.RotateKeysResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// RotateKeys indicates an expected call of RotateKeys.
+func (mr *MockKeyRotationServiceMockRecorder) RotateKeys(ctx, col, batchSize any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RotateKeys", reflect.TypeOf((*MockKeyRotationService)(nil).RotateKeys), ctx, col, batchSize)
+}
@@ -0,0 +1,56 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/service (interfaces: MailService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/mail_service_mock.go -package=mock . MailService
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	service "github.com/ferdiebergado/goweb/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockMailService is a mock of MailService interface.
+type MockMailService struct {
+	ctrl     *gomock.Controller
+	recorder *MockMailServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockMailServiceMockRecorder is the mock recorder for MockMailService.
+type MockMailServiceMockRecorder struct {
+	mock *MockMailService
+}
+
+// NewMockMailService creates a new mock instance.
+func NewMockMailService(ctrl *gomock.Controller) *MockMailService {
+	mock := &MockMailService{ctrl: ctrl}
+	mock.recorder = &MockMailServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockMailService) EXPECT() *MockMailServiceMockRecorder {
+	return m.recorder
+}
+
+// SendMail mocks base method.
+func (m *MockMailService) SendMail(ctx context.Context, job service.MailJob) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SendMail", ctx, job)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SendMail indicates an expected call of SendMail.
+func (mr *MockMailServiceMockRecorder) SendMail(ctx, job any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SendMail", reflect.TypeOf((*MockMailService)(nil).SendMail), ctx, job)
+}
@@ -0,0 +1,86 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/service (interfaces: OIDCService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/oidc_service_mock.go -package=mock . OIDCService
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	service "github.com/ferdiebergado/goweb/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockOIDCService is a mock of OIDCService interface.
+type MockOIDCService struct {
+	ctrl     *gomock.Controller
+	recorder *MockOIDCServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockOIDCServiceMockRecorder is the mock recorder for MockOIDCService.
+type MockOIDCServiceMockRecorder struct {
+	mock *MockOIDCService
+}
+
+// NewMockOIDCService creates a new mock instance.
+func NewMockOIDCService(ctrl *gomock.Controller) *MockOIDCService {
+	mock := &MockOIDCService{ctrl: ctrl}
+	mock.recorder = &MockOIDCServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockOIDCService) EXPECT() *MockOIDCServiceMockRecorder {
+	return m.recorder
+}
+
+// BeginOIDCLogin mocks base method.
+func (m *MockOIDCService) BeginOIDCLogin(ctx context.Context, provider string) (*service.OIDCLogin, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginOIDCLogin", ctx, provider)
+	ret0, _ := ret[0].(*service.OIDCLogin)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginOIDCLogin indicates an expected call of BeginOIDCLogin.
+func (mr *MockOIDCServiceMockRecorder) BeginOIDCLogin(ctx, provider any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginOIDCLogin", reflect.TypeOf((*MockOIDCService)(nil).BeginOIDCLogin), ctx, provider)
+}
+
+// CompleteOIDCLogin mocks base method.
+func (m *MockOIDCService) CompleteOIDCLogin(ctx context.Context, params service.CompleteOIDCLoginParams) (*service.LoginUserResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteOIDCLogin", ctx, params)
+	ret0, _ := ret[0].(*service.LoginUserResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteOIDCLogin indicates an expected call of CompleteOIDCLogin.
+func (mr *MockOIDCServiceMockRecorder) CompleteOIDCLogin(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteOIDCLogin", reflect.TypeOf((*MockOIDCService)(nil).CompleteOIDCLogin), ctx, params)
+}
+
+// Providers mocks base method.
+func (m *MockOIDCService) Providers() []service.OIDCProvider {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Providers")
+	ret0, _ := ret[0].([]service.OIDCProvider)
+	return ret0
+}
+
+// Providers indicates an expected call of Providers.
+func (mr *MockOIDCServiceMockRecorder) Providers() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Providers", reflect.TypeOf((*MockOIDCService)(nil).Providers))
+}
@@ -0,0 +1,146 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: github.com/ferdiebergado/goweb/internal/service (interfaces: TwoFactorService)
+//
+// Generated by this command:
+//
+//	mockgen -destination=mock/two_factor_service_mock.go -package=mock . TwoFactorService
+//
+
+// Package mock is a generated GoMock package.
+package mock
+
+import (
+	context "context"
+	reflect "reflect"
+
+	model "github.com/ferdiebergado/goweb/internal/model"
+	service "github.com/ferdiebergado/goweb/internal/service"
+	gomock "go.uber.org/mock/gomock"
+)
+
+// MockTwoFactorService is a mock of TwoFactorService interface.
+type MockTwoFactorService struct {
+	ctrl     *gomock.Controller
+	recorder *MockTwoFactorServiceMockRecorder
+	isgomock struct{}
+}
+
+// MockTwoFactorServiceMockRecorder is the mock recorder for MockTwoFactorService.
+type MockTwoFactorServiceMockRecorder struct {
+	mock *MockTwoFactorService
+}
+
+// NewMockTwoFactorService creates a new mock instance.
+func NewMockTwoFactorService(ctrl *gomock.Controller) *MockTwoFactorService {
+	mock := &MockTwoFactorService{ctrl: ctrl}
+	mock.recorder = &MockTwoFactorServiceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTwoFactorService) EXPECT() *MockTwoFactorServiceMockRecorder {
+	return m.recorder
+}
+
+// BeginEnrollment mocks base method.
+func (m *MockTwoFactorService) BeginEnrollment(ctx context.Context, user *model.User) (*service.TOTPEnrollment, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "BeginEnrollment", ctx, user)
+	ret0, _ := ret[0].(*service.TOTPEnrollment)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// BeginEnrollment indicates an expected call of BeginEnrollment.
+func (mr *MockTwoFactorServiceMockRecorder) BeginEnrollment(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "BeginEnrollment", reflect.TypeOf((*MockTwoFactorService)(nil).BeginEnrollment), ctx, user)
+}
+
+// CompleteLogin mocks base method.
+func (m *MockTwoFactorService) CompleteLogin(ctx context.Context, params service.CompleteLoginParams) (*service.LoginUserResult, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CompleteLogin", ctx, params)
+	ret0, _ := ret[0].(*service.LoginUserResult)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CompleteLogin indicates an expected call of CompleteLogin.
+func (mr *MockTwoFactorServiceMockRecorder) CompleteLogin(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CompleteLogin", reflect.TypeOf((*MockTwoFactorService)(nil).CompleteLogin), ctx, params)
+}
+
+// ConfirmEnrollment mocks base method.
+func (m *MockTwoFactorService) ConfirmEnrollment(ctx context.Context, params service.TwoFactorCodeParams) ([]string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ConfirmEnrollment", ctx, params)
+	ret0, _ := ret[0].([]string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ConfirmEnrollment indicates an expected call of ConfirmEnrollment.
+func (mr *MockTwoFactorServiceMockRecorder) ConfirmEnrollment(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ConfirmEnrollment", reflect.TypeOf((*MockTwoFactorService)(nil).ConfirmEnrollment), ctx, params)
+}
+
+// DisableTwoFactor mocks base method.
+func (m *MockTwoFactorService) DisableTwoFactor(ctx context.Context, params service.TwoFactorCodeParams) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DisableTwoFactor", ctx, params)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DisableTwoFactor indicates an expected call of DisableTwoFactor.
+func (mr *MockTwoFactorServiceMockRecorder) DisableTwoFactor(ctx, params any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DisableTwoFactor", reflect.TypeOf((*MockTwoFactorService)(nil).DisableTwoFactor), ctx, params)
+}
+
+// EnrollmentQRCode mocks base method.
+func (m *MockTwoFactorService) EnrollmentQRCode(ctx context.Context, user *model.User) ([]byte, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "EnrollmentQRCode", ctx, user)
+	ret0, _ := ret[0].([]byte)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// EnrollmentQRCode indicates an expected call of EnrollmentQRCode.
+func (mr *MockTwoFactorServiceMockRecorder) EnrollmentQRCode(ctx, user any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "EnrollmentQRCode", reflect.TypeOf((*MockTwoFactorService)(nil).EnrollmentQRCode), ctx, user)
+}
+
+// TwoFactorEnabled mocks base method.
+func (m *MockTwoFactorService) TwoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "TwoFactorEnabled", ctx, userID)
+	ret0, _ := ret[0].(bool)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// TwoFactorEnabled indicates an expected call of TwoFactorEnabled.
+func (mr *MockTwoFactorServiceMockRecorder) TwoFactorEnabled(ctx, userID any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "TwoFactorEnabled", reflect.TypeOf((*MockTwoFactorService)(nil).TwoFactorEnabled), ctx, userID)
+}
+
+// VerifyTwoFactor mocks base method.
+func (m *MockTwoFactorService) VerifyTwoFactor(ctx context.Context, userID, code string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "VerifyTwoFactor", ctx, userID, code)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// VerifyTwoFactor indicates an expected call of VerifyTwoFactor.
+func (mr *MockTwoFactorServiceMockRecorder) VerifyTwoFactor(ctx, userID, code any) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "VerifyTwoFactor", reflect.TypeOf((*MockTwoFactorService)(nil).VerifyTwoFactor), ctx, userID, code)
+}
@@ -0,0 +1,278 @@
+//go:generate mockgen -destination=mock/oidc_service_mock.go -package=mock . OIDCService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/oidc"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// OIDCService signs users in with the OpenID providers in the config.
+type OIDCService interface {
+	Providers() []OIDCProvider
+	BeginOIDCLogin(ctx context.Context, provider string) (*OIDCLogin, error)
+	CompleteOIDCLogin(ctx context.Context, params CompleteOIDCLoginParams) (*LoginUserResult, error)
+}
+
+type oidcService struct {
+	repo      *repository.Repository
+	cfg       *config.Config
+	providers map[string]*oidc.Provider
+}
+
+var _ OIDCService = (*oidcService)(nil)
+var ErrUnknownProvider = errors.New("unknown identity provider")
+var ErrOIDCLoginFailed = errors.New("identity provider sign in failed")
+var ErrOIDCEmailNotVerified = errors.New("identity provider did not return a verified email address")
+
+// Timeout of the requests made to a provider
+const oidcRequestTimeout = 10 * time.Second
+
+// Scopes requested when a provider does not configure any. The email is
+// needed to link the identity to an existing user.
+var defaultOIDCScopes = []string{"email", "profile"}
+
+func NewOIDCService(repo *repository.Repository, cfg *config.Config) OIDCService {
+	client := &http.Client{Timeout: oidcRequestTimeout}
+
+	providers := make(map[string]*oidc.Provider, len(cfg.OIDC.Providers))
+	for name, p := range cfg.OIDC.Providers {
+		scopes := p.Scopes
+		if len(scopes) == 0 {
+			scopes = defaultOIDCScopes
+		}
+
+		providers[name] = oidc.NewProvider(oidc.Config{
+			Issuer:       p.Issuer,
+			ClientID:     p.ClientID,
+			ClientSecret: p.ClientSecret,
+			RedirectURL:  OIDCRedirectURL(cfg.App.URL, name),
+			Scopes:       scopes,
+		}, client)
+	}
+
+	return &oidcService{
+		repo:      repo,
+		cfg:       cfg,
+		providers: providers,
+	}
+}
+
+// OIDCRedirectURL returns the URL that provider redirects back to, which has
+// to be registered with the provider.
+func OIDCRedirectURL(appURL, provider string) string {
+	return strings.TrimSuffix(appURL, "/") + "/auth/oidc/" + provider + "/callback"
+}
+
+type OIDCProvider struct {
+	Name        string
+	DisplayName string
+}
+
+// Providers returns the configured providers sorted by name.
+func (s *oidcService) Providers() []OIDCProvider {
+	providers := make([]OIDCProvider, 0, len(s.cfg.OIDC.Providers))
+	for name, p := range s.cfg.OIDC.Providers {
+		providers = append(providers, OIDCProvider{Name: name, DisplayName: p.DisplayName})
+	}
+	slices.SortFunc(providers, func(a, b OIDCProvider) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+	return providers
+}
+
+// OIDCLogin carries the URL of the provider to send the user to. State has
+// to be kept by the client and passed to CompleteOIDCLogin.
+type OIDCLogin struct {
+	AuthURL   string
+	State     string
+	ExpiresAt time.Time
+}
+
+// BeginOIDCLogin starts an authorization code flow with provider.
+func (s *oidcService) BeginOIDCLogin(ctx context.Context, provider string) (*OIDCLogin, error) {
+	p, ok := s.providers[provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	state, stateHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate state: %w", err)
+	}
+
+	nonce, _, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate nonce: %w", err)
+	}
+
+	verifier, err := oidc.GenerateVerifier()
+	if err != nil {
+		return nil, fmt.Errorf("generate code verifier: %w", err)
+	}
+
+	authURL, err := p.AuthCodeURL(ctx, state, nonce, verifier)
+	if err != nil {
+		return nil, fmt.Errorf("auth code url of %s: %w", provider, err)
+	}
+
+	expiresAt := time.Now().Add(time.Duration(s.cfg.OIDC.FlowTTL) * time.Second)
+	if err := s.repo.Identity.CreateOIDCFlow(ctx, repository.CreateOIDCFlowParams{
+		StateHash:    stateHash,
+		Provider:     provider,
+		Nonce:        nonce,
+		CodeVerifier: verifier,
+		ExpiresAt:    expiresAt,
+	}); err != nil {
+		return nil, fmt.Errorf("create oidc flow: %w", err)
+	}
+
+	return &OIDCLogin{
+		AuthURL:   authURL,
+		State:     state,
+		ExpiresAt: expiresAt,
+	}, nil
+}
+
+type CompleteOIDCLoginParams struct {
+	Provider  string
+	State     string
+	Code      string
+	UserAgent string
+	IPAddress string
+}
+
+// CompleteOIDCLogin redeems the code that the provider redirected back with
+// and signs in the user of the identity. An identity seen for the first time
+// is linked to the user with the same verified email, or to a new user
+// without a password. Like LoginUser, only a challenge is returned for users
+// with two-factor authentication.
+func (s *oidcService) CompleteOIDCLogin(ctx context.Context, params CompleteOIDCLoginParams) (*LoginUserResult, error) {
+	p, ok := s.providers[params.Provider]
+	if !ok {
+		return nil, ErrUnknownProvider
+	}
+
+	flow, err := s.repo.Identity.ConsumeOIDCFlow(ctx, security.HashToken(params.State))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("consume oidc flow: %w", err)
+	}
+
+	if flow.Provider != params.Provider || !flow.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	token, err := p.Exchange(ctx, params.Code, flow.CodeVerifier)
+	if err != nil {
+		if errors.Is(err, oidc.ErrExchange) {
+			return nil, fmt.Errorf("%w: %w", ErrOIDCLoginFailed, err)
+		}
+		return nil, fmt.Errorf("exchange code with %s: %w", params.Provider, err)
+	}
+
+	claims, err := p.VerifyIDToken(ctx, token.IDToken, flow.Nonce, time.Now())
+	if err != nil {
+		if errors.Is(err, oidc.ErrInvalidIDToken) {
+			return nil, fmt.Errorf("%w: %w", ErrOIDCLoginFailed, err)
+		}
+		return nil, fmt.Errorf("verify id token of %s: %w", params.Provider, err)
+	}
+
+	user, err := s.identityUser(ctx, params.Provider, claims)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := twoFactorEnabled(ctx, s.repo.TwoFactor, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		return createLoginChallenge(ctx, s.repo.Challenge, &s.cfg.TOTP, user)
+	}
+
+	return createSession(ctx, s.repo.Session, &s.cfg.Session, user, params.UserAgent, params.IPAddress)
+}
+
+// Returns the user linked to the identity in claims, linking one first if
+// there is none.
+func (s *oidcService) identityUser(ctx context.Context, provider string, claims *oidc.Claims) (*model.User, error) {
+	identity, err := s.repo.Identity.FindIdentity(ctx, provider, claims.Subject)
+	if err == nil {
+		user, err := s.repo.User.FindActiveUserByID(ctx, identity.UserID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return nil, ErrUserDisabled
+			}
+			return nil, fmt.Errorf("find user %s: %w", identity.UserID, err)
+		}
+		return user, nil
+	}
+
+	if !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("find identity %s of %s: %w", claims.Subject, provider, err)
+	}
+
+	// Linking by an email the provider did not verify would let anyone sign
+	// in as the user that owns it.
+	if claims.Email == "" || !claims.EmailVerified {
+		return nil, ErrOIDCEmailNotVerified
+	}
+
+	var user *model.User
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		user, err = repo.User.FindUserByEmail(ctx, claims.Email)
+		switch {
+		case err == nil:
+			if user.DisabledAt != nil {
+				return ErrUserDisabled
+			}
+
+			// The password of an unverified user may have been chosen by
+			// someone expecting the owner to sign in this way later.
+			if user.VerifiedAt == nil {
+				if _, err := repo.User.ClaimUnverifiedUser(ctx, user.ID); err != nil {
+					return fmt.Errorf("claim user %s: %w", user.ID, err)
+				}
+			}
+		case errors.Is(err, sql.ErrNoRows):
+			user, err = repo.User.CreateExternalUser(ctx, claims.Email)
+			if err != nil {
+				return fmt.Errorf("create user %s: %w", claims.Email, err)
+			}
+		default:
+			return fmt.Errorf("find user %s: %w", claims.Email, err)
+		}
+
+		if _, err := repo.Identity.CreateIdentity(ctx, repository.CreateIdentityParams{
+			UserID:   user.ID,
+			Provider: provider,
+			Subject:  claims.Subject,
+			Email:    claims.Email,
+		}); err != nil {
+			return fmt.Errorf("create identity %s of %s: %w", claims.Subject, provider, err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
@@ -0,0 +1,256 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/oidc/oidctest"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const testProvider = "example"
+
+type oidcMocks struct {
+	provider  *oidctest.Provider
+	user      *mock.MockUserRepo
+	session   *mock.MockSessionRepo
+	twoFactor *mock.MockTwoFactorRepo
+	challenge *mock.MockLoginChallengeRepo
+	identity  *mock.MockIdentityRepo
+}
+
+func newOIDCService(t *testing.T) (service.OIDCService, *oidcMocks) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	m := &oidcMocks{
+		provider:  oidctest.NewProvider(t),
+		user:      mock.NewMockUserRepo(ctrl),
+		session:   mock.NewMockSessionRepo(ctrl),
+		twoFactor: mock.NewMockTwoFactorRepo(ctrl),
+		challenge: mock.NewMockLoginChallengeRepo(ctrl),
+		identity:  mock.NewMockIdentityRepo(ctrl),
+	}
+
+	cfg := &config.Config{
+		App:     config.EnvConfig{URL: "http://localhost:8888"},
+		Session: config.SessionConfig{Lifetime: 3600},
+		TOTP:    config.TOTPConfig{ChallengeTTL: 300},
+		OIDC: config.OIDCConfig{
+			FlowTTL: 600,
+			Providers: map[string]config.OIDCProviderConfig{
+				testProvider: {
+					DisplayName:  "Example",
+					Issuer:       m.provider.Issuer(),
+					ClientID:     oidctest.ClientID,
+					ClientSecret: oidctest.ClientSecret,
+				},
+				"acme": {DisplayName: "Acme", Issuer: "https://acme.example.com", ClientID: "acme"},
+			},
+		},
+	}
+	repo := &repository.Repository{
+		User:      m.user,
+		Session:   m.session,
+		TwoFactor: m.twoFactor,
+		Challenge: m.challenge,
+		Identity:  m.identity,
+	}
+	return service.NewOIDCService(repo, cfg), m
+}
+
+// Runs the flow up to the redirect back from the provider and returns the
+// params to complete it with. The flow is handed back when it is consumed.
+func (m *oidcMocks) authorize(t *testing.T, svc service.OIDCService) service.CompleteOIDCLoginParams {
+	t.Helper()
+
+	var flow *model.OIDCFlow
+	m.identity.EXPECT().CreateOIDCFlow(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateOIDCFlowParams) error {
+			flow = &model.OIDCFlow{
+				StateHash:    params.StateHash,
+				Provider:     params.Provider,
+				Nonce:        params.Nonce,
+				CodeVerifier: params.CodeVerifier,
+				ExpiresAt:    params.ExpiresAt,
+			}
+			return nil
+		})
+
+	login, err := svc.BeginOIDCLogin(context.Background(), testProvider)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	code, state := m.provider.Authorize(t, login.AuthURL)
+	assert.Equal(t, login.State, state)
+	assert.NotEqual(t, flow.StateHash, state, "the state must be stored hashed")
+
+	m.identity.EXPECT().ConsumeOIDCFlow(gomock.Any(), flow.StateHash).Return(flow, nil).MaxTimes(1)
+
+	return service.CompleteOIDCLoginParams{Provider: testProvider, State: state, Code: code}
+}
+
+func (m *oidcMocks) expectSession(t *testing.T, userID string) {
+	t.Helper()
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), userID).Return(nil, sql.ErrNoRows)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateSessionParams) (*model.Session, error) {
+			assert.Equal(t, userID, params.UserID)
+			return &model.Session{UserID: params.UserID, ExpiresAt: params.ExpiresAt}, nil
+		})
+}
+
+func TestOIDCService_Providers(t *testing.T) {
+	svc, _ := newOIDCService(t)
+
+	assert.Equal(t, []service.OIDCProvider{
+		{Name: "acme", DisplayName: "Acme"},
+		{Name: testProvider, DisplayName: "Example"},
+	}, svc.Providers())
+}
+
+func TestOIDCService_BeginOIDCLoginUnknownProvider(t *testing.T) {
+	svc, _ := newOIDCService(t)
+
+	_, err := svc.BeginOIDCLogin(context.Background(), "nope")
+	assert.ErrorIs(t, err, service.ErrUnknownProvider)
+}
+
+func TestOIDCService_CompleteOIDCLoginNewUser(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).Return(nil, sql.ErrNoRows)
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(nil, sql.ErrNoRows)
+	m.user.EXPECT().CreateExternalUser(gomock.Any(), testEmail).Return(user, nil)
+	m.identity.EXPECT().CreateIdentity(gomock.Any(), repository.CreateIdentityParams{
+		UserID:   user.ID,
+		Provider: testProvider,
+		Subject:  m.provider.User.Subject,
+		Email:    testEmail,
+	}).Return(&model.Identity{}, nil)
+	m.expectSession(t, user.ID)
+
+	result, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+	assert.Equal(t, user, result.User)
+}
+
+func TestOIDCService_CompleteOIDCLoginLinkedIdentity(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).
+		Return(&model.Identity{UserID: user.ID}, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+	m.expectSession(t, user.ID)
+
+	result, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result.Token)
+}
+
+func TestOIDCService_CompleteOIDCLoginLinkedIdentityDisabled(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).
+		Return(&model.Identity{UserID: "1"}, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), "1").Return(nil, sql.ErrNoRows)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrUserDisabled)
+}
+
+func TestOIDCService_CompleteOIDCLoginClaimsUnverifiedUser(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed}
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).Return(nil, sql.ErrNoRows)
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	m.user.EXPECT().ClaimUnverifiedUser(gomock.Any(), user.ID).Return(true, nil)
+	m.identity.EXPECT().CreateIdentity(gomock.Any(), gomock.Any()).Return(&model.Identity{}, nil)
+	m.expectSession(t, user.ID)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.NoError(t, err)
+}
+
+func TestOIDCService_CompleteOIDCLoginTwoFactor(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	enabledAt := time.Now()
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).
+		Return(&model.Identity{UserID: user.ID}, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(&model.TOTP{UserID: user.ID, EnabledAt: &enabledAt}, nil)
+	m.challenge.EXPECT().CreateLoginChallenge(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateLoginChallengeParams) (*model.LoginChallenge, error) {
+			return &model.LoginChallenge{UserID: params.UserID, ExpiresAt: params.ExpiresAt}, nil
+		})
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	result, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token)
+	assert.NotEmpty(t, result.ChallengeToken)
+}
+
+func TestOIDCService_CompleteOIDCLoginEmailNotVerified(t *testing.T) {
+	svc, m := newOIDCService(t)
+	m.provider.User.EmailVerified = false
+	params := m.authorize(t, svc)
+
+	m.identity.EXPECT().FindIdentity(gomock.Any(), testProvider, m.provider.User.Subject).Return(nil, sql.ErrNoRows)
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrOIDCEmailNotVerified)
+}
+
+func TestOIDCService_CompleteOIDCLoginInvalidState(t *testing.T) {
+	svc, m := newOIDCService(t)
+
+	m.identity.EXPECT().ConsumeOIDCFlow(gomock.Any(), gomock.Any()).Return(nil, sql.ErrNoRows)
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), service.CompleteOIDCLoginParams{
+		Provider: testProvider,
+		State:    "state",
+		Code:     "code",
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestOIDCService_CompleteOIDCLoginWrongProvider(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	params.Provider = "acme"
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestOIDCService_CompleteOIDCLoginInvalidCode(t *testing.T) {
+	svc, m := newOIDCService(t)
+	params := m.authorize(t, svc)
+	params.Code = "forged"
+
+	_, err := svc.CompleteOIDCLogin(context.Background(), params)
+	assert.ErrorIs(t, err, service.ErrOIDCLoginFailed)
+}
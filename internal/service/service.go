@@ -1,18 +1,30 @@
 package service
 
 import (
+	"github.com/ferdiebergado/goweb/internal/config"
 	"github.com/ferdiebergado/goweb/internal/pkg/security"
 	"github.com/ferdiebergado/goweb/internal/repository"
 )
 
 type Service struct {
-	Base BaseService
-	User UserService
+	Base          BaseService
+	User          UserService
+	Authorization AuthorizationService
+	Token         TokenService
+	APIKey        APIKeyService
+	TwoFactor     TwoFactorService
+	OIDC          OIDCService
 }
 
-func NewService(repo *repository.Repository, hasher security.Hasher) *Service {
+func NewService(repo *repository.Repository, hasher security.Hasher, jwt *security.JWT, cfg *config.Config) *Service {
+	twoFactor := NewTwoFactorService(repo, cfg)
 	return &Service{
-		Base: NewBaseService(repo.Base),
-		User: NewUserService(repo.User, hasher),
+		Base:          NewBaseService(repo.Base),
+		User:          NewUserService(repo, hasher, cfg),
+		Authorization: NewAuthorizationService(repo),
+		Token:         NewTokenService(repo, hasher, jwt, twoFactor, &cfg.Lockout, &cfg.JWT),
+		APIKey:        NewAPIKeyService(repo, &cfg.Auth),
+		TwoFactor:     twoFactor,
+		OIDC:          NewOIDCService(repo, cfg),
 	}
 }
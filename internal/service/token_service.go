@@ -0,0 +1,239 @@
+//go:generate mockgen -destination=mock/token_service_mock.go -package=mock . TokenService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// TokenService issues the bearer tokens used by clients that cannot keep a
+// session cookie. Access tokens are short-lived JWTs while refresh tokens are
+// opaque, stored hashed and replaced on every use.
+type TokenService interface {
+	IssueTokens(ctx context.Context, params IssueTokensParams) (*TokenPair, error)
+	RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error)
+	RevokeRefreshToken(ctx context.Context, refreshToken string) error
+	AuthenticateAccessToken(ctx context.Context, accessToken string) (*model.User, error)
+}
+
+type tokenService struct {
+	repo      *repository.Repository
+	hasher    security.Hasher
+	jwt       *security.JWT
+	twoFactor TwoFactorService
+	throttle  *loginThrottle
+	cfg       *config.JWTConfig
+}
+
+var _ TokenService = (*tokenService)(nil)
+
+// Returned inside the refresh transaction when the token was already used.
+var errRefreshTokenReused = errors.New("refresh token reused")
+
+func NewTokenService(repo *repository.Repository, hasher security.Hasher, jwt *security.JWT,
+	twoFactor TwoFactorService, lockout *config.LockoutConfig, cfg *config.JWTConfig) TokenService {
+	return &tokenService{
+		repo:      repo,
+		hasher:    hasher,
+		jwt:       jwt,
+		twoFactor: twoFactor,
+		throttle:  newLoginThrottle(repo, lockout),
+		cfg:       cfg,
+	}
+}
+
+type TokenPair struct {
+	AccessToken           string
+	AccessTokenExpiresAt  time.Time
+	RefreshToken          string
+	RefreshTokenExpiresAt time.Time
+}
+
+type IssueTokensParams struct {
+	Email     string
+	Password  string
+	Code      string
+	IPAddress string
+}
+
+// IssueTokens checks the credentials like LoginUser and starts a new refresh
+// token family. Users with two-factor authentication enabled have to pass a
+// valid code along with their password. A wrong code counts as a failed
+// attempt since there is no challenge to limit the guesses.
+func (s *tokenService) IssueTokens(ctx context.Context, params IssueTokensParams) (*TokenPair, error) {
+	user, err := s.throttle.checkCredentials(ctx, s.hasher, params.Email, params.Password, params.IPAddress)
+	if err != nil {
+		return nil, err
+	}
+
+	enabled, err := s.twoFactor.TwoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	if enabled {
+		if params.Code == "" {
+			return nil, ErrTwoFactorRequired
+		}
+		if err := s.throttle.check(ctx, params.Email, params.IPAddress, func() error {
+			return s.twoFactor.VerifyTwoFactor(ctx, user.ID, params.Code)
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.throttle.reset(ctx, params.Email); err != nil {
+		return nil, err
+	}
+
+	return s.issue(ctx, s.repo, user.ID, "")
+}
+
+// RefreshTokens exchanges refreshToken for a new pair. A token that was
+// already exchanged means it has leaked, so its whole family is revoked and
+// the holder of the latest token has to sign in again.
+func (s *tokenService) RefreshTokens(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	token, err := s.repo.RefreshToken.FindRefreshTokenByHash(ctx, security.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find refresh token: %w", err)
+	}
+
+	if token.RevokedAt != nil {
+		return nil, s.revokeReused(ctx, token)
+	}
+
+	if time.Now().After(token.ExpiresAt) {
+		return nil, ErrInvalidToken
+	}
+
+	// A disabled user cannot stay signed in by refreshing.
+	if _, err := s.repo.User.FindActiveUserByID(ctx, token.UserID); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find user %s: %w", token.UserID, err)
+	}
+
+	var pair *TokenPair
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		revoked, err := repo.RefreshToken.RevokeRefreshToken(ctx, token.ID)
+		if err != nil {
+			return fmt.Errorf("revoke refresh token %s: %w", token.ID, err)
+		}
+
+		// Another request exchanged the token since it was read.
+		if !revoked {
+			return errRefreshTokenReused
+		}
+
+		pair, err = s.issue(ctx, repo, token.UserID, token.FamilyID)
+		return err
+	})
+
+	if err != nil {
+		if errors.Is(err, errRefreshTokenReused) {
+			return nil, s.revokeReused(ctx, token)
+		}
+		return nil, err
+	}
+
+	return pair, nil
+}
+
+// Revokes the family of a reused token and returns the error for the caller.
+func (s *tokenService) revokeReused(ctx context.Context, token *model.RefreshToken) error {
+	slog.Warn("refresh token reused, revoking its family", "user", token.UserID, "family", token.FamilyID)
+
+	if err := s.repo.RefreshToken.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return fmt.Errorf("revoke refresh token family %s: %w", token.FamilyID, err)
+	}
+
+	return ErrInvalidToken
+}
+
+// RevokeRefreshToken signs out the client holding refreshToken. Unknown
+// tokens are ignored like a logout without a session.
+func (s *tokenService) RevokeRefreshToken(ctx context.Context, refreshToken string) error {
+	token, err := s.repo.RefreshToken.FindRefreshTokenByHash(ctx, security.HashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("find refresh token: %w", err)
+	}
+
+	if err := s.repo.RefreshToken.RevokeRefreshTokenFamily(ctx, token.FamilyID); err != nil {
+		return fmt.Errorf("revoke refresh token family %s: %w", token.FamilyID, err)
+	}
+
+	return nil
+}
+
+// AuthenticateAccessToken returns the user that accessToken was issued to.
+// The user is loaded so that disabling an account takes effect immediately.
+func (s *tokenService) AuthenticateAccessToken(ctx context.Context, accessToken string) (*model.User, error) {
+	claims, err := s.jwt.Parse(accessToken, time.Now())
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.User.FindActiveUserByID(ctx, claims.Subject)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find user %s: %w", claims.Subject, err)
+	}
+
+	return user, nil
+}
+
+// Creates an access token and a refresh token in familyID, or in a new family
+// when familyID is empty.
+func (s *tokenService) issue(ctx context.Context, repo *repository.Repository, userID, familyID string) (*TokenPair, error) {
+	now := time.Now()
+	accessExpiresAt := now.Add(time.Duration(s.cfg.AccessTokenTTL) * time.Second)
+
+	accessToken, err := s.jwt.Sign(security.Claims{
+		Subject:   userID,
+		IssuedAt:  now.Unix(),
+		ExpiresAt: accessExpiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("sign access token: %w", err)
+	}
+
+	refreshToken, refreshHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate refresh token: %w", err)
+	}
+
+	created, err := repo.RefreshToken.CreateRefreshToken(ctx, repository.CreateRefreshTokenParams{
+		UserID:    userID,
+		FamilyID:  familyID,
+		TokenHash: refreshHash,
+		ExpiresAt: now.Add(time.Duration(s.cfg.RefreshTokenTTL) * time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create refresh token for user %s: %w", userID, err)
+	}
+
+	return &TokenPair{
+		AccessToken:           accessToken,
+		AccessTokenExpiresAt:  accessExpiresAt,
+		RefreshToken:          refreshToken,
+		RefreshTokenExpiresAt: created.ExpiresAt,
+	}, nil
+}
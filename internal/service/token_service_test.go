@@ -0,0 +1,255 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+
+	secMock "github.com/ferdiebergado/goweb/internal/pkg/security/mock"
+	svcMock "github.com/ferdiebergado/goweb/internal/service/mock"
+)
+
+var jwtCfg = &config.JWTConfig{
+	Algorithm:       security.AlgHS256,
+	Secret:          "0123456789abcdef0123456789abcdef",
+	Issuer:          "goweb",
+	AccessTokenTTL:  900,
+	RefreshTokenTTL: 3600,
+}
+
+var lockoutCfg = &config.LockoutConfig{
+	AccountThreshold: 3,
+	IPThreshold:      10,
+	LockDuration:     900,
+	Window:           900,
+	BaseDelay:        1,
+	MaxDelay:         30,
+}
+
+type tokenMocks struct {
+	user      *mock.MockUserRepo
+	refresh   *mock.MockRefreshTokenRepo
+	hasher    *secMock.MockHasher
+	twoFactor *svcMock.MockTwoFactorService
+}
+
+func newTokenService(t *testing.T) (service.TokenService, *tokenMocks, *security.JWT) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+	m := &tokenMocks{
+		user:      mock.NewMockUserRepo(ctrl),
+		refresh:   mock.NewMockRefreshTokenRepo(ctrl),
+		hasher:    secMock.NewMockHasher(ctrl),
+		twoFactor: svcMock.NewMockTwoFactorService(ctrl),
+	}
+
+	jwt, err := security.NewJWT(*jwtCfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	repo := &repository.Repository{
+		User:         m.user,
+		RefreshToken: m.refresh,
+		LoginAttempt: repository.NewMemoryLoginAttemptRepository(),
+	}
+	return service.NewTokenService(repo, m.hasher, jwt, m.twoFactor, lockoutCfg, jwtCfg), m, jwt
+}
+
+func TestTokenService_IssueTokens(t *testing.T) {
+	svc, m, jwt := newTokenService(t)
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+
+	m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+	m.hasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	m.hasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	m.twoFactor.EXPECT().TwoFactorEnabled(gomock.Any(), user.ID).Return(false, nil)
+	m.refresh.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateRefreshTokenParams) (*model.RefreshToken, error) {
+			assert.Equal(t, user.ID, params.UserID)
+			assert.Empty(t, params.FamilyID)
+			return &model.RefreshToken{ID: "2", UserID: user.ID, FamilyID: "3", ExpiresAt: params.ExpiresAt}, nil
+		})
+
+	pair, err := svc.IssueTokens(context.Background(), service.IssueTokensParams{Email: testEmail, Password: testPass})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, pair.RefreshToken)
+
+	claims, err := jwt.Parse(pair.AccessToken, time.Now())
+	assert.NoError(t, err)
+	assert.Equal(t, user.ID, claims.Subject)
+}
+
+func TestTokenService_IssueTokensTwoFactor(t *testing.T) {
+	const code = "123456"
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+
+	tests := []struct {
+		name    string
+		code    string
+		setup   func(m *tokenMocks)
+		wantErr error
+	}{
+		{
+			name:    "code missing",
+			wantErr: service.ErrTwoFactorRequired,
+		},
+		{
+			name: "invalid code",
+			code: code,
+			setup: func(m *tokenMocks) {
+				m.twoFactor.EXPECT().VerifyTwoFactor(gomock.Any(), user.ID, code).Return(service.ErrInvalidTwoFactorCode)
+			},
+			wantErr: service.ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "valid code",
+			code: code,
+			setup: func(m *tokenMocks) {
+				m.twoFactor.EXPECT().VerifyTwoFactor(gomock.Any(), user.ID, code).Return(nil)
+				m.refresh.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+					Return(&model.RefreshToken{ID: "2", UserID: user.ID, FamilyID: "3", ExpiresAt: time.Now().Add(time.Hour)}, nil)
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, m, _ := newTokenService(t)
+			m.user.EXPECT().FindUserByEmail(gomock.Any(), testEmail).Return(user, nil)
+			m.hasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+			m.hasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+			m.twoFactor.EXPECT().TwoFactorEnabled(gomock.Any(), user.ID).Return(true, nil)
+			if tt.setup != nil {
+				tt.setup(m)
+			}
+
+			pair, err := svc.IssueTokens(context.Background(), service.IssueTokensParams{
+				Email:    testEmail,
+				Password: testPass,
+				Code:     tt.code,
+			})
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				assert.Nil(t, pair)
+				return
+			}
+			assert.NoError(t, err)
+			assert.NotEmpty(t, pair.AccessToken)
+		})
+	}
+}
+
+func TestTokenService_RefreshTokens(t *testing.T) {
+	const refreshToken = "refresh"
+	svc, m, _ := newTokenService(t)
+
+	stored := &model.RefreshToken{ID: "2", UserID: "1", FamilyID: "3", ExpiresAt: time.Now().Add(time.Hour)}
+	m.refresh.EXPECT().FindRefreshTokenByHash(gomock.Any(), security.HashToken(refreshToken)).Return(stored, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), stored.UserID).
+		Return(&model.User{Model: model.Model{ID: stored.UserID}}, nil)
+	m.refresh.EXPECT().RevokeRefreshToken(gomock.Any(), stored.ID).Return(true, nil)
+	m.refresh.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateRefreshTokenParams) (*model.RefreshToken, error) {
+			assert.Equal(t, stored.FamilyID, params.FamilyID)
+			return &model.RefreshToken{ID: "4", UserID: stored.UserID, FamilyID: stored.FamilyID,
+				ExpiresAt: params.ExpiresAt}, nil
+		})
+
+	pair, err := svc.RefreshTokens(context.Background(), refreshToken)
+	assert.NoError(t, err)
+	assert.NotEqual(t, refreshToken, pair.RefreshToken)
+}
+
+func TestTokenService_RefreshTokensReused(t *testing.T) {
+	const refreshToken = "refresh"
+	revokedAt := time.Now()
+
+	var tests = []struct {
+		name  string
+		token *model.RefreshToken
+		// Whether the token is revoked by a concurrent request after it was read.
+		race bool
+	}{
+		{"Already revoked", &model.RefreshToken{ID: "2", FamilyID: "3", RevokedAt: &revokedAt,
+			ExpiresAt: time.Now().Add(time.Hour)}, false},
+		{"Revoked concurrently", &model.RefreshToken{ID: "2", FamilyID: "3",
+			ExpiresAt: time.Now().Add(time.Hour)}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, m, _ := newTokenService(t)
+			m.refresh.EXPECT().FindRefreshTokenByHash(gomock.Any(), security.HashToken(refreshToken)).Return(tt.token, nil)
+			if tt.race {
+				m.user.EXPECT().FindActiveUserByID(gomock.Any(), tt.token.UserID).
+					Return(&model.User{Model: model.Model{ID: "1"}}, nil)
+				m.refresh.EXPECT().RevokeRefreshToken(gomock.Any(), tt.token.ID).Return(false, nil)
+			}
+			m.refresh.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), tt.token.FamilyID).Return(nil)
+
+			_, err := svc.RefreshTokens(context.Background(), refreshToken)
+			assert.ErrorIs(t, err, service.ErrInvalidToken)
+		})
+	}
+}
+
+func TestTokenService_RefreshTokensDisabledUser(t *testing.T) {
+	const refreshToken = "refresh"
+	svc, m, _ := newTokenService(t)
+
+	// Disabling the user revoked the token.
+	revokedAt := time.Now()
+	stored := &model.RefreshToken{ID: "2", UserID: "1", FamilyID: "3", RevokedAt: &revokedAt,
+		ExpiresAt: time.Now().Add(time.Hour)}
+	m.refresh.EXPECT().FindRefreshTokenByHash(gomock.Any(), security.HashToken(refreshToken)).Return(stored, nil)
+	m.refresh.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), stored.FamilyID).Return(nil)
+	m.refresh.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.RefreshTokens(context.Background(), refreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestTokenService_RefreshTokensInactiveUser(t *testing.T) {
+	const refreshToken = "refresh"
+	svc, m, _ := newTokenService(t)
+
+	stored := &model.RefreshToken{ID: "2", UserID: "1", FamilyID: "3", ExpiresAt: time.Now().Add(time.Hour)}
+	m.refresh.EXPECT().FindRefreshTokenByHash(gomock.Any(), security.HashToken(refreshToken)).Return(stored, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), stored.UserID).Return(nil, sql.ErrNoRows)
+	m.refresh.EXPECT().RevokeRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+	m.refresh.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.RefreshTokens(context.Background(), refreshToken)
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestTokenService_AuthenticateAccessToken(t *testing.T) {
+	svc, m, jwt := newTokenService(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	token, err := jwt.Sign(security.Claims{Subject: user.ID, ExpiresAt: time.Now().Add(time.Minute).Unix()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+
+	got, err := svc.AuthenticateAccessToken(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	_, err = svc.AuthenticateAccessToken(context.Background(), token+"x")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
@@ -0,0 +1,386 @@
+//go:generate mockgen -destination=mock/two_factor_service_mock.go -package=mock . TwoFactorService
+package service
+
+import (
+	"context"
+	"database/sql"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// TwoFactorService manages TOTP enrollment and the second step of signing in
+// for users who enabled it.
+type TwoFactorService interface {
+	BeginEnrollment(ctx context.Context, user *model.User) (*TOTPEnrollment, error)
+	EnrollmentQRCode(ctx context.Context, user *model.User) ([]byte, error)
+	ConfirmEnrollment(ctx context.Context, params TwoFactorCodeParams) ([]string, error)
+	DisableTwoFactor(ctx context.Context, params TwoFactorCodeParams) error
+	TwoFactorEnabled(ctx context.Context, userID string) (bool, error)
+	VerifyTwoFactor(ctx context.Context, userID, code string) error
+	CompleteLogin(ctx context.Context, params CompleteLoginParams) (*LoginUserResult, error)
+}
+
+type twoFactorService struct {
+	repo     *repository.Repository
+	throttle *loginThrottle
+	cfg      *config.Config
+}
+
+var _ TwoFactorService = (*twoFactorService)(nil)
+var ErrTwoFactorEnabled = errors.New("two-factor authentication is already enabled")
+var ErrTwoFactorNotEnrolled = errors.New("two-factor authentication has not been set up")
+var ErrTwoFactorNotEnabled = errors.New("two-factor authentication is not enabled")
+var ErrInvalidTwoFactorCode = errors.New("invalid authentication code")
+var ErrTwoFactorRequired = errors.New("authentication code required")
+
+const (
+	// Number of recovery codes issued when 2FA is enabled
+	RecoveryCodeCount = 10
+	// Random bytes in a recovery code, 16 base32 characters
+	recoveryCodeLength = 10
+	// Steps either side of the current one that are still accepted
+	totpSkew = 1
+)
+
+func NewTwoFactorService(repo *repository.Repository, cfg *config.Config) TwoFactorService {
+	return &twoFactorService{
+		repo:     repo,
+		throttle: newLoginThrottle(repo, &cfg.Lockout),
+		cfg:      cfg,
+	}
+}
+
+// TOTPEnrollment carries the secret to show for manual entry and the URI to
+// render as a QR code.
+type TOTPEnrollment struct {
+	Secret string
+	URI    string
+}
+
+// BeginEnrollment generates a new secret for the user. It replaces the secret
+// of an enrollment that was not confirmed.
+func (s *twoFactorService) BeginEnrollment(ctx context.Context, user *model.User) (*TOTPEnrollment, error) {
+	enabled, err := s.TwoFactorEnabled(ctx, user.ID)
+	if err != nil {
+		return nil, err
+	}
+	if enabled {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	secret, err := security.GenerateTOTPSecret()
+	if err != nil {
+		return nil, fmt.Errorf("generate totp secret: %w", err)
+	}
+
+	if err := s.repo.TwoFactor.SaveTOTPSecret(ctx, user.ID, secret); err != nil {
+		return nil, fmt.Errorf("save totp secret of user %s: %w", user.ID, err)
+	}
+
+	return &TOTPEnrollment{
+		Secret: secret,
+		URI:    security.TOTPURI(s.cfg.TOTP.Issuer, user.Email, secret),
+	}, nil
+}
+
+// EnrollmentQRCode renders the URI of the pending enrollment as a PNG image.
+func (s *twoFactorService) EnrollmentQRCode(ctx context.Context, user *model.User) ([]byte, error) {
+	totp, err := s.findTOTP(ctx, user.ID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	return security.QRCodePNG(security.TOTPURI(s.cfg.TOTP.Issuer, user.Email, totp.Secret))
+}
+
+// TwoFactorCodeParams carries a code that the signed in user entered to
+// change their 2FA settings.
+type TwoFactorCodeParams struct {
+	User      *model.User
+	Code      string
+	IPAddress string
+}
+
+// ConfirmEnrollment enables 2FA once code proves that the authenticator was
+// set up. The plain recovery codes are returned only this once. A wrong code
+// counts as a failed sign in of the account.
+func (s *twoFactorService) ConfirmEnrollment(ctx context.Context, params TwoFactorCodeParams) ([]string, error) {
+	userID := params.User.ID
+	totp, err := s.findTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrTwoFactorNotEnrolled
+		}
+		return nil, err
+	}
+
+	if totp.EnabledAt != nil {
+		return nil, ErrTwoFactorEnabled
+	}
+
+	var step int64
+	err = s.throttle.check(ctx, params.User.Email, params.IPAddress, func() error {
+		var ok bool
+		var err error
+		step, ok, err = security.ValidateTOTP(totp.Secret, normalizeCode(params.Code), time.Now(), totpSkew)
+		if err != nil {
+			return fmt.Errorf("validate totp: %w", err)
+		}
+		if !ok {
+			return ErrInvalidTwoFactorCode
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	codes, hashes, err := generateRecoveryCodes()
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		enabled, err := repo.TwoFactor.EnableTOTP(ctx, userID, step)
+		if err != nil {
+			return fmt.Errorf("enable totp of user %s: %w", userID, err)
+		}
+		if !enabled {
+			return ErrTwoFactorEnabled
+		}
+
+		if err := repo.TwoFactor.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return fmt.Errorf("delete recovery codes of user %s: %w", userID, err)
+		}
+
+		for _, hash := range hashes {
+			if err := repo.TwoFactor.CreateRecoveryCode(ctx, userID, hash); err != nil {
+				return fmt.Errorf("create recovery code for user %s: %w", userID, err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return codes, nil
+}
+
+// DisableTwoFactor turns 2FA off after verifying a current code or an unused
+// recovery code. A wrong code counts as a failed sign in of the account.
+func (s *twoFactorService) DisableTwoFactor(ctx context.Context, params TwoFactorCodeParams) error {
+	userID := params.User.ID
+	if err := s.throttle.check(ctx, params.User.Email, params.IPAddress, func() error {
+		return s.VerifyTwoFactor(ctx, userID, params.Code)
+	}); err != nil {
+		return err
+	}
+
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.TwoFactor.DeleteRecoveryCodes(ctx, userID); err != nil {
+			return fmt.Errorf("delete recovery codes of user %s: %w", userID, err)
+		}
+		if err := repo.TwoFactor.DeleteTOTP(ctx, userID); err != nil {
+			return fmt.Errorf("delete totp of user %s: %w", userID, err)
+		}
+		return nil
+	})
+}
+
+func (s *twoFactorService) TwoFactorEnabled(ctx context.Context, userID string) (bool, error) {
+	return twoFactorEnabled(ctx, s.repo.TwoFactor, userID)
+}
+
+// VerifyTwoFactor accepts either a TOTP code or a recovery code. Both can
+// only be used once.
+func (s *twoFactorService) VerifyTwoFactor(ctx context.Context, userID, code string) error {
+	totp, err := s.findTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrTwoFactorNotEnabled
+		}
+		return err
+	}
+
+	if totp.EnabledAt == nil {
+		return ErrTwoFactorNotEnabled
+	}
+
+	code = normalizeCode(code)
+	if len(code) == security.TOTPDigits {
+		return s.verifyTOTP(ctx, userID, totp.Secret, code)
+	}
+
+	return s.useRecoveryCode(ctx, userID, code)
+}
+
+func (s *twoFactorService) verifyTOTP(ctx context.Context, userID, secret, code string) error {
+	step, ok, err := security.ValidateTOTP(secret, code, time.Now(), totpSkew)
+	if err != nil {
+		return fmt.Errorf("validate totp: %w", err)
+	}
+	if !ok {
+		return ErrInvalidTwoFactorCode
+	}
+
+	advanced, err := s.repo.TwoFactor.AdvanceTOTPStep(ctx, userID, step)
+	if err != nil {
+		return fmt.Errorf("advance totp step of user %s: %w", userID, err)
+	}
+
+	// The code, or a later one, was already used.
+	if !advanced {
+		return ErrInvalidTwoFactorCode
+	}
+
+	return nil
+}
+
+// Recovery codes are looked up by their hash, so a wrong one costs a single
+// query.
+func (s *twoFactorService) useRecoveryCode(ctx context.Context, userID, code string) error {
+	used, err := s.repo.TwoFactor.UseRecoveryCode(ctx, userID, security.HashToken(code))
+	if err != nil {
+		return fmt.Errorf("use recovery code of user %s: %w", userID, err)
+	}
+	if !used {
+		return ErrInvalidTwoFactorCode
+	}
+	return nil
+}
+
+type CompleteLoginParams struct {
+	ChallengeToken string
+	Code           string
+	UserAgent      string
+	IPAddress      string
+}
+
+// CompleteLogin exchanges the challenge issued by LoginUser and a valid code
+// for a session. The challenge is discarded once it runs out of attempts. A
+// wrong code also counts as a failed sign in of the account, so that asking
+// for new challenges does not give unlimited guesses.
+func (s *twoFactorService) CompleteLogin(ctx context.Context, params CompleteLoginParams) (*LoginUserResult, error) {
+	challenge, err := s.repo.Challenge.FindLoginChallengeByHash(ctx, security.HashToken(params.ChallengeToken))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find login challenge: %w", err)
+	}
+
+	if !challenge.ExpiresAt.After(time.Now()) {
+		return nil, ErrInvalidToken
+	}
+
+	user, err := s.repo.User.FindActiveUserByID(ctx, challenge.UserID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find user %s: %w", challenge.UserID, err)
+	}
+
+	err = s.throttle.check(ctx, user.Email, params.IPAddress, func() error {
+		attempts, err := s.repo.Challenge.IncrementLoginChallengeAttempts(ctx, challenge.ID)
+		if err != nil {
+			return fmt.Errorf("increment attempts of login challenge %s: %w", challenge.ID, err)
+		}
+
+		if attempts > s.cfg.TOTP.MaxAttempts {
+			if _, err := s.repo.Challenge.DeleteLoginChallenge(ctx, challenge.ID); err != nil {
+				return fmt.Errorf("delete login challenge %s: %w", challenge.ID, err)
+			}
+			return ErrInvalidToken
+		}
+
+		if err := s.VerifyTwoFactor(ctx, challenge.UserID, params.Code); err != nil {
+			if errors.Is(err, ErrTwoFactorNotEnabled) {
+				return ErrInvalidToken
+			}
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	// Deleting the challenge consumes it, a concurrent request that also got
+	// this far loses.
+	deleted, err := s.repo.Challenge.DeleteLoginChallenge(ctx, challenge.ID)
+	if err != nil {
+		return nil, fmt.Errorf("delete login challenge %s: %w", challenge.ID, err)
+	}
+	if !deleted {
+		return nil, ErrInvalidToken
+	}
+
+	if err := s.throttle.reset(ctx, user.Email); err != nil {
+		return nil, err
+	}
+
+	return createSession(ctx, s.repo.Session, &s.cfg.Session, user, params.UserAgent, params.IPAddress)
+}
+
+func (s *twoFactorService) findTOTP(ctx context.Context, userID string) (*model.TOTP, error) {
+	totp, err := s.repo.TwoFactor.FindTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		return nil, fmt.Errorf("find totp of user %s: %w", userID, err)
+	}
+	return totp, nil
+}
+
+// Returns the formatted recovery codes along with their hashes.
+func generateRecoveryCodes() (codes, hashes []string, err error) {
+	encoding := base32.StdEncoding.WithPadding(base32.NoPadding)
+	for range RecoveryCodeCount {
+		b, err := security.GenerateRandomBytes(recoveryCodeLength)
+		if err != nil {
+			return nil, nil, fmt.Errorf("generate recovery code: %w", err)
+		}
+
+		code := strings.ToLower(encoding.EncodeToString(b))
+		codes = append(codes, code[:4]+"-"+code[4:8]+"-"+code[8:12]+"-"+code[12:])
+		hashes = append(hashes, security.HashToken(code))
+	}
+	return codes, hashes, nil
+}
+
+// Strips the separators that users may type or copy along with a code.
+func normalizeCode(code string) string {
+	code = strings.ToLower(strings.TrimSpace(code))
+	return strings.NewReplacer("-", "", " ", "").Replace(code)
+}
+
+// Reports whether the user confirmed a TOTP enrollment.
+func twoFactorEnabled(ctx context.Context, repo repository.TwoFactorRepo, userID string) (bool, error) {
+	totp, err := repo.FindTOTP(ctx, userID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return false, nil
+		}
+		return false, fmt.Errorf("find totp of user %s: %w", userID, err)
+	}
+	return totp.EnabledAt != nil, nil
+}
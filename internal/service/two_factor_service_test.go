@@ -0,0 +1,358 @@
+package service_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
+	"github.com/ferdiebergado/goweb/internal/repository"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/service"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+const testTOTPSecret = "JBSWY3DPEHPK3PXP"
+
+type twoFactorMocks struct {
+	user      *mock.MockUserRepo
+	session   *mock.MockSessionRepo
+	twoFactor *mock.MockTwoFactorRepo
+	challenge *mock.MockLoginChallengeRepo
+	attempts  repository.LoginAttemptRepo
+}
+
+func newTwoFactorService(t *testing.T) (service.TwoFactorService, *twoFactorMocks) {
+	t.Helper()
+	ctrl := gomock.NewController(t)
+
+	m := &twoFactorMocks{
+		user:      mock.NewMockUserRepo(ctrl),
+		session:   mock.NewMockSessionRepo(ctrl),
+		twoFactor: mock.NewMockTwoFactorRepo(ctrl),
+		challenge: mock.NewMockLoginChallengeRepo(ctrl),
+		attempts:  repository.NewMemoryLoginAttemptRepository(),
+	}
+
+	cfg := &config.Config{
+		Session: config.SessionConfig{Lifetime: 3600},
+		TOTP:    config.TOTPConfig{Issuer: "GoWeb", ChallengeTTL: 300, MaxAttempts: 3},
+		Lockout: config.LockoutConfig{AccountThreshold: 5, IPThreshold: 100, LockDuration: 900, Window: 900},
+	}
+	repo := &repository.Repository{
+		User:         m.user,
+		Session:      m.session,
+		TwoFactor:    m.twoFactor,
+		Challenge:    m.challenge,
+		LoginAttempt: m.attempts,
+	}
+	return service.NewTwoFactorService(repo, cfg), m
+}
+
+// Returns the stored TOTP of user 1, enabled unless pending is set.
+func (m *twoFactorMocks) totp(t *testing.T, pending bool) *model.TOTP {
+	t.Helper()
+	totp := &model.TOTP{UserID: "1", Secret: testTOTPSecret}
+	if !pending {
+		enabledAt := time.Now()
+		totp.EnabledAt = &enabledAt
+	}
+	return totp
+}
+
+func currentCode(t *testing.T) (string, int64) {
+	t.Helper()
+	step := security.TOTPStep(time.Now())
+	code, err := security.TOTPCode(testTOTPSecret, step)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return code, step
+}
+
+func TestTwoFactorService_BeginEnrollment(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	var stored string
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(nil, sql.ErrNoRows)
+	m.twoFactor.EXPECT().SaveTOTPSecret(gomock.Any(), user.ID, gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, secret string) error {
+			stored = secret
+			return nil
+		})
+
+	enrollment, err := svc.BeginEnrollment(context.Background(), user)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(enrollment.URI, "otpauth://totp/"))
+	assert.Equal(t, enrollment.Secret, stored)
+}
+
+func TestTwoFactorService_BeginEnrollmentAlreadyEnabled(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(m.totp(t, false), nil)
+	m.twoFactor.EXPECT().SaveTOTPSecret(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.BeginEnrollment(context.Background(), user)
+	assert.ErrorIs(t, err, service.ErrTwoFactorEnabled)
+}
+
+func TestTwoFactorService_ConfirmEnrollment(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+	code, step := currentCode(t)
+
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), "1").Return(m.totp(t, true), nil)
+	m.twoFactor.EXPECT().EnableTOTP(gomock.Any(), "1", step).Return(true, nil)
+	m.twoFactor.EXPECT().DeleteRecoveryCodes(gomock.Any(), "1").Return(nil)
+	var hashes []string
+	m.twoFactor.EXPECT().CreateRecoveryCode(gomock.Any(), "1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, codeHash string) error {
+			hashes = append(hashes, codeHash)
+			return nil
+		}).Times(service.RecoveryCodeCount)
+
+	codes, err := svc.ConfirmEnrollment(context.Background(), service.TwoFactorCodeParams{User: testUser(), Code: code})
+	assert.NoError(t, err)
+	assert.Len(t, codes, service.RecoveryCodeCount)
+	assert.Regexp(t, `^[a-z2-7]{4}(-[a-z2-7]{4}){3}$`, codes[0])
+	assert.Contains(t, hashes, security.HashToken(strings.ReplaceAll(codes[0], "-", "")),
+		"the codes are stored as their SHA-256")
+}
+
+func TestTwoFactorService_ConfirmEnrollmentInvalidCode(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), "1").Return(m.totp(t, true), nil)
+	m.twoFactor.EXPECT().EnableTOTP(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.ConfirmEnrollment(context.Background(), service.TwoFactorCodeParams{User: testUser(), Code: "000000x"})
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	if assert.NoError(t, err, "a wrong code counts as a failed sign in") {
+		assert.Equal(t, 1, attempt.Failures)
+	}
+}
+
+func TestTwoFactorService_DisableTwoFactorWrongCode(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), "1").Return(m.totp(t, false), nil)
+	m.twoFactor.EXPECT().DeleteTOTP(gomock.Any(), gomock.Any()).Times(0)
+
+	err := svc.DisableTwoFactor(context.Background(), service.TwoFactorCodeParams{
+		User:      testUser(),
+		Code:      "000000",
+		IPAddress: testIP,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	if assert.NoError(t, err, "a wrong code counts as a failed sign in") {
+		assert.Equal(t, 1, attempt.Failures)
+	}
+}
+
+func TestTwoFactorService_DisableTwoFactorLocked(t *testing.T) {
+	svc, m := newTwoFactorService(t)
+
+	recordFailure(t, m.attempts, testEmail)
+	if err := m.attempts.LockLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail,
+		time.Now().Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), gomock.Any()).Times(0)
+
+	err := svc.DisableTwoFactor(context.Background(), service.TwoFactorCodeParams{User: testUser(), Code: "000000"})
+	var throttled *service.ThrottledError
+	assert.ErrorAs(t, err, &throttled)
+}
+
+// Returns user 1, the owner of the stored TOTP.
+func testUser() *model.User {
+	return &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+}
+
+func TestTwoFactorService_VerifyTwoFactor(t *testing.T) {
+	code, step := currentCode(t)
+
+	tests := []struct {
+		name    string
+		code    string
+		setup   func(m *twoFactorMocks)
+		wantErr error
+	}{
+		{
+			name: "totp code",
+			code: code,
+			setup: func(m *twoFactorMocks) {
+				m.twoFactor.EXPECT().AdvanceTOTPStep(gomock.Any(), "1", step).Return(true, nil)
+			},
+		},
+		{
+			name: "replayed totp code",
+			code: code,
+			setup: func(m *twoFactorMocks) {
+				m.twoFactor.EXPECT().AdvanceTOTPStep(gomock.Any(), "1", step).Return(false, nil)
+			},
+			wantErr: service.ErrInvalidTwoFactorCode,
+		},
+		{
+			name: "recovery code",
+			code: "ABCD-EFGH-IJKL-MNOP",
+			setup: func(m *twoFactorMocks) {
+				m.twoFactor.EXPECT().UseRecoveryCode(gomock.Any(), "1", security.HashToken("abcdefghijklmnop")).
+					Return(true, nil)
+			},
+		},
+		{
+			name: "used or unknown recovery code",
+			code: "abcd-efgh-ijkl-mnop",
+			setup: func(m *twoFactorMocks) {
+				m.twoFactor.EXPECT().UseRecoveryCode(gomock.Any(), "1", security.HashToken("abcdefghijklmnop")).
+					Return(false, nil)
+			},
+			wantErr: service.ErrInvalidTwoFactorCode,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			svc, m := newTwoFactorService(t)
+			m.twoFactor.EXPECT().FindTOTP(gomock.Any(), "1").Return(m.totp(t, false), nil)
+			tt.setup(m)
+
+			err := svc.VerifyTwoFactor(context.Background(), "1", tt.code)
+			if tt.wantErr != nil {
+				assert.ErrorIs(t, err, tt.wantErr)
+				return
+			}
+			assert.NoError(t, err)
+		})
+	}
+}
+
+func TestTwoFactorService_CompleteLogin(t *testing.T) {
+	const challengeToken = "challenge"
+	svc, m := newTwoFactorService(t)
+	code, step := currentCode(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	challenge := &model.LoginChallenge{ID: "2", UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}
+
+	// A wrong code of an earlier challenge
+	recordFailure(t, m.attempts, testEmail)
+
+	m.challenge.EXPECT().FindLoginChallengeByHash(gomock.Any(), security.HashToken(challengeToken)).Return(challenge, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+	m.challenge.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Return(1, nil)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(m.totp(t, false), nil)
+	m.twoFactor.EXPECT().AdvanceTOTPStep(gomock.Any(), user.ID, step).Return(true, nil)
+	m.challenge.EXPECT().DeleteLoginChallenge(gomock.Any(), challenge.ID).Return(true, nil)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateSessionParams) (*model.Session, error) {
+			assert.Equal(t, user.ID, params.UserID)
+			return &model.Session{UserID: params.UserID, ExpiresAt: params.ExpiresAt}, nil
+		})
+
+	result, err := svc.CompleteLogin(context.Background(), service.CompleteLoginParams{
+		ChallengeToken: challengeToken,
+		Code:           code,
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, user, result.User)
+	assert.NotEmpty(t, result.Token)
+
+	_, err = m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	assert.ErrorIs(t, err, sql.ErrNoRows, "the failures are reset once the second factor passed")
+}
+
+func TestTwoFactorService_CompleteLoginWrongCode(t *testing.T) {
+	const challengeToken = "challenge"
+	svc, m := newTwoFactorService(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	challenge := &model.LoginChallenge{ID: "2", UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}
+
+	m.challenge.EXPECT().FindLoginChallengeByHash(gomock.Any(), security.HashToken(challengeToken)).Return(challenge, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+	m.challenge.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Return(1, nil)
+	m.twoFactor.EXPECT().FindTOTP(gomock.Any(), user.ID).Return(m.totp(t, false), nil)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.CompleteLogin(context.Background(), service.CompleteLoginParams{
+		ChallengeToken: challengeToken,
+		Code:           "000000",
+		IPAddress:      testIP,
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidTwoFactorCode)
+
+	attempt, err := m.attempts.FindLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail)
+	if assert.NoError(t, err, "a wrong code counts as a failed sign in") {
+		assert.Equal(t, 1, attempt.Failures)
+	}
+}
+
+func TestTwoFactorService_CompleteLoginLocked(t *testing.T) {
+	const challengeToken = "challenge"
+	svc, m := newTwoFactorService(t)
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	challenge := &model.LoginChallenge{ID: "2", UserID: user.ID, ExpiresAt: time.Now().Add(time.Minute)}
+
+	lockedUntil := time.Now().Add(time.Minute)
+	recordFailure(t, m.attempts, testEmail)
+	if err := m.attempts.LockLoginAttempt(context.Background(), model.LoginScopeAccount, testEmail, lockedUntil); err != nil {
+		t.Fatal(err)
+	}
+
+	m.challenge.EXPECT().FindLoginChallengeByHash(gomock.Any(), security.HashToken(challengeToken)).Return(challenge, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), user.ID).Return(user, nil)
+	m.challenge.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), gomock.Any()).Times(0)
+
+	_, err := svc.CompleteLogin(context.Background(), service.CompleteLoginParams{
+		ChallengeToken: challengeToken,
+		Code:           "000000",
+	})
+	var throttled *service.ThrottledError
+	assert.ErrorAs(t, err, &throttled)
+}
+
+// Records a failed sign in of email.
+func recordFailure(t *testing.T, attempts repository.LoginAttemptRepo, email string) {
+	t.Helper()
+	now := time.Now()
+	if _, err := attempts.RecordLoginFailure(context.Background(), repository.RecordLoginFailureParams{
+		Scope:       model.LoginScopeAccount,
+		Key:         email,
+		FailedAt:    now.Add(-time.Hour),
+		WindowStart: now.Add(-2 * time.Hour),
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestTwoFactorService_CompleteLoginTooManyAttempts(t *testing.T) {
+	const challengeToken = "challenge"
+	svc, m := newTwoFactorService(t)
+	challenge := &model.LoginChallenge{ID: "2", UserID: "1", ExpiresAt: time.Now().Add(time.Minute)}
+
+	m.challenge.EXPECT().FindLoginChallengeByHash(gomock.Any(), security.HashToken(challengeToken)).Return(challenge, nil)
+	m.user.EXPECT().FindActiveUserByID(gomock.Any(), challenge.UserID).
+		Return(&model.User{Model: model.Model{ID: "1"}, Email: testEmail}, nil)
+	m.challenge.EXPECT().IncrementLoginChallengeAttempts(gomock.Any(), challenge.ID).Return(4, nil)
+	m.challenge.EXPECT().DeleteLoginChallenge(gomock.Any(), challenge.ID).Return(true, nil)
+	m.session.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	result, err := svc.CompleteLogin(context.Background(), service.CompleteLoginParams{
+		ChallengeToken: challengeToken,
+		Code:           "123456",
+	})
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+	assert.Nil(t, result)
+}
@@ -6,7 +6,11 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"log/slog"
+	"slices"
+	"time"
 
+	"github.com/ferdiebergado/goweb/internal/config"
 	"github.com/ferdiebergado/goweb/internal/model"
 	"github.com/ferdiebergado/goweb/internal/pkg/security"
 	"github.com/ferdiebergado/goweb/internal/repository"
@@ -14,20 +18,40 @@ import (
 
 type UserService interface {
 	RegisterUser(ctx context.Context, params RegisterUserParams) (*model.User, error)
+	LoginUser(ctx context.Context, params LoginUserParams) (*LoginUserResult, error)
+	LogoutUser(ctx context.Context, token string) error
+	AuthenticateSession(ctx context.Context, token string) (*model.User, error)
+	VerifyEmail(ctx context.Context, token string) error
+	RequestPasswordReset(ctx context.Context, email string) error
+	ResetPassword(ctx context.Context, params ResetPasswordParams) error
+	CreateUser(ctx context.Context, params CreateUserParams) (*model.User, error)
+	ListUsers(ctx context.Context) ([]model.User, error)
+	DisableUser(ctx context.Context, email string) error
+	SetUserPassword(ctx context.Context, email, password string) error
+	ImportUsers(ctx context.Context, users []ImportUserParams) (*ImportUsersResult, error)
 }
 
 type userService struct {
-	repo   repository.UserRepo
-	hasher security.Hasher
+	repo     *repository.Repository
+	hasher   security.Hasher
+	throttle *loginThrottle
+	cfg      *config.Config
 }
 
 var _ UserService = (*userService)(nil)
 var ErrDuplicateUser = errors.New("duplicate user")
+var ErrInvalidCredentials = errors.New("invalid email or password")
+var ErrUserNotVerified = errors.New("email address has not been verified")
+var ErrInvalidToken = errors.New("invalid or expired token")
+var ErrUserDisabled = errors.New("user account has been disabled")
+var ErrUserNotFound = errors.New("user not found")
 
-func NewUserService(repo repository.UserRepo, hasher security.Hasher) UserService {
+func NewUserService(repo *repository.Repository, hasher security.Hasher, cfg *config.Config) UserService {
 	return &userService{
-		repo:   repo,
-		hasher: hasher,
+		repo:     repo,
+		hasher:   hasher,
+		throttle: newLoginThrottle(repo, &cfg.Lockout),
+		cfg:      cfg,
 	}
 }
 
@@ -37,26 +61,447 @@ type RegisterUserParams struct {
 }
 
 func (s *userService) RegisterUser(ctx context.Context, params RegisterUserParams) (*model.User, error) {
-	existing, err := s.repo.FindUserByEmail(ctx, params.Email)
-	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+	return s.createUser(ctx, CreateUserParams{Email: params.Email, Password: params.Password})
+}
+
+// CreateUserParams are used by administrators to create a user directly.
+type CreateUserParams struct {
+	Email    string
+	Password string
+	// Marks the email as verified instead of sending a verification link.
+	Verified bool
+}
+
+func (s *userService) CreateUser(ctx context.Context, params CreateUserParams) (*model.User, error) {
+	return s.createUser(ctx, params)
+}
+
+func (s *userService) createUser(ctx context.Context, params CreateUserParams) (*model.User, error) {
+	// Hashed up front so that the transaction is not held open while it runs.
+	hash, err := s.hasher.Hash(params.Password)
+
+	if err != nil {
+		return nil, fmt.Errorf("hasher hash: %w", err)
+	}
+
+	var user *model.User
+
+	// Serializable so that a concurrent registration of the same email cannot
+	// slip in between the existence check and the insert.
+	err = s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		existing, err := repo.User.FindUserByEmail(ctx, params.Email)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		if existing != nil {
+			return fmt.Errorf("user with email %s already exists: %w", params.Email, ErrDuplicateUser)
+		}
+
+		user, err = repo.User.CreateUser(ctx, repository.CreateUserParams{Email: params.Email, PasswordHash: hash})
+
+		if err != nil {
+			return fmt.Errorf("create user %s: %w", params.Email, err)
+		}
+
+		if params.Verified {
+			if err := repo.User.MarkUserVerified(ctx, user.ID); err != nil {
+				return fmt.Errorf("mark user %s verified: %w", user.ID, err)
+			}
+			now := time.Now()
+			user.VerifiedAt = &now
+			return nil
+		}
+
+		if err := enqueueMail(ctx, repo.Job, MailVerifyEmail, user.ID); err != nil {
+			return fmt.Errorf("queue verification email: %w", err)
+		}
+		return nil
+	}, repository.WithIsolation(sql.LevelSerializable))
+
+	if err != nil {
+		// Both mean that another registration for the same email won the race.
+		if errors.Is(err, repository.ErrUniqueViolation) || errors.Is(err, repository.ErrSerializationFailure) {
+			return nil, fmt.Errorf("user with email %s already exists: %w: %w", params.Email, ErrDuplicateUser, err)
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+func (s *userService) VerifyEmail(ctx context.Context, token string) error {
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		userID, err := repo.Verification.ConsumeVerification(ctx, security.HashToken(token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("consume verification: %w", err)
+		}
+
+		if err := repo.User.MarkUserVerified(ctx, userID); err != nil {
+			return fmt.Errorf("mark user %s verified: %w", userID, err)
+		}
+
+		if err := repo.Verification.DeleteUserVerifications(ctx, userID); err != nil {
+			return fmt.Errorf("delete verifications of user %s: %w", userID, err)
+		}
+
+		return nil
+	})
+}
+
+type LoginUserParams struct {
+	Email     string
+	Password  string
+	UserAgent string
+	IPAddress string
+}
+
+// LoginUserResult carries the new session. When the user has two-factor
+// authentication enabled no session is created yet; ChallengeToken has to be
+// exchanged for one with CompleteLogin instead.
+type LoginUserResult struct {
+	User           *model.User
+	Token          string
+	ChallengeToken string
+	ExpiresAt      time.Time
+}
+
+func (s *userService) LoginUser(ctx context.Context, params LoginUserParams) (*LoginUserResult, error) {
+	user, err := s.throttle.checkCredentials(ctx, s.hasher, params.Email, params.Password, params.IPAddress)
+	if err != nil {
 		return nil, err
 	}
 
-	if existing != nil {
-		return nil, fmt.Errorf("user with email %s already exists: %w", params.Email, ErrDuplicateUser)
+	enabled, err := twoFactorEnabled(ctx, s.repo.TwoFactor, user.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	// The failures are kept until CompleteLogin so that wrong codes keep
+	// adding to them.
+	if enabled {
+		return createLoginChallenge(ctx, s.repo.Challenge, &s.cfg.TOTP, user)
+	}
+
+	if err := s.throttle.reset(ctx, params.Email); err != nil {
+		return nil, err
+	}
+
+	return createSession(ctx, s.repo.Session, &s.cfg.Session, user, params.UserAgent, params.IPAddress)
+}
+
+func createLoginChallenge(ctx context.Context, challenges repository.LoginChallengeRepo, cfg *config.TOTPConfig,
+	user *model.User) (*LoginUserResult, error) {
+	token, tokenHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate challenge token: %w", err)
+	}
+
+	challenge, err := challenges.CreateLoginChallenge(ctx, repository.CreateLoginChallengeParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		ExpiresAt: time.Now().Add(time.Duration(cfg.ChallengeTTL) * time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create login challenge for user %s: %w", user.ID, err)
+	}
+
+	return &LoginUserResult{
+		User:           user,
+		ChallengeToken: token,
+		ExpiresAt:      challenge.ExpiresAt,
+	}, nil
+}
+
+func createSession(ctx context.Context, sessions repository.SessionRepo, cfg *config.SessionConfig,
+	user *model.User, userAgent, ipAddress string) (*LoginUserResult, error) {
+	token, tokenHash, err := security.GenerateToken()
+	if err != nil {
+		return nil, fmt.Errorf("generate session token: %w", err)
+	}
+
+	session, err := sessions.CreateSession(ctx, repository.CreateSessionParams{
+		UserID:    user.ID,
+		TokenHash: tokenHash,
+		UserAgent: userAgent,
+		IPAddress: ipAddress,
+		ExpiresAt: time.Now().Add(time.Duration(cfg.Lifetime) * time.Second),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create session for user %s: %w", user.ID, err)
+	}
+
+	return &LoginUserResult{
+		User:      user,
+		Token:     token,
+		ExpiresAt: session.ExpiresAt,
+	}, nil
+}
+
+// Returns the user with email if password matches and the user may sign in.
+func checkCredentials(ctx context.Context, users repository.UserRepo, hasher security.Hasher,
+	email, password string) (*model.User, error) {
+	user, err := users.FindUserByEmail(ctx, email)
+	if err != nil {
+		if !errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("find user %s: %w", email, err)
+		}
+		return nil, invalidCredentials(hasher, password)
+	}
+
+	// Users that signed up through an identity provider have no password.
+	if user.PasswordHash == "" {
+		return nil, invalidCredentials(hasher, password)
+	}
+
+	ok, err := hasher.Verify(password, user.PasswordHash)
+	if err != nil {
+		return nil, fmt.Errorf("hasher verify: %w", err)
+	}
+
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+
+	if user.VerifiedAt == nil {
+		return nil, ErrUserNotVerified
 	}
 
+	if user.DisabledAt != nil {
+		return nil, ErrUserDisabled
+	}
+
+	if hasher.NeedsRehash(user.PasswordHash) {
+		rehashPassword(ctx, users, hasher, user, password)
+	}
+
+	return user, nil
+}
+
+// Hashes password before returning ErrInvalidCredentials so that a login
+// attempt without a hash to verify takes as long as one with. Hashing costs
+// the same as verifying a hash of the current parameters.
+func invalidCredentials(hasher security.Hasher, password string) error {
+	if _, err := hasher.Hash(password); err != nil {
+		return fmt.Errorf("hasher hash: %w", err)
+	}
+	return ErrInvalidCredentials
+}
+
+// Replaces the hash of user with one of the current parameters now that the
+// password is known. The sign in goes ahead if that fails, the hash is tried
+// again on the next one.
+func rehashPassword(ctx context.Context, users repository.UserRepo, hasher security.Hasher, user *model.User,
+	password string) {
+	hash, err := hasher.Hash(password)
+	if err != nil {
+		slog.Error("rehash password", "user", user.ID, "reason", err)
+		return
+	}
+
+	ok, err := users.RehashUserPassword(ctx, user.ID, user.PasswordHash, hash)
+	if err != nil {
+		slog.Error("rehash password", "user", user.ID, "reason", err)
+		return
+	}
+	if ok {
+		user.PasswordHash = hash
+	}
+}
+
+func (s *userService) LogoutUser(ctx context.Context, token string) error {
+	if err := s.repo.Session.DeleteSessionByTokenHash(ctx, security.HashToken(token)); err != nil {
+		return fmt.Errorf("delete session: %w", err)
+	}
+	return nil
+}
+
+// AuthenticateSession returns the user owning the session identified by token.
+func (s *userService) AuthenticateSession(ctx context.Context, token string) (*model.User, error) {
+	user, err := s.repo.User.FindUserBySessionTokenHash(ctx, security.HashToken(token))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, ErrInvalidToken
+		}
+		return nil, fmt.Errorf("find user by session: %w", err)
+	}
+	return user, nil
+}
+
+func (s *userService) RequestPasswordReset(ctx context.Context, email string) error {
+	user, err := s.repo.User.FindUserByEmail(ctx, email)
+	if err != nil {
+		// Do not reveal whether an account exists for the email.
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("find user %s: %w", email, err)
+	}
+
+	// The token is issued when the mail is sent.
+	if err := enqueueMail(ctx, s.repo.Job, MailResetPassword, user.ID); err != nil {
+		return fmt.Errorf("queue password reset email: %w", err)
+	}
+
+	return nil
+}
+
+type ResetPasswordParams struct {
+	Token    string
+	Password string
+}
+
+func (s *userService) ResetPassword(ctx context.Context, params ResetPasswordParams) error {
+	// Hashed up front so that the transaction is not held open while it runs.
 	hash, err := s.hasher.Hash(params.Password)
+	if err != nil {
+		return fmt.Errorf("hasher hash: %w", err)
+	}
 
+	// The token is consumed first so that concurrent requests with it cannot
+	// both change the password. The old sessions and refresh tokens must not
+	// outlive the old password.
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		userID, err := repo.PasswordReset.ConsumePasswordReset(ctx, security.HashToken(params.Token))
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return ErrInvalidToken
+			}
+			return fmt.Errorf("consume password reset: %w", err)
+		}
+
+		if err := repo.User.UpdateUserPassword(ctx, userID, hash); err != nil {
+			return fmt.Errorf("update password of user %s: %w", userID, err)
+		}
+
+		if err := repo.Session.DeleteUserSessions(ctx, userID); err != nil {
+			return fmt.Errorf("delete sessions of user %s: %w", userID, err)
+		}
+
+		if err := repo.RefreshToken.RevokeUserRefreshTokens(ctx, userID); err != nil {
+			return fmt.Errorf("revoke refresh tokens of user %s: %w", userID, err)
+		}
+
+		return nil
+	})
+}
+
+func (s *userService) ListUsers(ctx context.Context) ([]model.User, error) {
+	users, err := s.repo.User.ListUsers(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("hasher hash: %w", err)
+		return nil, fmt.Errorf("list users: %w", err)
 	}
+	return users, nil
+}
 
-	user, err := s.repo.CreateUser(ctx, repository.CreateUserParams{Email: params.Email, PasswordHash: hash})
+func (s *userService) DisableUser(ctx context.Context, email string) error {
+	user, err := s.findUser(ctx, email)
+	if err != nil {
+		return err
+	}
 
+	if err := s.repo.User.DisableUser(ctx, user.ID); err != nil {
+		return fmt.Errorf("disable user %s: %w", user.ID, err)
+	}
+
+	return nil
+}
+
+func (s *userService) SetUserPassword(ctx context.Context, email, password string) error {
+	user, err := s.findUser(ctx, email)
 	if err != nil {
-		return nil, fmt.Errorf("create user %s: %w", params.Email, err)
+		return err
 	}
 
+	hash, err := s.hasher.Hash(password)
+	if err != nil {
+		return fmt.Errorf("hasher hash: %w", err)
+	}
+
+	return s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+		if err := repo.User.UpdateUserPassword(ctx, user.ID, hash); err != nil {
+			return fmt.Errorf("update password of user %s: %w", user.ID, err)
+		}
+
+		if err := repo.Session.DeleteUserSessions(ctx, user.ID); err != nil {
+			return fmt.Errorf("delete sessions of user %s: %w", user.ID, err)
+		}
+
+		if err := repo.RefreshToken.RevokeUserRefreshTokens(ctx, user.ID); err != nil {
+			return fmt.Errorf("revoke refresh tokens of user %s: %w", user.ID, err)
+		}
+
+		return nil
+	})
+}
+
+// ImportUserParams is a user of another system. PasswordHash is kept as it
+// is so it has to be one that the hasher verifies.
+type ImportUserParams struct {
+	Email        string
+	PasswordHash string
+	VerifiedAt   *time.Time
+}
+
+type ImportUsersResult struct {
+	Imported int
+	// Emails that already had a user
+	Skipped []string
+}
+
+// Users inserted per transaction by ImportUsers
+const importBatchSize = 500
+
+// ImportUsers creates users with their existing password hashes, which are
+// replaced with the current algorithm as they sign in. Users whose email is
+// taken are skipped so that an import can be run again after a failure.
+func (s *userService) ImportUsers(ctx context.Context, users []ImportUserParams) (*ImportUsersResult, error) {
+	result := &ImportUsersResult{}
+
+	for batch := range slices.Chunk(users, importBatchSize) {
+		var imported int
+		var skipped []string
+
+		err := s.repo.WithTx(ctx, func(repo *repository.Repository) error {
+			for _, u := range batch {
+				ok, err := repo.User.ImportUser(ctx, repository.ImportUserParams{
+					Email:        u.Email,
+					PasswordHash: u.PasswordHash,
+					VerifiedAt:   u.VerifiedAt,
+				})
+				if err != nil {
+					return fmt.Errorf("import user %s: %w", u.Email, err)
+				}
+
+				if ok {
+					imported++
+				} else {
+					skipped = append(skipped, u.Email)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+
+		result.Imported += imported
+		result.Skipped = append(result.Skipped, skipped...)
+	}
+
+	return result, nil
+}
+
+func (s *userService) findUser(ctx context.Context, email string) (*model.User, error) {
+	user, err := s.repo.User.FindUserByEmail(ctx, email)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("%w: %s", ErrUserNotFound, email)
+		}
+		return nil, fmt.Errorf("find user %s: %w", email, err)
+	}
 	return user, nil
 }
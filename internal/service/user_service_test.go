@@ -3,10 +3,16 @@ package service_test
 import (
 	"context"
 	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
 	"testing"
 	"time"
 
+	"github.com/ferdiebergado/goweb/internal/config"
 	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/pkg/security"
 	"github.com/ferdiebergado/goweb/internal/repository"
 	"github.com/ferdiebergado/goweb/internal/repository/mock"
 	"github.com/ferdiebergado/goweb/internal/service"
@@ -16,12 +22,29 @@ import (
 	secMock "github.com/ferdiebergado/goweb/internal/pkg/security/mock"
 )
 
+const (
+	testEmail      = "abc@example.com"
+	testPass       = "test"
+	testPassHashed = "hashed"
+)
+
+// Expects a single job that sends the mail to the user.
+func expectMailJob(t *testing.T, jobRepo *mock.MockJobRepo, mail, userID string) {
+	t.Helper()
+	jobRepo.EXPECT().EnqueueJob(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.EnqueueJobParams) (string, error) {
+			assert.Equal(t, service.JobSendEmail, params.Kind)
+
+			var job service.MailJob
+			if err := json.Unmarshal(params.Payload, &job); err != nil {
+				t.Fatal(err)
+			}
+			assert.Equal(t, service.MailJob{Mail: mail, UserID: userID}, job)
+			return "1", nil
+		})
+}
+
 func TestUserService_RegisterUser(t *testing.T) {
-	const (
-		testEmail      = "abc@example.com"
-		testPass       = "test"
-		testPassHashed = "hashed"
-	)
 	ctrl := gomock.NewController(t)
 	mockRepo := mock.NewMockUserRepo(ctrl)
 	mockHasher := secMock.NewMockHasher(ctrl)
@@ -48,7 +71,11 @@ func TestUserService_RegisterUser(t *testing.T) {
 	ctx := context.Background()
 	mockRepo.EXPECT().CreateUser(ctx, params).Return(user, nil)
 
-	userService := service.NewUserService(mockRepo, mockHasher)
+	mockJobRepo := mock.NewMockJobRepo(ctrl)
+	expectMailJob(t, mockJobRepo, service.MailVerifyEmail, user.ID)
+
+	repo := &repository.Repository{User: mockRepo, Job: mockJobRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
 
 	newUser, err := userService.RegisterUser(ctx, regParams)
 	assert.NoError(t, err)
@@ -58,3 +85,550 @@ func TestUserService_RegisterUser(t *testing.T) {
 	assert.NotZero(t, newUser.CreatedAt)
 	assert.NotZero(t, newUser.UpdatedAt)
 }
+
+func TestUserService_RegisterUserConcurrent(t *testing.T) {
+	const n = 10
+
+	ctrl := gomock.NewController(t)
+	mockRepo := mock.NewMockUserRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	mockJobRepo := mock.NewMockJobRepo(ctrl)
+	ctx := context.Background()
+
+	// Every registration passes the existence check before any of them inserts.
+	var checked sync.WaitGroup
+	checked.Add(n)
+	mockRepo.EXPECT().FindUserByEmail(ctx, testEmail).Times(n).
+		DoAndReturn(func(context.Context, string) (*model.User, error) {
+			checked.Done()
+			checked.Wait()
+			return nil, sql.ErrNoRows
+		})
+
+	// Only the first insert gets past the unique constraint.
+	var mu sync.Mutex
+	var inserted bool
+	mockRepo.EXPECT().CreateUser(ctx, gomock.Any()).Times(n).
+		DoAndReturn(func(_ context.Context, params repository.CreateUserParams) (*model.User, error) {
+			mu.Lock()
+			defer mu.Unlock()
+			if inserted {
+				return nil, fmt.Errorf("%w on users_email_key", repository.ErrUniqueViolation)
+			}
+			inserted = true
+			return &model.User{Model: model.Model{ID: "1"}, Email: params.Email}, nil
+		})
+
+	mockHasher.EXPECT().Hash(testPass).Times(n).Return(testPassHashed, nil)
+	expectMailJob(t, mockJobRepo, service.MailVerifyEmail, "1")
+
+	repo := &repository.Repository{User: mockRepo, Job: mockJobRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
+
+	errs := make(chan error, n)
+	var wg sync.WaitGroup
+	for range n {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := userService.RegisterUser(ctx, service.RegisterUserParams{Email: testEmail, Password: testPass})
+			errs <- err
+		}()
+	}
+	wg.Wait()
+	close(errs)
+
+	var succeeded, duplicates int
+	for err := range errs {
+		switch {
+		case err == nil:
+			succeeded++
+		case errors.Is(err, service.ErrDuplicateUser):
+			duplicates++
+		default:
+			t.Errorf("unexpected error: %v", err)
+		}
+	}
+
+	assert.Equal(t, 1, succeeded)
+	assert.Equal(t, n-1, duplicates)
+}
+
+func TestUserService_LoginUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockTwoFactorRepo := mock.NewMockTwoFactorRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	cfg := &config.Config{Session: config.SessionConfig{Lifetime: 3600}, Lockout: *lockoutCfg}
+
+	verifiedAt := time.Now()
+	user := &model.User{
+		Model:        model.Model{ID: "1", CreatedAt: time.Now(), UpdatedAt: time.Now()},
+		Email:        testEmail,
+		PasswordHash: testPassHashed,
+		VerifiedAt:   &verifiedAt,
+	}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockHasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	mockTwoFactorRepo.EXPECT().FindTOTP(ctx, user.ID).Return(nil, sql.ErrNoRows)
+	mockSessionRepo.EXPECT().CreateSession(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateSessionParams) (*model.Session, error) {
+			assert.Equal(t, user.ID, params.UserID)
+			assert.NotEmpty(t, params.TokenHash)
+			assert.WithinDuration(t, time.Now().Add(time.Hour), params.ExpiresAt, time.Minute)
+			return &model.Session{UserID: params.UserID, TokenHash: params.TokenHash, ExpiresAt: params.ExpiresAt}, nil
+		})
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, TwoFactor: mockTwoFactorRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, cfg)
+
+	result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.NoError(t, err)
+	assert.Equal(t, user, result.User)
+	assert.NotEmpty(t, result.Token)
+	assert.NotZero(t, result.ExpiresAt)
+}
+
+func TestUserService_LoginUserRehashesPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockTwoFactorRepo := mock.NewMockTwoFactorRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	cfg := &config.Config{Session: config.SessionConfig{Lifetime: 3600}, Lockout: *lockoutCfg}
+
+	const rehashed = "rehashed"
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockHasher.EXPECT().NeedsRehash(testPassHashed).Return(true)
+	mockHasher.EXPECT().Hash(testPass).Return(rehashed, nil)
+	mockUserRepo.EXPECT().RehashUserPassword(ctx, user.ID, testPassHashed, rehashed).Return(true, nil)
+	mockTwoFactorRepo.EXPECT().FindTOTP(ctx, user.ID).Return(nil, sql.ErrNoRows)
+	mockSessionRepo.EXPECT().CreateSession(ctx, gomock.Any()).
+		Return(&model.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, TwoFactor: mockTwoFactorRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, cfg)
+
+	result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.NoError(t, err)
+	assert.Equal(t, rehashed, result.User.PasswordHash)
+}
+
+func TestUserService_LoginUserRehashFailureDoesNotFailLogin(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockTwoFactorRepo := mock.NewMockTwoFactorRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	cfg := &config.Config{Session: config.SessionConfig{Lifetime: 3600}, Lockout: *lockoutCfg}
+
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, PasswordHash: testPassHashed, VerifiedAt: &verifiedAt}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockHasher.EXPECT().NeedsRehash(testPassHashed).Return(true)
+	mockHasher.EXPECT().Hash(testPass).Return("rehashed", nil)
+	mockUserRepo.EXPECT().RehashUserPassword(ctx, user.ID, testPassHashed, "rehashed").Return(false, errors.New("db down"))
+	mockTwoFactorRepo.EXPECT().FindTOTP(ctx, user.ID).Return(nil, sql.ErrNoRows)
+	mockSessionRepo.EXPECT().CreateSession(ctx, gomock.Any()).
+		Return(&model.Session{UserID: user.ID, ExpiresAt: time.Now().Add(time.Hour)}, nil)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, TwoFactor: mockTwoFactorRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, cfg)
+
+	result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.NoError(t, err)
+	assert.Equal(t, testPassHashed, result.User.PasswordHash)
+}
+
+func TestUserService_LoginUserWithoutPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+
+	verifiedAt := time.Now()
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail, VerifiedAt: &verifiedAt}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Hash("").Return(testPassHashed, nil)
+
+	repo := &repository.Repository{User: mockUserRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{Lockout: *lockoutCfg})
+
+	_, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: ""})
+	assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+}
+
+func TestUserService_LoginUserTwoFactor(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockTwoFactorRepo := mock.NewMockTwoFactorRepo(ctrl)
+	mockChallengeRepo := mock.NewMockLoginChallengeRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	cfg := &config.Config{TOTP: config.TOTPConfig{ChallengeTTL: 300}, Lockout: *lockoutCfg}
+
+	verifiedAt := time.Now()
+	user := &model.User{
+		Model:        model.Model{ID: "1"},
+		Email:        testEmail,
+		PasswordHash: testPassHashed,
+		VerifiedAt:   &verifiedAt,
+	}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockHasher.EXPECT().NeedsRehash(testPassHashed).Return(false)
+	mockTwoFactorRepo.EXPECT().FindTOTP(ctx, user.ID).Return(&model.TOTP{UserID: user.ID, EnabledAt: &verifiedAt}, nil)
+	mockChallengeRepo.EXPECT().CreateLoginChallenge(ctx, gomock.Any()).
+		DoAndReturn(func(_ context.Context, params repository.CreateLoginChallengeParams) (*model.LoginChallenge, error) {
+			assert.Equal(t, user.ID, params.UserID)
+			assert.NotEmpty(t, params.TokenHash)
+			assert.WithinDuration(t, time.Now().Add(5*time.Minute), params.ExpiresAt, time.Minute)
+			return &model.LoginChallenge{ID: "2", UserID: params.UserID, ExpiresAt: params.ExpiresAt}, nil
+		})
+	mockSessionRepo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{
+		User:         mockUserRepo,
+		Session:      mockSessionRepo,
+		TwoFactor:    mockTwoFactorRepo,
+		Challenge:    mockChallengeRepo,
+		LoginAttempt: repository.NewMemoryLoginAttemptRepository(),
+	}
+	userService := service.NewUserService(repo, mockHasher, cfg)
+
+	result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Token)
+	assert.NotEmpty(t, result.ChallengeToken)
+}
+
+func TestUserService_LoginUserInvalidCredentials(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+
+	user := &model.User{
+		Model:        model.Model{ID: "1"},
+		Email:        testEmail,
+		PasswordHash: testPassHashed,
+	}
+
+	var tests = []struct {
+		name  string
+		setup func(ctx context.Context)
+	}{
+		{"Wrong password", func(ctx context.Context) {
+			mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+			mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(false, nil)
+		}},
+		{"Unknown email", func(ctx context.Context) {
+			mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(nil, sql.ErrNoRows)
+			mockHasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			tt.setup(ctx)
+			mockSessionRepo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+			repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+			userService := service.NewUserService(repo, mockHasher, &config.Config{Lockout: *lockoutCfg})
+
+			result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+			assert.ErrorIs(t, err, service.ErrInvalidCredentials)
+			assert.Nil(t, result)
+		})
+	}
+}
+
+func TestUserService_LoginUserNotVerified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+
+	user := &model.User{
+		Model:        model.Model{ID: "1"},
+		Email:        testEmail,
+		PasswordHash: testPassHashed,
+	}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockSessionRepo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{Lockout: *lockoutCfg})
+
+	result, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.ErrorIs(t, err, service.ErrUserNotVerified)
+	assert.Nil(t, result)
+}
+
+func TestUserService_VerifyEmail(t *testing.T) {
+	const token = "token"
+
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockVerificationRepo := mock.NewMockVerificationRepo(ctrl)
+	ctx := context.Background()
+
+	gomock.InOrder(
+		mockVerificationRepo.EXPECT().ConsumeVerification(ctx, security.HashToken(token)).Return("2", nil),
+		mockUserRepo.EXPECT().MarkUserVerified(ctx, "2").Return(nil),
+		mockVerificationRepo.EXPECT().DeleteUserVerifications(ctx, "2").Return(nil),
+	)
+
+	repo := &repository.Repository{User: mockUserRepo, Verification: mockVerificationRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	err := userService.VerifyEmail(ctx, token)
+	assert.NoError(t, err)
+}
+
+func TestUserService_VerifyEmailInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockVerificationRepo := mock.NewMockVerificationRepo(ctrl)
+	ctx := context.Background()
+
+	// Unknown, expired or already used
+	mockVerificationRepo.EXPECT().ConsumeVerification(ctx, gomock.Any()).Return("", sql.ErrNoRows)
+	mockUserRepo.EXPECT().MarkUserVerified(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, Verification: mockVerificationRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	err := userService.VerifyEmail(ctx, "token")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestUserService_RequestPasswordReset(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockJobRepo := mock.NewMockJobRepo(ctrl)
+	ctx := context.Background()
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	expectMailJob(t, mockJobRepo, service.MailResetPassword, user.ID)
+
+	repo := &repository.Repository{User: mockUserRepo, Job: mockJobRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	err := userService.RequestPasswordReset(ctx, testEmail)
+	assert.NoError(t, err)
+}
+
+func TestUserService_RequestPasswordResetUnknownEmail(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockJobRepo := mock.NewMockJobRepo(ctrl)
+	ctx := context.Background()
+
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(nil, sql.ErrNoRows)
+	mockJobRepo.EXPECT().EnqueueJob(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, Job: mockJobRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	err := userService.RequestPasswordReset(ctx, testEmail)
+	assert.NoError(t, err, "Unknown emails should not be reported")
+}
+
+func TestUserService_ResetPassword(t *testing.T) {
+	const token = "token"
+
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockResetRepo := mock.NewMockPasswordResetRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockRefreshRepo := mock.NewMockRefreshTokenRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	ctx := context.Background()
+
+	mockHasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+	gomock.InOrder(
+		mockResetRepo.EXPECT().ConsumePasswordReset(ctx, security.HashToken(token)).Return("2", nil),
+		mockUserRepo.EXPECT().UpdateUserPassword(ctx, "2", testPassHashed).Return(nil),
+		mockSessionRepo.EXPECT().DeleteUserSessions(ctx, "2").Return(nil),
+		mockRefreshRepo.EXPECT().RevokeUserRefreshTokens(ctx, "2").Return(nil),
+	)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, PasswordReset: mockResetRepo,
+		RefreshToken: mockRefreshRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
+
+	err := userService.ResetPassword(ctx, service.ResetPasswordParams{Token: token, Password: testPass})
+	assert.NoError(t, err)
+}
+
+func TestUserService_ResetPasswordInvalidToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockResetRepo := mock.NewMockPasswordResetRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	ctx := context.Background()
+
+	mockHasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+	// Unknown, expired or already used
+	mockResetRepo.EXPECT().ConsumePasswordReset(ctx, gomock.Any()).Return("", sql.ErrNoRows)
+	mockUserRepo.EXPECT().UpdateUserPassword(gomock.Any(), gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, PasswordReset: mockResetRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
+
+	err := userService.ResetPassword(ctx, service.ResetPasswordParams{Token: "token", Password: testPass})
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestUserService_LoginUserDisabled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+
+	now := time.Now()
+	user := &model.User{
+		Model:        model.Model{ID: "1"},
+		Email:        testEmail,
+		PasswordHash: testPassHashed,
+		VerifiedAt:   &now,
+		DisabledAt:   &now,
+	}
+
+	ctx := context.Background()
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Verify(testPass, testPassHashed).Return(true, nil)
+	mockSessionRepo.EXPECT().CreateSession(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, LoginAttempt: repository.NewMemoryLoginAttemptRepository()}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{Lockout: *lockoutCfg})
+
+	_, err := userService.LoginUser(ctx, service.LoginUserParams{Email: testEmail, Password: testPass})
+	assert.ErrorIs(t, err, service.ErrUserDisabled)
+}
+
+func TestUserService_CreateUserVerified(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockJobRepo := mock.NewMockJobRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	ctx := context.Background()
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	mockHasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(nil, sql.ErrNoRows)
+	mockUserRepo.EXPECT().CreateUser(ctx, repository.CreateUserParams{Email: testEmail, PasswordHash: testPassHashed}).
+		Return(user, nil)
+	mockUserRepo.EXPECT().MarkUserVerified(ctx, user.ID).Return(nil)
+	mockJobRepo.EXPECT().EnqueueJob(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo, Job: mockJobRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
+
+	created, err := userService.CreateUser(ctx, service.CreateUserParams{Email: testEmail, Password: testPass, Verified: true})
+	assert.NoError(t, err)
+	assert.NotNil(t, created.VerifiedAt)
+}
+
+func TestUserService_SetUserPassword(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	mockSessionRepo := mock.NewMockSessionRepo(ctrl)
+	mockRefreshRepo := mock.NewMockRefreshTokenRepo(ctrl)
+	mockHasher := secMock.NewMockHasher(ctrl)
+	ctx := context.Background()
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(user, nil)
+	mockHasher.EXPECT().Hash(testPass).Return(testPassHashed, nil)
+	mockUserRepo.EXPECT().UpdateUserPassword(ctx, user.ID, testPassHashed).Return(nil)
+	mockSessionRepo.EXPECT().DeleteUserSessions(ctx, user.ID).Return(nil)
+	mockRefreshRepo.EXPECT().RevokeUserRefreshTokens(ctx, user.ID).Return(nil)
+
+	repo := &repository.Repository{User: mockUserRepo, Session: mockSessionRepo, RefreshToken: mockRefreshRepo}
+	userService := service.NewUserService(repo, mockHasher, &config.Config{})
+
+	assert.NoError(t, userService.SetUserPassword(ctx, testEmail, testPass))
+}
+
+func TestUserService_DisableUnknownUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	ctx := context.Background()
+
+	mockUserRepo.EXPECT().FindUserByEmail(ctx, testEmail).Return(nil, sql.ErrNoRows)
+	mockUserRepo.EXPECT().DisableUser(gomock.Any(), gomock.Any()).Times(0)
+
+	repo := &repository.Repository{User: mockUserRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	assert.ErrorIs(t, userService.DisableUser(ctx, testEmail), service.ErrUserNotFound)
+}
+
+func TestUserService_AuthenticateSession(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	ctx := context.Background()
+
+	user := &model.User{Model: model.Model{ID: "1"}, Email: testEmail}
+	mockUserRepo.EXPECT().FindUserBySessionTokenHash(ctx, security.HashToken("valid")).Return(user, nil)
+	mockUserRepo.EXPECT().FindUserBySessionTokenHash(ctx, security.HashToken("expired")).Return(nil, sql.ErrNoRows)
+
+	repo := &repository.Repository{User: mockUserRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	got, err := userService.AuthenticateSession(ctx, "valid")
+	assert.NoError(t, err)
+	assert.Equal(t, user, got)
+
+	_, err = userService.AuthenticateSession(ctx, "expired")
+	assert.ErrorIs(t, err, service.ErrInvalidToken)
+}
+
+func TestUserService_ImportUsers(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	mockUserRepo := mock.NewMockUserRepo(ctrl)
+	ctx := context.Background()
+
+	verifiedAt := time.Now()
+	users := []service.ImportUserParams{
+		{Email: testEmail, PasswordHash: "$2b$10$hash", VerifiedAt: &verifiedAt},
+		{Email: "taken@example.com", PasswordHash: "$pbkdf2-sha256$29000$salt$key"},
+	}
+	mockUserRepo.EXPECT().ImportUser(ctx, repository.ImportUserParams{
+		Email: testEmail, PasswordHash: "$2b$10$hash", VerifiedAt: &verifiedAt,
+	}).Return(true, nil)
+	mockUserRepo.EXPECT().ImportUser(ctx, repository.ImportUserParams{
+		Email: "taken@example.com", PasswordHash: "$pbkdf2-sha256$29000$salt$key",
+	}).Return(false, nil)
+
+	repo := &repository.Repository{User: mockUserRepo}
+	userService := service.NewUserService(repo, nil, &config.Config{})
+
+	result, err := userService.ImportUsers(ctx, users)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, result.Imported)
+	assert.Equal(t, []string{"taken@example.com"}, result.Skipped)
+}
@@ -0,0 +1,21 @@
+package worker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/service"
+)
+
+// MailHandler sends jobs whose payload is a service.MailJob.
+func MailHandler(mails service.MailService) Handler {
+	return func(ctx context.Context, job *model.Job) error {
+		var mj service.MailJob
+		if err := json.Unmarshal(job.Payload, &mj); err != nil {
+			return fmt.Errorf("decode mail payload: %w", err)
+		}
+		return mails.SendMail(ctx, mj)
+	}
+}
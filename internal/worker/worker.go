@@ -0,0 +1,225 @@
+package worker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository"
+)
+
+// Handler performs the side effect of a job. A returned error schedules a retry.
+type Handler func(ctx context.Context, job *model.Job) error
+
+// Pool runs jobs from the Postgres queue on a fixed number of workers.
+type Pool struct {
+	repo     repository.JobRepo
+	cfg      config.WorkerConfig
+	handlers map[string]Handler
+	// Identifies the process in the locks of its workers
+	id string
+	wg sync.WaitGroup
+}
+
+func NewPool(repo repository.JobRepo, cfg config.WorkerConfig) *Pool {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unknown"
+	}
+	return &Pool{
+		repo:     repo,
+		cfg:      cfg,
+		handlers: make(map[string]Handler),
+		id:       fmt.Sprintf("%s:%d", host, os.Getpid()),
+	}
+}
+
+// Register sets the handler for jobs of the given kind. It must be called before Start.
+func (p *Pool) Register(kind string, h Handler) {
+	p.handlers[kind] = h
+}
+
+// Start launches the workers. They stop claiming jobs once ctx is done.
+func (p *Pool) Start(ctx context.Context) {
+	slog.Info("Starting workers...", slog.Int("concurrency", p.cfg.Concurrency))
+
+	for i := range p.cfg.Concurrency {
+		p.wg.Add(1)
+		go func() {
+			defer p.wg.Done()
+			p.work(ctx, fmt.Sprintf("%s:%d", p.id, i))
+		}()
+	}
+
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		p.reap(ctx)
+	}()
+}
+
+// Shutdown waits for in-flight jobs to finish or for ctx to expire.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		slog.Info("Workers stopped.")
+		return nil
+	case <-ctx.Done():
+		return fmt.Errorf("workers forced to stop: %w", ctx.Err())
+	}
+}
+
+// Runs jobs as the worker workerID, which holds the lock of the jobs it claims.
+func (p *Pool) work(ctx context.Context, workerID string) {
+	pollInterval := time.Duration(p.cfg.PollInterval) * time.Second
+
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+
+		jobs, err := p.repo.ClaimJobs(ctx, workerID, 1)
+		if err != nil && !errors.Is(err, context.Canceled) {
+			slog.Error("failed to claim jobs", "reason", err)
+		}
+
+		if len(jobs) == 0 {
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(pollInterval):
+			}
+			continue
+		}
+
+		for i := range jobs {
+			// In-flight jobs are allowed to finish after shutdown has been requested.
+			p.process(context.WithoutCancel(ctx), workerID, &jobs[i])
+		}
+	}
+}
+
+func (p *Pool) process(ctx context.Context, workerID string, job *model.Job) {
+	h, ok := p.handlers[job.Kind]
+	if !ok {
+		p.kill(ctx, workerID, job, fmt.Errorf("no handler registered for job kind %q", job.Kind))
+		return
+	}
+
+	jobCtx, cancel := context.WithTimeout(ctx, time.Duration(p.cfg.JobTimeout)*time.Second)
+	defer cancel()
+
+	if err := run(jobCtx, h, job); err != nil {
+		if job.Attempts >= p.cfg.MaxAttempts {
+			p.kill(ctx, workerID, job, err)
+			return
+		}
+
+		runAt := time.Now().Add(p.Backoff(job.Attempts))
+		slog.Warn("job failed, retrying", "id", job.ID, "kind", job.Kind,
+			slog.Int("attempts", job.Attempts), "run_at", runAt, "reason", err)
+		ok, err := p.repo.RetryJob(ctx, job.ID, workerID, runAt, err.Error())
+		if err != nil {
+			slog.Error("failed to reschedule job", "id", job.ID, "reason", err)
+		} else if !ok {
+			lockLost(job, "reschedule")
+		}
+		return
+	}
+
+	ok, err := p.repo.CompleteJob(ctx, job.ID, workerID)
+	if err != nil {
+		slog.Error("failed to complete job", "id", job.ID, "reason", err)
+	} else if !ok {
+		lockLost(job, "complete")
+	}
+}
+
+// Logs that the lock of job was reaped before the worker could settle it, so
+// the outcome of this attempt is dropped.
+func lockLost(job *model.Job, action string) {
+	slog.Warn("job lock lost, "+action+" dropped", "id", job.ID, "kind", job.Kind,
+		slog.Int("attempts", job.Attempts))
+}
+
+// Runs the handler turning a panic into an error so a bad job cannot take down the worker.
+func run(ctx context.Context, h Handler, job *model.Job) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("job panicked: %v", r)
+		}
+	}()
+	return h(ctx, job)
+}
+
+// Moves a job to the dead letter state where it is kept for inspection.
+func (p *Pool) kill(ctx context.Context, workerID string, job *model.Job, reason error) {
+	slog.Error("job failed permanently", "id", job.ID, "kind", job.Kind,
+		slog.Int("attempts", job.Attempts), "reason", reason)
+	ok, err := p.repo.KillJob(ctx, job.ID, workerID, reason.Error())
+	if err != nil {
+		slog.Error("failed to dead-letter job", "id", job.ID, "reason", err)
+	} else if !ok {
+		lockLost(job, "dead-letter")
+	}
+}
+
+// Backoff returns the delay before the next attempt of a job that has been
+// attempted the given number of times.
+func (p *Pool) Backoff(attempts int) time.Duration {
+	base := time.Duration(p.cfg.BackoffBase) * time.Second
+	maxDelay := time.Duration(p.cfg.BackoffMax) * time.Second
+
+	delay := base
+	for i := 1; i < attempts; i++ {
+		delay *= 2
+		if delay >= maxDelay {
+			return maxDelay
+		}
+	}
+
+	return min(delay, maxDelay)
+}
+
+// Periodically requeues jobs whose worker died before finishing them. Jobs
+// that have used up their attempts are killed instead.
+func (p *Pool) reap(ctx context.Context) {
+	lockTimeout := time.Duration(p.cfg.LockTimeout) * time.Second
+	if lockTimeout <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(lockTimeout)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			result, err := p.repo.RequeueStaleJobs(ctx, time.Now().Add(-lockTimeout), p.cfg.MaxAttempts)
+			if err != nil {
+				slog.Error("failed to requeue stale jobs", "reason", err)
+				continue
+			}
+			if result.Requeued > 0 {
+				slog.Warn("requeued stale jobs", slog.Int("count", result.Requeued))
+			}
+			if result.Killed > 0 {
+				slog.Error("dead-lettered stale jobs out of attempts", slog.Int("count", result.Killed))
+			}
+		}
+	}
+}
@@ -0,0 +1,152 @@
+package worker_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ferdiebergado/goweb/internal/config"
+	"github.com/ferdiebergado/goweb/internal/model"
+	"github.com/ferdiebergado/goweb/internal/repository/mock"
+	"github.com/ferdiebergado/goweb/internal/worker"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/mock/gomock"
+)
+
+var cfg = config.WorkerConfig{
+	Concurrency:  1,
+	PollInterval: 1,
+	MaxAttempts:  3,
+	BackoffBase:  1,
+	BackoffMax:   10,
+	JobTimeout:   5,
+}
+
+// Runs the pool until the claimed job has been settled by one of the final
+// repo calls. It returns the worker that claimed the job.
+func runOnce(t *testing.T, repo *mock.MockJobRepo, job model.Job, kind string, h worker.Handler) string {
+	t.Helper()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var claimedBy string
+	repo.EXPECT().ClaimJobs(gomock.Any(), gomock.Any(), 1).
+		DoAndReturn(func(_ context.Context, workerID string, _ int) ([]model.Job, error) {
+			claimedBy = workerID
+			return []model.Job{job}, nil
+		})
+	repo.EXPECT().ClaimJobs(gomock.Any(), gomock.Any(), 1).
+		DoAndReturn(func(context.Context, string, int) ([]model.Job, error) {
+			cancel()
+			return nil, context.Canceled
+		}).AnyTimes()
+
+	pool := worker.NewPool(repo, cfg)
+	pool.Register(kind, h)
+	pool.Start(ctx)
+
+	<-ctx.Done()
+	shutdownCtx, stop := context.WithTimeout(context.Background(), time.Second)
+	defer stop()
+	assert.NoError(t, pool.Shutdown(shutdownCtx))
+	return claimedBy
+}
+
+func TestPool_CompletesJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockJobRepo(ctrl)
+
+	job := model.Job{ID: "1", Kind: "test", Attempts: 1}
+	var completedBy string
+	repo.EXPECT().CompleteJob(gomock.Any(), "1", gomock.Any()).
+		DoAndReturn(func(_ context.Context, _, workerID string) (bool, error) {
+			completedBy = workerID
+			return true, nil
+		})
+
+	var ran bool
+	claimedBy := runOnce(t, repo, job, "test", func(context.Context, *model.Job) error {
+		ran = true
+		return nil
+	})
+	assert.True(t, ran)
+	assert.NotEmpty(t, claimedBy)
+	assert.Equal(t, claimedBy, completedBy, "Only the worker holding the job may complete it")
+}
+
+func TestPool_LostLock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockJobRepo(ctrl)
+
+	// The lock was reaped and the job claimed again while it ran.
+	job := model.Job{ID: "1", Kind: "test", Attempts: 1}
+	repo.EXPECT().CompleteJob(gomock.Any(), "1", gomock.Any()).Return(false, nil)
+
+	runOnce(t, repo, job, "test", func(context.Context, *model.Job) error {
+		return nil
+	})
+}
+
+func TestPool_RetriesFailedJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockJobRepo(ctrl)
+
+	job := model.Job{ID: "1", Kind: "test", Attempts: 1}
+	start := time.Now()
+	repo.EXPECT().RetryJob(gomock.Any(), "1", gomock.Any(), gomock.Any(), "boom").
+		DoAndReturn(func(_ context.Context, _, _ string, runAt time.Time, _ string) (bool, error) {
+			assert.WithinDuration(t, start.Add(time.Second), runAt, 500*time.Millisecond)
+			return true, nil
+		})
+
+	runOnce(t, repo, job, "test", func(context.Context, *model.Job) error {
+		return errors.New("boom")
+	})
+}
+
+func TestPool_DeadLettersExhaustedJob(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockJobRepo(ctrl)
+
+	job := model.Job{ID: "1", Kind: "test", Attempts: cfg.MaxAttempts}
+	repo.EXPECT().KillJob(gomock.Any(), "1", gomock.Any(), "job panicked: boom").Return(true, nil)
+
+	runOnce(t, repo, job, "test", func(context.Context, *model.Job) error {
+		panic("boom")
+	})
+}
+
+func TestPool_DeadLettersUnknownKind(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	repo := mock.NewMockJobRepo(ctrl)
+
+	job := model.Job{ID: "1", Kind: "unknown", Attempts: 1}
+	repo.EXPECT().KillJob(gomock.Any(), "1", gomock.Any(), gomock.Any()).Return(true, nil)
+
+	runOnce(t, repo, job, "test", func(context.Context, *model.Job) error {
+		t.Error("handler should not be called")
+		return nil
+	})
+}
+
+func TestPool_Backoff(t *testing.T) {
+	pool := worker.NewPool(nil, cfg)
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{20, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, pool.Backoff(tt.attempts), "attempts=%d", tt.attempts)
+	}
+}
@@ -1,6 +1,18 @@
 import Alpine from 'alpinejs';
-import { regForm } from './components';
+import {
+  regForm,
+  loginForm,
+  forgotPasswordForm,
+  resetPasswordForm,
+  twoFactorForm,
+  twoFactorSetup,
+} from './components';
 
 Alpine.data('regForm', regForm);
+Alpine.data('loginForm', loginForm);
+Alpine.data('forgotPasswordForm', forgotPasswordForm);
+Alpine.data('resetPasswordForm', resetPasswordForm);
+Alpine.data('twoFactorForm', twoFactorForm);
+Alpine.data('twoFactorSetup', twoFactorSetup);
 
 Alpine.start();
@@ -0,0 +1,48 @@
+import type { FormErrors } from '../@types/form';
+import { isValidEmail } from '../utils';
+import form from './form';
+import urls from '../endpoints';
+
+type Values = {
+  email: string;
+};
+
+type Errors = FormErrors<Values>;
+
+function validateFormValues(data: Values): Errors {
+  const { email } = data;
+  const formErrors: Errors = {};
+
+  if (!email) {
+    formErrors.email = 'Email is required.';
+  } else if (!isValidEmail(email)) {
+    formErrors.email = 'Invalid email format.';
+  }
+
+  return formErrors;
+}
+
+export default function () {
+  const data: Values = {
+    email: '',
+  };
+
+  const errors: Errors = {
+    email: '',
+  };
+
+  return form({
+    data,
+    submitUrl: urls.forgotPassword,
+    errors,
+    validateFn() {
+      return validateFormValues(this.data as Values);
+    },
+    onSuccess() {
+      return;
+    },
+    onError() {
+      return;
+    },
+  });
+}
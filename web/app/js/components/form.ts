@@ -1,5 +1,6 @@
 import type { APIResponse } from '../@types/api';
 import type { FormOptions } from '../@types/form';
+import { csrfToken } from '../utils';
 
 export default function (opts: FormOptions) {
   const { data, method, submitUrl, errors, validateFn, onSuccess, onError } =
@@ -31,7 +32,10 @@ export default function (opts: FormOptions) {
       try {
         const response = await fetch(this.submitUrl, {
           method: this.method,
-          headers: { 'Content-Type': 'application/json' },
+          headers: {
+            'Content-Type': 'application/json; charset=utf-8',
+            'X-CSRF-Token': csrfToken(),
+          },
           body: JSON.stringify(this.data),
         });
 
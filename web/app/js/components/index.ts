@@ -1,3 +1,15 @@
 import regForm from './reg_form';
+import loginForm from './login_form';
+import forgotPasswordForm from './forgot_password_form';
+import resetPasswordForm from './reset_password_form';
+import twoFactorForm from './two_factor_form';
+import twoFactorSetup from './two_factor_setup';
 
-export { regForm };
+export {
+  regForm,
+  loginForm,
+  forgotPasswordForm,
+  resetPasswordForm,
+  twoFactorForm,
+  twoFactorSetup,
+};
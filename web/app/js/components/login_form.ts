@@ -0,0 +1,67 @@
+import type { FormErrors } from '../@types/form';
+import { isValidEmail } from '../utils';
+import form from './form';
+import urls from '../endpoints';
+
+// Session storage key of the token that carries a login over to the second
+// step when the user has two-factor authentication enabled.
+export const challengeTokenKey = 'challengeToken';
+
+type Values = {
+  email: string;
+  password: string;
+};
+
+type Errors = FormErrors<Values>;
+
+function validateFormValues(data: Values): Errors {
+  const { email, password } = data;
+  const formErrors: Errors = {};
+
+  if (!email) {
+    formErrors.email = 'Email is required.';
+  } else if (!isValidEmail(email)) {
+    formErrors.email = 'Invalid email format.';
+  }
+
+  if (!password) {
+    formErrors.password = 'Password is required.';
+  }
+
+  return formErrors;
+}
+
+export default function () {
+  const data: Values = {
+    email: '',
+    password: '',
+  };
+
+  const errors: Errors = {
+    email: '',
+    password: '',
+  };
+
+  return form({
+    data,
+    submitUrl: urls.login,
+    errors,
+    validateFn() {
+      return validateFormValues(this.data as Values);
+    },
+    onSuccess({ data }) {
+      if (data.two_factor_required) {
+        sessionStorage.setItem(
+          challengeTokenKey,
+          data.challenge_token as string,
+        );
+        window.location.assign('/auth/two-factor');
+        return;
+      }
+      window.location.assign('/dashboard');
+    },
+    onError() {
+      return;
+    },
+  });
+}
@@ -0,0 +1,56 @@
+import type { FormErrors } from '../@types/form';
+import form from './form';
+import urls from '../endpoints';
+
+type Values = {
+  token: string;
+  password: string;
+  password_confirm: string;
+};
+
+type Errors = FormErrors<Values>;
+
+function validateFormValues(data: Values): Errors {
+  const { password, password_confirm } = data;
+  const formErrors: Errors = {};
+
+  if (!password) {
+    formErrors.password = 'Password is required.';
+  }
+
+  if (!password_confirm) {
+    formErrors.password_confirm = 'Password confirmation is required.';
+  } else if (password && password_confirm !== password) {
+    formErrors.password_confirm = 'Passwords should match.';
+  }
+
+  return formErrors;
+}
+
+export default function () {
+  const data: Values = {
+    token: new URLSearchParams(window.location.search).get('token') ?? '',
+    password: '',
+    password_confirm: '',
+  };
+
+  const errors: Errors = {
+    password: '',
+    password_confirm: '',
+  };
+
+  return form({
+    data,
+    submitUrl: urls.resetPassword,
+    errors,
+    validateFn() {
+      return validateFormValues(this.data as Values);
+    },
+    onSuccess() {
+      window.location.assign('/auth/login');
+    },
+    onError() {
+      return;
+    },
+  });
+}
@@ -0,0 +1,62 @@
+import type { FormErrors } from '../@types/form';
+import form from './form';
+import urls from '../endpoints';
+import { challengeTokenKey } from './login_form';
+
+type Values = {
+  challenge_token: string;
+  code: string;
+};
+
+type Errors = FormErrors<Values>;
+
+function validateFormValues(data: Values): Errors {
+  const formErrors: Errors = {};
+
+  if (!data.code) {
+    formErrors.code = 'Code is required.';
+  }
+
+  return formErrors;
+}
+
+// Signing in with an identity provider passes the challenge in the fragment.
+function takeChallengeFromHash() {
+  const params = new URLSearchParams(window.location.hash.slice(1));
+  const token = params.get('challenge');
+  if (!token) return;
+
+  sessionStorage.setItem(challengeTokenKey, token);
+  history.replaceState(null, '', window.location.pathname);
+}
+
+export default function () {
+  takeChallengeFromHash();
+  const challengeToken = sessionStorage.getItem(challengeTokenKey);
+  if (!challengeToken) window.location.assign('/auth/login');
+
+  const data: Values = {
+    challenge_token: challengeToken ?? '',
+    code: '',
+  };
+
+  const errors: Errors = {
+    code: '',
+  };
+
+  return form({
+    data,
+    submitUrl: urls.loginTwoFactor,
+    errors,
+    validateFn() {
+      return validateFormValues(this.data as Values);
+    },
+    onSuccess() {
+      sessionStorage.removeItem(challengeTokenKey);
+      window.location.assign('/dashboard');
+    },
+    onError() {
+      return;
+    },
+  });
+}
@@ -0,0 +1,84 @@
+import type { APIResponse } from '../@types/api';
+import urls from '../endpoints';
+import { csrfToken } from '../utils';
+
+type Enrollment = {
+  secret: string;
+  uri: string;
+};
+
+async function post(url: string, body?: unknown): Promise<APIResponse> {
+  const response = await fetch(url, {
+    method: 'POST',
+    headers: {
+      'Content-Type': 'application/json; charset=utf-8',
+      'X-CSRF-Token': csrfToken(),
+    },
+    body: body === undefined ? undefined : JSON.stringify(body),
+  });
+
+  const data: APIResponse = await response.json();
+  if (!response.ok) throw new Error(data.message);
+
+  return data;
+}
+
+export default function (enabled: boolean) {
+  return {
+    enabled,
+    enrollment: null as Enrollment | null,
+    qrCodeUrl: '',
+    recoveryCodes: [] as string[],
+    data: { code: '' },
+    errors: { code: '' },
+    isSubmitting: false,
+    isValid: true,
+    message: '',
+    async run(fn: () => Promise<void>): Promise<void> {
+      this.isSubmitting = true;
+      this.isValid = true;
+      this.message = '';
+
+      try {
+        await fn();
+      } catch (error) {
+        console.error(error);
+        this.isValid = false;
+        if (error instanceof Error) this.message = error.message;
+      } finally {
+        this.isSubmitting = false;
+      }
+    },
+    async enroll(): Promise<void> {
+      await this.run(async () => {
+        const { data } = await post(urls.twoFactorEnroll);
+        this.enrollment = data as Enrollment;
+        // Every enrollment has a new secret so the image must not be cached.
+        this.qrCodeUrl = `${urls.twoFactorQRCode}?t=${Date.now()}`;
+      });
+    },
+    async submit(): Promise<void> {
+      this.errors.code = this.data.code ? '' : 'Code is required.';
+      if (this.errors.code) return;
+
+      await this.run(async () => {
+        if (this.enabled) {
+          const { message } = await post(urls.twoFactorDisable, this.data);
+          this.enabled = false;
+          this.recoveryCodes = [];
+          this.message = message;
+        } else {
+          const { message, data } = await post(
+            urls.twoFactorConfirm,
+            this.data,
+          );
+          this.enabled = true;
+          this.enrollment = null;
+          this.recoveryCodes = data.recovery_codes as string[];
+          this.message = message;
+        }
+        this.data.code = '';
+      });
+    },
+  };
+}
@@ -1,3 +1,12 @@
 export default {
   register: '/api/auth/register',
+  login: '/api/auth/login',
+  loginTwoFactor: '/api/auth/login/2fa',
+  logout: '/api/auth/logout',
+  forgotPassword: '/api/auth/forgot-password',
+  resetPassword: '/api/auth/reset-password',
+  twoFactorEnroll: '/api/auth/2fa/enroll',
+  twoFactorQRCode: '/api/auth/2fa/qr.png',
+  twoFactorConfirm: '/api/auth/2fa/confirm',
+  twoFactorDisable: '/api/auth/2fa/disable',
 };
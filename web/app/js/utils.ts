@@ -2,3 +2,12 @@ export function isValidEmail(email: string): boolean {
   const emailRegex = /^[^\s@]+@[^\s@]+\.[^\s@]+$/;
   return emailRegex.test(email);
 }
+
+// Returns the CSRF token that the server rendered into the page. It has to
+// be sent with every request that is not a GET.
+export function csrfToken(): string {
+  const meta = document.querySelector<HTMLMetaElement>(
+    'meta[name="csrf-token"]',
+  );
+  return meta?.content ?? '';
+}